@@ -21,7 +21,7 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-var AllKinds = []string{"Topology", "Scenario", "Experiment", "Image", "User", "Role"}
+var AllKinds = []string{"Topology", "Scenario", "Experiment", "Image", "User", "Role", "Webhook"}
 
 var NameRegex = regexp.MustCompile(`^[a-zA-Z0-9_@.-]*$`)
 
@@ -177,6 +177,8 @@ func List(which string) (store.Configs, error) {
 		configs, err = store.List("User")
 	case "role":
 		configs, err = store.List("Role")
+	case "webhook":
+		configs, err = store.List("Webhook")
 	default:
 		return nil, util.HumanizeError(fmt.Errorf("unknown config kind provided: %s", which), "")
 	}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"phenix/api/config"
+	"phenix/api/webhook"
 	"phenix/app"
 	"phenix/scheduler"
 	"phenix/store"
@@ -23,6 +24,7 @@ import (
 	"phenix/util/mm"
 	"phenix/util/mm/mmcli"
 	"phenix/util/notes"
+	"phenix/util/plog"
 	"phenix/util/pubsub"
 
 	"github.com/activeshadow/structs"
@@ -31,6 +33,8 @@ import (
 )
 
 func init() {
+	pubsub.Share("delayed-start", "")
+
 	config.RegisterConfigHook("Experiment", func(stage string, c *store.Config) error {
 		exp, err := types.DecodeExperimentFromConfig(*c)
 		if err != nil {
@@ -43,11 +47,13 @@ func init() {
 
 			exp.Spec.Init()
 
-			if err := exp.Spec.VerifyScenario(context.TODO()); err != nil {
+			ctx := plog.WithExperiment(context.Background(), c.Metadata.Name)
+
+			if err := exp.Spec.VerifyScenario(ctx); err != nil {
 				return fmt.Errorf("verifying experiment scenario: %w", err)
 			}
 
-			if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCONFIG)); err != nil {
+			if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONCONFIG)); err != nil {
 				return fmt.Errorf("applying apps to experiment: %w", err)
 			}
 
@@ -166,6 +172,8 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		return fmt.Errorf("no topology name provided")
 	}
 
+	ctx = plog.WithExperiment(ctx, o.name)
+
 	var (
 		kind       = "Experiment"
 		apiVersion = version.StoredVersion[kind]
@@ -252,6 +260,10 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		return fmt.Errorf("creating experiment config: %w", err)
 	}
 
+	plog.Info(ctx, "experiment created", "topology", o.topology, "scenario", o.scenario)
+
+	webhook.Fire(webhook.NewEvent(webhook.ExperimentCreate, meta.Name).WithAnnotations(meta.Annotations))
+
 	return nil
 }
 
@@ -294,6 +306,8 @@ func Schedule(opts ...ScheduleOption) error {
 func Start(ctx context.Context, opts ...StartOption) error {
 	o := newStartOptions(opts...)
 
+	ctx = plog.WithExperiment(ctx, o.name)
+
 	c, _ := store.NewConfig("experiment/" + o.name)
 
 	if err := store.Get(c); err != nil {
@@ -427,6 +441,19 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			}
 		}
 
+		if err := applyImpairments(exp); err != nil {
+			if !o.mmErrAsWarn {
+				mm.ClearNamespace(exp.Spec.ExperimentName())
+				return fmt.Errorf("applying network impairments: %w", err)
+			}
+
+			if merr, ok := err.(*multierror.Error); ok {
+				notes.AddWarnings(ctx, false, merr.Errors...)
+			} else {
+				notes.AddWarnings(ctx, false, err)
+			}
+		}
+
 		schedule := make(map[string]string)
 
 		for _, vm := range mm.GetVMInfo(mm.NS(exp.Spec.ExperimentName())) {
@@ -466,7 +493,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
 				errors := multierror.Append(nil, fmt.Errorf("applying apps to experiment: %w", err))
 
-				if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(o.dryrun)); err != nil {
+				if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(o.dryrun)); err != nil {
 					errors = multierror.Append(errors, fmt.Errorf("cleaning up app experiments: %w", err))
 				}
 
@@ -484,14 +511,14 @@ func Start(ctx context.Context, opts ...StartOption) error {
 					if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
 						o.errChan <- fmt.Errorf("applying apps to experiment: %w", err)
 
-						if err := Stop(exp.Spec.ExperimentName()); err != nil {
+						if err := Stop(ctx, exp.Spec.ExperimentName()); err != nil {
 							o.errChan <- fmt.Errorf("stopping experiment: %w", err)
 						}
 					}
 				} else {
 					o.errChan <- fmt.Errorf("handling delayed VMs: %w", err)
 
-					if err := Stop(exp.Spec.ExperimentName()); err != nil {
+					if err := Stop(ctx, exp.Spec.ExperimentName()); err != nil {
 						o.errChan <- fmt.Errorf("stopping experiment: %w", err)
 					}
 				}
@@ -511,12 +538,24 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		return fmt.Errorf("updating experiment config: %w", err)
 	}
 
+	plog.Info(ctx, "experiment started", "dryrun", o.dryrun)
+
+	event := webhook.NewEvent(webhook.ExperimentStart, exp.Metadata.Name).WithAnnotations(exp.Metadata.Annotations)
+
+	if o.dryrun {
+		event.WithData("dryrun", true)
+	}
+
+	webhook.Fire(event)
+
 	return nil
 }
 
 // Stop stops the experiment with the given name. It returns any errors
 // encountered while stopping the experiment.
-func Stop(name string) error {
+func Stop(ctx context.Context, name string) error {
+	ctx = plog.WithExperiment(ctx, name)
+
 	c, _ := store.NewConfig("experiment/" + name)
 
 	if err := store.Get(c); err != nil {
@@ -536,7 +575,7 @@ func Stop(name string) error {
 
 	var errors error
 
-	if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(dryrun)); err != nil {
+	if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(dryrun)); err != nil {
 		errors = multierror.Append(errors, fmt.Errorf("cleaning up app experiments: %w", err))
 	}
 
@@ -555,6 +594,17 @@ func Stop(name string) error {
 		errors = multierror.Append(errors, fmt.Errorf("updating experiment config: %w", err))
 	}
 
+	event := webhook.NewEvent(webhook.ExperimentStop, exp.Metadata.Name).WithAnnotations(exp.Metadata.Annotations)
+
+	if errors != nil {
+		plog.Error(ctx, "experiment stopped with errors", "error", errors)
+		event.WithData("error", errors.Error())
+	} else {
+		plog.Info(ctx, "experiment stopped")
+	}
+
+	webhook.Fire(event)
+
 	return errors
 }
 
@@ -644,7 +694,7 @@ func Reconfigure(name string) error {
 		return fmt.Errorf("experiment is running")
 	}
 
-	if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCONFIG)); err != nil {
+	if err := app.ApplyApps(plog.WithExperiment(context.Background(), name), exp, app.Stage(app.ACTIONCONFIG)); err != nil {
 		return fmt.Errorf("configuring apps for experiment: %w", err)
 	}
 
@@ -697,6 +747,8 @@ func Delete(name string) error {
 		return fmt.Errorf("deleting experiment %s: %w", name, err)
 	}
 
+	webhook.Fire(webhook.NewEvent(webhook.ExperimentDelete, name).WithAnnotations(c.Metadata.Annotations))
+
 	exp, err := types.DecodeExperimentFromConfig(*c)
 	if err != nil {
 		return fmt.Errorf("decoding experiment from config: %w", err)
@@ -792,6 +844,72 @@ func deleteC2AndSnapshots(exp *types.Experiment) error {
 	return nil
 }
 
+// applyImpairments applies the network impairments declared in the topology to
+// the interfaces of all bootable VMs in the given experiment. Impairments
+// declared on an interface take precedence over those declared for the VLAN the
+// interface is connected to.
+func applyImpairments(exp *types.Experiment) error {
+	var (
+		topo = exp.Spec.Topology()
+		errs error
+	)
+
+	for _, node := range topo.BootableNodes() {
+		// The VM's info (e.g. its taps) is only looked up once, and only if one
+		// of its interfaces is impaired.
+		var info *mm.VM
+
+		for idx, iface := range node.Network().Interfaces() {
+			imp := iface.Impairment()
+
+			if imp == nil {
+				imp = topo.VLANImpairment(iface.VLAN())
+			}
+
+			if imp == nil {
+				continue
+			}
+
+			settings := mm.Impairment{
+				Delay:      imp.Delay(),
+				Jitter:     imp.Jitter(),
+				Loss:       imp.Loss(),
+				Corruption: imp.Corruption(),
+				Reordering: imp.Reordering(),
+				Rate:       imp.Rate(),
+			}
+
+			if settings.Empty() {
+				continue
+			}
+
+			opts := []mm.Option{
+				mm.NS(exp.Spec.ExperimentName()),
+				mm.VMName(node.General().Hostname()),
+			}
+
+			if info == nil {
+				vms := mm.GetVMInfo(opts...)
+
+				if len(vms) == 0 {
+					errs = multierror.Append(errs, fmt.Errorf("getting info for VM %s: %w", node.General().Hostname(), mm.ErrVMNotFound))
+					break
+				}
+
+				info = &vms[0]
+			}
+
+			opts = append(opts, mm.ImpairInterface(idx), mm.ImpairmentSettings(settings), mm.ImpairVMInfo(*info))
+
+			if err := mm.ImpairVMInterface(opts...); err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
+
 func handleDelayedVMs(ctx context.Context, ns string, delays map[string]time.Duration, c2s map[string]map[string]bool) error {
 	if len(delays) == 0 && len(c2s) == 0 {
 		return nil
@@ -12,6 +12,7 @@ import (
 	"os/exec"
 	"path"
 	"strings"
+	"time"
 
 	"phenix/store"
 	"phenix/tmpl"
@@ -219,9 +220,19 @@ func CreateFromConfig(name, saveas string, overlays, packages, scripts []string)
 // application is in the `$PATH`. Any errors encountered will be returned during
 // the process of getting an existing image configuration, decoding it,
 // generating the `vmdb` verbosconfiguration file, or executing the `vmdb` command.
-func Build(ctx context.Context, name string, verbosity int, cache bool, dryrun bool, output string) error {
-	var img v1.Image
-	var filename string
+//
+// Each image built from an image configuration gets a manifest, written next to
+// the image and stored in the image configuration's status, recording what
+// went into the image. Built images are cached by the digest of their manifest,
+// so building an unchanged image configuration copies the cached image instead
+// of rebuilding it unless `rebuild` is set.
+func Build(ctx context.Context, name string, verbosity int, cache bool, rebuild bool, dryrun bool, output string) error {
+	var (
+		img      v1.Image
+		filename string
+		config   *store.Config
+		manifest *v1.ImageManifest
+	)
 
 	if strings.Contains(name, ".vmdb") {
 		filename = name
@@ -237,6 +248,18 @@ func Build(ctx context.Context, name string, verbosity int, cache bool, dryrun b
 			return fmt.Errorf("decoding image spec: %w", err)
 		}
 
+		config = c
+
+		if !dryrun {
+			var err error
+
+			if manifest, err = newManifest(img); err != nil {
+				return fmt.Errorf("creating image manifest: %w", err)
+			}
+
+			img.PackagesFile = output + "/" + name + ".packages"
+		}
+
 		if verbosity >= V_VVVERBOSE {
 			img.VerboseLogs = true
 		}
@@ -255,6 +278,32 @@ func Build(ctx context.Context, name string, verbosity int, cache bool, dryrun b
 		}
 	}
 
+	// The cache only holds disk images, so images that also include a
+	// kernel/initrd pair are always built.
+	if manifest != nil && !rebuild && !img.Ramdisk {
+		disk := output + "/" + name
+
+		// The image may have been modified since it was built (for example, by
+		// injecting files into it), so it's only up to date if it still matches
+		// the checksum recorded when it was built.
+		if m, err := readManifest(disk + ".manifest.json"); err == nil && m.Digest == manifest.Digest && m.ImageHash != "" {
+			if hash, err := hashFile(disk); err == nil && hash == m.ImageHash {
+				fmt.Printf("Image %s is up to date (%s)\n", name, m.Digest)
+				return nil
+			}
+		}
+
+		cached, ok, err := fromCache(manifest, disk)
+		if err != nil {
+			return fmt.Errorf("getting image from cache: %w", err)
+		}
+
+		if ok {
+			fmt.Printf("Using cached image for %s (%s)\n", name, cached.Digest)
+			return saveManifest(config, disk, cached)
+		}
+	}
+
 	if !dryrun && !shell.CommandExists("vmdb2") {
 		return fmt.Errorf("vmdb2 app does not exist in your path")
 	}
@@ -310,6 +359,45 @@ func Build(ctx context.Context, name string, verbosity int, cache bool, dryrun b
 		if img.IncludeProtonuke {
 			notes.AddWarnings(ctx, false, fmt.Errorf("inject_protonuke setting is DEPRECATED - use 'image inject-miniexe' subcommand after image is built"))
 		}
+
+		if manifest != nil {
+			disk := output + "/" + name
+
+			if pkgs, err := readPackages(img.PackagesFile); err == nil {
+				manifest.Packages = pkgs
+				os.Remove(img.PackagesFile)
+			} else {
+				notes.AddWarnings(ctx, false, fmt.Errorf("unable to determine versions of packages installed in image: %w", err))
+			}
+
+			manifest.BuildTime = time.Now().UTC().Format(time.RFC3339)
+
+			if !img.Ramdisk {
+				if err := addToCache(manifest, disk); err != nil {
+					notes.AddWarnings(ctx, false, fmt.Errorf("unable to cache image: %w", err))
+				}
+			}
+
+			if err := saveManifest(config, disk, manifest); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// saveManifest writes the given manifest next to the given disk image and
+// updates the status of the given image configuration with it.
+func saveManifest(c *store.Config, disk string, m *v1.ImageManifest) error {
+	if err := writeManifest(disk+".manifest.json", m); err != nil {
+		return err
+	}
+
+	c.Status = structs.MapDefaultCase(v1.ImageStatus{Manifest: m}, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("updating image config status in store: %w", err)
 	}
 
 	return nil
@@ -335,6 +423,16 @@ func List() ([]types.Image, error) {
 
 		img := types.Image{Metadata: c.Metadata, Spec: spec}
 
+		if c.Status != nil {
+			status := new(v1.ImageStatus)
+
+			if err := mapstructure.Decode(c.Status, status); err != nil {
+				return nil, fmt.Errorf("decoding image status: %w", err)
+			}
+
+			img.Status = status
+		}
+
 		images = append(images, img)
 	}
 
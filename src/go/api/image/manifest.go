@@ -0,0 +1,434 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/store"
+	"phenix/tmpl"
+	v1 "phenix/types/version/v1"
+	"phenix/util/common"
+	"phenix/version"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// CacheDir returns the directory built images are cached in, addressed by the
+// digest of their manifest. Entries are never removed automatically; use
+// PruneCache (or `phenix image prune`) to remove them. It's relative to the
+// phenix base directory, which isn't known until the config is loaded.
+func CacheDir() string {
+	return filepath.Join(common.PhenixBase, "image-cache")
+}
+
+// Manifest returns the manifest of the most recent build of the given image.
+// If a manifest file exists for the given name (for example, when passed the
+// path to a built image) it's used, otherwise the manifest is retrieved from
+// the status of the named image configuration.
+func Manifest(name string) (*v1.ImageManifest, error) {
+	if m, err := readManifest(name + ".manifest.json"); err == nil {
+		return m, nil
+	}
+
+	c, _ := store.NewConfig("image/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting image config %s from store: %w", name, err)
+	}
+
+	var status v1.ImageStatus
+
+	if err := mapstructure.Decode(c.Status, &status); err != nil {
+		return nil, fmt.Errorf("decoding image status: %w", err)
+	}
+
+	if status.Manifest == nil {
+		return nil, fmt.Errorf("image %s has not been built", name)
+	}
+
+	return status.Manifest, nil
+}
+
+// newManifest creates a manifest for the given image configuration, hashing
+// the configuration, the vmdb configuration rendered for it, and the contents
+// of its overlays and scripts. Package versions aren't known until the image
+// is built, so only package names are included.
+func newManifest(img v1.Image) (*v1.ImageManifest, error) {
+	config, err := hashConfig(img)
+	if err != nil {
+		return nil, fmt.Errorf("hashing image config: %w", err)
+	}
+
+	template, err := hashTemplate(img)
+	if err != nil {
+		return nil, fmt.Errorf("hashing vmdb config: %w", err)
+	}
+
+	m := &v1.ImageManifest{
+		ConfigHash:   config,
+		TemplateHash: template,
+		Version:      version.Tag,
+		Mirror:       img.Mirror,
+		Release:      img.Release,
+		Variant:      img.Variant,
+		Format:       img.Format,
+	}
+
+	for _, pkg := range img.Packages {
+		m.Packages = append(m.Packages, v1.ImagePackage{Name: pkg})
+	}
+
+	for _, overlay := range img.Overlays {
+		hash, err := hashOverlay(overlay)
+		if err != nil {
+			return nil, fmt.Errorf("hashing overlay %s: %w", overlay, err)
+		}
+
+		m.Overlays = append(m.Overlays, v1.ImageFile{Name: overlay, Hash: hash})
+	}
+
+	for _, name := range img.ScriptOrder {
+		m.Scripts = append(m.Scripts, v1.ImageFile{Name: name, Hash: hashBytes([]byte(img.Scripts[name]))})
+	}
+
+	// The config hash covers everything in the config, including script
+	// contents, so only the overlays need to be added to the digest. The vmdb
+	// template (and how phenix builds images in general) can change between
+	// phenix versions without the config changing, so they're included too.
+	digest := sha256.New()
+	fmt.Fprintf(digest, "config %s\n", m.ConfigHash)
+	fmt.Fprintf(digest, "template %s\n", m.TemplateHash)
+	fmt.Fprintf(digest, "phenix %s\n", m.Version)
+
+	for _, overlay := range m.Overlays {
+		fmt.Fprintf(digest, "overlay %s %s\n", overlay.Name, overlay.Hash)
+	}
+
+	m.Digest = fmt.Sprintf("sha256:%x", digest.Sum(nil))
+
+	return m, nil
+}
+
+// hashConfig hashes the JSON encoding of the given image configuration. Fields
+// that only affect how an image is built (like verbosity) aren't encoded.
+func hashConfig(img v1.Image) (string, error) {
+	body, err := json.Marshal(img)
+	if err != nil {
+		return "", err
+	}
+
+	return hashBytes(body), nil
+}
+
+// hashTemplate hashes the vmdb configuration rendered for the given image
+// configuration. Fields that only affect how an image is built are cleared
+// first.
+func hashTemplate(img v1.Image) (string, error) {
+	img.Cache = false
+	img.VerboseLogs = false
+	img.PackagesFile = ""
+
+	var buf bytes.Buffer
+
+	if err := tmpl.GenerateFromTemplate("vmdb.tmpl", img, &buf); err != nil {
+		return "", err
+	}
+
+	return hashBytes(buf.Bytes()), nil
+}
+
+// hashOverlay hashes the name, mode, and contents of each file in the given
+// overlay directory.
+func hashOverlay(dir string) (string, error) {
+	h := sha256.New()
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s %s\n", rel, info.Mode())
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(h, "%s\n", target)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+
+			defer f.Close()
+
+			if _, err := io.Copy(h, f); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+func hashBytes(body []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// readPackages parses the package list written by `dpkg-query` when an image
+// is built. Each line contains a package name and version separated by a tab.
+func readPackages(path string) ([]v1.ImagePackage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	var (
+		pkgs    []v1.ImagePackage
+		scanner = bufio.NewScanner(f)
+	)
+
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 2)
+
+		if fields[0] == "" {
+			continue
+		}
+
+		pkg := v1.ImagePackage{Name: fields[0]}
+
+		if len(fields) == 2 {
+			pkg.Version = fields[1]
+		}
+
+		pkgs = append(pkgs, pkg)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Name < pkgs[j].Name })
+
+	return pkgs, nil
+}
+
+func readManifest(path string) (*v1.ImageManifest, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m v1.ImageManifest
+
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("decoding image manifest %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+func writeManifest(path string, m *v1.ImageManifest) error {
+	body, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding image manifest: %w", err)
+	}
+
+	if err := ioutil.WriteFile(path, body, 0644); err != nil {
+		return fmt.Errorf("writing image manifest %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// fromCache copies the image cached for the given manifest's digest, if there
+// is one, to the given path. It returns the manifest of the cached build. If
+// the cached image doesn't match the checksum recorded when it was cached, the
+// cache entry is removed.
+func fromCache(m *v1.ImageManifest, dst string) (*v1.ImageManifest, bool, error) {
+	var (
+		dir      = cachePath(m.Digest)
+		manifest = filepath.Join(dir, "manifest.json")
+	)
+
+	cached, err := readManifest(manifest)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	if cached.Digest != m.Digest {
+		return nil, false, nil
+	}
+
+	hash, err := copyFile(filepath.Join(dir, "image"), dst)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("copying cached image: %w", err)
+	}
+
+	if cached.ImageHash != "" && cached.ImageHash != hash {
+		os.Remove(dst)
+		os.RemoveAll(dir)
+
+		return nil, false, nil
+	}
+
+	// The modification time of the cached manifest records when the cache
+	// entry was last used so unused entries can be pruned.
+	now := time.Now()
+	os.Chtimes(manifest, now, now)
+
+	cached.ImageHash = hash
+	cached.Cached = true
+
+	return cached, true, nil
+}
+
+// addToCache adds the image built for the given manifest to the cache,
+// recording the checksum of the image in the manifest.
+func addToCache(m *v1.ImageManifest, src string) error {
+	dir := cachePath(m.Digest)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating image cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so a partially copied image is never
+	// mistaken for a cached one.
+	tmp := filepath.Join(dir, "image.tmp")
+
+	hash, err := copyFile(src, tmp)
+	if err != nil {
+		return fmt.Errorf("caching image: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(dir, "image")); err != nil {
+		return fmt.Errorf("caching image: %w", err)
+	}
+
+	m.ImageHash = hash
+
+	return writeManifest(filepath.Join(dir, "manifest.json"), m)
+}
+
+// PruneCache removes cached images that haven't been built or used within the
+// given duration, or all cached images if the given duration is zero. It
+// returns the digests of the removed images.
+func PruneCache(unused time.Duration) ([]string, error) {
+	entries, err := ioutil.ReadDir(CacheDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading image cache directory: %w", err)
+	}
+
+	var (
+		cutoff = time.Now().Add(-unused)
+		pruned []string
+	)
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(CacheDir(), entry.Name())
+
+		// Entries without a manifest (e.g. from an interrupted build) are always
+		// pruned.
+		if info, err := os.Stat(filepath.Join(dir, "manifest.json")); err == nil && unused > 0 && info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			return pruned, fmt.Errorf("removing cached image %s: %w", entry.Name(), err)
+		}
+
+		pruned = append(pruned, "sha256:"+entry.Name())
+	}
+
+	return pruned, nil
+}
+
+func cachePath(digest string) string {
+	return filepath.Join(CacheDir(), strings.TrimPrefix(digest, "sha256:"))
+}
+
+// copyFile copies the given source file to the given destination, returning
+// the checksum of the copied contents. Images aren't hard linked into and out
+// of the cache since they're often modified after being built (for example, by
+// injecting files into them).
+func copyFile(src, dst string) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+
+	if _, err := io.Copy(io.MultiWriter(out, h), in); err != nil {
+		out.Close()
+		return "", err
+	}
+
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
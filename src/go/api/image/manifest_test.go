@@ -0,0 +1,233 @@
+package image
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "phenix/types/version/v1"
+	"phenix/util/common"
+	"phenix/version"
+)
+
+func TestManifestDigest(t *testing.T) {
+	overlay := t.TempDir()
+
+	if err := ioutil.WriteFile(filepath.Join(overlay, "motd"), []byte("hello"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	img := v1.Image{
+		Variant:     "minbase",
+		Release:     "bionic",
+		Mirror:      "http://us.archive.ubuntu.com/ubuntu/",
+		Packages:    []string{"wireshark"},
+		Overlays:    []string{overlay},
+		Scripts:     map[string]string{"foo": "echo foo"},
+		ScriptOrder: []string{"foo"},
+	}
+
+	first, err := newManifest(img)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Settings that only affect how an image is built shouldn't change the
+	// digest.
+	img.VerboseLogs = true
+	img.Cache = true
+
+	second, err := newManifest(img)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if first.Digest != second.Digest {
+		t.Log("expected digest to be unchanged by build settings")
+		t.FailNow()
+	}
+
+	if len(second.Overlays) != 1 || len(second.Scripts) != 1 || len(second.Packages) != 1 {
+		t.Logf("expected overlay, script, and package in manifest, got %+v", second)
+		t.FailNow()
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(overlay, "motd"), []byte("goodbye"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	third, err := newManifest(img)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if third.Digest == second.Digest {
+		t.Log("expected digest to change when overlay contents change")
+		t.FailNow()
+	}
+
+	img.Scripts["foo"] = "echo bar"
+
+	fourth, err := newManifest(img)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if fourth.Digest == third.Digest || fourth.ConfigHash == third.ConfigHash {
+		t.Log("expected digest and config hash to change when script changes")
+		t.FailNow()
+	}
+
+	tag := version.Tag
+	defer func() { version.Tag = tag }()
+
+	version.Tag = "v0.0.0-test"
+
+	fifth, err := newManifest(img)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if fifth.Digest == fourth.Digest || fifth.ConfigHash != fourth.ConfigHash {
+		t.Log("expected only digest to change when phenix version changes")
+		t.FailNow()
+	}
+}
+
+func TestReadPackages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packages")
+
+	if err := ioutil.WriteFile(path, []byte("wireshark\t2.6.10-1\nbash\t4.4.18-2ubuntu1\n\n"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	pkgs, err := readPackages(path)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(pkgs) != 2 || pkgs[0].Name != "bash" || pkgs[0].Version != "4.4.18-2ubuntu1" || pkgs[1].Name != "wireshark" {
+		t.Logf("unexpected packages %+v", pkgs)
+		t.FailNow()
+	}
+}
+
+func TestImageCache(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		disk = filepath.Join(dir, "foo")
+		dst  = filepath.Join(dir, "bar")
+	)
+
+	common.PhenixBase = dir
+
+	m := &v1.ImageManifest{Digest: hashBytes([]byte("foo"))}
+
+	if _, ok, err := fromCache(m, dst); err != nil || ok {
+		t.Log("expected image to not be cached")
+		t.FailNow()
+	}
+
+	if err := ioutil.WriteFile(disk, []byte("disk"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := addToCache(m, disk); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	cached, ok, err := fromCache(m, dst)
+	if err != nil || !ok {
+		t.Log("expected image to be cached")
+		t.FailNow()
+	}
+
+	if !cached.Cached || cached.Digest != m.Digest {
+		t.Logf("unexpected cached manifest %+v", cached)
+		t.FailNow()
+	}
+
+	body, err := ioutil.ReadFile(dst)
+	if err != nil || string(body) != "disk" {
+		t.Log("expected cached image to be copied")
+		t.FailNow()
+	}
+
+	if cached.ImageHash != hashBytes([]byte("disk")) {
+		t.Logf("unexpected cached image hash %s", cached.ImageHash)
+		t.FailNow()
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(cachePath(m.Digest), "image"), []byte("corrupt"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, ok, err := fromCache(m, dst); err != nil || ok {
+		t.Log("expected corrupt cached image to not be used")
+		t.FailNow()
+	}
+
+	if _, err := os.Stat(cachePath(m.Digest)); !os.IsNotExist(err) {
+		t.Log("expected corrupt cached image to be removed")
+		t.FailNow()
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		disk = filepath.Join(dir, "disk")
+	)
+
+	common.PhenixBase = dir
+
+	if err := ioutil.WriteFile(disk, []byte("disk"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var (
+		used   = &v1.ImageManifest{Digest: hashBytes([]byte("used"))}
+		unused = &v1.ImageManifest{Digest: hashBytes([]byte("unused"))}
+	)
+
+	for _, m := range []*v1.ImageManifest{used, unused} {
+		if err := addToCache(m, disk); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(filepath.Join(cachePath(unused.Digest), "manifest.json"), old, old)
+
+	pruned, err := PruneCache(24 * time.Hour)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(pruned) != 1 || pruned[0] != unused.Digest {
+		t.Logf("expected only unused image to be pruned, got %v", pruned)
+		t.FailNow()
+	}
+
+	if pruned, _ := PruneCache(0); len(pruned) != 1 || pruned[0] != used.Digest {
+		t.Logf("expected remaining image to be pruned, got %v", pruned)
+		t.FailNow()
+	}
+}
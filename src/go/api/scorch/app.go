@@ -14,10 +14,13 @@ import (
 
 	"phenix/api/scorch/scorchexe"
 	"phenix/api/scorch/scorchmd"
+	"phenix/api/scorch/scorchsink"
+	"phenix/api/webhook"
 	"phenix/app"
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
 	"phenix/util"
+	"phenix/util/metrics"
 	"phenix/util/shell"
 	"phenix/web/scorch"
 
@@ -96,7 +99,7 @@ func (this *Scorch) Running(ctx context.Context, exp *types.Experiment) error {
 
 	var (
 		runID  = scorchexe.MustRunID(ctx)
-		runDir = filepath.Join(exp.FilesDir(), "scorch", fmt.Sprintf("run-%d", runID))
+		runDir = scorchexe.RunDir(exp, runID)
 		start  = time.Now().UTC()
 	)
 
@@ -108,6 +111,27 @@ func (this *Scorch) Running(ctx context.Context, exp *types.Experiment) error {
 		return fmt.Errorf("removing existing contents of run directory at %s: %w", runDir, err)
 	}
 
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return fmt.Errorf("creating run directory at %s: %w", runDir, err)
+	}
+
+	// All component output for the run is written to a log file in the run
+	// directory so it can be viewed (or followed) from the command line.
+	logFile, err := os.Create(scorchexe.LogFile(exp, runID))
+	if err != nil {
+		return fmt.Errorf("creating log file for run %d: %w", runID, err)
+	}
+
+	defer logFile.Close()
+
+	writers := []io.Writer{logFile}
+
+	if w, ok := scorchexe.Output(ctx); ok {
+		writers = append(writers, w)
+	}
+
+	ctx = scorchexe.SetOutput(ctx, newOutputWriter(writers...))
+
 	var (
 		cmd  *exec.Cmd
 		port int
@@ -127,14 +151,25 @@ func (this *Scorch) Running(ctx context.Context, exp *types.Experiment) error {
 		}
 	}
 
+	sinks, err := scorchsink.NewSinks(this.md.SinkSpecs(runID), runDir)
+	if err != nil {
+		if cmd != nil {
+			this.stopFilebeat(ctx, cmd, port)
+		}
+
+		return fmt.Errorf("creating result sinks for run %d: %w", runID, err)
+	}
+
+	ctx = setSinks(ctx, sinks, this.md.RunName(runID))
+
 	var (
 		errors error
 		run    = this.md.Runs[runID]
 		opts   = []Option{Experiment(*exp), RunID(runID), StartTime(start.Format(time.RubyDate))}
 	)
 
-	for i := 0; i < run.Count; i++ {
-		opts := append(opts, LoopCount(i))
+	for i := 0; i < run.Iterations(); i++ {
+		opts := append(opts, LoopCount(i), Parameters(run.Parameters(i)))
 
 		if err := executor(ctx, this.md.ComponentSpecs(), run, opts...); err != nil {
 			errors = multierror.Append(errors, fmt.Errorf("executing Scorch for run %d, count %d: %w", runID, i, err))
@@ -156,6 +191,10 @@ func (this *Scorch) Running(ctx context.Context, exp *types.Experiment) error {
 		this.stopFilebeat(ctx, cmd, port)
 	}
 
+	if err := sinks.Close(); err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("closing result sinks for run %d: %w", runID, err))
+	}
+
 	if _, err := os.Stat(runDir); err == nil {
 		archive := filepath.Join(exp.FilesDir(), fmt.Sprintf("scorch-run-%d_%s.tgz", runID, start.Format(time.RFC3339)))
 
@@ -167,6 +206,20 @@ func (this *Scorch) Running(ctx context.Context, exp *types.Experiment) error {
 	update.Status = "success"
 	scorch.UpdatePipeline(update)
 
+	metrics.ScorchRunDuration.WithLabelValues(exp.Metadata.Name, metrics.Status(errors)).Observe(time.Since(start).Seconds())
+
+	event := webhook.NewEvent(webhook.ScorchRunComplete, exp.Metadata.Name).
+		WithAnnotations(exp.Metadata.Annotations).
+		WithData("run", runID).
+		WithData("name", this.md.RunName(runID)).
+		WithData("status", "success")
+
+	if errors != nil {
+		event.WithData("status", "failure").WithData("error", errors.Error())
+	}
+
+	webhook.Fire(event)
+
 	return errors
 }
 
@@ -343,6 +396,18 @@ func executor(ctx context.Context, components scorchmd.ComponentSpecMap, exe *sc
 		Run:   options.Run,
 		Loop:  options.Loop,
 		Count: options.Count,
+		Label: scorchmd.ParameterLabel(options.Params),
+	}
+
+	// metadata returns the named component's metadata templated with the
+	// parameters for the current iteration.
+	metadata := func(name string) (scorchmd.ComponentMetadata, error) {
+		md, err := scorchmd.TemplateMetadata(components[name].Metadata, options.Params)
+		if err != nil {
+			return nil, fmt.Errorf("%s templating metadata for component %s for experiment %s: %w", loopPrefix, name, exp, err)
+		}
+
+		return md, nil
 	}
 
 	configure := func() error {
@@ -365,7 +430,16 @@ func executor(ctx context.Context, components scorchmd.ComponentSpecMap, exe *sc
 
 			scorch.UpdateComponent(update)
 
-			options := append(opts, Name(name), Type(typ), Stage(ACTIONCONFIG), Metadata(components[name].Metadata))
+			md, err := metadata(name)
+			if err != nil {
+				update.Status = "failure"
+				scorch.UpdateComponent(update)
+				scorch.UpdatePipeline(update)
+
+				return err
+			}
+
+			options := append(opts, Name(name), Type(typ), Stage(ACTIONCONFIG), Metadata(md))
 
 			status := "running"
 
@@ -416,7 +490,16 @@ func executor(ctx context.Context, components scorchmd.ComponentSpecMap, exe *sc
 
 			scorch.UpdateComponent(update)
 
-			options := append(opts, Name(name), Type(typ), Stage(ACTIONSTART), Metadata(components[name].Metadata))
+			md, err := metadata(name)
+			if err != nil {
+				update.Status = "failure"
+				scorch.UpdateComponent(update)
+				scorch.UpdatePipeline(update)
+
+				return err
+			}
+
+			options := append(opts, Name(name), Type(typ), Stage(ACTIONSTART), Metadata(md))
 
 			status := "running"
 
@@ -469,7 +552,17 @@ func executor(ctx context.Context, components scorchmd.ComponentSpecMap, exe *sc
 
 			scorch.UpdateComponent(update)
 
-			options := append(opts, Name(name), Type(typ), Stage(ACTIONSTOP), Metadata(components[name].Metadata))
+			md, err := metadata(name)
+			if err != nil {
+				update.Status = "failure"
+				scorch.UpdateComponent(update)
+				scorch.UpdatePipeline(update)
+
+				errors = multierror.Append(errors, err)
+				continue
+			}
+
+			options := append(opts, Name(name), Type(typ), Stage(ACTIONSTOP), Metadata(md))
 
 			update.Status = "running"
 			scorch.UpdateComponent(update)
@@ -513,13 +606,23 @@ func executor(ctx context.Context, components scorchmd.ComponentSpecMap, exe *sc
 
 			scorch.UpdateComponent(update)
 
-			options := append(opts, Name(name), Type(typ), Stage(ACTIONCLEANUP), Metadata(components[name].Metadata))
+			md, err := metadata(name)
+			if err != nil {
+				update.Status = "failure"
+				scorch.UpdateComponent(update)
+				scorch.UpdatePipeline(update)
+
+				errors = multierror.Append(errors, err)
+				continue
+			}
+
+			options := append(opts, Name(name), Type(typ), Stage(ACTIONCLEANUP), Metadata(md))
 
 			update.Status = "running"
 			scorch.UpdateComponent(update)
 			scorch.UpdatePipeline(update)
 
-			err := ExecuteComponent(ctx, options...)
+			err = ExecuteComponent(ctx, options...)
 			if err != nil {
 				update.Status = "failure"
 				scorch.UpdateComponent(update)
@@ -572,8 +675,8 @@ func executor(ctx context.Context, components scorchmd.ComponentSpecMap, exe *sc
 		update.Status = "running"
 		scorch.UpdatePipeline(update)
 
-		for i := 0; i < exe.Loop.Count; i++ {
-			opts := append(opts, CurrentLoop(options.Loop+1), LoopCount(i))
+		for i := 0; i < exe.Loop.Iterations(); i++ {
+			opts := append(opts, CurrentLoop(options.Loop+1), LoopCount(i), Parameters(exe.Loop.Parameters(i)))
 
 			if err := executor(ctx, components, exe.Loop, opts...); err != nil {
 				errors = multierror.Append(errors, err)
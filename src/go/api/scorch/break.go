@@ -124,9 +124,20 @@ func (this Break) breakPoint(ctx context.Context, stage Action) error {
 	}
 
 	if app.IsContextTriggerCLI(ctx) {
-		// this blocks until terminal is exited
-		if err := terminal(ctx, dir, cmd, args); err != nil {
-			return fmt.Errorf("starting bash terminal: %w", err)
+		if stdinIsTerminal() {
+			// this blocks until terminal is exited
+			if err := terminal(ctx, dir, cmd, args); err != nil {
+				return fmt.Errorf("starting bash terminal: %w", err)
+			}
+		} else {
+			sock := terminalSocket(exp, this.options.Run, this.options.Loop, string(stage), this.options.Name)
+
+			writeOutput(ctx, this.options, stage, []byte(fmt.Sprintf("waiting for terminal to be attached (phenix scorch attach %s %d)", exp, this.options.Run)))
+
+			// this blocks until a client attaches and the terminal is exited
+			if err := socketTerminal(ctx, sock, dir, cmd, args); err != nil {
+				return fmt.Errorf("serving bash terminal: %w", err)
+			}
 		}
 	} else if app.IsContextTriggerUI(ctx) {
 		done, err := scorch.CreateWebTerminal(ctx, exp, this.options.Run, this.options.Loop, string(stage), this.options.Name, dir, cmd, args)
@@ -20,6 +20,7 @@ type Options struct {
 	Run        int
 	Loop       int
 	Count      int
+	Params     map[string]interface{}
 	Background bool
 }
 
@@ -97,6 +98,24 @@ func LoopCount(c int) Option {
 	}
 }
 
+// Parameters adds the given matrix parameter values for the current iteration
+// to any parameters already set by outer loops.
+func Parameters(p map[string]interface{}) Option {
+	return func(o *Options) {
+		params := make(map[string]interface{}, len(o.Params)+len(p))
+
+		for k, v := range o.Params {
+			params[k] = v
+		}
+
+		for k, v := range p {
+			params[k] = v
+		}
+
+		o.Params = params
+	}
+}
+
 // Background marks the component to be run in the background.
 func Background() Option {
 	return func(o *Options) {
@@ -0,0 +1,77 @@
+package scorch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"phenix/api/scorch/scorchexe"
+	"phenix/api/scorch/scorchsink"
+
+	log "github.com/activeshadow/libminimega/minilog"
+)
+
+type sinksKey struct{}
+
+// runSinks holds the result sinks component output for a run is exported to.
+type runSinks struct {
+	sinks *scorchsink.Sinks
+	name  string // name of the run
+}
+
+func setSinks(ctx context.Context, sinks *scorchsink.Sinks, name string) context.Context {
+	return context.WithValue(ctx, sinksKey{}, runSinks{sinks: sinks, name: name})
+}
+
+// outputWriter serializes writes to the underlying writer since component
+// output can be written concurrently by background components.
+type outputWriter struct {
+	sync.Mutex
+
+	w io.Writer
+}
+
+func newOutputWriter(writers ...io.Writer) *outputWriter {
+	return &outputWriter{w: io.MultiWriter(writers...)}
+}
+
+func (this *outputWriter) Write(p []byte) (int, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	return this.w.Write(p)
+}
+
+// writeOutput writes the given line of component output to the output writer
+// set in the given context (if any), prefixed with the current run, loop, and
+// count along with the component name and stage. The line is also exported to
+// any result sinks configured for the run.
+func writeOutput(ctx context.Context, options Options, stage Action, line []byte) {
+	if w, ok := scorchexe.Output(ctx); ok {
+		fmt.Fprintf(
+			w, "[RUN: %d - LOOP: %d - COUNT: %d] %s (%s): %s\n",
+			options.Run, options.Loop, options.Count, options.Name, stage, line,
+		)
+	}
+
+	if s, ok := ctx.Value(sinksKey{}).(runSinks); ok {
+		record := scorchsink.Record{
+			Timestamp:  time.Now().UTC(),
+			Experiment: options.Exp.Spec.ExperimentName(),
+			RunID:      options.Run,
+			RunName:    s.name,
+			Loop:       options.Loop,
+			Count:      options.Count,
+			Component:  options.Name,
+			Stage:      string(stage),
+			Parameters: options.Params,
+			Message:    string(line),
+		}
+
+		if err := s.sinks.Write(record); err != nil {
+			log.Warn("exporting output for component %s: %v", options.Name, err)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package scorchexe
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
-type runIDKey struct{}
+type (
+	runIDKey  struct{}
+	outputKey struct{}
+)
 
 func MustRunID(ctx context.Context) int {
 	id := ctx.Value(runIDKey{}).(int)
@@ -17,3 +23,16 @@ func RunID(ctx context.Context) (int, bool) {
 func SetRunID(ctx context.Context, id int) context.Context {
 	return context.WithValue(ctx, runIDKey{}, id)
 }
+
+// Output returns the writer component output should be streamed to, if one has
+// been set for the given context.
+func Output(ctx context.Context) (io.Writer, bool) {
+	w, ok := ctx.Value(outputKey{}).(io.Writer)
+	return w, ok
+}
+
+// SetOutput sets the writer component output should be streamed to in addition
+// to the UI.
+func SetOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
@@ -3,15 +3,24 @@ package scorchexe
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	"phenix/api/scorch/scorchmd"
 	"phenix/app"
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
+	"phenix/util/shell"
 
 	"github.com/hashicorp/go-multierror"
 )
 
+// cancelPollInterval is how often executing runs check if they've been
+// canceled by another phenix process.
+var cancelPollInterval = time.Second
+
 func Execute(ctx context.Context, exp *types.Experiment, run int) error {
 	var config ifaces.ScenarioApp
 
@@ -38,7 +47,13 @@ func Execute(ctx context.Context, exp *types.Experiment, run int) error {
 	}
 
 	exp.Status.SetAppRunning("scorch", true)
-	exp.Status.SetAppStatus("scorch", scorchmd.ScorchStatus{RunID: run})
+	status := scorchmd.ScorchStatus{RunID: run}
+
+	if app.IsContextTriggerCLI(ctx) {
+		status.PID = os.Getpid()
+	}
+
+	exp.Status.SetAppStatus("scorch", status)
 
 	if err := exp.WriteToStore(true); err != nil {
 		return fmt.Errorf("error updating store with experiment %s: %v", exp.Metadata.Name, err)
@@ -47,6 +62,16 @@ func Execute(ctx context.Context, exp *types.Experiment, run int) error {
 	var errors error
 	ctx = SetRunID(ctx, run)
 
+	// Runs can be canceled by other phenix processes (see Cancel), so watch
+	// for the run's cancel marker while it executes.
+	marker := cancelMarker(exp, run)
+	os.Remove(marker)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go watchCancel(ctx, cancel, marker)
+
 	if err := scorch.Running(ctx, exp); err != nil {
 		errors = multierror.Append(errors, fmt.Errorf("running Scorch for experiment %s: %w", exp.Metadata.Name, err))
 	}
@@ -60,3 +85,99 @@ func Execute(ctx context.Context, exp *types.Experiment, run int) error {
 
 	return errors
 }
+
+// Cancel cancels the given Scorch run for the given experiment if it's
+// currently executing. Runs triggered from the command line are canceled by
+// signaling the phenix process executing them. Runs triggered from the UI are
+// canceled by writing a cancel marker to the run's directory, which the UI
+// process watches for.
+func Cancel(exp *types.Experiment, run int) error {
+	if running := exp.Status.AppRunning()["scorch"]; !running {
+		return fmt.Errorf("no Scorch runs are currently executing for experiment %s", exp.Metadata.Name)
+	}
+
+	var status scorchmd.ScorchStatus
+
+	if err := exp.Status.ParseAppStatus("scorch", &status); err != nil {
+		return fmt.Errorf("getting Scorch status for experiment %s: %w", exp.Metadata.Name, err)
+	}
+
+	if status.RunID != run {
+		return fmt.Errorf("Scorch run %d is not currently executing for experiment %s (run %d is)", run, exp.Metadata.Name, status.RunID)
+	}
+
+	if status.PID == 0 {
+		marker := cancelMarker(exp, run)
+
+		if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+			return fmt.Errorf("creating directory for Scorch run %d: %w", run, err)
+		}
+
+		if err := os.WriteFile(marker, nil, 0644); err != nil {
+			return fmt.Errorf("writing cancel marker for Scorch run %d: %w", run, err)
+		}
+
+		return nil
+	}
+
+	if !shell.ProcessExists(status.PID) {
+		// The process executing the run died without cleaning up after itself, so
+		// clear the stale status for the run.
+		exp.Status.SetAppRunning("scorch", false)
+		exp.Status.SetAppStatus("scorch", nil)
+
+		if err := exp.WriteToStore(true); err != nil {
+			return fmt.Errorf("error updating store with experiment %s: %v", exp.Metadata.Name, err)
+		}
+
+		return nil
+	}
+
+	proc, err := os.FindProcess(status.PID)
+	if err != nil {
+		return fmt.Errorf("finding process %d executing Scorch run %d: %w", status.PID, run, err)
+	}
+
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("signaling process %d executing Scorch run %d: %w", status.PID, run, err)
+	}
+
+	return nil
+}
+
+// cancelMarker returns the path to the file written to cancel the given Scorch
+// run from another phenix process.
+func cancelMarker(exp *types.Experiment, run int) string {
+	return filepath.Join(RunDir(exp, run), "CANCEL")
+}
+
+// watchCancel calls the given cancel function if the given cancel marker is
+// written before the given context is done.
+func watchCancel(ctx context.Context, cancel context.CancelFunc, marker string) {
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := os.Stat(marker); err == nil {
+				os.Remove(marker)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// RunDir returns the directory output for the given Scorch run is written to.
+func RunDir(exp *types.Experiment, run int) string {
+	return filepath.Join(exp.FilesDir(), "scorch", fmt.Sprintf("run-%d", run))
+}
+
+// LogFile returns the path to the file all component output for the given
+// Scorch run is written to.
+func LogFile(exp *types.Experiment, run int) string {
+	return filepath.Join(RunDir(exp, run), "scorch.log")
+}
@@ -0,0 +1,44 @@
+package scorchexe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchCancel(t *testing.T) {
+	cancelPollInterval = 10 * time.Millisecond
+
+	marker := filepath.Join(t.TempDir(), "CANCEL")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go watchCancel(ctx, cancel, marker)
+
+	select {
+	case <-ctx.Done():
+		t.Log("expected run to not be canceled without cancel marker")
+		t.FailNow()
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Log("expected run to be canceled after cancel marker written")
+		t.FailNow()
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Log("expected cancel marker to be removed")
+		t.FailNow()
+	}
+}
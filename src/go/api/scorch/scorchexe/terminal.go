@@ -0,0 +1,88 @@
+package scorchexe
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"phenix/util/common"
+)
+
+// TerminalSocketDir returns the directory containing the UNIX sockets for break
+// component terminals waiting to be attached to for the given experiment run.
+func TerminalSocketDir(exp string, run int) string {
+	return filepath.Join(common.PhenixBase, "scorch", "terminals", fmt.Sprintf("%s-%d", exp, run))
+}
+
+// ListenTerminal creates the given UNIX socket for serving a break component
+// terminal. The terminal is a root shell, so the directory containing the
+// socket must only be accessible by the current user and the socket itself is
+// only readable and writable by the current user.
+func ListenTerminal(sock string) (net.Listener, error) {
+	dir := filepath.Dir(sock)
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, fmt.Errorf("creating terminal socket base directory: %w", err)
+	}
+
+	if err := checkOwner(filepath.Dir(dir), false); err != nil {
+		return nil, err
+	}
+
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("creating terminal socket directory: %w", err)
+	}
+
+	// The directory may have already existed, so make sure it wasn't created by
+	// someone else.
+	if err := checkOwner(dir, true); err != nil {
+		return nil, err
+	}
+
+	os.Remove(sock)
+
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("listening on terminal socket %s: %w", sock, err)
+	}
+
+	if err := os.Chmod(sock, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("setting permissions on terminal socket %s: %w", sock, err)
+	}
+
+	return listener, nil
+}
+
+// checkOwner ensures the given path is a directory (not a symlink to one)
+// owned by the current user. If private is true, it also ensures the directory
+// is only accessible by the current user, otherwise it ensures it's not
+// writable by anyone else.
+func checkOwner(dir string, private bool) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("checking terminal socket directory %s: %w", dir, err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("terminal socket directory %s is not a directory", dir)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Geteuid() {
+		return fmt.Errorf("terminal socket directory %s is owned by another user (%d)", dir, stat.Uid)
+	}
+
+	mode := info.Mode().Perm()
+
+	if private && mode != 0700 {
+		return fmt.Errorf("terminal socket directory %s has mode %o (expected 700)", dir, mode)
+	}
+
+	if !private && mode&0022 != 0 {
+		return fmt.Errorf("terminal socket directory %s is writable by other users (mode %o)", dir, mode)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package scorchexe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenTerminal(t *testing.T) {
+	var (
+		base = filepath.Join(t.TempDir(), "terminals")
+		sock = filepath.Join(base, "foo-1", "0-running-shell.sock")
+	)
+
+	listener, err := ListenTerminal(sock)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer listener.Close()
+
+	info, err := os.Stat(sock)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Logf("expected socket mode 600, got %o", mode)
+		t.FailNow()
+	}
+
+	info, err = os.Stat(filepath.Dir(sock))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if mode := info.Mode().Perm(); mode != 0700 {
+		t.Logf("expected socket directory mode 700, got %o", mode)
+		t.FailNow()
+	}
+}
+
+func TestListenTerminalExistingDir(t *testing.T) {
+	base := t.TempDir()
+
+	// A directory created ahead of time with permissive access must not be
+	// used.
+	dir := filepath.Join(base, "foo-1")
+
+	if err := os.Mkdir(dir, 0777); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	os.Chmod(dir, 0777)
+
+	if _, err := ListenTerminal(filepath.Join(dir, "0-running-shell.sock")); err == nil {
+		t.Log("expected error for permissive terminal socket directory")
+		t.FailNow()
+	}
+
+	// Neither must a symlink to a directory.
+	target := filepath.Join(base, "target")
+
+	if err := os.Mkdir(target, 0700); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	link := filepath.Join(base, "foo-2")
+
+	if err := os.Symlink(target, link); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := ListenTerminal(filepath.Join(link, "0-running-shell.sock")); err == nil {
+		t.Log("expected error for symlinked terminal socket directory")
+		t.FailNow()
+	}
+}
@@ -1,7 +1,12 @@
 package scorchmd
 
 import (
+	"bytes"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
 
 	"phenix/types"
 	"phenix/util"
@@ -11,6 +16,11 @@ import (
 
 var ErrScorchNotConfigured = fmt.Errorf("scorch not configured for experiment")
 
+// singleParamRe matches strings that consist of nothing but a single parameter
+// reference (e.g. `{{ .delay }}`), which allows non-string parameter values to
+// be substituted into component metadata without being converted to strings.
+var singleParamRe = regexp.MustCompile(`^\{\{\s*\.(\w+)\s*\}\}$`)
+
 func DecodeMetadata(exp *types.Experiment) (ScorchMetadata, error) {
 	var (
 		ms map[string]interface{}
@@ -55,3 +65,86 @@ func ensureCount(run *Loop) {
 		ensureCount(run.Loop)
 	}
 }
+
+// TemplateMetadata returns a copy of the given component metadata with all
+// string values (including those nested in maps and slices) executed as Go
+// templates using the given parameters. The original metadata is returned
+// as-is if no parameters are provided.
+func TemplateMetadata(md ComponentMetadata, params map[string]interface{}) (ComponentMetadata, error) {
+	if len(params) == 0 || md == nil {
+		return md, nil
+	}
+
+	templated, err := templateValue(map[string]interface{}(md), params)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComponentMetadata(templated.(map[string]interface{})), nil
+}
+
+// ParameterLabel returns a human-readable label for the given parameters,
+// formatted as comma-separated key=value pairs sorted by key.
+func ParameterLabel(params map[string]interface{}) string {
+	var pairs []string
+
+	for k, v := range params {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, v))
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+func templateValue(v interface{}, params map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if match := singleParamRe.FindStringSubmatch(v); match != nil {
+			if p, ok := params[match[1]]; ok {
+				return p, nil
+			}
+		}
+
+		tmpl, err := template.New("metadata").Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("parsing metadata template %s: %w", v, err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := tmpl.Execute(&buf, params); err != nil {
+			return nil, fmt.Errorf("executing metadata template %s: %w", v, err)
+		}
+
+		return buf.String(), nil
+	case map[string]interface{}:
+		templated := make(map[string]interface{}, len(v))
+
+		for k, e := range v {
+			t, err := templateValue(e, params)
+			if err != nil {
+				return nil, err
+			}
+
+			templated[k] = t
+		}
+
+		return templated, nil
+	case []interface{}:
+		templated := make([]interface{}, len(v))
+
+		for i, e := range v {
+			t, err := templateValue(e, params)
+			if err != nil {
+				return nil, err
+			}
+
+			templated[i] = t
+		}
+
+		return templated, nil
+	default:
+		return v, nil
+	}
+}
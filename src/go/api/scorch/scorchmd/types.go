@@ -1,6 +1,8 @@
 package scorchmd
 
 import (
+	"sort"
+
 	"phenix/util"
 	"phenix/util/tap"
 )
@@ -10,6 +12,13 @@ spec:
   apps:
   - name: scorch
     metadata:
+      sinks:
+      - type: ndjson
+        config:
+          path: /phenix/scorch-output.ndjson
+      - type: webhook
+        config:
+          url: http://localhost:8080/scorch
       filebeat:
         enabled: false
         expNameAsIndexName: true
@@ -31,6 +40,9 @@ spec:
         cleanup: []
         loop:
           count: 2
+          matrix:
+            delay: [10ms, 100ms]
+            tool: [nmap, masscan]
           configure: []
           start: [mooncake_apps]
           stop: [mooncake_apps]
@@ -65,7 +77,7 @@ spec:
         metadata:
           inject:
           - test-one: [test.yml]
-          run_start: "bash foo /test.yml"
+          run_start: "bash foo /test.yml --delay {{ .delay }} --tool {{ .tool }}"
           extract:
           - test-one: [test.yml]
           run_stop: "bash cleanup"
@@ -78,6 +90,7 @@ spec:
 
 type ScorchMetadata struct {
 	Filebeat   FilebeatSpec    `mapstructure:"filebeat"`
+	Sinks      []SinkSpec      `mapstructure:"sinks"`
 	Runs       []*Loop         `mapstructure:"runs"`
 	Components []ComponentSpec `mapstructure:"components"`
 
@@ -125,8 +138,22 @@ func (this ScorchMetadata) UseExpNameAsIndexName(id int) bool {
 	return false
 }
 
+// SinkSpecs returns the result sinks component output should be exported to
+// for the given run. Sinks configured for the run override any configured
+// globally.
+func (this ScorchMetadata) SinkSpecs(id int) []SinkSpec {
+	run := this.Runs[id]
+
+	if run.Sinks != nil {
+		return run.Sinks
+	}
+
+	return this.Sinks
+}
+
 type Loop struct {
 	Filebeat  *FilebeatSpec `mapstructure:"filebeat"`
+	Sinks     []SinkSpec    `mapstructure:"sinks"`
 	Count     int           `mapstructure:"count"`
 	Name      string        `mapstructure:"name"`
 	Configure []string      `mapstructure:"configure"`
@@ -134,6 +161,70 @@ type Loop struct {
 	Stop      []string      `mapstructure:"stop"`
 	Cleanup   []string      `mapstructure:"cleanup"`
 	Loop      *Loop         `mapstructure:"loop"` // using a pointer here to avoid cyclical references
+
+	// Matrix maps parameter names to the list of values to sweep over. Each
+	// combination of parameter values is executed Count times.
+	Matrix map[string][]interface{} `mapstructure:"matrix"`
+}
+
+// Iterations returns the total number of iterations to execute for this loop,
+// which is the loop count multiplied by the number of parameter combinations
+// in the loop's matrix (if any).
+func (this Loop) Iterations() int {
+	return this.Count * len(this.combinations())
+}
+
+// Parameters returns the parameter values to use for the given iteration of
+// this loop. It returns nil if the loop doesn't have a parameter matrix.
+func (this Loop) Parameters(iteration int) map[string]interface{} {
+	combos := this.combinations()
+
+	if this.Count == 0 || len(combos) == 0 {
+		return nil
+	}
+
+	return combos[(iteration/this.Count)%len(combos)]
+}
+
+// combinations returns the cartesian product of all the parameter values in
+// the loop's matrix. Parameter names are processed in sorted order so the
+// combinations are returned in a deterministic order. A single nil combination
+// is returned if the loop doesn't have a parameter matrix.
+func (this Loop) combinations() []map[string]interface{} {
+	if len(this.Matrix) == 0 {
+		return []map[string]interface{}{nil}
+	}
+
+	var keys []string
+
+	for k := range this.Matrix {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	combos := []map[string]interface{}{{}}
+
+	for _, k := range keys {
+		var next []map[string]interface{}
+
+		for _, combo := range combos {
+			for _, v := range this.Matrix[k] {
+				c := make(map[string]interface{}, len(combo)+1)
+
+				for ck, cv := range combo {
+					c[ck] = cv
+				}
+
+				c[k] = v
+				next = append(next, c)
+			}
+		}
+
+		combos = next
+	}
+
+	return combos
 }
 
 func (this Loop) ContainsComponent(name string) bool {
@@ -173,10 +264,19 @@ type FilebeatSpec struct {
 	Config     map[string]interface{} `mapstructure:"config"`
 }
 
+type SinkSpec struct {
+	Type   string                 `mapstructure:"type"`
+	Config map[string]interface{} `mapstructure:"config"`
+}
+
 type ComponentMetadata map[string]interface{}
 type ComponentSpecMap map[string]ComponentSpec
 
 type ScorchStatus struct {
 	RunID int                 `structs:"runID" mapstructure:"runID"`
 	Taps  map[string]*tap.Tap `structs:"taps" mapstructure:"taps"`
+
+	// PID is the ID of the phenix process executing the run when it was
+	// triggered from the command line. It's zero for runs triggered by the UI.
+	PID int `structs:"pid" mapstructure:"pid"`
 }
@@ -0,0 +1,109 @@
+package scorchmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoopIterationsNoMatrix(t *testing.T) {
+	loop := Loop{Count: 3}
+
+	if n := loop.Iterations(); n != 3 {
+		t.Logf("expected 3 iterations, got %d", n)
+		t.FailNow()
+	}
+
+	if params := loop.Parameters(1); params != nil {
+		t.Logf("expected nil parameters, got %v", params)
+		t.FailNow()
+	}
+}
+
+func TestLoopIterationsMatrix(t *testing.T) {
+	loop := Loop{
+		Count: 2,
+		Matrix: map[string][]interface{}{
+			"tool":  {"nmap", "masscan"},
+			"delay": {"10ms", "100ms", "1s"},
+		},
+	}
+
+	if n := loop.Iterations(); n != 12 {
+		t.Logf("expected 12 iterations, got %d", n)
+		t.FailNow()
+	}
+
+	expected := []map[string]interface{}{
+		{"delay": "10ms", "tool": "nmap"},
+		{"delay": "10ms", "tool": "nmap"},
+		{"delay": "10ms", "tool": "masscan"},
+		{"delay": "10ms", "tool": "masscan"},
+		{"delay": "100ms", "tool": "nmap"},
+		{"delay": "100ms", "tool": "nmap"},
+		{"delay": "100ms", "tool": "masscan"},
+		{"delay": "100ms", "tool": "masscan"},
+		{"delay": "1s", "tool": "nmap"},
+		{"delay": "1s", "tool": "nmap"},
+		{"delay": "1s", "tool": "masscan"},
+		{"delay": "1s", "tool": "masscan"},
+	}
+
+	for i, e := range expected {
+		if params := loop.Parameters(i); !reflect.DeepEqual(params, e) {
+			t.Logf("iteration %d: expected %v, got %v", i, e, params)
+			t.FailNow()
+		}
+	}
+}
+
+func TestTemplateMetadata(t *testing.T) {
+	md := ComponentMetadata{
+		"run_start": "bash attack.sh --tool {{ .tool }}",
+		"clients":   "{{ .clients }}",
+		"inject": []interface{}{
+			map[string]interface{}{"client-{{ .clients }}": "results-{{ .tool }}.json"},
+		},
+		"memory": 512,
+	}
+
+	params := map[string]interface{}{"tool": "nmap", "clients": 4}
+
+	templated, err := TemplateMetadata(md, params)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := ComponentMetadata{
+		"run_start": "bash attack.sh --tool nmap",
+		"clients":   4,
+		"inject": []interface{}{
+			map[string]interface{}{"client-{{ .clients }}": "results-nmap.json"},
+		},
+		"memory": 512,
+	}
+
+	if !reflect.DeepEqual(templated, expected) {
+		t.Logf("expected %v, got %v", expected, templated)
+		t.FailNow()
+	}
+
+	if md["run_start"] != "bash attack.sh --tool {{ .tool }}" {
+		t.Log("original metadata was modified")
+		t.FailNow()
+	}
+
+	if _, err := TemplateMetadata(md, map[string]interface{}{"clients": 4}); err == nil {
+		t.Log("expected error for missing parameter")
+		t.FailNow()
+	}
+}
+
+func TestParameterLabel(t *testing.T) {
+	label := ParameterLabel(map[string]interface{}{"tool": "nmap", "delay": "10ms"})
+
+	if label != "delay=10ms, tool=nmap" {
+		t.Logf("unexpected label %s", label)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,103 @@
+package scorchsink
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultBatchSize = 100
+	defaultTimeout   = 10 * time.Second
+
+	// retryInterval is how long to wait before retrying a failed flush.
+	retryInterval = 5 * time.Second
+
+	// maxPendingBatches is the number of batches worth of records kept for
+	// retrying failed flushes before the oldest records are dropped.
+	maxPendingBatches = 10
+)
+
+// batch buffers records until the configured batch size is reached, at which
+// point the buffered records are flushed. Records that fail to flush are kept
+// and retried with the next batch.
+type batch struct {
+	size    int
+	records []Record
+	flush   func([]Record) error
+
+	// retry is when a failed flush can be retried.
+	retry time.Time
+}
+
+func newBatch(size int, flush func([]Record) error) *batch {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+
+	return &batch{size: size, flush: flush}
+}
+
+func (this *batch) add(r Record) error {
+	this.records = append(this.records, r)
+
+	if len(this.records) >= this.size && !time.Now().Before(this.retry) {
+		return this.drain()
+	}
+
+	return nil
+}
+
+// drain flushes all the buffered records. If the flush fails, the records are
+// kept to be retried (up to maxPendingBatches worth, dropping the oldest).
+func (this *batch) drain() error {
+	if len(this.records) == 0 {
+		return nil
+	}
+
+	if err := this.flush(this.records); err != nil {
+		this.retry = time.Now().Add(retryInterval)
+
+		if max := this.size * maxPendingBatches; len(this.records) > max {
+			this.records = this.records[len(this.records)-max:]
+		}
+
+		return err
+	}
+
+	this.records = nil
+	this.retry = time.Time{}
+
+	return nil
+}
+
+// send sends the given body to the given URL, returning an error if the
+// response status code isn't in the 2xx range.
+func send(client *http.Client, method, url, contentType string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request to %s: %w", url, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, url, bytes.TrimSpace(msg))
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package scorchsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Kafka produces records to a Kafka topic via a Kafka REST Proxy (v2 API).
+type Kafka struct {
+	config kafkaConfig
+	client *http.Client
+	batch  *batch
+}
+
+type kafkaConfig struct {
+	// Base URL of the Kafka REST Proxy (e.g. http://localhost:8082).
+	URL string `mapstructure:"url"`
+
+	// Topic to produce records to. Defaults to `scorch`.
+	Topic string `mapstructure:"topic"`
+
+	// Additional HTTP headers to include (e.g. Authorization).
+	Headers map[string]string `mapstructure:"headers"`
+
+	BatchSize int           `mapstructure:"batchSize"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value Record `json:"value"`
+}
+
+func (this *Kafka) Init(config map[string]interface{}, _ string) error {
+	if err := decodeConfig(config, &this.config); err != nil {
+		return err
+	}
+
+	if this.config.URL == "" {
+		return fmt.Errorf("missing Kafka REST Proxy URL")
+	}
+
+	if this.config.Topic == "" {
+		this.config.Topic = "scorch"
+	}
+
+	if this.config.Timeout == 0 {
+		this.config.Timeout = defaultTimeout
+	}
+
+	this.client = &http.Client{Timeout: this.config.Timeout}
+	this.batch = newBatch(this.config.BatchSize, this.produce)
+
+	return nil
+}
+
+func (this *Kafka) Write(r Record) error {
+	return this.batch.add(r)
+}
+
+func (this *Kafka) Close() error {
+	return this.batch.drain()
+}
+
+func (this *Kafka) produce(records []Record) error {
+	body := struct {
+		Records []kafkaRecord `json:"records"`
+	}{}
+
+	for _, r := range records {
+		// Key records by experiment so all records for an experiment end up in
+		// the same partition and retain their order.
+		body.Records = append(body.Records, kafkaRecord{Key: r.Experiment, Value: r})
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshaling Kafka produce request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/topics/%s", strings.TrimSuffix(this.config.URL, "/"), this.config.Topic)
+
+	if err := send(this.client, http.MethodPost, url, "application/vnd.kafka.json.v2+json", this.config.Headers, data); err != nil {
+		return fmt.Errorf("producing records to Kafka: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package scorchsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Loki pushes records to a Grafana Loki server using its HTTP push API. Each
+// record is pushed as a JSON-encoded log line, with streams labeled by
+// experiment, run, component, and stage.
+type Loki struct {
+	config lokiConfig
+	client *http.Client
+	batch  *batch
+}
+
+type lokiConfig struct {
+	// URL of the Loki push API (e.g. http://localhost:3100/loki/api/v1/push).
+	URL string `mapstructure:"url"`
+
+	// Additional labels to add to all streams.
+	Labels map[string]string `mapstructure:"labels"`
+
+	// Additional HTTP headers to include (e.g. X-Scope-OrgID).
+	Headers map[string]string `mapstructure:"headers"`
+
+	BatchSize int           `mapstructure:"batchSize"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (this *Loki) Init(config map[string]interface{}, _ string) error {
+	if err := decodeConfig(config, &this.config); err != nil {
+		return err
+	}
+
+	if this.config.URL == "" {
+		return fmt.Errorf("missing Loki push URL")
+	}
+
+	if this.config.Timeout == 0 {
+		this.config.Timeout = defaultTimeout
+	}
+
+	this.client = &http.Client{Timeout: this.config.Timeout}
+	this.batch = newBatch(this.config.BatchSize, this.push)
+
+	return nil
+}
+
+func (this *Loki) Write(r Record) error {
+	return this.batch.add(r)
+}
+
+func (this *Loki) Close() error {
+	return this.batch.drain()
+}
+
+func (this *Loki) push(records []Record) error {
+	var (
+		streams = make(map[string]*lokiStream)
+		keys    []string
+	)
+
+	for _, r := range records {
+		labels := map[string]string{
+			"experiment": r.Experiment,
+			"run":        strconv.Itoa(r.RunID),
+			"component":  r.Component,
+			"stage":      r.Stage,
+		}
+
+		for k, v := range this.config.Labels {
+			labels[k] = v
+		}
+
+		key := lokiStreamKey(labels)
+
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("marshaling record: %w", err)
+		}
+
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(r.Timestamp.UnixNano(), 10), string(line)})
+	}
+
+	body := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+
+	for _, key := range keys {
+		body.Streams = append(body.Streams, streams[key])
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshaling Loki push request: %w", err)
+	}
+
+	if err := send(this.client, http.MethodPost, this.config.URL, "application/json", this.config.Headers, data); err != nil {
+		return fmt.Errorf("pushing records to Loki: %w", err)
+	}
+
+	return nil
+}
+
+func lokiStreamKey(labels map[string]string) string {
+	var pairs []string
+
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
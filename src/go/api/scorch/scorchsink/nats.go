@@ -0,0 +1,202 @@
+package scorchsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATS publishes records to a subject on a NATS server using the NATS client
+// protocol. Each record is published as a JSON-encoded message.
+type NATS struct {
+	sync.Mutex // protects writes to connection
+
+	config natsConfig
+	conn   net.Conn
+	writer *bufio.Writer
+
+	err  error // asynchronous error sent by server
+	done chan struct{}
+}
+
+type natsConfig struct {
+	// URL of the NATS server (e.g. nats://localhost:4222).
+	URL string `mapstructure:"url"`
+
+	// Subject to publish records to. Defaults to `scorch`.
+	Subject string `mapstructure:"subject"`
+
+	User     string `mapstructure:"user"`
+	Password string `mapstructure:"password"`
+	Token    string `mapstructure:"token"`
+
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+func (this *NATS) Init(config map[string]interface{}, _ string) error {
+	if err := decodeConfig(config, &this.config); err != nil {
+		return err
+	}
+
+	if this.config.URL == "" {
+		return fmt.Errorf("missing NATS server URL")
+	}
+
+	if this.config.Subject == "" {
+		this.config.Subject = "scorch"
+	}
+
+	if this.config.Timeout == 0 {
+		this.config.Timeout = defaultTimeout
+	}
+
+	addr := this.config.URL
+
+	if strings.Contains(addr, "://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return fmt.Errorf("parsing NATS server URL: %w", err)
+		}
+
+		addr = u.Host
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, this.config.Timeout)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS server at %s: %w", addr, err)
+	}
+
+	this.conn = conn
+	this.writer = bufio.NewWriter(conn)
+	this.done = make(chan struct{})
+
+	if err := this.handshake(); err != nil {
+		conn.Close()
+		return err
+	}
+
+	return nil
+}
+
+func (this *NATS) Write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshaling record: %w", err)
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.err != nil {
+		return fmt.Errorf("NATS server error: %w", this.err)
+	}
+
+	fmt.Fprintf(this.writer, "PUB %s %d\r\n", this.config.Subject, len(data))
+	this.writer.Write(data)
+	this.writer.WriteString("\r\n")
+
+	if err := this.writer.Flush(); err != nil {
+		return fmt.Errorf("publishing record to NATS: %w", err)
+	}
+
+	return nil
+}
+
+func (this *NATS) Close() error {
+	if this.conn == nil {
+		return nil
+	}
+
+	this.Lock()
+	err := this.writer.Flush()
+	this.Unlock()
+
+	this.conn.Close()
+	<-this.done
+
+	return err
+}
+
+// handshake reads the server's INFO message, sends the client's CONNECT
+// message, and then round-trips a PING to ensure the server accepted the
+// connection before processing server messages in the background.
+func (this *NATS) handshake() error {
+	this.conn.SetDeadline(time.Now().Add(this.config.Timeout))
+	defer this.conn.SetDeadline(time.Time{})
+
+	reader := bufio.NewReader(this.conn)
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("reading INFO from NATS server: %w", err)
+	}
+
+	if !strings.HasPrefix(line, "INFO") {
+		return fmt.Errorf("unexpected message from NATS server: %s", strings.TrimSpace(line))
+	}
+
+	connect, _ := json.Marshal(natsConnect{
+		Name:  "phenix-scorch",
+		User:  this.config.User,
+		Pass:  this.config.Password,
+		Token: this.config.Token,
+	})
+
+	fmt.Fprintf(this.writer, "CONNECT %s\r\nPING\r\n", connect)
+
+	if err := this.writer.Flush(); err != nil {
+		return fmt.Errorf("sending CONNECT to NATS server: %w", err)
+	}
+
+	line, err = reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("reading PONG from NATS server: %w", err)
+	}
+
+	if line = strings.TrimSpace(line); line != "PONG" {
+		return fmt.Errorf("connecting to NATS server: %s", line)
+	}
+
+	go this.process(reader)
+
+	return nil
+}
+
+// process handles messages sent by the server after the connection has been
+// established, replying to PINGs and recording any errors.
+func (this *NATS) process(reader *bufio.Reader) {
+	defer close(this.done)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		switch line = strings.TrimSpace(line); {
+		case line == "PING":
+			this.Lock()
+			this.writer.WriteString("PONG\r\n")
+			this.writer.Flush()
+			this.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			this.Lock()
+			this.err = fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+			this.Unlock()
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package scorchsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// NDJSON writes records to a local file as newline-delimited JSON.
+type NDJSON struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+type ndjsonConfig struct {
+	// Path to write records to. Relative paths are relative to the run
+	// directory. Defaults to `output.ndjson` in the run directory.
+	Path string `mapstructure:"path"`
+}
+
+func (this *NDJSON) Init(config map[string]interface{}, runDir string) error {
+	var c ndjsonConfig
+
+	if err := decodeConfig(config, &c); err != nil {
+		return err
+	}
+
+	if c.Path == "" {
+		c.Path = "output.ndjson"
+	}
+
+	if !filepath.IsAbs(c.Path) {
+		c.Path = filepath.Join(runDir, c.Path)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return fmt.Errorf("creating directory for NDJSON file: %w", err)
+	}
+
+	f, err := os.OpenFile(c.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("opening NDJSON file %s: %w", c.Path, err)
+	}
+
+	this.file = f
+	this.enc = json.NewEncoder(f)
+
+	return nil
+}
+
+func (this *NDJSON) Write(r Record) error {
+	if err := this.enc.Encode(r); err != nil {
+		return fmt.Errorf("writing record to NDJSON file: %w", err)
+	}
+
+	return nil
+}
+
+func (this *NDJSON) Close() error {
+	if this.file == nil {
+		return nil
+	}
+
+	return this.file.Close()
+}
@@ -0,0 +1,196 @@
+// Package scorchsink provides pluggable sinks for exporting the output
+// generated by SCORCH components to external systems.
+package scorchsink
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"phenix/api/scorch/scorchmd"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Record represents a single line of output generated by a SCORCH component.
+type Record struct {
+	Timestamp  time.Time              `json:"timestamp"`
+	Experiment string                 `json:"experiment"`
+	RunID      int                    `json:"run_id"`
+	RunName    string                 `json:"run_name,omitempty"`
+	Loop       int                    `json:"loop"`
+	Count      int                    `json:"count"`
+	Component  string                 `json:"component"`
+	Stage      string                 `json:"stage"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Message    string                 `json:"message"`
+}
+
+// Sink is the interface that all SCORCH result sinks must implement.
+type Sink interface {
+	// Init is used to initialize the sink with the config provided for it in the
+	// SCORCH metadata. The directory output for the current run is written to is
+	// provided for sinks that write to local files.
+	Init(config map[string]interface{}, runDir string) error
+
+	// Write exports the given record. Sinks are free to buffer records until
+	// Close is called.
+	Write(Record) error
+
+	// Close flushes any buffered records and releases any resources used by the
+	// sink.
+	Close() error
+}
+
+// SinkFactory is a function that returns a new sink struct.
+type SinkFactory func() Sink
+
+var factories = map[string]SinkFactory{
+	"ndjson":  func() Sink { return new(NDJSON) },
+	"loki":    func() Sink { return new(Loki) },
+	"kafka":   func() Sink { return new(Kafka) },
+	"nats":    func() Sink { return new(NATS) },
+	"webhook": func() Sink { return new(Webhook) },
+}
+
+// Register registers the given sink factory under the given type name, making
+// it available for use in SCORCH metadata.
+func Register(typ string, factory SinkFactory) {
+	factories[typ] = factory
+}
+
+// New returns a new, initialized sink for the given sink spec.
+func New(spec scorchmd.SinkSpec, runDir string) (Sink, error) {
+	factory, ok := factories[spec.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown sink type %s", spec.Type)
+	}
+
+	sink := factory()
+
+	if err := sink.Init(spec.Config, runDir); err != nil {
+		return nil, fmt.Errorf("initializing %s sink: %w", spec.Type, err)
+	}
+
+	return sink, nil
+}
+
+// queueSize is the number of records that can be waiting to be written to a
+// collection of sinks before new records are dropped.
+const queueSize = 10000
+
+// Sinks is a collection of sinks that are written to as one. It is safe for
+// concurrent use. Records are written to the sinks in the background so
+// writers (component output) are never held up by sinks that are slow to
+// flush.
+type Sinks struct {
+	sync.Mutex
+
+	sinks   []Sink
+	records chan Record
+	done    chan struct{}
+	closed  bool
+}
+
+// NewSinks returns a new collection of initialized sinks for the given sink
+// specs. Any sinks already initialized are closed if one fails to initialize.
+func NewSinks(specs []scorchmd.SinkSpec, runDir string) (*Sinks, error) {
+	sinks := &Sinks{records: make(chan Record, queueSize), done: make(chan struct{})}
+
+	for _, spec := range specs {
+		sink, err := New(spec, runDir)
+		if err != nil {
+			for _, s := range sinks.sinks {
+				s.Close()
+			}
+
+			return nil, err
+		}
+
+		sinks.sinks = append(sinks.sinks, sink)
+	}
+
+	go sinks.write()
+
+	return sinks, nil
+}
+
+// Write queues the given record to be written to all the sinks in the
+// collection. It only returns an error if the record had to be dropped.
+func (this *Sinks) Write(r Record) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.closed {
+		return fmt.Errorf("sinks already closed")
+	}
+
+	select {
+	case this.records <- r:
+		return nil
+	default:
+		return fmt.Errorf("sink queue is full")
+	}
+}
+
+// Close writes any queued records to the sinks in the collection and then
+// closes them.
+func (this *Sinks) Close() error {
+	this.Lock()
+
+	if this.closed {
+		this.Unlock()
+		return nil
+	}
+
+	this.closed = true
+	close(this.records)
+
+	this.Unlock()
+
+	<-this.done
+
+	var errs error
+
+	for _, sink := range this.sinks {
+		if err := sink.Close(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	this.sinks = nil
+
+	return errs
+}
+
+func (this *Sinks) write() {
+	defer close(this.done)
+
+	for r := range this.records {
+		for _, sink := range this.sinks {
+			if err := sink.Write(r); err != nil {
+				log.Warn("writing SCORCH output to sink: %v", err)
+			}
+		}
+	}
+}
+
+func decodeConfig(config map[string]interface{}, v interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		WeaklyTypedInput: true,
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		Result:           v,
+	})
+
+	if err != nil {
+		return fmt.Errorf("creating config decoder: %w", err)
+	}
+
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("decoding sink config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,364 @@
+package scorchsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"phenix/api/scorch/scorchmd"
+)
+
+func testRecords() []Record {
+	ts := time.Date(2022, 7, 4, 12, 0, 0, 0, time.UTC)
+
+	return []Record{
+		{Timestamp: ts, Experiment: "foo", RunID: 0, Component: "nmap", Stage: "start", Message: "line one"},
+		{Timestamp: ts, Experiment: "foo", RunID: 0, Component: "nmap", Stage: "start", Message: "line two"},
+		{Timestamp: ts, Experiment: "foo", RunID: 0, Component: "tshark", Stage: "stop", Message: "line three"},
+	}
+}
+
+func writeRecords(t *testing.T, spec scorchmd.SinkSpec, runDir string) {
+	sinks, err := NewSinks([]scorchmd.SinkSpec{spec}, runDir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, r := range testRecords() {
+		if err := sinks.Write(r); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	if err := sinks.Close(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestUnknownSink(t *testing.T) {
+	if _, err := New(scorchmd.SinkSpec{Type: "foobar"}, ""); err == nil {
+		t.Log("expected error for unknown sink type")
+		t.FailNow()
+	}
+}
+
+func TestNDJSONSink(t *testing.T) {
+	dir := t.TempDir()
+
+	writeRecords(t, scorchmd.SinkSpec{Type: "ndjson"}, dir)
+
+	data, err := os.ReadFile(filepath.Join(dir, "output.ndjson"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	if len(lines) != 3 {
+		t.Logf("expected 3 lines, got %d", len(lines))
+		t.FailNow()
+	}
+
+	var r Record
+
+	if err := json.Unmarshal([]byte(lines[2]), &r); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if r.Component != "tshark" || r.Message != "line three" {
+		t.Logf("unexpected record %+v", r)
+		t.FailNow()
+	}
+}
+
+// receiver returns a local HTTP server that sends the body of each request it
+// receives on the returned channel.
+func receiver(t *testing.T, path, contentType string) (*httptest.Server, chan []byte) {
+	bodies := make(chan []byte, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != contentType {
+			http.Error(w, "bad content type "+ct, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, bodies
+}
+
+func TestLokiSink(t *testing.T) {
+	server, bodies := receiver(t, "/loki/api/v1/push", "application/json")
+
+	spec := scorchmd.SinkSpec{
+		Type: "loki",
+		Config: map[string]interface{}{
+			"url":    server.URL + "/loki/api/v1/push",
+			"labels": map[string]interface{}{"job": "scorch"},
+		},
+	}
+
+	writeRecords(t, spec, "")
+
+	var push struct {
+		Streams []lokiStream `json:"streams"`
+	}
+
+	if err := json.Unmarshal(<-bodies, &push); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(push.Streams) != 2 {
+		t.Logf("expected 2 streams, got %d", len(push.Streams))
+		t.FailNow()
+	}
+
+	stream := push.Streams[0]
+
+	if stream.Stream["component"] != "nmap" || stream.Stream["job"] != "scorch" || len(stream.Values) != 2 {
+		t.Logf("unexpected stream %+v", stream)
+		t.FailNow()
+	}
+
+	if stream.Values[0][0] != fmt.Sprintf("%d", testRecords()[0].Timestamp.UnixNano()) {
+		t.Logf("unexpected timestamp %s", stream.Values[0][0])
+		t.FailNow()
+	}
+}
+
+func TestKafkaSink(t *testing.T) {
+	server, bodies := receiver(t, "/topics/results", "application/vnd.kafka.json.v2+json")
+
+	spec := scorchmd.SinkSpec{
+		Type:   "kafka",
+		Config: map[string]interface{}{"url": server.URL, "topic": "results", "batchSize": "2"},
+	}
+
+	writeRecords(t, spec, "")
+
+	var produce struct {
+		Records []kafkaRecord `json:"records"`
+	}
+
+	// first batch should contain two records
+	if err := json.Unmarshal(<-bodies, &produce); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(produce.Records) != 2 || produce.Records[0].Key != "foo" {
+		t.Logf("unexpected first batch %+v", produce)
+		t.FailNow()
+	}
+
+	// remaining record should be flushed on close
+	if err := json.Unmarshal(<-bodies, &produce); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(produce.Records) != 1 || produce.Records[0].Value.Message != "line three" {
+		t.Logf("unexpected second batch %+v", produce)
+		t.FailNow()
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	server, bodies := receiver(t, "/hook", "application/json")
+
+	spec := scorchmd.SinkSpec{
+		Type:   "webhook",
+		Config: map[string]interface{}{"url": server.URL + "/hook"},
+	}
+
+	writeRecords(t, spec, "")
+
+	var records []Record
+
+	if err := json.Unmarshal(<-bodies, &records); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(records) != 3 {
+		t.Logf("expected 3 records, got %d", len(records))
+		t.FailNow()
+	}
+}
+
+func TestWebhookSinkError(t *testing.T) {
+	server, _ := receiver(t, "/hook", "application/json")
+
+	spec := scorchmd.SinkSpec{
+		Type:   "webhook",
+		Config: map[string]interface{}{"url": server.URL + "/missing"},
+	}
+
+	sink, err := New(spec, "")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	sink.Write(testRecords()[0])
+
+	if err := sink.Close(); err == nil {
+		t.Log("expected error for unsuccessful response")
+		t.FailNow()
+	}
+}
+
+// natsServer is a minimal stand-in for a NATS server that sends the payload of
+// each message published to it on the returned channel.
+func natsServer(t *testing.T) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	msgs := make(chan string, 10)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		fmt.Fprintf(conn, "INFO {\"server_id\":\"test\"}\r\n")
+
+		reader := bufio.NewReader(conn)
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(msgs)
+				return
+			}
+
+			fields := strings.Fields(line)
+
+			switch fields[0] {
+			case "PING":
+				fmt.Fprintf(conn, "PONG\r\n")
+			case "PUB":
+				var size int
+				fmt.Sscanf(fields[2], "%d", &size)
+
+				payload := make([]byte, size+2) // include trailing CRLF
+				io.ReadFull(reader, payload)
+
+				msgs <- fields[1] + " " + string(payload[:size])
+			}
+		}
+	}()
+
+	return "nats://" + listener.Addr().String(), msgs
+}
+
+func TestNATSSink(t *testing.T) {
+	url, msgs := natsServer(t)
+
+	spec := scorchmd.SinkSpec{
+		Type:   "nats",
+		Config: map[string]interface{}{"url": url, "subject": "scorch.results"},
+	}
+
+	writeRecords(t, spec, "")
+
+	var count int
+
+	for msg := range msgs {
+		tokens := strings.SplitN(msg, " ", 2)
+
+		if tokens[0] != "scorch.results" {
+			t.Logf("unexpected subject %s", tokens[0])
+			t.FailNow()
+		}
+
+		var r Record
+
+		if err := json.Unmarshal([]byte(tokens[1]), &r); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		count++
+	}
+
+	if count != 3 {
+		t.Logf("expected 3 messages, got %d", count)
+		t.FailNow()
+	}
+}
+
+func TestBatchRetry(t *testing.T) {
+	var (
+		fail    = true
+		flushed []Record
+	)
+
+	b := newBatch(2, func(records []Record) error {
+		if fail {
+			return fmt.Errorf("sink unavailable")
+		}
+
+		flushed = append(flushed, records...)
+		return nil
+	})
+
+	records := testRecords()
+
+	b.add(records[0])
+
+	if err := b.add(records[1]); err == nil {
+		t.Log("expected error flushing batch")
+		t.FailNow()
+	}
+
+	// Failed flushes aren't retried until the retry interval passes.
+	if err := b.add(records[2]); err != nil {
+		t.Logf("unexpected error adding record before retry interval: %v", err)
+		t.FailNow()
+	}
+
+	fail = false
+
+	if err := b.drain(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(flushed) != 3 || flushed[0].Message != "line one" || flushed[2].Message != "line three" {
+		t.Logf("expected failed batch to be retried, got %+v", flushed)
+		t.FailNow()
+	}
+}
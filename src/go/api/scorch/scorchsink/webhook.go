@@ -0,0 +1,72 @@
+package scorchsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook sends batches of records as a JSON array to a generic HTTP endpoint.
+type Webhook struct {
+	config webhookConfig
+	client *http.Client
+	batch  *batch
+}
+
+type webhookConfig struct {
+	URL string `mapstructure:"url"`
+
+	// HTTP method to use. Defaults to POST.
+	Method string `mapstructure:"method"`
+
+	// Additional HTTP headers to include (e.g. Authorization).
+	Headers map[string]string `mapstructure:"headers"`
+
+	BatchSize int           `mapstructure:"batchSize"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+func (this *Webhook) Init(config map[string]interface{}, _ string) error {
+	if err := decodeConfig(config, &this.config); err != nil {
+		return err
+	}
+
+	if this.config.URL == "" {
+		return fmt.Errorf("missing webhook URL")
+	}
+
+	if this.config.Method == "" {
+		this.config.Method = http.MethodPost
+	}
+
+	if this.config.Timeout == 0 {
+		this.config.Timeout = defaultTimeout
+	}
+
+	this.client = &http.Client{Timeout: this.config.Timeout}
+	this.batch = newBatch(this.config.BatchSize, this.post)
+
+	return nil
+}
+
+func (this *Webhook) Write(r Record) error {
+	return this.batch.add(r)
+}
+
+func (this *Webhook) Close() error {
+	return this.batch.drain()
+}
+
+func (this *Webhook) post(records []Record) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("marshaling records: %w", err)
+	}
+
+	if err := send(this.client, this.config.Method, this.config.URL, "application/json", this.config.Headers, data); err != nil {
+		return fmt.Errorf("sending records to webhook: %w", err)
+	}
+
+	return nil
+}
@@ -1,14 +1,21 @@
 package scorch
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
 
+	"phenix/api/scorch/scorchexe"
+
 	"github.com/creack/pty"
 	"github.com/fatih/color"
 	"golang.org/x/term"
@@ -57,3 +64,142 @@ func terminal(ctx context.Context, dir, cmd string, args []string, envs ...strin
 
 	return nil
 }
+
+func terminalSocket(exp string, run, loop int, stage, name string) string {
+	return filepath.Join(scorchexe.TerminalSocketDir(exp, run), fmt.Sprintf("%d-%s-%s.sock", loop, stage, name))
+}
+
+// stdinIsTerminal returns true if STDIN is attached to a terminal. When it's
+// not (e.g. Scorch is being driven by automation), break components serve their
+// terminal over a UNIX socket instead.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// socketTerminal serves a terminal for the given command over a UNIX socket at
+// the given path. It blocks until a client attaches to the socket and the
+// command exits, or until the given context is canceled.
+func socketTerminal(ctx context.Context, sock, dir, cmd string, args []string, envs ...string) error {
+	listener, err := scorchexe.ListenTerminal(sock)
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(sock)
+	defer listener.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Unblock the call to `Accept` below if the context is canceled.
+	go func() {
+		select {
+		case <-ctx.Done():
+			listener.Close()
+		case <-done:
+		}
+	}()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		return fmt.Errorf("accepting connection on terminal socket %s: %w", sock, err)
+	}
+
+	defer conn.Close()
+
+	// Attaching clients send their terminal size (rows and columns) as the first
+	// line written to the socket.
+	reader := bufio.NewReader(conn)
+
+	header, err := reader.ReadString('\n')
+	if err != nil {
+		return fmt.Errorf("reading terminal size from client: %w", err)
+	}
+
+	var size pty.Winsize
+
+	if _, err := fmt.Sscanf(header, "%d %d", &size.Rows, &size.Cols); err != nil {
+		return fmt.Errorf("parsing terminal size from client: %w", err)
+	}
+
+	c := exec.CommandContext(ctx, cmd, args...)
+	c.Env = append(c.Env, envs...)
+	c.Dir = dir
+
+	tty, err := pty.StartWithSize(c, &size)
+	if err != nil {
+		return fmt.Errorf("starting pty for %s: %w", cmd, err)
+	}
+
+	defer tty.Close()
+
+	go io.Copy(tty, reader)
+	io.Copy(conn, tty)
+
+	c.Wait()
+
+	return nil
+}
+
+// AttachTerminal attaches the current terminal to a break component waiting
+// for the given experiment run. If name is provided, only break components with
+// the given name are considered. If multiple break components are waiting, the
+// first one (ordered by loop, stage, and name) is attached to.
+func AttachTerminal(ctx context.Context, exp string, run int, name string) error {
+	socks, err := filepath.Glob(filepath.Join(scorchexe.TerminalSocketDir(exp, run), "*.sock"))
+	if err != nil {
+		return fmt.Errorf("finding terminal sockets: %w", err)
+	}
+
+	sort.Strings(socks)
+
+	var sock string
+
+	for _, s := range socks {
+		if name == "" || strings.HasSuffix(s, fmt.Sprintf("-%s.sock", name)) {
+			sock = s
+			break
+		}
+	}
+
+	if sock == "" {
+		return fmt.Errorf("no break components are waiting for Scorch run %d of experiment %s", run, exp)
+	}
+
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "unix", sock)
+	if err != nil {
+		return fmt.Errorf("connecting to terminal socket %s: %w", sock, err)
+	}
+
+	defer conn.Close()
+
+	rows, cols, err := pty.Getsize(os.Stdin)
+	if err != nil {
+		rows, cols = 24, 80
+	}
+
+	if _, err := fmt.Fprintf(conn, "%d %d\n", rows, cols); err != nil {
+		return fmt.Errorf("sending terminal size: %w", err)
+	}
+
+	printer := color.New(color.FgGreen)
+	printer.Printf("Attached to %s: exiting the shell will continue the Scorch run...\n\n", filepath.Base(sock))
+
+	old, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return fmt.Errorf("putting STDIN into raw mode: %w", err)
+	}
+
+	defer term.Restore(int(os.Stdin.Fd()), old)
+
+	go io.Copy(conn, os.Stdin)
+	io.Copy(os.Stdout, conn)
+
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strconv"
 
+	"phenix/api/scorch/scorchexe"
 	"phenix/store"
 	"phenix/util"
 	"phenix/util/common"
@@ -93,6 +96,28 @@ func (this UserComponent) run(ctx context.Context, stage Action, cmd string, dat
 		Status:  "running",
 	}
 
+	// Each iteration of a loop gets its own output directory so outputs from
+	// different parameter combinations don't overwrite each other.
+	outDir := filepath.Join(
+		scorchexe.RunDir(&this.options.Exp, this.options.Run),
+		this.options.Name, fmt.Sprintf("loop-%d-count-%d", this.options.Loop, this.options.Count),
+	)
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("creating output directory for component %s: %w", this.options.Name, err)
+	}
+
+	params, err := json.Marshal(this.options.Params)
+	if err != nil {
+		return fmt.Errorf("marshaling parameters to JSON: %w", err)
+	}
+
+	if len(this.options.Params) > 0 {
+		if err := os.WriteFile(filepath.Join(outDir, "parameters.json"), params, 0644); err != nil {
+			return fmt.Errorf("writing parameters for component %s: %w", this.options.Name, err)
+		}
+	}
+
 	stdout := make(chan []byte)
 
 	opts := []shell.Option{
@@ -107,11 +132,15 @@ func (this UserComponent) run(ctx context.Context, stage Action, cmd string, dat
 			"PHENIX_LOG_FILE="+util.GetEnv("PHENIX_LOG_FILE", common.LogFile),
 			"PHENIX_DRYRUN="+strconv.FormatBool(this.options.Exp.DryRun()),
 			"PHENIX_SCORCH_STARTTIME="+this.options.StartTime,
+			"PHENIX_SCORCH_OUTPUT_DIR="+outDir,
+			"PHENIX_SCORCH_PARAMETERS="+string(params),
 		),
 	}
 
 	go func() {
 		for output := range stdout {
+			writeOutput(ctx, this.options, stage, output)
+
 			update.Output = append(output, []byte("\n")...)
 			scorch.UpdateComponent(update)
 		}
@@ -7,13 +7,17 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"sort"
 	"strings"
 	"time"
 
+	"phenix/api/webhook"
 	"phenix/app"
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
+	"phenix/util/metrics"
 	"phenix/util/mm"
+	"phenix/util/pubsub"
 
 	"github.com/activeshadow/structs"
 	"github.com/fatih/color"
@@ -22,6 +26,8 @@ import (
 
 func init() {
 	app.RegisterUserApp("soh", func() app.App { return newSOH() })
+
+	pubsub.Share("soh", Publication{})
 }
 
 type SOH struct {
@@ -383,6 +389,8 @@ func (this *SOH) runChecks(ctx context.Context, exp *types.Experiment) error {
 		return ctx.Err()
 	}
 
+	this.reportResults(exp)
+
 	// TODO: this does not include errors from above function calls
 	if wg.ErrCount > 0 {
 		return fmt.Errorf("errors encountered in state of health app")
@@ -505,6 +513,54 @@ func (this *SOH) getFlows(ctx context.Context, exp *types.Experiment) {
 	this.packetCapture["flows"] = flows
 }
 
+// reportResults records the number of passed and failed state of health
+// checks, and fires a webhook event listing the hosts that failed any of them.
+func (this SOH) reportResults(exp *types.Experiment) {
+	failed := make(map[string][]string)
+
+	for host, state := range this.status {
+		checks := map[string][]State{
+			"networking":   state.Networking,
+			"reachability": state.Reachability,
+			"processes":    state.Processes,
+			"listeners":    state.Listeners,
+			"custom":       state.CustomTests,
+		}
+
+		for check, states := range checks {
+			for _, s := range states {
+				result := "pass"
+
+				if s.Error != "" {
+					result = "fail"
+					failed[host] = append(failed[host], s.Error)
+				}
+
+				metrics.SoHChecks.WithLabelValues(exp.Metadata.Name, check, result).Inc()
+			}
+		}
+	}
+
+	if len(failed) == 0 {
+		return
+	}
+
+	hosts := make([]string, 0, len(failed))
+
+	for host := range failed {
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	event := webhook.NewEvent(webhook.SoHFailure, exp.Metadata.Name).
+		WithAnnotations(exp.Metadata.Annotations).
+		WithData("hosts", hosts).
+		WithData("errors", failed)
+
+	webhook.Fire(event)
+}
+
 func (this SOH) writeResults(exp *types.Experiment) {
 	appStatus := make(map[string]interface{})
 
@@ -524,4 +580,18 @@ func (this SOH) writeResults(exp *types.Experiment) {
 
 	exp.Status.SetAppStatus("soh", appStatus)
 	exp.WriteToStore(true)
+
+	pub := Publication{Experiment: exp.Metadata.Name, Hosts: len(this.status)}
+
+	for _, state := range this.status {
+		for _, states := range [][]State{state.Networking, state.Reachability, state.Processes, state.Listeners, state.CustomTests} {
+			for _, s := range states {
+				if s.Error != "" {
+					pub.Errors++
+				}
+			}
+		}
+	}
+
+	pubsub.Publish("soh", pub)
 }
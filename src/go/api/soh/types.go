@@ -241,3 +241,11 @@ func (this *sohProfile) init() error {
 
 	return nil
 }
+
+// Publication is published to the "soh" topic each time the state of health
+// results for an experiment are updated.
+type Publication struct {
+	Experiment string `json:"experiment"`
+	Hosts      int    `json:"hosts"`
+	Errors     int    `json:"errors"`
+}
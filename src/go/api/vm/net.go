@@ -45,3 +45,48 @@ func Disonnect(expName, vmName string, iface int) error {
 
 	return nil
 }
+
+// Impair applies the given impairment to traffic sent out of the given
+// interface for the given VM in the given experiment, replacing any impairment
+// already applied to the interface. It returns any errors encountered while
+// applying the impairment.
+func Impair(expName, vmName string, iface int, imp mm.Impairment) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	if imp.Empty() {
+		return fmt.Errorf("no impairment settings provided")
+	}
+
+	err := mm.ImpairVMInterface(mm.NS(expName), mm.VMName(vmName), mm.ImpairInterface(iface), mm.ImpairmentSettings(imp))
+	if err != nil {
+		return fmt.Errorf("impairing VM interface: %w", err)
+	}
+
+	return nil
+}
+
+// ClearImpairment removes any impairment applied to the given interface for the
+// given VM in the given experiment. It returns any errors encountered while
+// clearing the impairment.
+func ClearImpairment(expName, vmName string, iface int) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	err := mm.ClearVMInterfaceImpairment(mm.NS(expName), mm.VMName(vmName), mm.ImpairInterface(iface))
+	if err != nil {
+		return fmt.Errorf("clearing VM interface impairment: %w", err)
+	}
+
+	return nil
+}
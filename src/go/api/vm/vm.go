@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"phenix/api/experiment"
+	"phenix/api/webhook"
 	"phenix/util"
 	"phenix/util/common"
 	"phenix/util/file"
@@ -344,6 +345,8 @@ func Pause(expName, vmName string) error {
 		return fmt.Errorf("pausing VM: %w", err)
 	}
 
+	webhook.Fire(webhook.NewEvent(webhook.VMPause, expName).WithVM(vmName))
+
 	return nil
 }
 
@@ -366,18 +369,21 @@ func Restart(expName, vmName string) error {
 
 	//Using "system_reset" on a VM that is in the "QUIT" state fails
 	if state == "QUIT" {
-		return mm.StartVM(mm.NS(expName), mm.VMName(vmName))
+		if err := mm.StartVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
+			return err
+		}
+	} else {
+		cmd := mmcli.NewNamespacedCommand(expName)
+		qmp := fmt.Sprintf(`{ "execute": "system_reset" }`)
+		cmd.Command = fmt.Sprintf("vm qmp %s '%s'", vmName, qmp)
 
+		_, err = mmcli.SingleResponse(mmcli.Run(cmd))
+		if err != nil {
+			return fmt.Errorf("restarting VM %s: %w", vmName, err)
+		}
 	}
 
-	cmd := mmcli.NewNamespacedCommand(expName)
-	qmp := fmt.Sprintf(`{ "execute": "system_reset" }`)
-	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", vmName, qmp)
-
-	_, err = mmcli.SingleResponse(mmcli.Run(cmd))
-	if err != nil {
-		return fmt.Errorf("restarting VM %s: %w", vmName, err)
-	}
+	webhook.Fire(webhook.NewEvent(webhook.VMRestart, expName).WithVM(vmName))
 
 	return nil
 }
@@ -451,6 +457,8 @@ func Shutdown(expName, vmName string) error {
 		}
 	}
 
+	webhook.Fire(webhook.NewEvent(webhook.VMShutdown, expName).WithVM(vmName))
+
 	return nil
 }
 
@@ -554,6 +562,8 @@ func Resume(expName, vmName string) error {
 		return fmt.Errorf("resuming VM: %w", err)
 	}
 
+	webhook.Fire(webhook.NewEvent(webhook.VMResume, expName).WithVM(vmName))
+
 	return nil
 }
 
@@ -612,6 +622,8 @@ func Redeploy(expName, vmName string, opts ...RedeployOption) error {
 		return fmt.Errorf("redeploying VM: %w", err)
 	}
 
+	webhook.Fire(webhook.NewEvent(webhook.VMRedeploy, expName).WithVM(vmName))
+
 	return nil
 }
 
@@ -630,6 +642,8 @@ func Kill(expName, vmName string) error {
 		return fmt.Errorf("killing VM: %w", err)
 	}
 
+	webhook.Fire(webhook.NewEvent(webhook.VMKill, expName).WithVM(vmName))
+
 	return nil
 }
 
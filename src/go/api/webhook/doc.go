@@ -0,0 +1,2 @@
+// Implementation of the phenix Webhook API.
+package webhook
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Event types fired by phenix.
+const (
+	ExperimentCreate = "experiment.create"
+	ExperimentStart  = "experiment.start"
+	ExperimentStop   = "experiment.stop"
+	ExperimentDelete = "experiment.delete"
+
+	VMPause    = "vm.pause"
+	VMResume   = "vm.resume"
+	VMRestart  = "vm.restart"
+	VMShutdown = "vm.shutdown"
+	VMKill     = "vm.kill"
+	VMRedeploy = "vm.redeploy"
+
+	SoHFailure = "soh.failure"
+
+	ScorchRunComplete = "scorch.run.complete"
+
+	Test = "webhook.test"
+)
+
+// Event is the body of each webhook request.
+type Event struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	Timestamp  string                 `json:"timestamp"`
+	Experiment string                 `json:"experiment,omitempty"`
+	VM         string                 `json:"vm,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+
+	// Annotations of the event's experiment, used to match webhook annotation
+	// selectors. If nil, they're looked up from the store when needed.
+	Annotations map[string]string `json:"-"`
+}
+
+// NewEvent returns a new event of the given type for the given experiment.
+func NewEvent(typ, exp string) *Event {
+	return &Event{
+		ID:         uuid.Must(uuid.NewV4()).String(),
+		Type:       typ,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		Experiment: exp,
+	}
+}
+
+func (this *Event) WithVM(name string) *Event {
+	this.VM = name
+	return this
+}
+
+func (this *Event) WithData(key string, value interface{}) *Event {
+	if this.Data == nil {
+		this.Data = make(map[string]interface{})
+	}
+
+	this.Data[key] = value
+	return this
+}
+
+func (this *Event) WithAnnotations(annotations map[string]string) *Event {
+	this.Annotations = annotations
+
+	if this.Annotations == nil {
+		// Keep from looking them up in the store.
+		this.Annotations = make(map[string]string)
+	}
+
+	return this
+}
@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	DefaultRetries = 3
+	DefaultBackoff = 1 * time.Second
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultExitWait is how long the command line waits for pending
+	// deliveries before exiting, so an unreachable receiver doesn't hold up
+	// every command for its full retry budget.
+	DefaultExitWait = 5 * time.Second
+)
+
+// SignatureHeader is the request header containing the hex encoded
+// HMAC-SHA256 signature of the request body, prefixed with `sha256=`, for
+// webhooks configured with a secret.
+const SignatureHeader = "X-Phenix-Signature"
+
+// pending tracks deliveries in progress so callers (e.g. the command line) can
+// wait for them before exiting.
+var pending sync.WaitGroup
+
+type Webhook struct {
+	Name string
+	Spec *v1.WebhookSpec
+}
+
+// List returns all the webhooks in the store.
+func List() ([]Webhook, error) {
+	configs, err := store.List("Webhook")
+	if err != nil {
+		return nil, fmt.Errorf("getting webhook configs: %w", err)
+	}
+
+	hooks := make([]Webhook, len(configs))
+
+	for i, c := range configs {
+		var spec v1.WebhookSpec
+
+		if err := mapstructure.Decode(c.Spec, &spec); err != nil {
+			return nil, fmt.Errorf("decoding webhook %s: %w", c.Metadata.Name, err)
+		}
+
+		hooks[i] = Webhook{Name: c.Metadata.Name, Spec: &spec}
+	}
+
+	return hooks, nil
+}
+
+// Get returns the webhook with the given name from the store.
+func Get(name string) (*Webhook, error) {
+	c, _ := store.NewConfig("webhook/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting webhook %s from store: %w", name, err)
+	}
+
+	var spec v1.WebhookSpec
+
+	if err := mapstructure.Decode(c.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("decoding webhook %s: %w", name, err)
+	}
+
+	return &Webhook{Name: name, Spec: &spec}, nil
+}
+
+// Fire sends the given event to each webhook in the store that matches it.
+// Requests are sent in the background, and errors are logged once all retries
+// have been exhausted.
+func Fire(event *Event) {
+	hooks, err := List()
+	if err != nil {
+		log.Error("unable to fire %s webhooks: %v", event.Type, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !hook.Matches(event) {
+			continue
+		}
+
+		pending.Add(1)
+
+		go func(hook Webhook) {
+			defer pending.Done()
+
+			if err := hook.Send(context.Background(), event); err != nil {
+				log.Error("sending %s event to webhook %s: %v", event.Type, hook.Name, err)
+			}
+		}(hook)
+	}
+}
+
+// Wait blocks until all events fired so far have been sent or the given
+// timeout expires, returning false if any deliveries were still pending.
+func Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Matches returns true if the webhook's event type and experiment filters
+// match the given event.
+func (this Webhook) Matches(event *Event) bool {
+	if len(this.Spec.Events) > 0 && !globMatch(this.Spec.Events, event.Type) {
+		return false
+	}
+
+	if len(this.Spec.Experiments) > 0 && !globMatch(this.Spec.Experiments, event.Experiment) {
+		return false
+	}
+
+	if len(this.Spec.AnnotationSelector) > 0 {
+		if event.Annotations == nil {
+			event.Annotations = experimentAnnotations(event.Experiment)
+		}
+
+		for k, v := range this.Spec.AnnotationSelector {
+			if matched, _ := filepath.Match(v, event.Annotations[k]); !matched {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Send posts the given event to the webhook, retrying with exponential backoff
+// if the request fails or the receiver responds with an error status.
+func (this Webhook) Send(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	var (
+		retries = DefaultRetries
+		backoff = DefaultBackoff
+		timeout = DefaultTimeout
+	)
+
+	if this.Spec.Retries != nil {
+		retries = *this.Spec.Retries
+	}
+
+	if this.Spec.Backoff != "" {
+		if backoff, err = time.ParseDuration(this.Spec.Backoff); err != nil {
+			return fmt.Errorf("parsing backoff for webhook %s: %w", this.Name, err)
+		}
+	}
+
+	if this.Spec.Timeout != "" {
+		if timeout, err = time.ParseDuration(this.Spec.Timeout); err != nil {
+			return fmt.Errorf("parsing timeout for webhook %s: %w", this.Name, err)
+		}
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	for attempt := 0; ; attempt++ {
+		if err = this.post(ctx, client, event, body); err == nil {
+			return nil
+		}
+
+		if attempt >= retries {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff << attempt):
+		}
+	}
+}
+
+func (this Webhook) post(ctx context.Context, client *http.Client, event *Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, this.Spec.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Phenix-Event", event.Type)
+	req.Header.Set("X-Phenix-Delivery", event.ID)
+
+	if this.Spec.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(this.Spec.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the given body using
+// the given secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func globMatch(patterns []string, value string) bool {
+	// If every pattern is a negation, anything not excluded matches.
+	matched := true
+
+	for _, p := range patterns {
+		if !strings.HasPrefix(p, "!") {
+			matched = false
+			break
+		}
+	}
+
+	for _, p := range patterns {
+		negate := strings.HasPrefix(p, "!")
+
+		if ok, _ := filepath.Match(strings.TrimPrefix(p, "!"), value); ok {
+			if negate {
+				return false
+			}
+
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+func experimentAnnotations(name string) map[string]string {
+	if name == "" {
+		return nil
+	}
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil
+	}
+
+	return c.Metadata.Annotations
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "phenix/types/version/v1"
+)
+
+func TestWebhookMatches(t *testing.T) {
+	hook := Webhook{
+		Name: "test",
+		Spec: &v1.WebhookSpec{
+			Events:             []string{"experiment.*", "soh.failure"},
+			Experiments:        []string{"foo*", "!foobar"},
+			AnnotationSelector: map[string]string{"team": "red"},
+		},
+	}
+
+	cases := []struct {
+		event    *Event
+		expected bool
+	}{
+		{NewEvent(ExperimentStart, "foo").WithAnnotations(map[string]string{"team": "red"}), true},
+		{NewEvent(SoHFailure, "foo-1").WithAnnotations(map[string]string{"team": "red"}), true},
+		{NewEvent(VMPause, "foo").WithAnnotations(map[string]string{"team": "red"}), false},
+		{NewEvent(ExperimentStart, "foobar").WithAnnotations(map[string]string{"team": "red"}), false},
+		{NewEvent(ExperimentStart, "bar").WithAnnotations(map[string]string{"team": "red"}), false},
+		{NewEvent(ExperimentStart, "foo").WithAnnotations(map[string]string{"team": "blue"}), false},
+		{NewEvent(ExperimentStart, "foo").WithAnnotations(nil), false},
+	}
+
+	for _, c := range cases {
+		if actual := hook.Matches(c.event); actual != c.expected {
+			t.Logf("expected %s event for %s with annotations %v to match: %v", c.event.Type, c.event.Experiment, c.event.Annotations, c.expected)
+			t.FailNow()
+		}
+	}
+
+	// Experiment filters made up only of negations match every other experiment.
+	hook.Spec.Experiments = []string{"!scratch-*"}
+
+	if !hook.Matches(NewEvent(ExperimentStart, "foo").WithAnnotations(map[string]string{"team": "red"})) {
+		t.Log("expected negated experiment filter to match experiment foo")
+		t.FailNow()
+	}
+
+	if hook.Matches(NewEvent(ExperimentStart, "scratch-1").WithAnnotations(map[string]string{"team": "red"})) {
+		t.Log("expected negated experiment filter to not match experiment scratch-1")
+		t.FailNow()
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	var (
+		attempts int
+		received Event
+	)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+
+		// Fail the first request to exercise retries.
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+
+		if sig := r.Header.Get(SignatureHeader); sig != "sha256="+Sign("secret", body) {
+			t.Logf("unexpected signature %s", sig)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		json.Unmarshal(body, &received)
+	}))
+
+	defer receiver.Close()
+
+	retries := 2
+
+	hook := Webhook{
+		Name: "test",
+		Spec: &v1.WebhookSpec{URL: receiver.URL, Secret: "secret", Retries: &retries, Backoff: "1ms"},
+	}
+
+	event := NewEvent(ExperimentStop, "foo").WithData("reason", "testing")
+
+	if err := hook.Send(context.Background(), event); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if attempts != 2 {
+		t.Logf("expected 2 attempts, got %d", attempts)
+		t.FailNow()
+	}
+
+	if received.ID != event.ID || received.Type != ExperimentStop || received.Experiment != "foo" || received.Data["reason"] != "testing" {
+		t.Logf("unexpected event received: %+v", received)
+		t.FailNow()
+	}
+
+	retries = 0
+	hook.Spec.Secret = "wrong"
+
+	if err := hook.Send(context.Background(), event); err == nil {
+		t.Log("expected error sending event with wrong signature and no retries")
+		t.FailNow()
+	}
+}
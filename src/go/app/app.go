@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -9,7 +10,9 @@ import (
 
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
+	"phenix/util/metrics"
 	"phenix/util/notes"
+	"phenix/util/plog"
 	"phenix/util/pubsub"
 	"phenix/util/shell"
 
@@ -29,6 +32,44 @@ type Publication struct {
 	Error      error
 }
 
+// MarshalJSON encodes the publication's error as its message so publications
+// can be shared with other phenix instances.
+func (this Publication) MarshalJSON() ([]byte, error) {
+	type alias Publication
+
+	pub := struct {
+		alias
+		Error string `json:",omitempty"`
+	}{alias: alias(this)}
+
+	if this.Error != nil {
+		pub.Error = this.Error.Error()
+	}
+
+	return json.Marshal(pub)
+}
+
+func (this *Publication) UnmarshalJSON(data []byte) error {
+	type alias Publication
+
+	var pub struct {
+		alias
+		Error string
+	}
+
+	if err := json.Unmarshal(data, &pub); err != nil {
+		return err
+	}
+
+	*this = Publication(pub.alias)
+
+	if pub.Error != "" {
+		this.Error = errors.New(pub.Error)
+	}
+
+	return nil
+}
+
 const (
 	ACTIONCONFIG    Action = "configure"
 	ACTIONPRESTART  Action = "pre-start"
@@ -59,6 +100,8 @@ func init() {
 
 	// External user apps
 	apps["user-shell"] = func() App { return new(UserApp) }
+
+	pubsub.Share("trigger-app", Publication{})
 }
 
 func RegisterUserApp(name string, factory AppFactory) error {
@@ -176,6 +219,9 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 		exp.Status.ResetAppStatus()
 	}
 
+	// Include the experiment and stage in all logs generated by apps.
+	ctx = plog.WithStage(plog.WithExperiment(ctx, exp.Spec.ExperimentName()), string(options.Stage))
+
 	for _, name := range DefaultApps() {
 		if ctx.Err() != nil {
 			return ctx.Err()
@@ -184,6 +230,10 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 		a := GetApp(name)
 		a.Init(Name(name), DryRun(options.DryRun))
 
+		ctx := plog.WithApp(ctx, a.Name())
+
+		start := time.Now()
+
 		switch options.Stage {
 		case ACTIONCONFIG:
 			err = a.Configure(ctx, exp)
@@ -197,6 +247,8 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 			err = a.Cleanup(ctx, exp)
 		}
 
+		observeStage(ctx, options.Stage, start, err)
+
 		var (
 			status  = "✓"
 			printer = color.New(color.FgGreen)
@@ -228,6 +280,10 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 			a := GetApp(app.Name())
 			a.Init(Name(app.Name()), DryRun(options.DryRun))
 
+			ctx := plog.WithApp(ctx, a.Name())
+
+			start := time.Now()
+
 			switch options.Stage {
 			case ACTIONCONFIG:
 				exp.Status.SetAppRunning(app.Name(), true)
@@ -300,6 +356,10 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 				exp.WriteToStore(true)
 			}
 
+			if !errors.Is(err, ErrUserAppNotFound) {
+				observeStage(ctx, options.Stage, start, err)
+			}
+
 			var (
 				status  = "✓"
 				printer = color.New(color.FgGreen)
@@ -335,6 +395,23 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 	return nil
 }
 
+// observeStage logs and records how long the app in the given context took to
+// apply the given stage, along with whether it failed.
+func observeStage(ctx context.Context, stage Action, start time.Time, err error) {
+	var (
+		name, _  = plog.Fields(ctx)[plog.AppKey].(string)
+		duration = time.Since(start)
+	)
+
+	metrics.AppStageDuration.WithLabelValues(name, string(stage), metrics.Status(err)).Observe(duration.Seconds())
+
+	if err != nil {
+		plog.Error(ctx, "app stage failed", "duration", duration.String(), "error", err)
+	} else {
+		plog.Info(ctx, "app stage applied", "duration", duration.String())
+	}
+}
+
 // PeriodicallyRunApps checks the configuration for each app in the scenario to
 // see if it's configured to have its "running" stage run periodically. A
 // Goroutine is scheduled for each applicable app.
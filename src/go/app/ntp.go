@@ -5,21 +5,74 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"phenix/tmpl"
 	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+	"phenix/util/plog"
 
 	"github.com/mitchellh/mapstructure"
 )
 
+// DefaultNTPStratum is the stratum used for the local clock of NTP servers that
+// don't have a source and don't specify a stratum.
+const DefaultNTPStratum = 8
+
 type NTPAppMetadata struct {
 	DefaultSource NTPAppSource `mapstructure:"defaultSource"`
+
+	// StartTime is an RFC 3339 timestamp the clocks of all VMs in the experiment
+	// will be set to when they boot, so the exercise can take place at a
+	// simulated date. It takes precedence over TimeOffset.
+	StartTime string `mapstructure:"startTime"`
+
+	// TimeOffset is a duration (e.g. -8760h) the clocks of all VMs in the
+	// experiment will be offset by from the current time when they boot.
+	TimeOffset string `mapstructure:"timeOffset"`
+
+	Verify NTPAppVerify `mapstructure:"verify"`
+}
+
+// NTPAppVerify configures verification that NTP clients are synchronized to
+// their sources after the experiment starts. Verification uses minimega's C2
+// (miniccc) capabilities, so clients must have miniccc installed.
+type NTPAppVerify struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Timeout string `mapstructure:"timeout"`
+
+	// Wait makes starting the experiment wait for verification to finish,
+	// failing the start if any clients aren't synchronized before the timeout.
+	// Otherwise verification runs in the background and failures are logged.
+	Wait bool `mapstructure:"wait"`
 }
 
 type NTPAppHostMetadata struct {
 	Client string       `mapstructure:"client"`
 	Server string       `mapstructure:"server"`
 	Source NTPAppSource `mapstructure:"source"`
+
+	// Stratum is the stratum served by an NTP server when it falls back to its
+	// local clock. If not set, servers whose source is another server in the
+	// app are one stratum below their source and all other servers use
+	// DefaultNTPStratum. It's ignored for Windows servers, since w32time
+	// doesn't support configuring the stratum it serves.
+	Stratum int `mapstructure:"stratum"`
+
+	// Domain configures Windows clients to synchronize to their Active Directory
+	// domain's time hierarchy instead of a source. Windows servers always
+	// announce themselves as reliable time sources, so a domain controller
+	// configured as a server is the source for the rest of the domain.
+	Domain bool `mapstructure:"domain"`
+}
+
+// ntpConfig is the data passed to the NTP config templates.
+type ntpConfig struct {
+	Source  string
+	Stratum int
+	Server  bool
+	Domain  bool
 }
 
 type NTPAppSource struct {
@@ -83,9 +136,22 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 				var amd NTPAppMetadata
 				mapstructure.Decode(app.Metadata(), &amd)
 
+				if err := setClockBase(exp, amd); err != nil {
+					return err
+				}
+
+				strata, err := ntpStrata(exp, app)
+				if err != nil {
+					return err
+				}
+
 				// Might be an empty string, but that's okay... for now.
 				defaultSource := amd.DefaultSource.IPAddress(exp)
 
+				if err := os.MkdirAll(ntpDir, 0755); err != nil {
+					return fmt.Errorf("creating experiment NTP directory path: %w", err)
+				}
+
 				for _, host := range app.Hosts() {
 					node := exp.Spec.Topology().FindNodeByName(host.Hostname())
 					if node == nil {
@@ -101,7 +167,12 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 					)
 
 					if hmd.Client != "" {
-						if source == "" {
+						// Windows domain members get their time from the domain hierarchy.
+						domain := hmd.Domain && strings.EqualFold(hmd.Client, "windows")
+
+						if domain {
+							source = ""
+						} else if source == "" {
 							if defaultSource == "" {
 								return fmt.Errorf("no NTP source configured for host %s (and no default source configured)", host.Hostname())
 							}
@@ -109,13 +180,21 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 							source = defaultSource
 						}
 
+						data := ntpConfig{Source: source, Stratum: DefaultNTPStratum, Domain: domain}
+
 						switch strings.ToLower(hmd.Client) {
 						case "ntp":
-							if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", source, cfg); err != nil {
+							if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", data, cfg); err != nil {
 								return fmt.Errorf("generating NTP client config for host %s: %w", host.Hostname(), err)
 							}
 
 							node.AddInject(cfg, "/etc/ntp.conf", "", "")
+						case "chrony":
+							if err := tmpl.CreateFileFromTemplate("chrony.tmpl", data, cfg); err != nil {
+								return fmt.Errorf("generating NTP client config for host %s: %w", host.Hostname(), err)
+							}
+
+							node.AddInject(cfg, chronyConfigPath(node), "", "")
 						case "systemd":
 							if err := tmpl.CreateFileFromTemplate("systemd-timesyncd.tmpl", source, cfg); err != nil {
 								return fmt.Errorf("generating NTP client config for host %s: %w", host.Hostname(), err)
@@ -123,7 +202,7 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 
 							node.AddInject(cfg, "/etc/systemd/timesyncd.conf", "", "")
 						case "windows":
-							if err := tmpl.CreateFileFromTemplate("ntp_windows.tmpl", source, cfg); err != nil {
+							if err := tmpl.CreateFileFromTemplate("ntp_windows.tmpl", data, cfg); err != nil {
 								return fmt.Errorf("generating NTP client config for host %s: %w", host.Hostname(), err)
 							}
 
@@ -136,16 +215,30 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 					}
 
 					if hmd.Server != "" {
+						// It's okay if `source` is an empty string here. If it is, the
+						// template will generate a config for the NTP server that prefers
+						// the host's clock as the source.
+						data := ntpConfig{Source: source, Stratum: strata[host.Hostname()], Server: true}
+
 						switch strings.ToLower(hmd.Server) {
 						case "ntpd":
-							// It's okay if `source` is an empty string here. If it is, the
-							// template will generate a config for the NTP server that prefers
-							// the host's clock as the source.
-							if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", source, cfg); err != nil {
+							if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", data, cfg); err != nil {
 								return fmt.Errorf("generating NTP server config for host %s: %w", host.Hostname(), err)
 							}
 
 							node.AddInject(cfg, "/etc/ntp.conf", "", "")
+						case "chrony":
+							if err := tmpl.CreateFileFromTemplate("chrony.tmpl", data, cfg); err != nil {
+								return fmt.Errorf("generating NTP server config for host %s: %w", host.Hostname(), err)
+							}
+
+							node.AddInject(cfg, chronyConfigPath(node), "", "")
+						case "windows":
+							if err := tmpl.CreateFileFromTemplate("ntp_windows.tmpl", data, cfg); err != nil {
+								return fmt.Errorf("generating NTP server config for host %s: %w", host.Hostname(), err)
+							}
+
+							node.AddInject(cfg, "/phenix/startup/25-ntp.ps1", "0755", "")
 						default:
 							return fmt.Errorf("unknown NTP server type %s provided for host %s", hmd.Server, host.Hostname())
 						}
@@ -186,8 +279,10 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 
 		ntpFile := ntpDir + "/" + node.General().Hostname() + "_ntp"
 
+		data := ntpConfig{Source: serverAddr, Stratum: DefaultNTPStratum}
+
 		if strings.ToLower(node.Type()) == "router" {
-			if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", serverAddr, ntpFile); err != nil {
+			if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", data, ntpFile); err != nil {
 				return fmt.Errorf("generating Router NTP script: %w", err)
 			}
 
@@ -203,13 +298,13 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 
 		switch strings.ToLower(node.Hardware().OSType()) {
 		case "linux", "rhel", "centos":
-			if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", serverAddr, ntpFile); err != nil {
+			if err := tmpl.CreateFileFromTemplate("ntp_linux.tmpl", data, ntpFile); err != nil {
 				return fmt.Errorf("generating Linux NTP script: %w", err)
 			}
 
 			node.AddInject(ntpFile, "/etc/ntp.conf", "", "")
 		case "windows":
-			if err := tmpl.CreateFileFromTemplate("ntp_windows.tmpl", serverAddr, ntpFile); err != nil {
+			if err := tmpl.CreateFileFromTemplate("ntp_windows.tmpl", data, ntpFile); err != nil {
 				return fmt.Errorf("generating Windows NTP script: %w", err)
 			}
 
@@ -221,6 +316,126 @@ func (NTP) PreStart(ctx context.Context, exp *types.Experiment) error {
 }
 
 func (NTP) PostStart(ctx context.Context, exp *types.Experiment) error {
+	for _, app := range exp.Apps() {
+		if app.Name() != "ntp" {
+			continue
+		}
+
+		var amd NTPAppMetadata
+		mapstructure.Decode(app.Metadata(), &amd)
+
+		if !amd.Verify.Enabled {
+			return nil
+		}
+
+		timeout := 5 * time.Minute
+
+		if amd.Verify.Timeout != "" {
+			var err error
+
+			timeout, err = time.ParseDuration(amd.Verify.Timeout)
+			if err != nil {
+				return fmt.Errorf("parsing NTP verification timeout: %w", err)
+			}
+		}
+
+		if amd.Verify.Wait {
+			return verifyNTP(ctx, exp, app, amd, timeout)
+		}
+
+		// Don't hold up the experiment start (or cancel verification when the
+		// request starting it completes).
+		go func(ctx context.Context, app ifaces.ScenarioApp) {
+			if err := verifyNTP(ctx, exp, app, amd, timeout); err != nil {
+				plog.Error(ctx, "NTP verification failed", "error", err)
+				return
+			}
+
+			plog.Info(ctx, "NTP clients synchronized")
+		}(plog.Detach(ctx), app)
+	}
+
+	return nil
+}
+
+// verifyNTP waits up to the given timeout for each NTP client in the given app
+// to be synchronized to its source.
+func verifyNTP(ctx context.Context, exp *types.Experiment, app ifaces.ScenarioApp, amd NTPAppMetadata, timeout time.Duration) error {
+	var (
+		defaultSource = amd.DefaultSource.IPAddress(exp)
+		wg            = new(mm.StateGroup)
+		delay         = 10 * time.Second
+	)
+
+	for _, host := range app.Hosts() {
+		var hmd NTPAppHostMetadata
+		mapstructure.Decode(host.Metadata(), &hmd)
+
+		if hmd.Client == "" {
+			continue
+		}
+
+		var (
+			client = strings.ToLower(hmd.Client)
+			source = hmd.Source.IPAddress(exp)
+		)
+
+		// Windows domain members can be synchronized to any domain controller.
+		if hmd.Domain && client == "windows" {
+			source = ""
+		} else if source == "" {
+			source = defaultSource
+		}
+
+		var (
+			hostname = host.Hostname()
+			meta     = map[string]interface{}{"host": hostname, "source": source}
+			retries  = int(timeout / delay)
+		)
+
+		cmd := &mm.C2ParallelCommand{
+			Wait:    wg,
+			Options: []mm.C2Option{mm.C2NS(exp.Spec.ExperimentName()), mm.C2VM(hostname), mm.C2Command(ntpSyncCommand(client))},
+			Meta:    meta,
+			Expected: func(resp string) error {
+				if ntpSynced(client, source, resp) {
+					wg.AddSuccess("synchronized", meta)
+					return nil
+				}
+
+				if retries > 0 {
+					retries--
+					return mm.C2RetryError{Delay: delay}
+				}
+
+				if source == "" {
+					return fmt.Errorf("not synchronized after %v", timeout)
+				}
+
+				return fmt.Errorf("not synchronized to %s after %v", source, timeout)
+			},
+		}
+
+		mm.ScheduleC2ParallelCommand(plog.WithVM(ctx, hostname), cmd)
+	}
+
+	wg.Wait()
+
+	if wg.ErrCount > 0 {
+		var errs []string
+
+		for _, state := range wg.States {
+			if state.Err != nil {
+				host, _ := state.Meta["host"].(string)
+				plog.Warn(plog.WithVM(ctx, host), "NTP client not synchronized", "source", state.Meta["source"], "error", state.Err)
+
+				errs = append(errs, fmt.Sprintf("%s: %v", host, state.Err))
+			}
+		}
+
+		return fmt.Errorf("verifying NTP clients are synchronized: %s", strings.Join(errs, "; "))
+	}
+
 	return nil
 }
 
@@ -231,3 +446,202 @@ func (NTP) Running(ctx context.Context, exp *types.Experiment) error {
 func (NTP) Cleanup(ctx context.Context, exp *types.Experiment) error {
 	return nil
 }
+
+// ntpStrata returns the stratum for each NTP server in the given app, keyed by
+// hostname. Servers can use other servers in the app as their source to create
+// a multi-tier hierarchy, as long as each server's stratum is greater than its
+// source's stratum.
+func ntpStrata(exp *types.Experiment, app ifaces.ScenarioApp) (map[string]int, error) {
+	servers := make(map[string]NTPAppHostMetadata)
+
+	for _, host := range app.Hosts() {
+		var hmd NTPAppHostMetadata
+		mapstructure.Decode(host.Metadata(), &hmd)
+
+		if hmd.Server != "" {
+			servers[host.Hostname()] = hmd
+		}
+	}
+
+	var (
+		strata  = make(map[string]int)
+		visited = make(map[string]bool)
+		resolve func(string) (int, error)
+	)
+
+	resolve = func(name string) (int, error) {
+		if stratum, ok := strata[name]; ok {
+			return stratum, nil
+		}
+
+		if visited[name] {
+			return 0, fmt.Errorf("NTP server %s is its own source", name)
+		}
+
+		visited[name] = true
+
+		var (
+			hmd     = servers[name]
+			stratum = hmd.Stratum
+		)
+
+		// Only sources that are servers in the app are part of the hierarchy.
+		if _, ok := servers[hmd.Source.Hostname]; ok && hmd.Source.IPAddress(exp) != "" {
+			parent, err := resolve(hmd.Source.Hostname)
+			if err != nil {
+				return 0, err
+			}
+
+			if stratum == 0 {
+				stratum = parent + 1
+			} else if stratum <= parent {
+				return 0, fmt.Errorf("NTP server %s stratum %d must be greater than source %s stratum %d", name, stratum, hmd.Source.Hostname, parent)
+			}
+		} else if stratum == 0 {
+			stratum = DefaultNTPStratum
+		}
+
+		if stratum < 1 || stratum > 15 {
+			return 0, fmt.Errorf("NTP server %s stratum %d must be between 1 and 15", name, stratum)
+		}
+
+		strata[name] = stratum
+
+		return stratum, nil
+	}
+
+	for name := range servers {
+		if _, err := resolve(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return strata, nil
+}
+
+// setClockBase sets the real-time clock of every KVM VM in the experiment to
+// the start time (or time offset) configured in the given app metadata, if
+// any. The clocks are set via QEMU so they're correct as soon as VMs boot,
+// rather than stepping once NTP clients synchronize.
+func setClockBase(exp *types.Experiment, md NTPAppMetadata) error {
+	var base time.Time
+
+	switch {
+	case md.StartTime != "":
+		var err error
+
+		base, err = time.Parse(time.RFC3339, md.StartTime)
+		if err != nil {
+			return fmt.Errorf("parsing NTP start time: %w", err)
+		}
+	case md.TimeOffset != "":
+		offset, err := time.ParseDuration(md.TimeOffset)
+		if err != nil {
+			return fmt.Errorf("parsing NTP time offset: %w", err)
+		}
+
+		base = time.Now().Add(offset)
+	default:
+		return nil
+	}
+
+	rtc := "base=" + base.UTC().Format("2006-01-02T15:04:05") + ",clock=vm"
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if !strings.EqualFold(node.General().VMType(), "kvm") {
+			continue
+		}
+
+		var (
+			current = strings.Fields(node.Advanced()["qemu-append"])
+			args    []string
+		)
+
+		// Replace any existing RTC settings (from a previous start).
+		for i := 0; i < len(current); i++ {
+			if current[i] == "-rtc" {
+				i++
+				continue
+			}
+
+			args = append(args, current[i])
+		}
+
+		// Any other args are kept. The minimega script adds the default args for
+		// Linux VMs (e.g. `-vga qxl`) ahead of them.
+		args = append(args, "-rtc", rtc)
+
+		node.AddAdvanced("qemu-append", strings.Join(args, " "))
+	}
+
+	return nil
+}
+
+// chronyConfigPath returns the path chrony reads its config from, which
+// differs between Debian and Red Hat based distributions.
+func chronyConfigPath(node ifaces.NodeSpec) string {
+	switch strings.ToLower(node.Hardware().OSType()) {
+	case "rhel", "centos":
+		return "/etc/chrony.conf"
+	default:
+		return "/etc/chrony/chrony.conf"
+	}
+}
+
+// ntpSyncCommand returns the command used to check if the given type of NTP
+// client is synchronized.
+func ntpSyncCommand(client string) string {
+	switch client {
+	case "ntp":
+		return "ntpq -pn"
+	case "chrony":
+		return "chronyc -n sources"
+	case "systemd":
+		return "timedatectl show -p NTPSynchronized --value"
+	case "windows":
+		return "w32tm /query /status"
+	default:
+		return ""
+	}
+}
+
+// ntpSynced returns true if the given output of the sync command for the given
+// type of NTP client shows it's synchronized to the given source.
+func ntpSynced(client, source, output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch client {
+		case "ntp":
+			// ntpq marks the peer being synchronized to with an asterisk.
+			if strings.HasPrefix(line, "*"+source) {
+				return true
+			}
+		case "chrony":
+			// chronyc marks the source being synchronized to with ^*.
+			if strings.HasPrefix(line, "^* "+source) {
+				return true
+			}
+		case "systemd":
+			if line == "yes" {
+				return true
+			}
+		case "windows":
+			if !strings.HasPrefix(line, "Source:") {
+				continue
+			}
+
+			// Domain members can be synchronized to any domain controller, just not
+			// their own clock.
+			if source == "" {
+				return !strings.Contains(line, "Local CMOS Clock") && !strings.Contains(line, "Free-running")
+			}
+
+			if strings.Contains(line, source) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
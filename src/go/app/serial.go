@@ -11,6 +11,12 @@ import (
 	ifaces "phenix/types/interfaces"
 )
 
+// DefaultSerialAgent is the agent used on Windows nodes to bridge COM ports to
+// serial links if a node doesn't have a `serial/agent` annotation. It's
+// expected to be a Cygwin build of socat, but any agent that accepts socat
+// address arguments can be used.
+const DefaultSerialAgent = `C:\phenix\socat\socat.exe`
+
 type Serial struct{}
 
 func (Serial) Init(...Option) error {
@@ -24,39 +30,36 @@ func (Serial) Name() string {
 func (Serial) Configure(ctx context.Context, exp *types.Experiment) error {
 	// loop through nodes
 	for _, node := range exp.Spec.Topology().Nodes() {
-		// We only care about configuring serial interfaces on Linux VMs.
-		// TODO: handle rhel and centos OS types.
-		if node.Hardware().OSType() != "linux" {
+		if serialInterfaces(node) == nil {
 			continue
 		}
 
-		var serial bool
-
-		// Loop through interface type to see if any of the interfaces are serial.
-		for _, iface := range node.Network().Interfaces() {
-			if iface.Type() == "serial" {
-				serial = true
-				break
-			}
-		}
+		startupDir := exp.Spec.BaseDir() + "/startup"
 
-		if serial {
+		switch strings.ToLower(node.Hardware().OSType()) {
+		case "linux", "rhel", "centos":
 			// update injections to include serial type (src and dst)
-			serialFile := exp.Spec.BaseDir() + "/startup/" + node.General().Hostname() + "-serial.bash"
+			serialFile := startupDir + "/" + node.General().Hostname() + "-serial.bash"
 
 			node.AddInject(serialFile, "/etc/phenix/serial-startup.bash", "0755", "")
 
 			node.AddInject(
-				exp.Spec.BaseDir()+"/startup/serial-startup.service",
+				startupDir+"/serial-startup.service",
 				"/etc/systemd/system/serial-startup.service",
 				"", "",
 			)
 
 			node.AddInject(
-				exp.Spec.BaseDir()+"/startup/symlinks/serial-startup.service",
+				startupDir+"/symlinks/serial-startup.service",
 				"/etc/systemd/system/multi-user.target.wants/serial-startup.service",
 				"", "",
 			)
+		case "windows":
+			// The Windows startup script executes all the PowerShell scripts in the
+			// /phenix/startup directory at boot.
+			serialFile := startupDir + "/" + node.General().Hostname() + "-serial.ps1"
+
+			node.AddInject(serialFile, "/phenix/startup/30-serial.ps1", "0755", "")
 		}
 	}
 
@@ -66,31 +69,46 @@ func (Serial) Configure(ctx context.Context, exp *types.Experiment) error {
 func (Serial) PreStart(ctx context.Context, exp *types.Experiment) error {
 	// loop through nodes
 	for _, node := range exp.Spec.Topology().Nodes() {
-		// We only care about configuring serial interfaces on Linux VMs.
-		// TODO: handle rhel and centos OS types.
-		if node.Hardware().OSType() != "linux" {
+		serial := serialInterfaces(node)
+
+		if serial == nil {
 			continue
 		}
 
-		var serial []ifaces.NodeNetworkInterface
-
-		// Loop through interface type to see if any of the interfaces are serial.
-		for _, iface := range node.Network().Interfaces() {
-			if iface.Type() == "serial" {
-				serial = append(serial, iface)
+		var (
+			startupDir = exp.Spec.BaseDir() + "/startup"
+			hostname   = node.General().Hostname()
+			data       = struct {
+				Interfaces []ifaces.NodeNetworkInterface
+				Agent      string
+			}{
+				Interfaces: serial,
+				Agent:      DefaultSerialAgent,
 			}
-		}
+		)
 
-		if serial != nil {
-			startupDir := exp.Spec.BaseDir() + "/startup"
+		if agent, ok := node.GetAnnotation("serial/agent"); ok {
+			data.Agent = fmt.Sprint(agent)
+		}
 
+		switch strings.ToLower(node.Hardware().OSType()) {
+		case "linux", "rhel", "centos":
 			if err := os.MkdirAll(startupDir, 0755); err != nil {
 				return fmt.Errorf("creating experiment startup directory path: %w", err)
 			}
 
-			serialFile := startupDir + "/" + node.General().Hostname() + "-serial.bash"
+			var (
+				serialFile = startupDir + "/" + hostname + "-serial.bash"
+				template   = "serial_startup.tmpl"
+			)
+
+			// RHEL and CentOS nodes use NetworkManager and iproute2 rather than
+			// net-tools, and typically have firewalld enabled.
+			if !strings.EqualFold(node.Hardware().OSType(), "linux") {
+				template = "serial_startup_rhel.tmpl"
+			}
 
-			if err := tmpl.CreateFileFromTemplate("serial_startup.tmpl", serial, serialFile); err != nil {
+			if err := tmpl.CreateFileFromTemplate(template, data, serialFile); err != nil {
 				return fmt.Errorf("generating serial script: %w", err)
 			}
 
@@ -110,6 +128,16 @@ func (Serial) PreStart(ctx context.Context, exp *types.Experiment) error {
 					return fmt.Errorf("creating symlink for serial-startup.service: %w", err)
 				}
 			}
+		case "windows":
+			if err := os.MkdirAll(startupDir, 0755); err != nil {
+				return fmt.Errorf("creating experiment startup directory path: %w", err)
+			}
+
+			serialFile := startupDir + "/" + hostname + "-serial.ps1"
+
+			if err := tmpl.CreateFileFromTemplate("serial_windows.tmpl", data, serialFile); err != nil {
+				return fmt.Errorf("generating Windows serial script: %w", err)
+			}
 		}
 	}
 
@@ -127,3 +155,23 @@ func (Serial) Running(ctx context.Context, exp *types.Experiment) error {
 func (Serial) Cleanup(ctx context.Context, exp *types.Experiment) error {
 	return nil
 }
+
+// serialInterfaces returns the serial interfaces for the given node, or nil if
+// the node has no serial interfaces or its OS type doesn't support them.
+func serialInterfaces(node ifaces.NodeSpec) []ifaces.NodeNetworkInterface {
+	switch strings.ToLower(node.Hardware().OSType()) {
+	case "linux", "rhel", "centos", "windows":
+	default:
+		return nil
+	}
+
+	var serial []ifaces.NodeNetworkInterface
+
+	for _, iface := range node.Network().Interfaces() {
+		if iface.Type() == "serial" {
+			serial = append(serial, iface)
+		}
+	}
+
+	return serial
+}
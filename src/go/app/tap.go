@@ -25,8 +25,9 @@ type TapAppMetadata struct {
 }
 
 type TapAppStatus struct {
-	Host string     `structs:"host" mapstructure:"host"`
-	Taps []*tap.Tap `structs:"taps" mapstructure:"taps"`
+	Host     string              `structs:"host" mapstructure:"host"`
+	Taps     []*tap.Tap          `structs:"taps" mapstructure:"taps"`
+	Forwards []tap.ActiveForward `structs:"forwards" mapstructure:"forwards"`
 }
 
 type Tap struct{}
@@ -67,11 +68,26 @@ func (this *Tap) PostStart(ctx context.Context, exp *types.Experiment) error {
 	rand.Seed(time.Now().UnixNano())
 
 	var (
-		host  = hosts[rand.Intn(len(hosts))].Name
-		pairs = this.discoverUsedPairs()
-		vlans []string
+		host     = hosts[rand.Intn(len(hosts))].Name
+		pairs    = this.discoverUsedPairs()
+		vlans    []string
+		forwards = make(map[string]string)
 	)
 
+	// All taps are created on the same host, so make sure port forwards for
+	// different taps don't use the same host port.
+	for _, t := range amd.Taps {
+		for _, f := range t.External.Forwards {
+			key := fmt.Sprintf("%s/%d", f.Proto(), f.HostPort)
+
+			if vlan, ok := forwards[key]; ok {
+				return fmt.Errorf("host port %s forwarded for both VLAN %s and VLAN %s", key, vlan, t.VLAN)
+			}
+
+			forwards[key] = t.VLAN
+		}
+	}
+
 	status := TapAppStatus{Host: host}
 
 	// TODO: prepopulate `pairs` with taps from other experiments
@@ -91,6 +107,7 @@ func (this *Tap) PostStart(ctx context.Context, exp *types.Experiment) error {
 		}
 
 		status.Taps = append(status.Taps, t)
+		status.Forwards = append(status.Forwards, t.ActiveForwards(host)...)
 		vlans = append(vlans, t.VLAN)
 	}
 
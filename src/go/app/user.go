@@ -14,6 +14,7 @@ import (
 	"phenix/util"
 	"phenix/util/common"
 	"phenix/util/mm"
+	"phenix/util/plog"
 	"phenix/util/shell"
 )
 
@@ -111,6 +112,7 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 			"PHENIX_LOG_FILE="+util.GetEnv("PHENIX_LOG_FILE", common.LogFile),
 			"PHENIX_DRYRUN="+strconv.FormatBool(this.options.DryRun),
 			"PHENIX_STORE_ENDPOINT="+common.StoreEndpoint,
+			"PHENIX_REQUEST_ID="+plog.RequestID(ctx),
 		),
 	}
 
@@ -133,8 +135,9 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 			}
 		}
 
-		// FIXME: improve on this
-		fmt.Println(string(stdErr))
+		if stdErr := strings.TrimSpace(string(stdErr)); stdErr != "" {
+			plog.Error(ctx, "user app failed", "command", cmdName, "stderr", stdErr)
+		}
 
 		return fmt.Errorf("user app %s command %s failed: %w", this.options.Name, cmdName, err)
 	}
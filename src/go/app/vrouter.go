@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,7 +15,10 @@ import (
 	ifaces "phenix/types/interfaces"
 	"phenix/types/version"
 	"phenix/util"
+	"phenix/util/firewall"
 	"phenix/util/mm/mmcli"
+	"phenix/util/notes"
+	"phenix/util/plog"
 
 	"github.com/mitchellh/mapstructure"
 	"inet.af/netaddr"
@@ -97,6 +101,19 @@ func (this NATRule) TranslationPort() string {
 	return ""
 }
 
+func (this NATRule) firewallNAT() firewall.NAT {
+	return firewall.NAT{
+		Interface:          this.Interface,
+		SourceAddress:      this.SourceAddress,
+		SourcePort:         this.SourcePort,
+		DestinationAddress: this.DestinationAddress,
+		DestinationPort:    this.DestinationPort,
+		Protocol:           this.Protocol,
+		TranslationAddress: this.TranslationAddress(),
+		TranslationPort:    this.TranslationPort(),
+	}
+}
+
 type Vrouter struct {
 	ipsecPresharedKeys map[string]string
 }
@@ -148,9 +165,65 @@ func (this *Vrouter) PreStart(ctx context.Context, exp *types.Experiment) error
 		}
 	}
 
+	vrouterDir := exp.Spec.BaseDir() + "/vrouter"
+
 	// loop through nodes
 	for _, node := range exp.Spec.Topology().Nodes() {
-		if !strings.EqualFold(node.Type(), "router") && !strings.EqualFold(node.Type(), "firewall") {
+		ctx := plog.WithVM(ctx, node.General().Hostname())
+
+		isRouter := strings.EqualFold(node.Type(), "router") || strings.EqualFold(node.Type(), "firewall")
+
+		if strings.EqualFold(node.Hardware().OSType(), "linux") {
+			var (
+				frr bool
+				fw  bool
+
+				fwAnnotated bool
+			)
+
+			// Plain Linux routers running FRRouting are configured entirely from the
+			// topology network config.
+			if val, ok := node.GetAnnotation("vrouter/frr"); ok && isRouter {
+				frr, _ = strconv.ParseBool(fmt.Sprint(val))
+			}
+
+			// Any plain Linux node can have its topology rulesets and NAT configs
+			// rendered into a firewall config for the backend named by the
+			// annotation (or nftables if the annotation is simply `true`). Nodes
+			// without the annotation get an nftables config if they have any
+			// rulesets or NAT configs so they aren't silently ignored.
+			if val, ok := node.GetAnnotation("vrouter/firewall"); ok {
+				fwAnnotated = true
+
+				if enabled, err := strconv.ParseBool(fmt.Sprint(val)); err != nil || enabled {
+					fw = true
+				} else if hasFirewallConfig(node) {
+					notes.AddWarnings(ctx, false, fmt.Errorf("rulesets and NAT configs for node %s not rendered since 'vrouter/firewall' annotation is false", node.General().Hostname()))
+				}
+			} else {
+				fw = hasFirewallConfig(node)
+			}
+
+			if frr {
+				if err := this.configureFRR(vrouterDir, node); err != nil {
+					return fmt.Errorf("configuring FRR for router %s: %w", node.General().Hostname(), err)
+				}
+			}
+
+			if fw {
+				if err := this.configureFirewall(exp, vrouterDir, node); err != nil {
+					return fmt.Errorf("configuring firewall for node %s: %w", node.General().Hostname(), err)
+				}
+			}
+
+			// Legacy Linux routers without annotations still get a vyatta config
+			// below, along with any firewall config rendered by default.
+			if frr || (fw && fwAnnotated) {
+				continue
+			}
+		}
+
+		if !isRouter {
 			continue
 		}
 
@@ -160,20 +233,21 @@ func (this *Vrouter) PreStart(ctx context.Context, exp *types.Experiment) error
 		// support.
 		if !util.StringSliceContains([]string{"vyatta", "vyos", "linux"}, strings.ToLower(node.Hardware().OSType())) {
 			if strings.ToLower(node.Hardware().OSType()) != "minirouter" {
-				fmt.Printf("  === OS Type %s for Node Type %s unsupported ===\n", node.Hardware().OSType(), node.Type())
+				plog.Warn(ctx, "unsupported OS type for router", "os_type", node.Hardware().OSType(), "node_type", node.Type())
 			}
 
 			continue
 		}
 
 		if strings.EqualFold(node.Hardware().OSType(), "linux") {
-			fmt.Printf("  === using OS Type 'linux' for Node Type %s is depricated ===\n", node.Type())
-			fmt.Printf("  === use 'vyatta', 'vyos', or 'minirouter' OS type instead ===\n")
+			plog.Warn(
+				ctx, "using OS type 'linux' for routers is deprecated -- use 'vyatta', 'vyos', or 'minirouter' OS type instead, or set the 'vrouter/frr' and 'vrouter/firewall' annotations to use FRRouting and nftables",
+				"node_type", node.Type(),
+			)
 		}
 
 		var (
 			isVyos       = strings.EqualFold(node.Hardware().OSType(), "vyos")
-			vrouterDir   = exp.Spec.BaseDir() + "/vrouter"
 			vyattaFile   = vrouterDir + "/" + node.General().Hostname() + ".boot"
 			vyattaConfig = "/opt/vyatta/etc/config/config.boot"
 		)
@@ -310,6 +384,126 @@ func (this *Vrouter) PreStart(ctx context.Context, exp *types.Experiment) error
 	return nil
 }
 
+// configureFRR generates FRRouting configs for the given plain Linux router
+// from the interfaces, static routes, OSPF, and BGP settings in its topology
+// network config and injects them into the router's image. Interface names in
+// the topology must match the interface names in the router's image.
+func (Vrouter) configureFRR(vrouterDir string, node ifaces.NodeSpec) error {
+	var (
+		hostname    = node.General().Hostname()
+		frrFile     = vrouterDir + "/" + hostname + "-frr.conf"
+		daemonsFile = vrouterDir + "/" + hostname + "-frr-daemons"
+		sysctlFile  = vrouterDir + "/" + hostname + "-frr-sysctl.conf"
+	)
+
+	if err := os.MkdirAll(vrouterDir, 0755); err != nil {
+		return fmt.Errorf("creating experiment vrouter directory path: %w", err)
+	}
+
+	if err := tmpl.CreateFileFromTemplate("frr.tmpl", node, frrFile); err != nil {
+		return fmt.Errorf("generating FRR config: %w", err)
+	}
+
+	if err := tmpl.CreateFileFromTemplate("frr_daemons.tmpl", node, daemonsFile); err != nil {
+		return fmt.Errorf("generating FRR daemons config: %w", err)
+	}
+
+	if err := os.WriteFile(sysctlFile, []byte("net.ipv4.ip_forward=1\n"), 0644); err != nil {
+		return fmt.Errorf("generating IP forwarding sysctl config: %w", err)
+	}
+
+	node.AddInject(frrFile, "/etc/frr/frr.conf", "0644", "")
+	node.AddInject(daemonsFile, "/etc/frr/daemons", "0644", "")
+	node.AddInject(sysctlFile, "/etc/sysctl.d/99-phenix-frr.conf", "0644", "")
+
+	return nil
+}
+
+// hasFirewallConfig returns true if the given node's topology network config
+// includes any rulesets or NAT configs.
+func hasFirewallConfig(node ifaces.NodeSpec) bool {
+	if node.Network() == nil {
+		return false
+	}
+
+	return len(node.Network().Rulesets()) > 0 || len(node.Network().NAT()) > 0
+}
+
+// configureFirewall renders the rulesets applied to interfaces and the NAT
+// configs in the given plain Linux node's topology network config (along with
+// any SNAT and DNAT configs in the vrouter app's metadata for the node) into a
+// config for the firewall backend named by the node's `vrouter/firewall`
+// annotation and injects it into the node's image.
+func (this *Vrouter) configureFirewall(exp *types.Experiment, vrouterDir string, node ifaces.NodeSpec) error {
+	val, _ := node.GetAnnotation("vrouter/firewall")
+
+	backend, err := firewall.ParseBackend(fmt.Sprint(val))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := firewall.NewConfig(node.Network())
+	if err != nil {
+		return fmt.Errorf("processing topology network config: %w", err)
+	}
+
+	for _, app := range exp.Apps() {
+		if app.Name() != "vrouter" {
+			continue
+		}
+
+		for _, host := range app.Hosts() {
+			if host.Hostname() != node.General().Hostname() {
+				continue
+			}
+
+			sources, destinations, err := this.processNAT(host.Metadata(), node.Network().Interfaces())
+			if err != nil {
+				return fmt.Errorf("processing NAT metadata: %w", err)
+			}
+
+			// NAT configs in app metadata take precedence over topology NAT configs,
+			// same as for vyatta/vyos routers.
+			if len(sources) > 0 {
+				cfg.SNAT = nil
+			}
+
+			for _, rule := range sources {
+				cfg.SNAT = append(cfg.SNAT, rule.firewallNAT())
+			}
+
+			for _, rule := range destinations {
+				cfg.DNAT = append(cfg.DNAT, rule.firewallNAT())
+			}
+		}
+	}
+
+	if cfg.Empty() {
+		return nil
+	}
+
+	var (
+		hostname = node.General().Hostname()
+		fwFile   = vrouterDir + "/" + hostname + "-firewall.nft"
+	)
+
+	if backend == firewall.IPTables {
+		fwFile = vrouterDir + "/" + hostname + "-firewall.rules"
+	}
+
+	if err := os.MkdirAll(vrouterDir, 0755); err != nil {
+		return fmt.Errorf("creating experiment vrouter directory path: %w", err)
+	}
+
+	if err := firewall.CreateFile(backend, cfg, fwFile); err != nil {
+		return fmt.Errorf("generating %s config: %w", backend, err)
+	}
+
+	node.AddInject(fwFile, backend.Path(), "0644", "")
+
+	return nil
+}
+
 func (Vrouter) PostStart(ctx context.Context, exp *types.Experiment) error {
 	var app ifaces.ScenarioApp
 
@@ -3,9 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"phenix/store"
 	"phenix/util"
+	"phenix/util/audit"
 	"phenix/util/printer"
 
 	"github.com/spf13/cobra"
@@ -39,14 +41,21 @@ func newEventListCmd() *cobra.Command {
 			} else {
 				var show store.Events
 
-				if MustGetBool(cmd.Flags(), "show-history") {
-					show = events
-				} else {
-					for _, event := range events {
-						if event.Type != store.EventTypeHistory {
-							show = append(show, event)
-						}
+				var (
+					history = MustGetBool(cmd.Flags(), "show-history")
+					audits  = MustGetBool(cmd.Flags(), "show-audit")
+				)
+
+				for _, event := range events {
+					if event.Type == store.EventTypeHistory && !history {
+						continue
+					}
+
+					if event.Type == store.EventTypeAudit && !audits {
+						continue
 					}
+
+					show = append(show, event)
 				}
 
 				show.SortByTimestamp(true)
@@ -59,6 +68,7 @@ func newEventListCmd() *cobra.Command {
 
 	cmd.Flags().Bool("show-id", false, "Include event IDs in table")
 	cmd.Flags().Bool("show-history", false, "Include history events in table")
+	cmd.Flags().Bool("show-audit", false, "Include audit events in table")
 
 	return cmd
 }
@@ -83,11 +93,98 @@ func newEventShowCmd() *cobra.Command {
 	return cmd
 }
 
+func newEventExportCmd() *cobra.Command {
+	desc := `Export events as JSON lines
+
+  Writes matching events to STDOUT (or the given file) as JSON lines, oldest
+  first. Events can be filtered by type, metadata (e.g. the user, action, or
+  outcome of audit events), and time range.`
+
+	example := `
+  phenix event export --type audit --since 2023-01-01T00:00:00Z > audit.jsonl
+  phenix event export --type audit --metadata user=alice --metadata outcome=denied`
+
+	cmd := &cobra.Command{
+		Use:     "export",
+		Short:   "Export events as JSON lines",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			metadata, _ := cmd.Flags().GetStringToString("metadata")
+
+			filter := store.Event{
+				Type:     store.EventType(MustGetString(cmd.Flags(), "type")),
+				Metadata: metadata,
+			}
+
+			if len(filter.Metadata) == 0 {
+				filter.Metadata = nil
+			}
+
+			var since, until time.Time
+
+			if s := MustGetString(cmd.Flags(), "since"); s != "" {
+				var err error
+
+				if since, err = time.Parse(time.RFC3339, s); err != nil {
+					return fmt.Errorf("invalid --since time (must be RFC3339): %w", err)
+				}
+			}
+
+			if u := MustGetString(cmd.Flags(), "until"); u != "" {
+				var err error
+
+				if until, err = time.Parse(time.RFC3339, u); err != nil {
+					return fmt.Errorf("invalid --until time (must be RFC3339): %w", err)
+				}
+			}
+
+			events, err := store.GetEventsBy(filter)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get matching events")
+				return err.Humanized()
+			}
+
+			events = events.Between(since, until)
+			events.SortByTimestamp(true)
+
+			out := os.Stdout
+
+			if path := MustGetString(cmd.Flags(), "output"); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return fmt.Errorf("creating output file: %w", err)
+				}
+
+				defer f.Close()
+
+				out = f
+			}
+
+			if err := audit.Export(out, events); err != nil {
+				err := util.HumanizeError(err, "Unable to export events")
+				return err.Humanized()
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("type", "", "Only export events of the given type (e.g. audit, history, info, error)")
+	cmd.Flags().StringToString("metadata", nil, "Only export events with the given metadata key/value pairs")
+	cmd.Flags().String("since", "", "Only export events at or after the given time (RFC3339)")
+	cmd.Flags().String("until", "", "Only export events at or before the given time (RFC3339)")
+	cmd.Flags().StringP("output", "o", "", "Write events to the given file instead of STDOUT")
+
+	return cmd
+}
+
 func init() {
 	eventCmd := newEventCmd()
 
 	eventCmd.AddCommand(newEventListCmd())
 	eventCmd.AddCommand(newEventShowCmd())
+	eventCmd.AddCommand(newEventExportCmd())
 
 	rootCmd.AddCommand(eventCmd)
 }
@@ -165,7 +165,7 @@ func newExperimentCreateCmd() *cobra.Command {
 				experiment.CreateWithVLANMax(MustGetInt(cmd.Flags(), "vlan-max")),
 			}
 
-			ctx := notes.Context(context.Background(), false)
+			ctx := notes.Context(cmd.Context(), false)
 
 			if err := experiment.Create(ctx, opts...); err != nil {
 				err := util.HumanizeError(err, "Unable to create the "+args[0]+" experiment")
@@ -343,7 +343,7 @@ func newExperimentStartCmd() *cobra.Command {
 				periodic    = MustGetBool(cmd.Flags(), "honor-run-periodically")
 				experiments []types.Experiment
 
-				ctx = notes.Context(sigterm.CancelContext(context.Background()), true)
+				ctx = notes.Context(sigterm.CancelContext(cmd.Context()), true)
 				wg  sync.WaitGroup
 			)
 
@@ -461,7 +461,7 @@ func newExperimentStopCmd() *cobra.Command {
 					continue
 				}
 
-				if err := experiment.Stop(exp.Metadata.Name); err != nil {
+				if err := experiment.Stop(cmd.Context(), exp.Metadata.Name); err != nil {
 					err := util.HumanizeError(err, "Problem encountered while stopping the "+exp.Metadata.Name+" experiment")
 					return err.Humanized()
 				}
@@ -494,7 +494,7 @@ func newExperimentRestartCmd() *cobra.Command {
 				dryrun      = MustGetBool(cmd.Flags(), "dry-run")
 				experiments []types.Experiment
 
-				ctx = sigterm.CancelContext(context.Background())
+				ctx = sigterm.CancelContext(cmd.Context())
 			)
 
 			if name == "all" {
@@ -521,7 +521,7 @@ func newExperimentRestartCmd() *cobra.Command {
 					continue
 				}
 
-				if err := experiment.Stop(exp.Metadata.Name); err != nil {
+				if err := experiment.Stop(ctx, exp.Metadata.Name); err != nil {
 					err := util.HumanizeError(err, "Unable to stop the "+exp.Metadata.Name+" experiment")
 					return err.Humanized()
 				}
@@ -620,7 +620,7 @@ func newExperimentTriggerRunningCmd() *cobra.Command {
 				name        = args[0]
 				experiments []types.Experiment
 
-				ctx = sigterm.CancelContext(context.Background())
+				ctx = sigterm.CancelContext(cmd.Context())
 			)
 
 			if name == "all" {
@@ -679,7 +679,7 @@ func newExperimentScorchCmd() *cobra.Command {
 			var (
 				name = args[0]
 				run  = MustGetInt(cmd.Flags(), "run")
-				ctx  = sigterm.CancelContext(context.Background())
+				ctx  = sigterm.CancelContext(cmd.Context())
 			)
 
 			exp, err := experiment.Get(name)
@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -14,6 +14,7 @@ import (
 	"phenix/util/printer"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 func newImageCmd() *cobra.Command {
@@ -259,7 +260,13 @@ func newImageBuildCmd() *cobra.Command {
 	desc := `Build a virtual disk image
 
   Used to build a new virtual disk using an exisitng configuration; vmdb2 must
-  be in path.`
+  be in path.
+
+  A manifest recording what went into the image is written next to it. Built
+  images are cached, and building an unchanged configuration again copies the
+  cached image instead of rebuilding it. Since package versions aren't pinned,
+  use --rebuild to pick up package updates from the mirror. The cache is never
+  pruned automatically; use 'phenix image prune' to remove cached images.`
 
 	example := `
   phenix image build <configuration name>
@@ -278,6 +285,7 @@ func newImageBuildCmd() *cobra.Command {
 			var (
 				name      = args[0]
 				cache     = MustGetBool(cmd.Flags(), "cache")
+				rebuild   = MustGetBool(cmd.Flags(), "rebuild")
 				dryrun    = MustGetBool(cmd.Flags(), "dry-run")
 				output    string
 				verbosity int
@@ -307,9 +315,9 @@ func newImageBuildCmd() *cobra.Command {
 				verbosity = verbosity | image.V_VVVERBOSE
 			}
 
-			ctx := notes.Context(context.Background(), false)
+			ctx := notes.Context(cmd.Context(), false)
 
-			if err := image.Build(ctx, name, verbosity, cache, dryrun, output); err != nil {
+			if err := image.Build(ctx, name, verbosity, cache, rebuild, dryrun, output); err != nil {
 				err := util.HumanizeError(err, "Unable to build the "+name+" image")
 				return err.Humanized()
 			}
@@ -327,12 +335,114 @@ func newImageBuildCmd() *cobra.Command {
 	cmd.Flags().BoolP("very-verbose", "w", false, "Enable very verbose output")
 	cmd.Flags().BoolP("very-very-verbose", "x", false, "Enable very verbose output plus additional verbose output from debootstrap")
 	cmd.Flags().BoolP("cache", "c", false, "Cache rootfs as tar archive")
+	cmd.Flags().BoolP("rebuild", "", false, "Rebuild the image even if an unchanged build of it is cached")
 	cmd.Flags().BoolP("dry-run", "", false, "Do everything but actually call out to vmdb2")
 	cmd.Flags().StringP("output", "o", "", "Specify the output directory for the disk image to be saved to")
 
 	return cmd
 }
 
+func newImageInspectCmd() *cobra.Command {
+	desc := `Show the manifest of a built virtual disk image
+
+  Used to show what went into the most recent build of an image. Either the
+  name of an image configuration or the path to a built disk image can be
+  provided.`
+
+	example := `
+  phenix image inspect <configuration name>
+  phenix image inspect --output json </path/to/disk>`
+
+	cmd := &cobra.Command{
+		Use:     "inspect <configuration name>",
+		Short:   "Show the manifest of a built virtual disk image",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("The name of a configuration or path to a disk image is required")
+			}
+
+			name := args[0]
+
+			manifest, err := image.Manifest(name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get the manifest for the "+name+" image")
+				return err.Humanized()
+			}
+
+			output := MustGetString(cmd.Flags(), "output")
+
+			switch output {
+			case "table":
+				printer.PrintImageManifest(os.Stdout, manifest)
+			case "yaml":
+				m, err := yaml.Marshal(manifest)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to convert manifest to YAML")
+					return err.Humanized()
+				}
+
+				fmt.Println(string(m))
+			case "json":
+				m, err := json.MarshalIndent(manifest, "", "  ")
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to convert manifest to JSON")
+					return err.Humanized()
+				}
+
+				fmt.Println(string(m))
+			default:
+				return fmt.Errorf("Unrecognized output format '%s'", output)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "table", "Manifest output format ('table', 'yaml', or 'json')")
+
+	return cmd
+}
+
+func newImagePruneCmd() *cobra.Command {
+	desc := `Remove cached virtual disk images
+
+  Used to remove images from the image cache that haven't been built or used
+  recently. The cache is never pruned automatically. By default, every cached
+  image is removed.`
+
+	example := `
+  phenix image prune
+  phenix image prune --unused-for 720h`
+
+	cmd := &cobra.Command{
+		Use:     "prune",
+		Short:   "Remove cached virtual disk images",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pruned, err := image.PruneCache(MustGetDuration(cmd.Flags(), "unused-for"))
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to prune the image cache")
+				return err.Humanized()
+			}
+
+			for _, digest := range pruned {
+				fmt.Printf("Removed cached image %s\n", digest)
+			}
+
+			fmt.Printf("Removed %d cached image(s) from %s\n", len(pruned), image.CacheDir())
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("unused-for", 0, "Only remove cached images not built or used within this duration")
+
+	return cmd
+}
+
 func newImageDeleteCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "delete <configuration name>",
@@ -564,6 +674,8 @@ func init() {
 	imageCmd.AddCommand(newImageCreateFromCmd())
 	imageCmd.AddCommand(newImageEditCmd())
 	imageCmd.AddCommand(newImageBuildCmd())
+	imageCmd.AddCommand(newImageInspectCmd())
+	imageCmd.AddCommand(newImagePruneCmd())
 	imageCmd.AddCommand(newImageDeleteCmd())
 	imageCmd.AddCommand(newImageAppendCmd())
 	imageCmd.AddCommand(newImageRemoveCmd())
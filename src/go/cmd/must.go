@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -32,3 +33,21 @@ func MustGetInt(flags *pflag.FlagSet, name string) int {
 
 	return val
 }
+
+func MustGetFloat64(flags *pflag.FlagSet, name string) float64 {
+	val, err := flags.GetFloat64(name)
+	if err != nil {
+		panic(fmt.Sprintf("Getting value for %s: %v", name, err))
+	}
+
+	return val
+}
+
+func MustGetDuration(flags *pflag.FlagSet, name string) time.Duration {
+	val, err := flags.GetDuration(name)
+	if err != nil {
+		panic(fmt.Sprintf("Getting value for %s: %v", name, err))
+	}
+
+	return val
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	v1 "phenix/types/version/v1"
+	"phenix/util"
+	"phenix/util/printer"
+	"phenix/web/rbac"
+
+	"github.com/spf13/cobra"
+)
+
+func newRoleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "role",
+		Short: "Role management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newRoleListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Display a table of roles",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roles, err := rbac.GetRoles()
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get roles")
+				return err.Humanized()
+			}
+
+			if len(roles) == 0 {
+				fmt.Printf("\nThere are no roles available\n\n")
+			} else {
+				printer.PrintTableOfRoles(os.Stdout, roles...)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newRoleShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <name>",
+		Short: "Display the policies for a role",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			role, err := rbac.RoleFromConfig(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get role %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("\nRole: %s (%s)\n\n", role.Spec.Name, role.ConfigName())
+			printer.PrintTableOfPolicies(os.Stdout, role.Spec.Policies)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newRoleCreateCmd() *cobra.Command {
+	desc := `Create a role
+
+  Creates a new role with one or more policies in the form of
+  <resources>:<verbs>[:<resource names>], where each is a comma-separated list.
+  Roles with label or annotation selectors can be created from a file using
+  'phenix config create'.`
+
+	example := `
+  phenix role create red-team --role-name "Red Team" --policy experiments:get,list --policy vms,vms/*:get,list:*_kali*`
+
+	cmd := &cobra.Command{
+		Use:     "create <name>",
+		Short:   "Create a role",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := rbac.RoleFromConfig(args[0]); err == nil {
+				return fmt.Errorf("role %s already exists", args[0])
+			}
+
+			flags, _ := cmd.Flags().GetStringArray("policy")
+
+			var policies []*v1.PolicySpec
+
+			for _, f := range flags {
+				policy, err := rbac.ParsePolicy(f)
+				if err != nil {
+					return err
+				}
+
+				policies = append(policies, policy)
+			}
+
+			rname := MustGetString(cmd.Flags(), "role-name")
+			if rname == "" {
+				rname = args[0]
+			}
+
+			if _, err := rbac.NewRole(args[0], rname, policies); err != nil {
+				err := util.HumanizeError(err, "Unable to create role %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("Role %s created\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("role-name", "", "display name of the role (default is the role's config name)")
+	cmd.Flags().StringArray("policy", nil, "policy to include in the role (<resources>:<verbs>[:<resource names>])")
+
+	return cmd
+}
+
+func init() {
+	roleCmd := newRoleCmd()
+
+	roleCmd.AddCommand(newRoleListCmd())
+	roleCmd.AddCommand(newRoleShowCmd())
+	roleCmd.AddCommand(newRoleCreateCmd())
+
+	rootCmd.AddCommand(roleCmd)
+}
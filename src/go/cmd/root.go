@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/user"
@@ -9,11 +10,15 @@ import (
 
 	"phenix/api/config"
 	_ "phenix/api/scorch"
+	"phenix/api/webhook"
 	"phenix/store"
 	"phenix/util"
+	"phenix/util/audit"
 	"phenix/util/common"
+	"phenix/util/plog"
 	"phenix/web"
 
+	log "github.com/activeshadow/libminimega/minilog"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -25,12 +30,26 @@ var (
 	hostnameSuffixes string
 	storeEndpoint    string
 	errFile          string
+
+	// storeReady is set once the store has been initialized, since commands that
+	// fail before then (e.g. due to invalid flags) can't be audited.
+	storeReady bool
+
+	// executed is the command being executed, captured before it runs so it can
+	// be audited once it completes.
+	executed *cobra.Command
 )
 
+// unauditedCommands are commands that only display information, so running
+// them isn't audited.
+var unauditedCommands = []string{"list", "show", "get", "inspect", "export", "help", "version", "completion"}
+
 var rootCmd = &cobra.Command{
 	Use:   "phenix",
 	Short: "A cli application for phēnix",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		executed = cmd
+
 		common.PhenixBase = viper.GetString("base-dir.phenix")
 		common.MinimegaBase = viper.GetString("base-dir.minimega")
 		common.HostnameSuffixes = viper.GetString("hostname-suffixes")
@@ -48,6 +67,13 @@ var rootCmd = &cobra.Command{
 			return fmt.Errorf("initializing storage: %w", err)
 		}
 
+		storeReady = true
+
+		// Structured logs written by the api and app packages are written to
+		// STDERR as text by default. The UI command replaces this handler
+		// based on its log settings.
+		plog.AddTextHandler("stderr", os.Stderr, log.WARN)
+
 		if err := util.InitFatalLogWriter(errFile, errOut); err != nil {
 			return fmt.Errorf("unable to initialize fatal log writer: %w", err)
 		}
@@ -69,11 +95,53 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	// Each command invocation gets its own request ID so all the logs it
+	// generates can be correlated.
+	ctx := plog.WithRequestID(context.Background(), plog.NewRequestID())
+
+	err := rootCmd.ExecuteContext(ctx)
+
+	auditCommand(executed, err)
+
+	// Don't exit before any webhook events fired by the command have been sent,
+	// but don't wait on unreachable receivers for long either.
+	if !webhook.Wait(webhook.DefaultExitWait) {
+		log.Warn("exiting before all webhook events were sent")
+	}
+
+	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// auditCommand records an audit event for the given command, if it modified
+// anything, along with whether it failed.
+func auditCommand(cmd *cobra.Command, err error) {
+	if !storeReady || cmd == nil || !cmd.Runnable() || cmd == rootCmd {
+		return
+	}
+
+	if util.StringSliceContains(unauditedCommands, cmd.Name()) {
+		return
+	}
+
+	rec := audit.Record{
+		User:     currentUsername(),
+		Address:  util.MustHostname(),
+		Via:      "cli",
+		Action:   strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "),
+		Resource: strings.Join(cmd.Flags().Args(), " "),
+		Outcome:  audit.OutcomeSuccess,
+	}
+
+	if err != nil {
+		rec.Outcome = audit.OutcomeFailure
+		rec.Detail = err.Error()
+	}
+
+	audit.Log(rec)
+}
+
 func init() {
 	uid, home := getCurrentUserInfo()
 	var homePath string
@@ -172,3 +240,18 @@ func getCurrentUserInfo() (string, string) {
 
 	return uid, home
 }
+
+// currentUsername returns the name of the user running phenix, preferring the
+// user that ran sudo (if any) so audit events reflect who actually ran it.
+func currentUsername() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	if sudo := os.Getenv("SUDO_USER"); u.Uid == "0" && sudo != "" {
+		return sudo
+	}
+
+	return u.Username
+}
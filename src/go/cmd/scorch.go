@@ -0,0 +1,322 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/api/scorch"
+	"phenix/api/scorch/scorchexe"
+	"phenix/api/scorch/scorchmd"
+	"phenix/app"
+	"phenix/util"
+	"phenix/util/printer"
+	"phenix/util/sigterm"
+
+	"github.com/hpcloud/tail"
+	"github.com/spf13/cobra"
+)
+
+func newScorchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scorch",
+		Short: "Scorch pipeline management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newScorchRunCmd() *cobra.Command {
+	desc := `Execute a Scorch run for an experiment
+
+  Used to execute the Scorch run with the given run ID for the given experiment.
+  The command blocks until the run completes, streaming component output to
+  STDOUT as it's generated. Sending SIGINT or SIGTERM cancels the run.
+
+  If STDIN is not a terminal (e.g. when driven by automation), break components
+  wait for a terminal to be attached via 'phenix scorch attach'.`
+
+	cmd := &cobra.Command{
+		Use:   "run <experiment name> <run ID>",
+		Short: "Execute a Scorch run for an experiment",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, run, err := parseScorchArgs(args)
+			if err != nil {
+				return err
+			}
+
+			exp, err := experiment.Get(name)
+			if err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to get the %s experiment", name))
+				return err.Humanized()
+			}
+
+			ctx := sigterm.CancelContext(cmd.Context())
+			ctx = app.SetContextTriggerCLI(ctx)
+
+			if !MustGetBool(cmd.Flags(), "quiet") {
+				ctx = scorchexe.SetOutput(ctx, os.Stdout)
+			}
+
+			if err := scorchexe.Execute(ctx, exp, run); err != nil {
+				if errors.Is(err, context.Canceled) {
+					fmt.Printf("Scorch run %d for experiment %s was canceled\n", run, name)
+					return nil
+				}
+
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to execute Scorch run %d for the %s experiment", run, name))
+				return err.Humanized()
+			}
+
+			fmt.Printf("Scorch run %d for experiment %s completed successfully\n", run, name)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolP("quiet", "q", false, "Don't stream component output to STDOUT")
+
+	return cmd
+}
+
+func newScorchCancelCmd() *cobra.Command {
+	desc := `Cancel a Scorch run for an experiment
+
+  Used to cancel the Scorch run with the given run ID for the given experiment,
+  whether it was started from the command line or the UI.`
+
+	cmd := &cobra.Command{
+		Use:   "cancel <experiment name> <run ID>",
+		Short: "Cancel a Scorch run for an experiment",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, run, err := parseScorchArgs(args)
+			if err != nil {
+				return err
+			}
+
+			exp, err := experiment.Get(name)
+			if err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to get the %s experiment", name))
+				return err.Humanized()
+			}
+
+			if err := scorchexe.Cancel(exp, run); err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to cancel Scorch run %d for the %s experiment", run, name))
+				return err.Humanized()
+			}
+
+			fmt.Printf("Scorch run %d for experiment %s canceled\n", run, name)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newScorchStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status <experiment name>",
+		Short: "Display a table of Scorch runs for an experiment",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			exp, err := experiment.Get(name)
+			if err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to get the %s experiment", name))
+				return err.Humanized()
+			}
+
+			md, err := scorchmd.DecodeMetadata(exp)
+			if err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to decode Scorch metadata for the %s experiment", name))
+				return err.Humanized()
+			}
+
+			var status *scorchmd.ScorchStatus
+
+			if exp.Status.AppRunning()["scorch"] {
+				status = new(scorchmd.ScorchStatus)
+
+				if err := exp.Status.ParseAppStatus("scorch", status); err != nil {
+					err := util.HumanizeError(err, fmt.Sprintf("Unable to get Scorch status for the %s experiment", name))
+					return err.Humanized()
+				}
+			}
+
+			printer.PrintTableOfScorchRuns(os.Stdout, md, status)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newScorchLogsCmd() *cobra.Command {
+	desc := `Display component output for a Scorch run
+
+  Used to display the output generated by components for the most recent
+  execution of the given run ID for the given experiment. When following, new
+  output is displayed as it's generated until the run completes.`
+
+	cmd := &cobra.Command{
+		Use:   "logs <experiment name> <run ID>",
+		Short: "Display component output for a Scorch run",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, run, err := parseScorchArgs(args)
+			if err != nil {
+				return err
+			}
+
+			exp, err := experiment.Get(name)
+			if err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to get the %s experiment", name))
+				return err.Humanized()
+			}
+
+			path := scorchexe.LogFile(exp, run)
+
+			if !MustGetBool(cmd.Flags(), "follow") {
+				f, err := os.Open(path)
+				if err != nil {
+					err := util.HumanizeError(err, fmt.Sprintf("Unable to open output for Scorch run %d for the %s experiment", run, name))
+					return err.Humanized()
+				}
+
+				defer f.Close()
+
+				io.Copy(os.Stdout, f)
+				return nil
+			}
+
+			logs, err := tail.TailFile(path, tail.Config{Follow: true, ReOpen: true, Poll: true, Logger: tail.DiscardingLogger})
+			if err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to follow output for Scorch run %d for the %s experiment", run, name))
+				return err.Humanized()
+			}
+
+			defer logs.Cleanup()
+
+			var (
+				ctx    = sigterm.CancelContext(cmd.Context())
+				ticker = time.NewTicker(2 * time.Second)
+			)
+
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case line := <-logs.Lines:
+					fmt.Println(line.Text)
+				case <-ticker.C:
+					// Stop following once the run is no longer executing.
+					if !scorchRunning(name, run) {
+						logs.StopAtEOF()
+
+						for line := range logs.Lines {
+							fmt.Println(line.Text)
+						}
+
+						return nil
+					}
+				}
+			}
+		},
+	}
+
+	cmd.Flags().BoolP("follow", "f", false, "Follow output until the run completes")
+
+	return cmd
+}
+
+func newScorchAttachCmd() *cobra.Command {
+	desc := `Attach to a break component waiting in a Scorch run
+
+  Used to attach the current terminal to a break component waiting in a Scorch
+  run that was started from the command line without a terminal. Exiting the
+  shell allows the run to continue.`
+
+	cmd := &cobra.Command{
+		Use:   "attach <experiment name> <run ID>",
+		Short: "Attach to a break component waiting in a Scorch run",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, run, err := parseScorchArgs(args)
+			if err != nil {
+				return err
+			}
+
+			ctx := sigterm.CancelContext(cmd.Context())
+
+			if err := scorch.AttachTerminal(ctx, name, run, MustGetString(cmd.Flags(), "component")); err != nil {
+				err := util.HumanizeError(err, fmt.Sprintf("Unable to attach to Scorch run %d for the %s experiment", run, name))
+				return err.Humanized()
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("component", "c", "", "Name of break component to attach to (defaults to first one waiting)")
+
+	return cmd
+}
+
+func parseScorchArgs(args []string) (string, int, error) {
+	run, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("The run ID provided is not a valid integer")
+	}
+
+	return args[0], run, nil
+}
+
+func scorchRunning(name string, run int) bool {
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return false
+	}
+
+	if !exp.Status.AppRunning()["scorch"] {
+		return false
+	}
+
+	var status scorchmd.ScorchStatus
+
+	if err := exp.Status.ParseAppStatus("scorch", &status); err != nil {
+		return false
+	}
+
+	return status.RunID == run
+}
+
+func init() {
+	scorchCmd := newScorchCmd()
+
+	scorchCmd.AddCommand(newScorchRunCmd())
+	scorchCmd.AddCommand(newScorchCancelCmd())
+	scorchCmd.AddCommand(newScorchStatusCmd())
+	scorchCmd.AddCommand(newScorchLogsCmd())
+	scorchCmd.AddCommand(newScorchAttachCmd())
+
+	rootCmd.AddCommand(scorchCmd)
+}
@@ -8,7 +8,10 @@ import (
 
 	"phenix/util"
 	"phenix/util/common"
+	"phenix/util/plog"
 	"phenix/web"
+	"phenix/web/jwtkeys"
+	"phenix/web/oidc"
 
 	log "github.com/activeshadow/libminimega/minilog"
 	"github.com/spf13/cobra"
@@ -33,8 +36,14 @@ func newUiCmd() *cobra.Command {
 				return err
 			}
 
+			// Route minimega-style logs through plog so all UI logs are written as
+			// structured records.
+			log.AddLogger("plog", plog.MinilogWriter(), level, false)
+
 			if viper.GetBool("ui.log-verbose") {
-				log.AddLogger("stderr", os.Stderr, level, true)
+				plog.AddTextHandler("stderr", os.Stderr, level)
+			} else {
+				plog.DelHandler("stderr")
 			}
 
 			if path := viper.GetString("ui.logs.phenix-path"); path != "" {
@@ -45,15 +54,17 @@ func newUiCmd() *cobra.Command {
 					return err
 				}
 
-				log.AddLogger("file", logfile, level, false)
+				plog.AddJSONHandler("file", logfile, level)
 				common.LogFile = path
 			}
 
 			opts := []web.ServerOption{
 				web.ServeOnEndpoint(viper.GetString("ui.listen-endpoint")),
 				web.ServeOnUnixSocket(viper.GetString("ui.unix-socket-endpoint")),
+				web.ServeGRPCOnEndpoint(viper.GetString("ui.grpc-endpoint")),
 				web.ServeBasePath(viper.GetString("ui.base-path")),
 				web.ServeWithJWTKey(viper.GetString("ui.jwt-signing-key")),
+				web.ServeWithJWTKeySet(viper.GetString("ui.jwt-key-set")),
 				web.ServeWithJWTLifetime(viper.GetDuration("ui.jwt-lifetime")),
 				web.ServeWithUsers(viper.GetStringSlice("ui.users")),
 				web.ServeWithTLS(viper.GetString("ui.tls-key"), viper.GetString("ui.tls-cert")),
@@ -61,6 +72,31 @@ func newUiCmd() *cobra.Command {
 				web.ServeMinimegaLogs(viper.GetString("ui.logs.minimega-path")),
 				web.ServeWithFeatures(viper.GetStringSlice("ui.features")),
 				web.ServeWithProxyAuthHeader(viper.GetString("ui.proxy-auth-header")),
+				web.ServeWithTrustedProxies(viper.GetStringSlice("ui.trusted-proxies")),
+			}
+
+			if issuer := viper.GetString("ui.oidc.issuer"); issuer != "" {
+				c := oidc.Config{
+					Issuer:        issuer,
+					ClientID:      viper.GetString("ui.oidc.client-id"),
+					ClientSecret:  viper.GetString("ui.oidc.client-secret"),
+					RedirectURL:   viper.GetString("ui.oidc.redirect-url"),
+					Scopes:        viper.GetStringSlice("ui.oidc.scopes"),
+					UsernameClaim: viper.GetString("ui.oidc.username-claim"),
+					GroupsClaim:   viper.GetString("ui.oidc.groups-claim"),
+					DefaultRole:   viper.GetString("ui.oidc.default-role"),
+				}
+
+				for _, m := range viper.GetStringSlice("ui.oidc.role-mappings") {
+					mapping, err := oidc.ParseRoleMapping(m)
+					if err != nil {
+						return err
+					}
+
+					c.RoleMappings = append(c.RoleMappings, mapping)
+				}
+
+				opts = append(opts, web.ServeWithOIDC(c))
 			}
 
 			if viper.GetString("ui.minimega-path") != "" {
@@ -92,10 +128,13 @@ func newUiCmd() *cobra.Command {
 
 	cmd.Flags().StringP("listen-endpoint", "e", "0.0.0.0:3000", "endpoint to listen on")
 	cmd.Flags().String("unix-socket-endpoint", "", "unix socket path to listen on (no auth, only exposes workflow API)")
+	cmd.Flags().String("grpc-endpoint", "", "endpoint to serve gRPC API on (disabled if not set)")
 	cmd.Flags().StringP("base-path", "b", "/", "base path to use for UI (must run behind proxy if not '/')")
 	cmd.Flags().StringP("jwt-signing-key", "k", "", "Secret key used to sign JWT for authentication")
+	cmd.PersistentFlags().String("jwt-key-set", "", "directory of asymmetric keys used to sign and verify JWT (see `phenix ui rotate-keys`)")
 	cmd.Flags().Duration("jwt-lifetime", 24*time.Hour, "Lifetime of JWT authentication tokens")
 	cmd.Flags().String("proxy-auth-header", "", "header containing username when using proxy authentication")
+	cmd.Flags().StringSlice("trusted-proxies", nil, "addresses or CIDR networks of proxies trusted to set the X-Forwarded-For header")
 	cmd.Flags().StringSlice("users", nil, "pipe-delimited list of initial users to add")
 	cmd.Flags().String("tls-key", "", "path to TLS key file")
 	cmd.Flags().String("tls-cert", "", "path to TLS cert file")
@@ -107,13 +146,25 @@ func newUiCmd() *cobra.Command {
 	cmd.Flags().StringSlice("features", nil, "list of features to enable (options: vm-mount)")
 	cmd.Flags().String("minimega-path", "", "path to minimega executable (for console access) - DEPRECATED (use --minimega-console instead)")
 	cmd.Flags().Bool("minimega-console", false, "enable minimega console access in UI")
+	cmd.Flags().String("oidc.issuer", "", "OpenID Connect issuer URL to enable OIDC login with")
+	cmd.Flags().String("oidc.client-id", "", "OpenID Connect client ID")
+	cmd.Flags().String("oidc.client-secret", "", "OpenID Connect client secret (optional when using PKCE)")
+	cmd.Flags().String("oidc.redirect-url", "", "OpenID Connect redirect URL (<phenix URL>/api/v1/login/oidc/callback)")
+	cmd.Flags().StringSlice("oidc.scopes", nil, "OpenID Connect scopes to request (default openid,profile,email,groups)")
+	cmd.Flags().String("oidc.username-claim", "email", "ID token claim to use as phenix username")
+	cmd.Flags().String("oidc.groups-claim", "groups", "ID token claim containing user groups")
+	cmd.Flags().StringSlice("oidc.role-mappings", nil, "list of <group>:<role>[:<resource>...] or <claim>=<value>:<role>[:<resource>...] mappings")
+	cmd.Flags().String("oidc.default-role", "", "role to give OIDC users not matching any role mappings (default is to deny login)")
 
 	viper.BindPFlag("ui.listen-endpoint", cmd.Flags().Lookup("listen-endpoint"))
 	viper.BindPFlag("ui.unix-socket-endpoint", cmd.Flags().Lookup("unix-socket-endpoint"))
+	viper.BindPFlag("ui.grpc-endpoint", cmd.Flags().Lookup("grpc-endpoint"))
 	viper.BindPFlag("ui.base-path", cmd.Flags().Lookup("base-path"))
 	viper.BindPFlag("ui.jwt-signing-key", cmd.Flags().Lookup("jwt-signing-key"))
+	viper.BindPFlag("ui.jwt-key-set", cmd.PersistentFlags().Lookup("jwt-key-set"))
 	viper.BindPFlag("ui.jwt-lifetime", cmd.Flags().Lookup("jwt-lifetime"))
 	viper.BindPFlag("ui.proxy-auth-header", cmd.Flags().Lookup("proxy-auth-header"))
+	viper.BindPFlag("ui.trusted-proxies", cmd.Flags().Lookup("trusted-proxies"))
 	viper.BindPFlag("ui.users", cmd.Flags().Lookup("users"))
 	viper.BindPFlag("ui.tls-key", cmd.Flags().Lookup("tls-key"))
 	viper.BindPFlag("ui.tls-cert", cmd.Flags().Lookup("tls-cert"))
@@ -124,13 +175,25 @@ func newUiCmd() *cobra.Command {
 	viper.BindPFlag("ui.features", cmd.Flags().Lookup("features"))
 	viper.BindPFlag("ui.minimega-path", cmd.Flags().Lookup("minimega-path"))
 	viper.BindPFlag("ui.minimega-console", cmd.Flags().Lookup("minimega-console"))
+	viper.BindPFlag("ui.oidc.issuer", cmd.Flags().Lookup("oidc.issuer"))
+	viper.BindPFlag("ui.oidc.client-id", cmd.Flags().Lookup("oidc.client-id"))
+	viper.BindPFlag("ui.oidc.client-secret", cmd.Flags().Lookup("oidc.client-secret"))
+	viper.BindPFlag("ui.oidc.redirect-url", cmd.Flags().Lookup("oidc.redirect-url"))
+	viper.BindPFlag("ui.oidc.scopes", cmd.Flags().Lookup("oidc.scopes"))
+	viper.BindPFlag("ui.oidc.username-claim", cmd.Flags().Lookup("oidc.username-claim"))
+	viper.BindPFlag("ui.oidc.groups-claim", cmd.Flags().Lookup("oidc.groups-claim"))
+	viper.BindPFlag("ui.oidc.role-mappings", cmd.Flags().Lookup("oidc.role-mappings"))
+	viper.BindPFlag("ui.oidc.default-role", cmd.Flags().Lookup("oidc.default-role"))
 
 	viper.BindEnv("ui.listen-endpoint")
 	viper.BindEnv("ui.unix-socket-endpoint")
+	viper.BindEnv("ui.grpc-endpoint")
 	viper.BindEnv("ui.base-path")
 	viper.BindEnv("ui.jwt-signing-key")
+	viper.BindEnv("ui.jwt-key-set")
 	viper.BindEnv("ui.jwt-lifetime")
 	viper.BindEnv("ui.proxy-auth-header")
+	viper.BindEnv("ui.trusted-proxies")
 	viper.BindEnv("ui.users")
 	viper.BindEnv("ui.tls-key")
 	viper.BindEnv("ui.tls-cert")
@@ -141,6 +204,15 @@ func newUiCmd() *cobra.Command {
 	viper.BindEnv("ui.features")
 	viper.BindEnv("ui.minimega-path")
 	viper.BindEnv("ui.minimega-console")
+	viper.BindEnv("ui.oidc.issuer")
+	viper.BindEnv("ui.oidc.client-id")
+	viper.BindEnv("ui.oidc.client-secret")
+	viper.BindEnv("ui.oidc.redirect-url")
+	viper.BindEnv("ui.oidc.scopes")
+	viper.BindEnv("ui.oidc.username-claim")
+	viper.BindEnv("ui.oidc.groups-claim")
+	viper.BindEnv("ui.oidc.role-mappings")
+	viper.BindEnv("ui.oidc.default-role")
 
 	cmd.Flags().Bool("log-requests", false, "Log API requests")
 	cmd.Flags().Bool("log-full", false, "Log API requests and responses")
@@ -151,6 +223,57 @@ func newUiCmd() *cobra.Command {
 	return cmd
 }
 
+func newUiRotateKeysCmd() *cobra.Command {
+	desc := `Rotate the JWT signing key set
+
+  Creates a new key in the key set directory provided via --jwt-key-set (or
+  the PHENIX_UI_JWT_KEY_SET environment variable), creating the key set if it
+  doesn't exist yet. The new key is used to sign all new JWTs, including by UI
+  servers already running with the key set. Previously active keys are still
+  used to verify existing JWTs until they've been retired for longer than the
+  retention period, after which they're removed by the next rotation and any
+  JWTs signed with them are no longer valid.`
+
+	cmd := &cobra.Command{
+		Use:   "rotate-keys",
+		Short: "Rotate the JWT signing key set",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := viper.GetString("ui.jwt-key-set")
+			if dir == "" {
+				return fmt.Errorf("must provide JWT key set directory via --jwt-key-set")
+			}
+
+			alg, err := jwtkeys.ParseAlgorithm(MustGetString(cmd.Flags(), "alg"))
+			if err != nil {
+				return err
+			}
+
+			kid, pruned, err := jwtkeys.Rotate(dir, alg, MustGetDuration(cmd.Flags(), "retain"))
+			if err != nil {
+				return util.HumanizeError(err, "Unable to rotate JWT key set").Humanized()
+			}
+
+			fmt.Printf("New %s signing key %s added to key set %s\n", alg, kid, dir)
+
+			for _, id := range pruned {
+				fmt.Printf("Retired signing key %s removed from key set\n", id)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("alg", "RS256", "signing algorithm for the new key (options: RS256, EdDSA)")
+	cmd.Flags().Duration("retain", 30*24*time.Hour, "how long retired keys are kept to verify existing JWTs")
+
+	return cmd
+}
+
 func init() {
-	rootCmd.AddCommand(newUiCmd())
+	uiCmd := newUiCmd()
+
+	uiCmd.AddCommand(newUiRotateKeysCmd())
+
+	rootCmd.AddCommand(uiCmd)
 }
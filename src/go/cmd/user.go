@@ -0,0 +1,471 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"phenix/api/config"
+	v1 "phenix/types/version/v1"
+	"phenix/util"
+	"phenix/util/printer"
+	"phenix/web/jwtkeys"
+	"phenix/web/rbac"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+func newUserCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "User management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newUserCreateCmd() *cobra.Command {
+	desc := `Create a user
+
+  Creates a new user with the given role, optionally limited to the given
+  resource names. The password is prompted for, or read from the first line of
+  STDIN when --password-stdin is set or when not run in a terminal.`
+
+	example := `
+  phenix user create alice --role "Global Admin"
+  phenix user create bob --role experiment-user --resource-names foo,bar_*
+  cat password.txt | phenix user create carol --role "VM Viewer" --password-stdin`
+
+	cmd := &cobra.Command{
+		Use:     "create <username>",
+		Short:   "Create a user",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			uname := args[0]
+
+			if _, err := rbac.GetUser(uname); err == nil {
+				return fmt.Errorf("user %s already exists", uname)
+			}
+
+			rname := MustGetString(cmd.Flags(), "role")
+			if rname == "" {
+				return fmt.Errorf("must provide a role via --role")
+			}
+
+			// Make sure the role exists before creating the user.
+			if _, err := rbac.RoleFromConfig(rname); err != nil {
+				err := util.HumanizeError(err, "Unable to get role %s", rname)
+				return err.Humanized()
+			}
+
+			pword, err := getPassword(cmd)
+			if err != nil {
+				return err
+			}
+
+			user := rbac.NewUser(uname, pword)
+			if user == nil {
+				return fmt.Errorf("unable to create user %s", uname)
+			}
+
+			user.Spec.FirstName = MustGetString(cmd.Flags(), "first-name")
+			user.Spec.LastName = MustGetString(cmd.Flags(), "last-name")
+
+			names, _ := cmd.Flags().GetStringSlice("resource-names")
+
+			if err := user.SetRoleByName(rname, names...); err != nil {
+				// Don't leave behind a user without a role.
+				if err := config.Delete("user/" + uname); err != nil {
+					fmt.Fprintf(os.Stderr, "Unable to delete user %s after failing to set its role: %v\n", uname, err)
+				}
+
+				err := util.HumanizeError(err, "Unable to set role for user %s", uname)
+				return err.Humanized()
+			}
+
+			fmt.Printf("User %s created with role %s\n", uname, user.RoleName())
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("role", "r", "", "role to give the user")
+	cmd.Flags().StringSlice("resource-names", nil, "resource names to limit the user's role to (default all)")
+	cmd.Flags().StringP("password", "p", "", "password for the user (prompted for if not provided)")
+	cmd.Flags().Bool("password-stdin", false, "read the password from STDIN")
+	cmd.Flags().MarkDeprecated("password", "it's visible to other users in the process list; use the prompt or --password-stdin instead")
+	cmd.Flags().String("first-name", "", "first name of the user")
+	cmd.Flags().String("last-name", "", "last name of the user")
+
+	return cmd
+}
+
+func newUserListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Display a table of users",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			users, err := rbac.GetUsers()
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get users")
+				return err.Humanized()
+			}
+
+			if len(users) == 0 {
+				fmt.Printf("\nThere are no users available\n\n")
+			} else {
+				printer.PrintTableOfUsers(os.Stdout, users...)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newUserDeleteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete <username>",
+		Short: "Delete a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := rbac.GetUser(args[0]); err != nil {
+				err := util.HumanizeError(err, "Unable to get user %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := config.Delete("user/" + args[0]); err != nil {
+				err := util.HumanizeError(err, "Unable to delete user %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("User %s deleted\n", args[0])
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newUserSetRoleCmd() *cobra.Command {
+	example := `
+  phenix user set-role alice "Experiment Admin"
+  phenix user set-role bob experiment-viewer foo bar_*`
+
+	cmd := &cobra.Command{
+		Use:     "set-role <username> <role> [resource names...]",
+		Short:   "Set the role for a user",
+		Example: example,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			user, err := rbac.GetUser(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := user.SetRoleByName(args[1], args[2:]...); err != nil {
+				err := util.HumanizeError(err, "Unable to set role for user %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("Role for user %s set to %s\n", args[0], user.RoleName())
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newUserPasswdCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "passwd <username>",
+		Short: "Reset the password for a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			user, err := rbac.GetUser(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user %s", args[0])
+				return err.Humanized()
+			}
+
+			pword, err := getPassword(cmd)
+			if err != nil {
+				return err
+			}
+
+			if err := user.SetPassword(pword); err != nil {
+				err := util.HumanizeError(err, "Unable to set password for user %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("Password for user %s updated\n", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("password", "p", "", "new password for the user (prompted for if not provided)")
+	cmd.Flags().Bool("password-stdin", false, "read the new password from STDIN")
+	cmd.Flags().MarkDeprecated("password", "it's visible to other users in the process list; use the prompt or --password-stdin instead")
+
+	return cmd
+}
+
+func newUserTokenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "User API token management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newUserTokenListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list <username>",
+		Short: "Display a table of API tokens for a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			user, err := rbac.GetUser(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user %s", args[0])
+				return err.Humanized()
+			}
+
+			if tokens := user.APITokens(); len(tokens) == 0 {
+				fmt.Printf("\nThere are no API tokens for user %s\n\n", args[0])
+			} else {
+				printer.PrintTableOfAPITokens(os.Stdout, tokens)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newUserTokenCreateCmd() *cobra.Command {
+	desc := `Create an API token for a user
+
+  Creates an API token for a user, optionally scoped by one or more policies
+  in the form of <resources>:<verbs>[:<resource names>]. The token is signed
+  with the same JWT signing key or key set used by the UI server, provided
+  via flags or the PHENIX_UI_JWT_SIGNING_KEY or PHENIX_UI_JWT_KEY_SET
+  environment variables. The token is only displayed once.`
+
+	example := `
+  phenix user token create alice --lifetime 720h --desc "CI pipeline"
+  phenix user token create bob --policy experiments:get,list:foo --policy vms:list:foo_*`
+
+	cmd := &cobra.Command{
+		Use:     "create <username>",
+		Short:   "Create an API token for a user",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			user, err := rbac.GetUser(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user %s", args[0])
+				return err.Humanized()
+			}
+
+			lifetime := MustGetDuration(cmd.Flags(), "lifetime")
+			if lifetime <= 0 {
+				return fmt.Errorf("token lifetime must be greater than zero")
+			}
+
+			flags, _ := cmd.Flags().GetStringArray("policy")
+
+			var policies []*v1.PolicySpec
+
+			for _, f := range flags {
+				policy, err := rbac.ParsePolicy(f)
+				if err != nil {
+					return err
+				}
+
+				policies = append(policies, policy)
+			}
+
+			var (
+				id  = rbac.NewTokenID()
+				exp = time.Now().Add(lifetime)
+			)
+
+			signed, err := signUserToken(cmd, jwt.MapClaims{
+				"sub": user.Username(),
+				"exp": exp.Unix(),
+				"jti": id,
+			})
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to sign API token")
+				return err.Humanized()
+			}
+
+			note := fmt.Sprintf("manually generated - %s", time.Now().Format(time.RFC3339))
+			if d := MustGetString(cmd.Flags(), "desc"); d != "" {
+				note = d
+			}
+
+			if err := user.AddAPIToken(id, signed, note, exp, policies); err != nil {
+				err := util.HumanizeError(err, "Unable to add API token for user %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("API token %s for user %s expires %s\n\n%s\n", id, args[0], exp.Format(time.RFC3339), signed)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("lifetime", 30*24*time.Hour, "lifetime of the API token")
+	cmd.Flags().String("desc", "", "description of the API token")
+	cmd.Flags().StringArray("policy", nil, "policy to scope the API token to (<resources>:<verbs>[:<resource names>])")
+	cmd.Flags().String("jwt-signing-key", "", "secret key used by the UI server to sign JWTs")
+	cmd.Flags().String("jwt-key-set", "", "directory of asymmetric keys used by the UI server to sign JWTs")
+
+	return cmd
+}
+
+func newUserTokenRevokeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "revoke <username> <token ID>",
+		Short: "Revoke an API token for a user",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			user, err := rbac.GetUser(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get user %s", args[0])
+				return err.Humanized()
+			}
+
+			if err := user.DeleteAPIToken(args[1]); err != nil {
+				err := util.HumanizeError(err, "Unable to revoke API token %s for user %s", args[1], args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("API token %s for user %s revoked\n", args[1], args[0])
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// getPassword returns the password provided via the (deprecated) --password
+// flag, prompting for it if it wasn't provided. If --password-stdin is set or
+// not running in a terminal, the password is read from the first line of STDIN
+// instead so it can be scripted without exposing it in the process list.
+func getPassword(cmd *cobra.Command) (string, error) {
+	if pword := MustGetString(cmd.Flags(), "password"); pword != "" {
+		return pword, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+
+	if MustGetBool(cmd.Flags(), "password-stdin") || !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading password from STDIN: %w", err)
+		}
+
+		if pword := strings.TrimRight(line, "\r\n"); pword != "" {
+			return pword, nil
+		}
+
+		return "", fmt.Errorf("password cannot be empty")
+	}
+
+	fmt.Print("Password: ")
+	pword, err := term.ReadPassword(fd)
+	fmt.Println()
+
+	if err != nil {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+
+	fmt.Print("Confirm password: ")
+	confirm, err := term.ReadPassword(fd)
+	fmt.Println()
+
+	if err != nil {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+
+	if string(pword) != string(confirm) {
+		return "", fmt.Errorf("passwords do not match")
+	}
+
+	if len(pword) == 0 {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+
+	return string(pword), nil
+}
+
+// signUserToken signs the given claims the same way the UI server does, using
+// the JWT key set if one is configured and the JWT signing key otherwise.
+func signUserToken(cmd *cobra.Command, claims jwt.MapClaims) (string, error) {
+	dir := MustGetString(cmd.Flags(), "jwt-key-set")
+	if dir == "" {
+		dir = viper.GetString("ui.jwt-key-set")
+	}
+
+	if dir != "" {
+		keys, err := jwtkeys.Load(dir)
+		if err != nil {
+			return "", fmt.Errorf("loading JWT key set: %w", err)
+		}
+
+		return keys.Sign(claims)
+	}
+
+	key := MustGetString(cmd.Flags(), "jwt-signing-key")
+	if key == "" {
+		key = viper.GetString("ui.jwt-signing-key")
+	}
+
+	if key == "" {
+		return "", fmt.Errorf("must provide JWT signing key via --jwt-signing-key or key set via --jwt-key-set")
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(key))
+}
+
+func init() {
+	userCmd := newUserCmd()
+	tokenCmd := newUserTokenCmd()
+
+	tokenCmd.AddCommand(newUserTokenCreateCmd())
+	tokenCmd.AddCommand(newUserTokenListCmd())
+	tokenCmd.AddCommand(newUserTokenRevokeCmd())
+
+	userCmd.AddCommand(newUserCreateCmd())
+	userCmd.AddCommand(newUserListCmd())
+	userCmd.AddCommand(newUserDeleteCmd())
+	userCmd.AddCommand(newUserSetRoleCmd())
+	userCmd.AddCommand(newUserPasswdCmd())
+	userCmd.AddCommand(tokenCmd)
+
+	rootCmd.AddCommand(userCmd)
+}
@@ -343,8 +343,8 @@ func newVMNetCmd() *cobra.Command {
 	desc := `Modify network connectivity for a VM
 
   Used to modify the network connectivity for a virtual machine in a running
-  experiment; see command help for connect or disconnect for additional
-  arguments.`
+  experiment; see command help for connect, disconnect, or impair for
+  additional arguments.`
 
 	cmd := &cobra.Command{
 		Use:   "net",
@@ -414,8 +414,79 @@ func newVMNetCmd() *cobra.Command {
 		},
 	}
 
+	impair := &cobra.Command{
+		Use:   "impair <experiment name> <vm name> <iface index>",
+		Short: "Impair traffic sent out of a VM interface",
+		Long: `Impair traffic sent out of a VM interface
+
+  Used to apply delay, jitter, loss, corruption, reordering, and/or rate
+  limiting to traffic sent out of a VM interface in a running experiment,
+  replacing any impairment already applied to the interface. Percentages are
+  between 0 and 100, delay and jitter are durations (e.g. 100ms), and rate is
+  a bandwidth with a kbit, mbit, or gbit unit (e.g. 10mbit). Jitter and
+  reordering require a delay. Use --clear to remove all impairments from the
+  interface.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return fmt.Errorf("Must provide an experiment name, VM name, and iface index")
+			}
+
+			var (
+				expName = args[0]
+				vmName  = args[1]
+			)
+
+			iface, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("The network interface index must be an integer")
+			}
+
+			if MustGetBool(cmd.Flags(), "clear") {
+				if err := vm.ClearImpairment(expName, vmName, iface); err != nil {
+					err := util.HumanizeError(err, "Unable to clear the impairment on the "+vmName+" VM")
+					return err.Humanized()
+				}
+
+				fmt.Printf("The impairment on the %d interface on the %s VM in the %s experiment was cleared\n", iface, vmName, expName)
+
+				return nil
+			}
+
+			imp := mm.Impairment{
+				Delay:      MustGetString(cmd.Flags(), "delay"),
+				Jitter:     MustGetString(cmd.Flags(), "jitter"),
+				Loss:       MustGetFloat64(cmd.Flags(), "loss"),
+				Corruption: MustGetFloat64(cmd.Flags(), "corrupt"),
+				Reordering: MustGetFloat64(cmd.Flags(), "reorder"),
+				Rate:       MustGetString(cmd.Flags(), "rate"),
+			}
+
+			if _, _, err := imp.Validate(); err != nil {
+				return fmt.Errorf("Invalid impairment: %w", err)
+			}
+
+			if err := vm.Impair(expName, vmName, iface, imp); err != nil {
+				err := util.HumanizeError(err, "Unable to impair the interface on the "+vmName+" VM")
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %d interface on the %s VM in the %s experiment was impaired\n", iface, vmName, expName)
+
+			return nil
+		},
+	}
+
+	impair.Flags().String("delay", "", "Delay to add to outgoing packets (e.g. 100ms)")
+	impair.Flags().String("jitter", "", "Random variation in delay of outgoing packets (e.g. 10ms)")
+	impair.Flags().Float64("loss", 0, "Percentage of outgoing packets to drop")
+	impair.Flags().Float64("corrupt", 0, "Percentage of outgoing packets to corrupt")
+	impair.Flags().Float64("reorder", 0, "Percentage of outgoing packets to send immediately, out of order")
+	impair.Flags().String("rate", "", "Maximum bandwidth of outgoing traffic (e.g. 10mbit)")
+	impair.Flags().Bool("clear", false, "Clear all impairments from the interface")
+
 	cmd.AddCommand(connect)
 	cmd.AddCommand(disconnect)
+	cmd.AddCommand(impair)
 
 	return cmd
 }
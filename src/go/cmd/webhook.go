@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+
+	"phenix/api/webhook"
+	"phenix/util"
+
+	"github.com/spf13/cobra"
+)
+
+func newWebhookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Webhook management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newWebhookTestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test <name>",
+		Short: "Send a test event to a webhook",
+		Long: `Send a test event to a webhook
+
+  Sends a webhook.test event to the webhook with the given name, regardless
+  of its event type and experiment filters, retrying per the webhook config.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hook, err := webhook.Get(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get webhook %s", args[0])
+				return err.Humanized()
+			}
+
+			event := webhook.NewEvent(webhook.Test, MustGetString(cmd.Flags(), "experiment"))
+
+			if err := hook.Send(cmd.Context(), event); err != nil {
+				err := util.HumanizeError(err, "Unable to send test event to webhook %s", args[0])
+				return err.Humanized()
+			}
+
+			fmt.Printf("Test event %s sent to webhook %s\n", event.ID, args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("experiment", "", "experiment name to include in the test event")
+
+	return cmd
+}
+
+func init() {
+	webhookCmd := newWebhookCmd()
+
+	webhookCmd.AddCommand(newWebhookTestCmd())
+
+	rootCmd.AddCommand(webhookCmd)
+}
@@ -47,6 +47,12 @@ func (this *Etcd) Init(opts ...Option) error {
 	return nil
 }
 
+// Client returns the Etcd client used by the store so it can be shared with
+// other packages that need to coordinate through Etcd.
+func (this Etcd) Client() *clientv3.Client {
+	return this.cli
+}
+
 func (this Etcd) Close() error {
 	return this.cli.Close()
 }
@@ -3,6 +3,8 @@ package store
 import (
 	"fmt"
 	"net/url"
+
+	"go.etcd.io/etcd/v3/clientv3"
 )
 
 var DefaultStore Store = NewBoltDB()
@@ -27,6 +29,16 @@ func Init(opts ...Option) error {
 	return DefaultStore.Init(opts...)
 }
 
+// EtcdClient returns the Etcd client used by the default store, if the default
+// store is an Etcd store.
+func EtcdClient() (*clientv3.Client, bool) {
+	if etcd, ok := DefaultStore.(*Etcd); ok && etcd.cli != nil {
+		return etcd.cli, true
+	}
+
+	return nil, false
+}
+
 func Close() error {
 	return DefaultStore.Close()
 }
@@ -22,6 +22,7 @@ var ErrInvalidFormat = fmt.Errorf("invalid formatting")
 
 type (
 	Configs     []Config
+	Labels      map[string]string
 	Annotations map[string]string
 )
 
@@ -37,6 +38,7 @@ type ConfigMetadata struct {
 	Name        string      `json:"name" yaml:"name"`
 	Created     string      `json:"created" yaml:"created"`
 	Updated     string      `json:"updated" yaml:"updated"`
+	Labels      Labels      `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Annotations Annotations `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 }
 
@@ -205,6 +207,7 @@ const (
 	EventTypeError   EventType = "error"
 	EventTypeUnknown EventType = "unknown"
 	EventTypeHistory EventType = "history"
+	EventTypeAudit   EventType = "audit"
 )
 
 type Event struct {
@@ -248,6 +251,13 @@ func NewHistoryEvent(history string) *Event {
 	return event
 }
 
+func NewAuditEvent(format string, args ...interface{}) *Event {
+	event := NewEvent(format, args...)
+	event.Type = EventTypeAudit
+
+	return event
+}
+
 func (this *Event) WithMetadata(k, v string) *Event {
 	if this.Metadata == nil {
 		this.Metadata = make(map[string]string)
@@ -259,6 +269,26 @@ func (this *Event) WithMetadata(k, v string) *Event {
 
 type Events []Event
 
+// Between returns the events with a timestamp in the given range. A zero since
+// or until time leaves that end of the range open.
+func (this Events) Between(since, until time.Time) Events {
+	var events Events
+
+	for _, e := range this {
+		if !since.IsZero() && e.Timestamp.Before(since) {
+			continue
+		}
+
+		if !until.IsZero() && e.Timestamp.After(until) {
+			continue
+		}
+
+		events = append(events, e)
+	}
+
+	return events
+}
+
 func (this Events) SortByTimestamp(asc bool) {
 	sort.Slice(this, func(i, j int) bool {
 		if asc {
@@ -0,0 +1,132 @@
+package tmpl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"phenix/tmpl"
+	v1 "phenix/types/version/v1"
+)
+
+func TestFRRTemplate(t *testing.T) {
+	var (
+		localPref = 200
+		metric    = 50
+	)
+
+	bgp := &v1.BGP{
+		ASNF:      65001,
+		RouterIDF: "1.1.1.1",
+		NeighborsF: []v1.BGPNeighbor{
+			{
+				AddressF:     "10.0.0.2",
+				RemoteASNF:   65002,
+				DescriptionF: "upstream",
+				PasswordF:    "secret",
+				MultihopF:    2,
+				RouteMapInF:  "UPSTREAM-IN",
+				RouteMapOutF: "UPSTREAM-OUT",
+			},
+			{AddressF: "10.0.1.2", RemoteASNF: 65003},
+		},
+		NetworksF:     []string{"192.168.10.0/24", "192.168.20.0/24"},
+		RedistributeF: []string{"connected"},
+		RouteMapsF: []v1.RouteMap{
+			{
+				NameF: "UPSTREAM-IN",
+				EntriesF: []v1.RouteMapEntry{
+					{SeqF: 10, ActionF: "permit", MatchPrefixesF: []string{"172.16.0.0/16", "172.17.0.0/16"}, LocalPrefF: &localPref},
+					{SeqF: 20, ActionF: "deny"},
+				},
+			},
+			{
+				NameF: "UPSTREAM-OUT",
+				EntriesF: []v1.RouteMapEntry{
+					{SeqF: 10, ActionF: "permit", MetricF: &metric, ASPathPrependF: []int{65001, 65001}, CommunityF: "65001:100"},
+				},
+			},
+		},
+	}
+
+	node := func(bgp *v1.BGP) *v1.Node {
+		return &v1.Node{
+			GeneralF: &v1.General{HostnameF: "router"},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "eth0", ProtoF: "static", AddressF: "10.0.0.1", MaskF: 24, GatewayF: "10.0.0.254"},
+					{NameF: "eth1", ProtoF: "static", AddressF: "10.0.1.1", MaskF: 24},
+				},
+				BGPF: bgp,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		data     *v1.Node
+		expected []string
+		excluded []string
+	}{
+		{
+			name: "bgp",
+			data: node(bgp),
+			expected: []string{
+				"hostname router\n",
+				"interface eth0\n ip address 10.0.0.1/24\n",
+				"ip route 0.0.0.0/0 10.0.0.254\n",
+				"router bgp 65001\n bgp router-id 1.1.1.1\n no bgp ebgp-requires-policy\n",
+				// neighbors
+				" neighbor 10.0.0.2 remote-as 65002\n neighbor 10.0.0.2 description upstream\n neighbor 10.0.0.2 password secret\n neighbor 10.0.0.2 ebgp-multihop 2\n",
+				" neighbor 10.0.1.2 remote-as 65003\n !\n",
+				// networks
+				" address-family ipv4 unicast\n  network 192.168.10.0/24\n  network 192.168.20.0/24\n  redistribute connected\n",
+				"  neighbor 10.0.0.2 route-map UPSTREAM-IN in\n  neighbor 10.0.0.2 route-map UPSTREAM-OUT out\n exit-address-family\n",
+				// route maps
+				"ip prefix-list UPSTREAM-IN-10 seq 1 permit 172.16.0.0/16\nip prefix-list UPSTREAM-IN-10 seq 2 permit 172.17.0.0/16\n",
+				"route-map UPSTREAM-IN permit 10\n match ip address prefix-list UPSTREAM-IN-10\n set local-preference 200\n!\n",
+				"route-map UPSTREAM-IN deny 20\n!\n",
+				"route-map UPSTREAM-OUT permit 10\n set metric 50\n set as-path prepend 65001 65001\n set community 65001:100\n!\n",
+			},
+			excluded: []string{
+				"neighbor 10.0.1.2 description",
+				"neighbor 10.0.1.2 ebgp-multihop",
+				"neighbor 10.0.1.2 route-map",
+				"prefix-list UPSTREAM-IN-20",
+				"prefix-list UPSTREAM-OUT-10",
+				"router ospf",
+			},
+		},
+		{
+			name:     "no bgp",
+			data:     node(nil),
+			expected: []string{"hostname router\n", "interface eth1\n ip address 10.0.1.1/24\n", "line vty\n"},
+			excluded: []string{"router bgp", "route-map", "prefix-list", "address-family"},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+
+		if err := tmpl.GenerateFromTemplate("frr.tmpl", test.data, &buf); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		output := buf.String() + "\n"
+
+		for _, line := range test.expected {
+			if !strings.Contains(output, line) {
+				t.Logf("%s output missing %q\n%s", test.name, line, output)
+				t.FailNow()
+			}
+		}
+
+		for _, line := range test.excluded {
+			if strings.Contains(output, line) {
+				t.Logf("%s output unexpectedly includes %q\n%s", test.name, line, output)
+				t.FailNow()
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package tmpl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"phenix/tmpl"
+)
+
+func TestNTPTemplates(t *testing.T) {
+	type config struct {
+		Source  string
+		Stratum int
+		Server  bool
+		Domain  bool
+	}
+
+	tests := []struct {
+		name     string
+		data     config
+		expected []string
+		excluded []string
+	}{
+		{
+			name:     "ntp_linux.tmpl",
+			data:     config{Source: "10.0.0.1", Stratum: 8},
+			expected: []string{"server 10.0.0.1 iburst prefer", "fudge 127.127.1.1 stratum 8"},
+		},
+		{
+			name:     "ntp_linux.tmpl",
+			data:     config{Stratum: 3, Server: true},
+			expected: []string{"server 127.127.1.1 iburst prefer", "fudge 127.127.1.1 stratum 3"},
+			excluded: []string{"server 10.0.0.1"},
+		},
+		{
+			name:     "chrony.tmpl",
+			data:     config{Source: "10.0.0.1", Stratum: 8},
+			expected: []string{"server 10.0.0.1 iburst prefer", "makestep 1 -1"},
+			excluded: []string{"allow", "local stratum"},
+		},
+		{
+			name:     "chrony.tmpl",
+			data:     config{Source: "10.0.0.1", Stratum: 9, Server: true},
+			expected: []string{"server 10.0.0.1 iburst prefer", "allow", "local stratum 9 orphan"},
+		},
+		{
+			name:     "chrony.tmpl",
+			data:     config{Stratum: 8, Server: true},
+			expected: []string{"allow", "local stratum 8\n"},
+			excluded: []string{"server ", "orphan"},
+		},
+		{
+			name:     "ntp_windows.tmpl",
+			data:     config{Source: "10.0.0.1"},
+			expected: []string{`/manualpeerlist:"10.0.0.1" /syncfromflags:manual`, "w32tm /monitor /computers:10.0.0.1", "w32tm /resync /force"},
+			excluded: []string{"domhier", "NtpServer"},
+		},
+		{
+			name:     "ntp_windows.tmpl",
+			data:     config{Domain: true},
+			expected: []string{"w32tm /config /syncfromflags:domhier /update", "w32tm /resync /force"},
+			excluded: []string{"manualpeerlist", "w32tm /monitor", "NtpServer"},
+		},
+		{
+			name:     "ntp_windows.tmpl",
+			data:     config{Server: true},
+			expected: []string{`NtpServer' -Name 'Enabled' -Value 1`, `'AnnounceFlags' -Value 5`, "/syncfromflags:NO /reliable:YES"},
+			excluded: []string{"manualpeerlist", "w32tm /resync"},
+		},
+	}
+
+	for _, test := range tests {
+		var buf bytes.Buffer
+
+		if err := tmpl.GenerateFromTemplate(test.name, test.data, &buf); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		output := buf.String() + "\n"
+
+		for _, line := range test.expected {
+			if !strings.Contains(output, line) {
+				t.Logf("%s output missing %q\n%s", test.name, line, output)
+				t.FailNow()
+			}
+		}
+
+		for _, line := range test.excluded {
+			if strings.Contains(output, line) {
+				t.Logf("%s output unexpectedly includes %q\n%s", test.name, line, output)
+				t.FailNow()
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package tmpl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"phenix/tmpl"
+	ifaces "phenix/types/interfaces"
+	v1 "phenix/types/version/v1"
+)
+
+func TestSerialTemplates(t *testing.T) {
+	data := struct {
+		Interfaces []ifaces.NodeNetworkInterface
+		Agent      string
+	}{
+		Interfaces: []ifaces.NodeNetworkInterface{
+			&v1.Interface{
+				TypeF:     "serial",
+				AddressF:  "10.0.0.1",
+				MaskF:     24,
+				UDPPortF:  8989,
+				BaudRateF: 9600,
+				DeviceF:   "/dev/ttyS1",
+			},
+		},
+		Agent: `C:\phenix\socat\socat.exe`,
+	}
+
+	// expected lines for each OS type's serial template
+	expected := map[string][]string{
+		"serial_startup.tmpl": {
+			"until ping -c1 10.0.0.1 > /dev/null 2>&1; do sleep 1; done;",
+			"socat -lf/tmp/socat.log -d -d -d -d pty,raw,echo=0,link=/dev/ttyS1,b9600 UDP4-DATAGRAM:224.1.0.1:8989,bind=:8989,range=10.0.0.0/24,ip-add-membership=224.1.0.1:10.0.0.1,ip-multicast-loop=0 &",
+			"route add -net 224.0.0.0/3 gw 10.0.0.1",
+		},
+		"serial_startup_rhel.tmpl": {
+			"nm-online -s -q -t 60",
+			`until ip -o -4 addr show | grep -q " 10.0.0.1/"; do sleep 1; done;`,
+			"firewall-cmd --add-port=8989/udp",
+			"socat -lf/tmp/socat.log -d -d -d -d pty,raw,echo=0,link=/dev/ttyS1,b9600 UDP4-DATAGRAM:224.1.0.1:8989,bind=:8989,range=10.0.0.0/24,ip-add-membership=224.1.0.1:10.0.0.1,ip-multicast-loop=0 &",
+			"ip route replace 224.0.0.0/3 dev $dev src 10.0.0.1",
+		},
+		"serial_windows.tmpl": {
+			`$agent = 'C:\phenix\socat\socat.exe'`,
+			"route ADD 224.0.0.0 MASK 224.0.0.0 10.0.0.1 IF $idx",
+			"New-NetFirewallRule -DisplayName 'phenix serial 8989' -Direction Inbound -Protocol UDP -LocalPort 8989 -Action Allow | Out-Null",
+			`Start-Process -FilePath $agent -WindowStyle Hidden -ArgumentList "/dev/ttyS1,raw,echo=0,b9600", "UDP4-DATAGRAM:224.1.0.1:8989,bind=:8989,range=10.0.0.0/24,ip-add-membership=224.1.0.1:10.0.0.1,ip-multicast-loop=0"`,
+		},
+	}
+
+	for name, lines := range expected {
+		var buf bytes.Buffer
+
+		if err := tmpl.GenerateFromTemplate(name, data, &buf); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		output := buf.String()
+
+		for _, line := range lines {
+			if !strings.Contains(output, line) {
+				t.Logf("%s output missing line %q\n%s", name, line, output)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestSerialWindowsDevice(t *testing.T) {
+	data := struct {
+		Interfaces []ifaces.NodeNetworkInterface
+		Agent      string
+	}{
+		Interfaces: []ifaces.NodeNetworkInterface{
+			&v1.Interface{
+				TypeF:     "serial",
+				AddressF:  "10.0.0.1",
+				MaskF:     24,
+				UDPPortF:  8989,
+				BaudRateF: 9600,
+				DeviceF:   "/dev/ttyS0",
+			},
+		},
+		Agent: `C:\phenix\socat\socat.exe`,
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.GenerateFromTemplate("serial_windows.tmpl", data, &buf); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	output := buf.String()
+
+	// The first serial device (COM1 in Windows) must be passed to the agent as
+	// /dev/ttyS0.
+	expected := []string{
+		`$com = [int]('/dev/ttyS0' -replace '\D', '') + 1`,
+		`-ArgumentList "/dev/ttyS0,raw,echo=0,b9600"`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Logf("serial_windows.tmpl output missing line %q\n%s", line, output)
+			t.FailNow()
+		}
+	}
+
+	if strings.Contains(output, "ttyS-1") || strings.Contains(output, "$com - 1") {
+		t.Logf("serial_windows.tmpl output has invalid device\n%s", output)
+		t.FailNow()
+	}
+}
@@ -29,6 +29,13 @@ func GenerateFromTemplate(name string, data interface{}, w io.Writer) error {
 
 			return *b
 		},
+		"derefInt": func(i *int) int {
+			if i == nil {
+				return 0
+			}
+
+			return *i
+		},
 		"cidrToMask": func(a string) string {
 			_, ipv4Net, err := net.ParseCIDR(a)
 			if err != nil {
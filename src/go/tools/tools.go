@@ -5,5 +5,6 @@ package tools
 import (
 	_ "github.com/go-bindata/go-bindata/v3/go-bindata"
 	_ "github.com/golang/mock/mockgen"
+	_ "github.com/golang/protobuf/protoc-gen-go"
 	_ "google.golang.org/protobuf/cmd/protoc-gen-go"
 )
@@ -8,4 +8,5 @@ import (
 type Image struct {
 	Metadata store.ConfigMetadata
 	Spec     *v1.Image
+	Status   *v1.ImageStatus
 }
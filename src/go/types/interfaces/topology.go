@@ -10,6 +10,8 @@ type TopologySpec interface {
 	FindNodesWithLabels(...string) []NodeSpec
 	FindDelayedNodes() []NodeSpec
 
+	VLANImpairment(string) NodeNetworkImpairment
+
 	AddNode(string, string) NodeSpec
 	RemoveNode(string)
 
@@ -80,6 +82,7 @@ type NodeNetwork interface {
 	Interfaces() []NodeNetworkInterface
 	Routes() []NodeNetworkRoute
 	OSPF() NodeNetworkOSPF
+	BGP() NodeNetworkBGP
 	Rulesets() []NodeNetworkRuleset
 	NAT() []NodeNetworkNAT
 
@@ -108,6 +111,7 @@ type NodeNetworkInterface interface {
 	DNS() []string
 	RulesetIn() string
 	RulesetOut() string
+	Impairment() NodeNetworkImpairment
 
 	SetName(string)
 	SetType(string)
@@ -126,6 +130,16 @@ type NodeNetworkInterface interface {
 	SetDNS([]string)
 	SetRulesetIn(string)
 	SetRulesetOut(string)
+	SetImpairment(NodeNetworkImpairment)
+}
+
+type NodeNetworkImpairment interface {
+	Delay() string
+	Jitter() string
+	Loss() float64
+	Corruption() float64
+	Reordering() float64
+	Rate() string
 }
 
 type NodeNetworkRoute interface {
@@ -151,6 +165,40 @@ type NodeNetworkOSPFAreaNetwork interface {
 	Network() string
 }
 
+type NodeNetworkBGP interface {
+	ASN() int
+	RouterID() string
+	Neighbors() []NodeNetworkBGPNeighbor
+	Networks() []string
+	Redistribute() []string
+	RouteMaps() []NodeNetworkRouteMap
+}
+
+type NodeNetworkBGPNeighbor interface {
+	Address() string
+	RemoteASN() int
+	Description() string
+	Password() string
+	Multihop() int
+	RouteMapIn() string
+	RouteMapOut() string
+}
+
+type NodeNetworkRouteMap interface {
+	Name() string
+	Entries() []NodeNetworkRouteMapEntry
+}
+
+type NodeNetworkRouteMapEntry interface {
+	Seq() int
+	Action() string
+	MatchPrefixes() []string
+	LocalPref() *int
+	Metric() *int
+	ASPathPrepend() []int
+	Community() string
+}
+
 type NodeNetworkRuleset interface {
 	Name() string
 	Description() string
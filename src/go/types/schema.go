@@ -28,6 +28,7 @@ components:
           - Topology
           - Scenario
           - Experiment
+          - Webhook
         metadata:
           type: object
           required:
@@ -49,6 +49,10 @@ func (this Network) Rulesets() []ifaces.NodeNetworkRuleset {
 	return sets
 }
 
+func (Network) BGP() ifaces.NodeNetworkBGP {
+	return nil
+}
+
 func (Network) NAT() []ifaces.NodeNetworkNAT {
 	return nil
 }
@@ -170,6 +174,10 @@ func (this Interface) RulesetOut() string {
 	return this.RulesetOutF
 }
 
+func (Interface) Impairment() ifaces.NodeNetworkImpairment {
+	return nil
+}
+
 func (this *Interface) SetName(name string) {
 	this.NameF = name
 }
@@ -238,6 +246,8 @@ func (this *Interface) SetRulesetOut(rule string) {
 	this.RulesetOutF = rule
 }
 
+func (Interface) SetImpairment(ifaces.NodeNetworkImpairment) {}
+
 type Route struct {
 	DestinationF string `json:"destination" yaml:"destination" structs:"destination" mapstructure:"destination"`
 	NextF        string `json:"next" yaml:"next" structs:"next" mapstructure:"next"`
@@ -31,9 +31,10 @@ type Image struct {
 	IncludeMiniccc   bool `json:"include_miniccc" yaml:"include_miniccc" structs:"include_miniccc" mapstructure:"include_miniccc"`
 	IncludeProtonuke bool `json:"include_protonuke" yaml:"include_protonuke" structs:"include_protonuke" mapstructure:"include_protonuke"`
 
-	Cache       bool     `json:"-" yaml:"-" structs:"-" mapstructure:"-"`
-	ScriptPaths []string `json:"-" yaml:"-" structs:"-" mapstructure:"-"`
-	VerboseLogs bool     `json:"-" yaml:"-" structs:"-" mapstructure:"-"`
+	Cache        bool     `json:"-" yaml:"-" structs:"-" mapstructure:"-"`
+	ScriptPaths  []string `json:"-" yaml:"-" structs:"-" mapstructure:"-"`
+	VerboseLogs  bool     `json:"-" yaml:"-" structs:"-" mapstructure:"-"`
+	PackagesFile string   `json:"-" yaml:"-" structs:"-" mapstructure:"-"`
 }
 
 func (this Image) PackageList() string {
@@ -70,3 +71,44 @@ func (this Image) Verbose() string {
 
 	return ""
 }
+
+// ImageStatus is the status of an image configuration, updated each time an
+// image is built from it.
+type ImageStatus struct {
+	Manifest *ImageManifest `json:"manifest,omitempty" yaml:"manifest,omitempty" structs:"manifest,omitempty" mapstructure:"manifest"`
+}
+
+// ImageManifest records what went into a built image. The digest is derived
+// from the config hash, the vmdb template hash, the phenix version, and the
+// overlay hashes, and is used to address built images in the image cache. The
+// image hash is the checksum of the built image itself.
+type ImageManifest struct {
+	Digest       string         `json:"digest" yaml:"digest" structs:"digest" mapstructure:"digest"`
+	ConfigHash   string         `json:"config_hash" yaml:"config_hash" structs:"config_hash" mapstructure:"config_hash"`
+	TemplateHash string         `json:"template_hash" yaml:"template_hash" structs:"template_hash" mapstructure:"template_hash"`
+	Version      string         `json:"version" yaml:"version" structs:"version" mapstructure:"version"`
+	ImageHash    string         `json:"image_hash,omitempty" yaml:"image_hash,omitempty" structs:"image_hash" mapstructure:"image_hash"`
+	Mirror       string         `json:"mirror" yaml:"mirror" structs:"mirror" mapstructure:"mirror"`
+	Release      string         `json:"release" yaml:"release" structs:"release" mapstructure:"release"`
+	Variant      string         `json:"variant" yaml:"variant" structs:"variant" mapstructure:"variant"`
+	Format       Format         `json:"format" yaml:"format" structs:"format" mapstructure:"format"`
+	Packages     []ImagePackage `json:"packages" yaml:"packages" structs:"packages" mapstructure:"packages"`
+	Overlays     []ImageFile    `json:"overlays" yaml:"overlays" structs:"overlays" mapstructure:"overlays"`
+	Scripts      []ImageFile    `json:"scripts" yaml:"scripts" structs:"scripts" mapstructure:"scripts"`
+	BuildTime    string         `json:"build_time" yaml:"build_time" structs:"build_time" mapstructure:"build_time"`
+	Cached       bool           `json:"cached" yaml:"cached" structs:"cached" mapstructure:"cached"`
+}
+
+// ImagePackage is a package installed in a built image. The version is empty
+// if it couldn't be determined from the image.
+type ImagePackage struct {
+	Name    string `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty" structs:"version" mapstructure:"version"`
+}
+
+// ImageFile is an overlay or script included in a built image along with the
+// hash of its contents.
+type ImageFile struct {
+	Name string `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	Hash string `json:"hash" yaml:"hash" structs:"hash" mapstructure:"hash"`
+}
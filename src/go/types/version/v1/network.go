@@ -12,6 +12,7 @@ type Network struct {
 	InterfacesF []*Interface `json:"interfaces" yaml:"interfaces" structs:"interfaces" mapstructure:"interfaces"`
 	RoutesF     []Route      `json:"routes" yaml:"routes" structs:"routes" mapstructure:"routes"`
 	OSPFF       *OSPF        `json:"ospf" yaml:"ospf" structs:"ospf" mapstructure:"ospf"`
+	BGPF        *BGP         `json:"bgp" yaml:"bgp" structs:"bgp" mapstructure:"bgp"`
 	RulesetsF   []*Ruleset   `json:"rulesets" yaml:"rulesets" structs:"rulesets" mapstructure:"rulesets"`
 	NATF        []NAT        `json:"nat" yaml:"nat" structs:"nat" mapstructure:"nat"`
 }
@@ -58,6 +59,18 @@ func (this *Network) OSPF() ifaces.NodeNetworkOSPF {
 	return this.OSPFF
 }
 
+func (this *Network) BGP() ifaces.NodeNetworkBGP {
+	if this == nil {
+		return nil
+	}
+
+	if this.BGPF == nil {
+		return nil
+	}
+
+	return this.BGPF
+}
+
 func (this *Network) Rulesets() []ifaces.NodeNetworkRuleset {
 	if this == nil {
 		return nil
@@ -129,6 +142,8 @@ type Interface struct {
 	DNSF        []string `json:"dns" yaml:"dns" structs:"dns" mapstructure:"dns"`
 	RulesetInF  string   `json:"ruleset_in" yaml:"ruleset_in" structs:"ruleset_in" mapstructure:"ruleset_in"`
 	RulesetOutF string   `json:"ruleset_out" yaml:"ruleset_out" structs:"ruleset_out" mapstructure:"ruleset_out"`
+
+	ImpairmentF *Impairment `json:"impairment" yaml:"impairment" structs:"impairment" mapstructure:"impairment"`
 }
 
 func (this Interface) Name() string {
@@ -203,6 +218,14 @@ func (this Interface) RulesetOut() string {
 	return this.RulesetOutF
 }
 
+func (this Interface) Impairment() ifaces.NodeNetworkImpairment {
+	if this.ImpairmentF == nil {
+		return nil
+	}
+
+	return this.ImpairmentF
+}
+
 func (this *Interface) SetName(name string) {
 	this.NameF = name
 }
@@ -271,6 +294,59 @@ func (this *Interface) SetRulesetOut(rule string) {
 	this.RulesetOutF = rule
 }
 
+func (this *Interface) SetImpairment(imp ifaces.NodeNetworkImpairment) {
+	if imp == nil {
+		this.ImpairmentF = nil
+		return
+	}
+
+	this.ImpairmentF = &Impairment{
+		DelayF:      imp.Delay(),
+		JitterF:     imp.Jitter(),
+		LossF:       imp.Loss(),
+		CorruptionF: imp.Corruption(),
+		ReorderingF: imp.Reordering(),
+		RateF:       imp.Rate(),
+	}
+}
+
+// Impairment describes the network conditions (delay, loss, etc.) to emulate
+// on traffic transmitted out of a VM interface. Percentages are between 0 and
+// 100, durations are Go duration strings (e.g. 100ms) and the rate is a
+// bandwidth with a kbit, mbit, or gbit unit (e.g. 10mbit).
+type Impairment struct {
+	DelayF      string  `json:"delay" yaml:"delay" structs:"delay" mapstructure:"delay"`
+	JitterF     string  `json:"jitter" yaml:"jitter" structs:"jitter" mapstructure:"jitter"`
+	LossF       float64 `json:"loss" yaml:"loss" structs:"loss" mapstructure:"loss"`
+	CorruptionF float64 `json:"corruption" yaml:"corruption" structs:"corruption" mapstructure:"corruption"`
+	ReorderingF float64 `json:"reordering" yaml:"reordering" structs:"reordering" mapstructure:"reordering"`
+	RateF       string  `json:"rate" yaml:"rate" structs:"rate" mapstructure:"rate"`
+}
+
+func (this Impairment) Delay() string {
+	return this.DelayF
+}
+
+func (this Impairment) Jitter() string {
+	return this.JitterF
+}
+
+func (this Impairment) Loss() float64 {
+	return this.LossF
+}
+
+func (this Impairment) Corruption() float64 {
+	return this.CorruptionF
+}
+
+func (this Impairment) Reordering() float64 {
+	return this.ReorderingF
+}
+
+func (this Impairment) Rate() string {
+	return this.RateF
+}
+
 type Route struct {
 	DestinationF string `json:"destination" yaml:"destination" structs:"destination" mapstructure:"destination"`
 	NextF        string `json:"next" yaml:"next" structs:"next" mapstructure:"next"`
@@ -350,6 +426,146 @@ func (this AreaNetwork) Network() string {
 	return this.NetworkF
 }
 
+type BGP struct {
+	ASNF          int           `json:"asn" yaml:"asn" structs:"asn" mapstructure:"asn"`
+	RouterIDF     string        `json:"router_id" yaml:"router_id" structs:"router_id" mapstructure:"router_id"`
+	NeighborsF    []BGPNeighbor `json:"neighbors" yaml:"neighbors" structs:"neighbors" mapstructure:"neighbors"`
+	NetworksF     []string      `json:"networks" yaml:"networks" structs:"networks" mapstructure:"networks"`
+	RedistributeF []string      `json:"redistribute" yaml:"redistribute" structs:"redistribute" mapstructure:"redistribute"`
+	RouteMapsF    []RouteMap    `json:"route_maps" yaml:"route_maps" structs:"route_maps" mapstructure:"route_maps"`
+}
+
+func (this BGP) ASN() int {
+	return this.ASNF
+}
+
+func (this BGP) RouterID() string {
+	return this.RouterIDF
+}
+
+func (this BGP) Neighbors() []ifaces.NodeNetworkBGPNeighbor {
+	neighbors := make([]ifaces.NodeNetworkBGPNeighbor, len(this.NeighborsF))
+
+	for i, n := range this.NeighborsF {
+		neighbors[i] = n
+	}
+
+	return neighbors
+}
+
+func (this BGP) Networks() []string {
+	return this.NetworksF
+}
+
+func (this BGP) Redistribute() []string {
+	return this.RedistributeF
+}
+
+func (this BGP) RouteMaps() []ifaces.NodeNetworkRouteMap {
+	maps := make([]ifaces.NodeNetworkRouteMap, len(this.RouteMapsF))
+
+	for i, m := range this.RouteMapsF {
+		maps[i] = m
+	}
+
+	return maps
+}
+
+type BGPNeighbor struct {
+	AddressF     string `json:"address" yaml:"address" structs:"address" mapstructure:"address"`
+	RemoteASNF   int    `json:"remote_asn" yaml:"remote_asn" structs:"remote_asn" mapstructure:"remote_asn"`
+	DescriptionF string `json:"description" yaml:"description" structs:"description" mapstructure:"description"`
+	PasswordF    string `json:"password" yaml:"password" structs:"password" mapstructure:"password"`
+	MultihopF    int    `json:"multihop" yaml:"multihop" structs:"multihop" mapstructure:"multihop"`
+	RouteMapInF  string `json:"route_map_in" yaml:"route_map_in" structs:"route_map_in" mapstructure:"route_map_in"`
+	RouteMapOutF string `json:"route_map_out" yaml:"route_map_out" structs:"route_map_out" mapstructure:"route_map_out"`
+}
+
+func (this BGPNeighbor) Address() string {
+	return this.AddressF
+}
+
+func (this BGPNeighbor) RemoteASN() int {
+	return this.RemoteASNF
+}
+
+func (this BGPNeighbor) Description() string {
+	return this.DescriptionF
+}
+
+func (this BGPNeighbor) Password() string {
+	return this.PasswordF
+}
+
+func (this BGPNeighbor) Multihop() int {
+	return this.MultihopF
+}
+
+func (this BGPNeighbor) RouteMapIn() string {
+	return this.RouteMapInF
+}
+
+func (this BGPNeighbor) RouteMapOut() string {
+	return this.RouteMapOutF
+}
+
+type RouteMap struct {
+	NameF    string          `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	EntriesF []RouteMapEntry `json:"entries" yaml:"entries" structs:"entries" mapstructure:"entries"`
+}
+
+func (this RouteMap) Name() string {
+	return this.NameF
+}
+
+func (this RouteMap) Entries() []ifaces.NodeNetworkRouteMapEntry {
+	entries := make([]ifaces.NodeNetworkRouteMapEntry, len(this.EntriesF))
+
+	for i, e := range this.EntriesF {
+		entries[i] = e
+	}
+
+	return entries
+}
+
+type RouteMapEntry struct {
+	SeqF           int      `json:"seq" yaml:"seq" structs:"seq" mapstructure:"seq"`
+	ActionF        string   `json:"action" yaml:"action" structs:"action" mapstructure:"action"`
+	MatchPrefixesF []string `json:"match_prefixes" yaml:"match_prefixes" structs:"match_prefixes" mapstructure:"match_prefixes"`
+	LocalPrefF     *int     `json:"local_pref" yaml:"local_pref" structs:"local_pref" mapstructure:"local_pref"`
+	MetricF        *int     `json:"metric" yaml:"metric" structs:"metric" mapstructure:"metric"`
+	ASPathPrependF []int    `json:"as_path_prepend" yaml:"as_path_prepend" structs:"as_path_prepend" mapstructure:"as_path_prepend"`
+	CommunityF     string   `json:"community" yaml:"community" structs:"community" mapstructure:"community"`
+}
+
+func (this RouteMapEntry) Seq() int {
+	return this.SeqF
+}
+
+func (this RouteMapEntry) Action() string {
+	return this.ActionF
+}
+
+func (this RouteMapEntry) MatchPrefixes() []string {
+	return this.MatchPrefixesF
+}
+
+func (this RouteMapEntry) LocalPref() *int {
+	return this.LocalPrefF
+}
+
+func (this RouteMapEntry) Metric() *int {
+	return this.MetricF
+}
+
+func (this RouteMapEntry) ASPathPrepend() []int {
+	return this.ASPathPrependF
+}
+
+func (this RouteMapEntry) Community() string {
+	return this.CommunityF
+}
+
 type Ruleset struct {
 	NameF        string  `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
 	DescriptionF string  `json:"description" yaml:"description" structs:"description" mapstructure:"description"`
@@ -9,4 +9,10 @@ type PolicySpec struct {
 	Resources     []string `yaml:"resources" json:"resources" structs:"resources" mapstructure:"resources"`
 	ResourceNames []string `yaml:"resourceNames" json:"resourceNames" structs:"resourceNames" mapstructure:"resourceNames"`
 	Verbs         []string `yaml:"verbs" json:"verbs" structs:"verbs" mapstructure:"verbs"`
+
+	// LabelSelector and AnnotationSelector limit the policy to resources (e.g.
+	// experiments and VMs) with matching labels and annotations. Values can be
+	// glob patterns.
+	LabelSelector      map[string]string `yaml:"labelSelector" json:"labelSelector" structs:"labelSelector" mapstructure:"labelSelector"`
+	AnnotationSelector map[string]string `yaml:"annotationSelector" json:"annotationSelector" structs:"annotationSelector" mapstructure:"annotationSelector"`
 }
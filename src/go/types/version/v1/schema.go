@@ -95,6 +95,16 @@ components:
                 type: array
                 items:
                   type: string
+              labelSelector:
+                type: object
+                nullable: true
+                additionalProperties:
+                  type: string
+              annotationSelector:
+                type: object
+                nullable: true
+                additionalProperties:
+                  type: string
           example:
           - resources:
             - experiments
@@ -104,6 +114,14 @@ components:
             verbs:
             - list
             - get
+          - resources:
+            - vms
+            - vms/*
+            annotationSelector:
+              team: red
+            verbs:
+            - list
+            - get
         roleName:
           type: string
           example: Example Role
@@ -128,9 +146,54 @@ components:
           allOf:
           - $ref: "#/components/schemas/Role"
           readOnly: true
+        source:
+          type: string
+          example: oidc
+          readOnly: true
         username:
           type: string
           example: johndoe@example.com
+    Webhook:
+      type: object
+      required:
+      - url
+      properties:
+        url:
+          type: string
+          minLength: 1
+          example: https://hooks.example.com/phenix
+        events:
+          type: array
+          items:
+            type: string
+          example:
+          - experiment.*
+          - soh.failure
+        experiments:
+          type: array
+          items:
+            type: string
+          example:
+          - exp-*
+        annotationSelector:
+          type: object
+          additionalProperties:
+            type: string
+          example:
+            team: red
+        secret:
+          type: string
+          example: '<signing secret>'
+        retries:
+          type: integer
+          minimum: 0
+          example: 3
+        backoff:
+          type: string
+          example: 1s
+        timeout:
+          type: string
+          example: 10s
     Topology:
       type: object
       required:
@@ -140,6 +203,15 @@ components:
           type: array
           items:
             $ref: "#/components/schemas/Node"
+        vlan_impairments:
+          type: object
+          nullable: true
+          additionalProperties:
+            $ref: '#/components/schemas/impairment'
+          example:
+            EXP-1:
+              delay: 50ms
+              loss: 0.5
     Scenario:
       type: object
       required:
@@ -386,6 +458,130 @@ components:
                               type: string
                               minLength: 1
                               example: 10.1.25.0/24
+            bgp:
+              type: object
+              nullable: true
+              required:
+              - asn
+              properties:
+                asn:
+                  type: integer
+                  minimum: 1
+                  maximum: 4294967295
+                  example: 65001
+                router_id:
+                  type: string
+                  example: 0.0.0.1
+                neighbors:
+                  type: array
+                  nullable: true
+                  items:
+                    type: object
+                    required:
+                    - address
+                    - remote_asn
+                    properties:
+                      address:
+                        type: string
+                        minLength: 1
+                        example: 10.0.0.2
+                      remote_asn:
+                        type: integer
+                        minimum: 1
+                        maximum: 4294967295
+                        example: 65002
+                      description:
+                        type: string
+                        example: upstream ISP
+                      password:
+                        type: string
+                      multihop:
+                        type: integer
+                        minimum: 0
+                        maximum: 255
+                        example: 2
+                      route_map_in:
+                        type: string
+                        example: FROM-ISP
+                      route_map_out:
+                        type: string
+                        example: TO-ISP
+                networks:
+                  type: array
+                  nullable: true
+                  items:
+                    type: string
+                  example:
+                  - 192.168.0.0/16
+                redistribute:
+                  type: array
+                  nullable: true
+                  items:
+                    type: string
+                    enum:
+                    - connected
+                    - static
+                    - ospf
+                  example:
+                  - connected
+                route_maps:
+                  type: array
+                  nullable: true
+                  items:
+                    type: object
+                    required:
+                    - name
+                    - entries
+                    properties:
+                      name:
+                        type: string
+                        minLength: 1
+                        pattern: '^[\w-]+$'
+                        example: FROM-ISP
+                      entries:
+                        type: array
+                        items:
+                          type: object
+                          required:
+                          - seq
+                          - action
+                          properties:
+                            seq:
+                              type: integer
+                              minimum: 1
+                              example: 10
+                            action:
+                              type: string
+                              enum:
+                              - permit
+                              - deny
+                              example: permit
+                            match_prefixes:
+                              type: array
+                              nullable: true
+                              items:
+                                type: string
+                              example:
+                              - 10.0.0.0/8 le 24
+                            local_pref:
+                              type: integer
+                              nullable: true
+                              example: 200
+                            metric:
+                              type: integer
+                              nullable: true
+                              example: 100
+                            as_path_prepend:
+                              type: array
+                              nullable: true
+                              items:
+                                type: integer
+                              example:
+                              - 65001
+                              - 65001
+                            community:
+                              type: string
+                              example: 65001:100
             rulesets:
               type: array
               nullable: true
@@ -544,6 +740,37 @@ components:
         driver:
           type: string
           example: e1000
+        impairment:
+          $ref: '#/components/schemas/impairment'
+    impairment:
+      type: object
+      nullable: true
+      properties:
+        delay:
+          type: string
+          example: 100ms
+        jitter:
+          type: string
+          example: 10ms
+        loss:
+          type: number
+          minimum: 0
+          maximum: 100
+          example: 1.5
+        corruption:
+          type: number
+          minimum: 0
+          maximum: 100
+          example: 0.1
+        reordering:
+          type: number
+          minimum: 0
+          maximum: 100
+          example: 25
+        rate:
+          type: string
+          pattern: '^\d+(kbit|mbit|gbit)$'
+          example: 10mbit
     iface_address:
       type: object
       required:
@@ -1,11 +1,17 @@
 package v1
 
 import (
+	"strings"
+
 	ifaces "phenix/types/interfaces"
 )
 
 type TopologySpec struct {
 	NodesF []*Node `json:"nodes" yaml:"nodes" structs:"nodes" mapstructure:"nodes"`
+
+	// VLANImpairmentsF maps VLAN aliases to the impairment applied to every VM
+	// interface connected to the VLAN, unless overridden by the interface.
+	VLANImpairmentsF map[string]*Impairment `json:"vlan_impairments" yaml:"vlan_impairments" structs:"vlan_impairments" mapstructure:"vlan_impairments"`
 }
 
 func (this *TopologySpec) Nodes() []ifaces.NodeSpec {
@@ -86,6 +92,16 @@ func (this TopologySpec) FindDelayedNodes() []ifaces.NodeSpec {
 	return nodes
 }
 
+func (this TopologySpec) VLANImpairment(vlan string) ifaces.NodeNetworkImpairment {
+	for alias, imp := range this.VLANImpairmentsF {
+		if strings.EqualFold(alias, vlan) && imp != nil {
+			return imp
+		}
+	}
+
+	return nil
+}
+
 func (this *TopologySpec) AddNode(typ, hostname string) ifaces.NodeSpec {
 	n := &Node{
 		TypeF: typ,
@@ -7,5 +7,27 @@ type UserSpec struct {
 	LastName  string    `yaml:"lastName" json:"last_name" structs:"last_name" mapstructure:"last_name"`
 	Role      *RoleSpec `yaml:"rbac" json:"rbac" structs:"rbac" mapstructure:"rbac"`
 
-	Tokens map[string]string `yaml:"tokens" json:"tokens" structs:"tokens" mapstructure:"tokens"`
+	// Source is set to `oidc` for users created when logging in via an OIDC
+	// provider. Such users don't have a local password, and are bound to the
+	// issuer and subject of the provider account that created them.
+	Source      string `yaml:"source,omitempty" json:"source,omitempty" structs:"source" mapstructure:"source"`
+	OIDCIssuer  string `yaml:"oidcIssuer,omitempty" json:"oidc_issuer,omitempty" structs:"oidc_issuer" mapstructure:"oidc_issuer"`
+	OIDCSubject string `yaml:"oidcSubject,omitempty" json:"oidc_subject,omitempty" structs:"oidc_subject" mapstructure:"oidc_subject"`
+
+	Tokens    map[string]string `yaml:"tokens" json:"tokens" structs:"tokens" mapstructure:"tokens"`
+	APITokens []*APITokenSpec   `yaml:"apiTokens" json:"api_tokens" structs:"api_tokens" mapstructure:"api_tokens"`
+}
+
+// APITokenSpec describes an API token created for a user. Only a hash of the
+// token itself is stored. If policies are present, the token is limited to the
+// intersection of the policies and the user's role.
+type APITokenSpec struct {
+	ID           string        `yaml:"id" json:"id" structs:"id" mapstructure:"id"`
+	Hash         string        `yaml:"hash" json:"hash" structs:"hash" mapstructure:"hash"`
+	Description  string        `yaml:"description" json:"description" structs:"description" mapstructure:"description"`
+	Created      string        `yaml:"created" json:"created" structs:"created" mapstructure:"created"`
+	Expires      string        `yaml:"expires" json:"expires" structs:"expires" mapstructure:"expires"`
+	LastUsed     string        `yaml:"lastUsed" json:"last_used" structs:"last_used" mapstructure:"last_used"`
+	LastUsedFrom string        `yaml:"lastUsedFrom" json:"last_used_from" structs:"last_used_from" mapstructure:"last_used_from"`
+	Policies     []*PolicySpec `yaml:"policies" json:"policies" structs:"policies" mapstructure:"policies"`
 }
@@ -0,0 +1,25 @@
+package v1
+
+// WebhookSpec describes an outbound webhook fired for experiment and VM
+// events.
+type WebhookSpec struct {
+	URL string `yaml:"url" json:"url" structs:"url" mapstructure:"url"`
+
+	// Events limits the webhook to the given event types (e.g.
+	// `experiment.start`). Values can be glob patterns. All events are sent if
+	// no event types are given.
+	Events []string `yaml:"events" json:"events" structs:"events" mapstructure:"events"`
+
+	// Experiments and AnnotationSelector limit the webhook to events for
+	// experiments with matching names and annotations. Values can be glob
+	// patterns, and experiment names prefixed with `!` are excluded.
+	Experiments        []string          `yaml:"experiments" json:"experiments" structs:"experiments" mapstructure:"experiments"`
+	AnnotationSelector map[string]string `yaml:"annotationSelector" json:"annotationSelector" structs:"annotationSelector" mapstructure:"annotationSelector"`
+
+	// Secret, if set, is used to sign the body of each request using HMAC-SHA256.
+	Secret string `yaml:"secret" json:"secret" structs:"secret" mapstructure:"secret"`
+
+	Retries *int   `yaml:"retries" json:"retries" structs:"retries" mapstructure:"retries"`
+	Backoff string `yaml:"backoff" json:"backoff" structs:"backoff" mapstructure:"backoff"`
+	Timeout string `yaml:"timeout" json:"timeout" structs:"timeout" mapstructure:"timeout"`
+}
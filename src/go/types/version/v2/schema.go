@@ -129,9 +129,54 @@ components:
           allOf:
           - $ref: "#/components/schemas/Role"
           readOnly: true
+        source:
+          type: string
+          example: oidc
+          readOnly: true
         username:
           type: string
           example: johndoe@example.com
+    Webhook:
+      type: object
+      required:
+      - url
+      properties:
+        url:
+          type: string
+          minLength: 1
+          example: https://hooks.example.com/phenix
+        events:
+          type: array
+          items:
+            type: string
+          example:
+          - experiment.*
+          - soh.failure
+        experiments:
+          type: array
+          items:
+            type: string
+          example:
+          - exp-*
+        annotationSelector:
+          type: object
+          additionalProperties:
+            type: string
+          example:
+            team: red
+        secret:
+          type: string
+          example: '<signing secret>'
+        retries:
+          type: integer
+          minimum: 0
+          example: 3
+        backoff:
+          type: string
+          example: 1s
+        timeout:
+          type: string
+          example: 10s
     Topology:
       type: object
       required:
@@ -25,6 +25,7 @@ var StoredVersion = map[string]string{
 	"Role":       "v1",
 	"Node":       "v1",
 	"Ruleset":    "v1",
+	"Webhook":    "v1",
 }
 
 const LATEST_VERSION = "v2"
@@ -83,6 +84,13 @@ func GetVersionedSpecForKind(kind, version string) (interface{}, error) {
 		default:
 			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
 		}
+	case "Webhook":
+		switch version {
+		case "v1":
+			return new(v1.WebhookSpec), nil
+		default:
+			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
+		}
 	default:
 		return nil, fmt.Errorf("unknown kind %s", kind)
 	}
@@ -99,6 +107,13 @@ func GetVersionedStatusForKind(kind, version string) (interface{}, error) {
 		default:
 			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
 		}
+	case "Image":
+		switch version {
+		case "v1":
+			return new(v1.ImageStatus), nil
+		default:
+			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
+		}
 	default:
 		return nil, fmt.Errorf("unknown kind %s", kind)
 	}
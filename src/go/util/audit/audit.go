@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"phenix/store"
+
+	log "github.com/activeshadow/libminimega/minilog"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeDenied  Outcome = "denied"
+)
+
+// OutcomeFromStatus returns the outcome of an HTTP request based on the status
+// code of its response.
+func OutcomeFromStatus(status int) Outcome {
+	switch {
+	case status == 401, status == 403:
+		return OutcomeDenied
+	case status >= 400:
+		return OutcomeFailure
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// Record is a single audited action taken by a user via the web API, the
+// WebSocket broker, or the command line.
+type Record struct {
+	User     string
+	Role     string
+	Address  string
+	Via      string
+	Action   string
+	Resource string
+	Outcome  Outcome
+	Status   int
+	Detail   string
+}
+
+// Event returns the store event the record is persisted as. Each of the
+// record's fields is included as event metadata so audit events can be
+// filtered by them when getting history.
+func (this Record) Event() *store.Event {
+	event := store.NewAuditEvent("%s %s %s: %s", this.user(), this.Action, this.Resource, this.Outcome).
+		WithMetadata("user", this.user()).
+		WithMetadata("via", this.Via).
+		WithMetadata("action", this.Action).
+		WithMetadata("outcome", string(this.Outcome))
+
+	if this.Role != "" {
+		event.WithMetadata("role", this.Role)
+	}
+
+	if this.Address != "" {
+		event.WithMetadata("address", this.Address)
+	}
+
+	if this.Resource != "" {
+		event.WithMetadata("resource", this.Resource)
+	}
+
+	if this.Status != 0 {
+		event.WithMetadata("status", strconv.Itoa(this.Status))
+	}
+
+	if this.Detail != "" {
+		event.WithMetadata("detail", this.Detail)
+	}
+
+	return event
+}
+
+func (this Record) user() string {
+	if this.User == "" {
+		return "anonymous"
+	}
+
+	return this.User
+}
+
+// Log persists the given record to the store as an audit event. Failing to do
+// so is logged, but doesn't fail the action being audited.
+func Log(rec Record) {
+	if err := store.AddEvent(*rec.Event()); err != nil {
+		log.Error("recording audit event for %s %s: %v", rec.Action, rec.Resource, err)
+	}
+}
+
+// Export writes the given events to the given writer as JSON lines (one JSON
+// encoded event per line).
+func Export(w io.Writer, events store.Events) error {
+	enc := json.NewEncoder(w)
+
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("encoding event %s: %w", e.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"phenix/store"
+)
+
+func TestRecordEvent(t *testing.T) {
+	rec := Record{
+		User:     "alice",
+		Role:     "Experiment Admin",
+		Address:  "10.0.0.1",
+		Via:      "http",
+		Action:   "POST /experiments/{name}/start",
+		Resource: "/experiments/foo/start",
+		Outcome:  OutcomeFromStatus(403),
+		Status:   403,
+	}
+
+	event := rec.Event()
+
+	if event.Type != store.EventTypeAudit {
+		t.Logf("expected audit event type, got %s", event.Type)
+		t.FailNow()
+	}
+
+	expected := map[string]string{
+		"user":     "alice",
+		"role":     "Experiment Admin",
+		"address":  "10.0.0.1",
+		"via":      "http",
+		"action":   "POST /experiments/{name}/start",
+		"resource": "/experiments/foo/start",
+		"outcome":  "denied",
+		"status":   "403",
+	}
+
+	for k, v := range expected {
+		if event.Metadata[k] != v {
+			t.Logf("expected metadata %s to be %s, got %s", k, v, event.Metadata[k])
+			t.FailNow()
+		}
+	}
+
+	if _, ok := event.Metadata["detail"]; ok {
+		t.Log("expected no detail metadata")
+		t.FailNow()
+	}
+
+	if anon := (Record{Action: "POST /login"}).Event(); anon.Metadata["user"] != "anonymous" {
+		t.Logf("expected anonymous user, got %s", anon.Metadata["user"])
+		t.FailNow()
+	}
+}
+
+func TestExport(t *testing.T) {
+	events := store.Events{
+		*Record{User: "alice", Action: "experiment start", Outcome: OutcomeSuccess}.Event(),
+		*Record{User: "bob", Action: "experiment stop", Outcome: OutcomeFailure}.Event(),
+	}
+
+	var buf bytes.Buffer
+
+	if err := Export(&buf, events); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var (
+		scanner = bufio.NewScanner(&buf)
+		lines   int
+	)
+
+	for scanner.Scan() {
+		var event store.Event
+
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if event.ID != events[lines].ID {
+			t.Logf("expected event %s on line %d, got %s", events[lines].ID, lines+1, event.ID)
+			t.FailNow()
+		}
+
+		lines++
+	}
+
+	if lines != len(events) {
+		t.Logf("expected %d lines, got %d", len(events), lines)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,263 @@
+package firewall
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"phenix/tmpl"
+)
+
+// Render writes the given firewall config to the given writer in the format
+// expected by the given backend (an nftables script or an iptables-restore
+// file).
+//
+// Rulesets are translated into chains jumped to from the input and forward
+// chains for inbound bindings and the forward and output chains for outbound
+// bindings. Accepting a packet in a ruleset returns it to the calling chain so
+// all rulesets bound to the packet's interfaces get evaluated, matching the
+// behavior of rulesets on vyatta/vyos routers.
+func Render(backend Backend, cfg Config, w io.Writer) error {
+	d, err := translate(backend, cfg)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.GenerateFromTemplate(backend.template(), d, w)
+}
+
+// CreateFile writes the given firewall config to the given file in the format
+// expected by the given backend.
+func CreateFile(backend Backend, cfg Config, filename string) error {
+	d, err := translate(backend, cfg)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.CreateFileFromTemplate(backend.template(), d, filename)
+}
+
+func translate(backend Backend, cfg Config) (data, error) {
+	var t translator
+
+	switch backend {
+	case NFTables:
+		t = nftables{}
+	case IPTables:
+		t = iptables{}
+	default:
+		return data{}, fmt.Errorf("unknown firewall backend %s", backend)
+	}
+
+	d, err := build(t, cfg)
+	if err != nil {
+		return d, fmt.Errorf("translating firewall config for %s: %w", backend, err)
+	}
+
+	return d, nil
+}
+
+// match is a backend-agnostic set of packet match criteria.
+type match struct {
+	in, out          string
+	protocol         string
+	srcAddr, dstAddr string
+	srcPort, dstPort string
+	stateful         bool
+}
+
+// translator translates backend-agnostic match criteria and actions into rule
+// syntax for a specific backend.
+type translator interface {
+	rule(chain string, m match, verdict string, comment string) (string, error)
+	jump(chain string, m match, target string) string
+	snat(m match, addr, port string) (string, error)
+	dnat(m match, addr, port string) (string, error)
+}
+
+type chain struct {
+	Name  string
+	Rules []string
+}
+
+type data struct {
+	Chains      []chain
+	Input       []string
+	Forward     []string
+	Output      []string
+	Prerouting  []string
+	Postrouting []string
+}
+
+func build(t translator, cfg Config) (data, error) {
+	var d data
+
+	for _, ruleset := range cfg.Rulesets {
+		var bound bool
+
+		for _, b := range cfg.Bindings {
+			if b.Ruleset == ruleset.Name() {
+				bound = true
+				break
+			}
+		}
+
+		// Don't render rulesets that aren't applied to any interfaces.
+		if !bound {
+			continue
+		}
+
+		c := chain{Name: ruleset.Name()}
+
+		rules := ruleset.Rules()
+		sort.SliceStable(rules, func(i, j int) bool { return rules[i].ID() < rules[j].ID() })
+
+		for _, r := range rules {
+			m := match{protocol: r.Protocol(), stateful: r.Stateful()}
+
+			if src := r.Source(); src != nil {
+				m.srcAddr = src.Address()
+				m.srcPort = port(src.Port())
+			}
+
+			if dst := r.Destination(); dst != nil {
+				m.dstAddr = dst.Address()
+				m.dstPort = port(dst.Port())
+			}
+
+			comment := fmt.Sprintf("%s rule %d", ruleset.Name(), r.ID())
+
+			if r.Description() != "" {
+				comment = fmt.Sprintf("%s: %s", comment, r.Description())
+			}
+
+			rule, err := t.rule(c.Name, m, r.Action(), comment)
+			if err != nil {
+				return d, fmt.Errorf("translating rule %d in ruleset %s: %w", r.ID(), ruleset.Name(), err)
+			}
+
+			c.Rules = append(c.Rules, rule)
+		}
+
+		def := ruleset.Default()
+		if def == "" {
+			def = "accept"
+		}
+
+		rule, err := t.rule(c.Name, match{}, def, ruleset.Name()+" default action")
+		if err != nil {
+			return d, fmt.Errorf("translating default action for ruleset %s: %w", ruleset.Name(), err)
+		}
+
+		c.Rules = append(c.Rules, rule)
+		d.Chains = append(d.Chains, c)
+	}
+
+	for _, b := range cfg.Bindings {
+		switch b.Direction {
+		case In:
+			m := match{in: b.Interface}
+
+			d.Input = append(d.Input, t.jump("INPUT", m, b.Ruleset))
+			d.Forward = append(d.Forward, t.jump("FORWARD", m, b.Ruleset))
+		case Out:
+			m := match{out: b.Interface}
+
+			d.Forward = append(d.Forward, t.jump("FORWARD", m, b.Ruleset))
+			d.Output = append(d.Output, t.jump("OUTPUT", m, b.Ruleset))
+		default:
+			return d, fmt.Errorf("unknown direction %s for ruleset %s", b.Direction, b.Ruleset)
+		}
+	}
+
+	for _, n := range cfg.SNAT {
+		m := match{
+			out:      n.Interface,
+			protocol: n.Protocol,
+			srcAddr:  n.SourceAddress,
+			srcPort:  n.SourcePort,
+			dstAddr:  n.DestinationAddress,
+			dstPort:  n.DestinationPort,
+		}
+
+		rule, err := t.snat(m, n.TranslationAddress, n.TranslationPort)
+		if err != nil {
+			return d, fmt.Errorf("translating SNAT rule for interface %s: %w", n.Interface, err)
+		}
+
+		d.Postrouting = append(d.Postrouting, rule)
+	}
+
+	for _, n := range cfg.DNAT {
+		m := match{
+			in:       n.Interface,
+			protocol: n.Protocol,
+			srcAddr:  n.SourceAddress,
+			srcPort:  n.SourcePort,
+			dstAddr:  n.DestinationAddress,
+			dstPort:  n.DestinationPort,
+		}
+
+		if n.TranslationAddress == "" {
+			return d, fmt.Errorf("DNAT rule for interface %s missing translation address", n.Interface)
+		}
+
+		rule, err := t.dnat(m, n.TranslationAddress, n.TranslationPort)
+		if err != nil {
+			return d, fmt.Errorf("translating DNAT rule for interface %s: %w", n.Interface, err)
+		}
+
+		d.Prerouting = append(d.Prerouting, rule)
+	}
+
+	return d, nil
+}
+
+// validate checks that ports are only matched when the protocol supports them.
+func (this match) validate() error {
+	if this.srcPort == "" && this.dstPort == "" {
+		return nil
+	}
+
+	switch this.proto() {
+	case "tcp", "udp":
+		return nil
+	default:
+		return fmt.Errorf("ports can only be matched for tcp or udp protocols")
+	}
+}
+
+// proto returns the normalized protocol, treating `all` as no protocol.
+func (this match) proto() string {
+	p := strings.ToLower(this.protocol)
+
+	if p == "all" {
+		return ""
+	}
+
+	return p
+}
+
+func port(p int) string {
+	if p == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(p)
+}
+
+// negated splits a leading `!` (used by vyatta/vyos to negate matches) from the
+// given value.
+func negated(v string) (string, bool) {
+	if strings.HasPrefix(v, "!") {
+		return strings.TrimPrefix(v, "!"), true
+	}
+
+	return v, false
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `'`) + `"`
+}
@@ -0,0 +1,234 @@
+package firewall
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	v1 "phenix/types/version/v1"
+)
+
+func testNetwork() *v1.Network {
+	return &v1.Network{
+		InterfacesF: []*v1.Interface{
+			{NameF: "eth0", TypeF: "ethernet", ProtoF: "static", AddressF: "192.168.10.1", MaskF: 24, RulesetInF: "inbound"},
+			{NameF: "eth1", TypeF: "ethernet", ProtoF: "dhcp", RulesetOutF: "outbound"},
+		},
+		RulesetsF: []*v1.Ruleset{
+			{
+				NameF:    "inbound",
+				DefaultF: "drop",
+				RulesF: []*v1.Rule{
+					{
+						IDF:          20,
+						DescriptionF: "allow web",
+						ActionF:      "accept",
+						ProtocolF:    "tcp",
+						SourceF:      &v1.AddrPort{AddressF: "!10.0.0.0/8"},
+						DestinationF: &v1.AddrPort{AddressF: "192.168.10.5", PortF: 443},
+					},
+					{
+						IDF:       10,
+						ActionF:   "accept",
+						ProtocolF: "all",
+						StatefulF: true,
+					},
+				},
+			},
+			{
+				NameF:    "outbound",
+				DefaultF: "accept",
+				RulesF: []*v1.Rule{
+					{IDF: 10, ActionF: "reject", ProtocolF: "icmp"},
+				},
+			},
+			{
+				NameF: "unused",
+				RulesF: []*v1.Rule{
+					{IDF: 10, ActionF: "drop", ProtocolF: "udp"},
+				},
+			},
+		},
+		NATF: []v1.NAT{{InF: []string{"eth0"}, OutF: "eth1"}},
+	}
+}
+
+func testConfig(t *testing.T) Config {
+	cfg, err := NewConfig(testNetwork())
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	cfg.DNAT = []NAT{
+		{
+			Interface:          "eth1",
+			Protocol:           "tcp",
+			DestinationPort:    "8080,8443",
+			TranslationAddress: "192.168.10.5",
+			TranslationPort:    "443",
+		},
+	}
+
+	return cfg
+}
+
+func render(t *testing.T, backend Backend, cfg Config) string {
+	var buf bytes.Buffer
+
+	if err := Render(backend, cfg, &buf); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return buf.String()
+}
+
+func TestNewConfig(t *testing.T) {
+	cfg := testConfig(t)
+
+	if len(cfg.Bindings) != 2 {
+		t.Logf("expected 2 bindings, got %d", len(cfg.Bindings))
+		t.FailNow()
+	}
+
+	if len(cfg.SNAT) != 1 || cfg.SNAT[0].SourceAddress != "192.168.10.0/24" || cfg.SNAT[0].Interface != "eth1" {
+		t.Logf("unexpected SNAT config %+v", cfg.SNAT)
+		t.FailNow()
+	}
+
+	network := testNetwork()
+	network.InterfacesF[0].RulesetInF = "missing"
+
+	if _, err := NewConfig(network); err == nil {
+		t.Log("expected error for missing ruleset")
+		t.FailNow()
+	}
+}
+
+func TestRenderBackends(t *testing.T) {
+	// The same rules rendered for each backend, in the order they should appear
+	// in the rendered output.
+	expected := map[Backend][]string{
+		NFTables: {
+			`chain inbound {`,
+			`ct state established,related counter return comment "inbound rule 10"`,
+			`ip saddr != 10.0.0.0/8 ip daddr 192.168.10.5 tcp dport 443 counter return comment "inbound rule 20: allow web"`,
+			`counter drop comment "inbound default action"`,
+			`chain outbound {`,
+			`meta l4proto icmp counter reject comment "outbound rule 10"`,
+			`counter return comment "outbound default action"`,
+			`type filter hook input priority 0; policy accept;`,
+			`iifname "eth0" jump inbound`,
+			`type filter hook forward priority 0; policy accept;`,
+			`iifname "eth0" jump inbound`,
+			`oifname "eth1" jump outbound`,
+			`type filter hook output priority 0; policy accept;`,
+			`oifname "eth1" jump outbound`,
+			`table ip nat {`,
+			`iifname "eth1" tcp dport { 8080, 8443 } dnat to 192.168.10.5:443`,
+			`oifname "eth1" ip saddr 192.168.10.0/24 masquerade`,
+		},
+		IPTables: {
+			`*filter`,
+			`:inbound - [0:0]`,
+			`:outbound - [0:0]`,
+			`-A inbound -m conntrack --ctstate ESTABLISHED,RELATED -m comment --comment "inbound rule 10" -j RETURN`,
+			`-A inbound -p tcp ! -s 10.0.0.0/8 -d 192.168.10.5 --dport 443 -m comment --comment "inbound rule 20: allow web" -j RETURN`,
+			`-A inbound -m comment --comment "inbound default action" -j DROP`,
+			`-A outbound -p icmp -m comment --comment "outbound rule 10" -j REJECT`,
+			`-A outbound -m comment --comment "outbound default action" -j RETURN`,
+			`-A INPUT -i eth0 -j inbound`,
+			`-A FORWARD -i eth0 -j inbound`,
+			`-A FORWARD -o eth1 -j outbound`,
+			`-A OUTPUT -o eth1 -j outbound`,
+			`COMMIT`,
+			`*nat`,
+			`-A PREROUTING -i eth1 -p tcp -m multiport --dports 8080,8443 -j DNAT --to-destination 192.168.10.5:443`,
+			`-A POSTROUTING -o eth1 -s 192.168.10.0/24 -j MASQUERADE`,
+			`COMMIT`,
+		},
+	}
+
+	cfg := testConfig(t)
+
+	for backend, lines := range expected {
+		var (
+			output = render(t, backend, cfg)
+			idx    int
+		)
+
+		for _, line := range strings.Split(output, "\n") {
+			if idx < len(lines) && strings.TrimSpace(line) == lines[idx] {
+				idx++
+			}
+		}
+
+		if idx != len(lines) {
+			t.Logf("%s output missing (or out of order) line %q\n%s", backend, lines[idx], output)
+			t.FailNow()
+		}
+
+		if strings.Contains(output, "unused") {
+			t.Logf("%s output includes unbound ruleset\n%s", backend, output)
+			t.FailNow()
+		}
+	}
+}
+
+func TestRenderNoNAT(t *testing.T) {
+	network := testNetwork()
+	network.NATF = nil
+
+	cfg, err := NewConfig(network)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if output := render(t, NFTables, cfg); strings.Contains(output, "table ip nat") {
+		t.Logf("nftables output includes NAT table\n%s", output)
+		t.FailNow()
+	}
+
+	if output := render(t, IPTables, cfg); strings.Contains(output, "*nat") {
+		t.Logf("iptables output includes NAT table\n%s", output)
+		t.FailNow()
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	network := testNetwork()
+	network.RulesetsF[0].RulesF[0].ProtocolF = "icmp"
+
+	cfg, err := NewConfig(network)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, backend := range []Backend{NFTables, IPTables} {
+		if err := Render(backend, cfg, new(bytes.Buffer)); err == nil {
+			t.Logf("expected %s error for ports without tcp or udp protocol", backend)
+			t.FailNow()
+		}
+	}
+
+	cfg = testConfig(t)
+	cfg.DNAT[0].TranslationAddress = ""
+
+	if err := Render(NFTables, cfg, new(bytes.Buffer)); err == nil {
+		t.Log("expected error for DNAT rule without translation address")
+		t.FailNow()
+	}
+
+	if err := Render("pf", testConfig(t), new(bytes.Buffer)); err == nil {
+		t.Log("expected error for unknown backend")
+		t.FailNow()
+	}
+
+	if _, err := ParseBackend("pf"); err == nil {
+		t.Log("expected error parsing unknown backend")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,159 @@
+package firewall
+
+import (
+	"fmt"
+	"strings"
+)
+
+type iptables struct{}
+
+func (iptables) match(m match) ([]string, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	var parts []string
+
+	if m.in != "" {
+		parts = append(parts, "-i "+m.in)
+	}
+
+	if m.out != "" {
+		parts = append(parts, "-o "+m.out)
+	}
+
+	proto := m.proto()
+
+	if proto != "" {
+		parts = append(parts, "-p "+proto)
+	}
+
+	if addr, neg := negated(m.srcAddr); addr != "" {
+		parts = append(parts, iptOp(neg)+"-s "+addr)
+	}
+
+	if addr, neg := negated(m.dstAddr); addr != "" {
+		parts = append(parts, iptOp(neg)+"-d "+addr)
+	}
+
+	if port, neg := negated(m.srcPort); port != "" {
+		parts = append(parts, iptPorts("sport", port, neg))
+	}
+
+	if port, neg := negated(m.dstPort); port != "" {
+		parts = append(parts, iptPorts("dport", port, neg))
+	}
+
+	if m.stateful {
+		parts = append(parts, "-m conntrack --ctstate ESTABLISHED,RELATED")
+	}
+
+	return parts, nil
+}
+
+func (this iptables) rule(chain string, m match, verdict, comment string) (string, error) {
+	parts, err := this.match(m)
+	if err != nil {
+		return "", err
+	}
+
+	switch strings.ToLower(verdict) {
+	case "accept":
+		// Return to the calling chain so other rulesets can still be evaluated.
+		verdict = "RETURN"
+	case "drop", "reject":
+		verdict = strings.ToUpper(verdict)
+	default:
+		return "", fmt.Errorf("unknown action %s", verdict)
+	}
+
+	parts = append([]string{"-A " + chain}, parts...)
+	parts = append(parts, "-m comment --comment "+quote(comment), "-j "+verdict)
+
+	return strings.Join(parts, " "), nil
+}
+
+func (this iptables) jump(chain string, m match, target string) string {
+	parts, _ := this.match(m)
+
+	parts = append([]string{"-A " + chain}, parts...)
+	parts = append(parts, "-j "+target)
+
+	return strings.Join(parts, " ")
+}
+
+func (this iptables) snat(m match, addr, port string) (string, error) {
+	parts, err := this.match(m)
+	if err != nil {
+		return "", err
+	}
+
+	parts = append([]string{"-A POSTROUTING"}, parts...)
+
+	if addr == "" {
+		parts = append(parts, "-j MASQUERADE")
+	} else {
+		to, err := iptTranslation(m, addr, port)
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, "-j SNAT --to-source "+to)
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+func (this iptables) dnat(m match, addr, port string) (string, error) {
+	parts, err := this.match(m)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := iptTranslation(m, addr, port)
+	if err != nil {
+		return "", err
+	}
+
+	parts = append([]string{"-A PREROUTING"}, parts...)
+	parts = append(parts, "-j DNAT --to-destination "+to)
+
+	return strings.Join(parts, " "), nil
+}
+
+func iptTranslation(m match, addr, port string) (string, error) {
+	if port == "" {
+		return addr, nil
+	}
+
+	if p := m.proto(); p != "tcp" && p != "udp" {
+		return "", fmt.Errorf("port translation requires tcp or udp protocol")
+	}
+
+	return addr + ":" + port, nil
+}
+
+func iptOp(neg bool) string {
+	if neg {
+		return "! "
+	}
+
+	return ""
+}
+
+// iptPorts returns the match for the given ports. Ranges (80-90) use the
+// iptables range syntax (80:90) and comma-separated lists use the multiport
+// extension.
+func iptPorts(dir, ports string, neg bool) string {
+	if strings.Contains(ports, ",") {
+		list := strings.Split(ports, ",")
+
+		for i, p := range list {
+			list[i] = strings.TrimSpace(p)
+		}
+
+		return fmt.Sprintf("-m multiport %s--%ss %s", iptOp(neg), dir, strings.Join(list, ","))
+	}
+
+	return fmt.Sprintf("%s--%s %s", iptOp(neg), dir, strings.ReplaceAll(ports, "-", ":"))
+}
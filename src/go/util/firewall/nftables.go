@@ -0,0 +1,152 @@
+package firewall
+
+import (
+	"fmt"
+	"strings"
+)
+
+type nftables struct{}
+
+func (nftables) match(m match) ([]string, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	var parts []string
+
+	if m.in != "" {
+		parts = append(parts, "iifname "+quote(m.in))
+	}
+
+	if m.out != "" {
+		parts = append(parts, "oifname "+quote(m.out))
+	}
+
+	if addr, neg := negated(m.srcAddr); addr != "" {
+		parts = append(parts, "ip saddr "+nftOp(neg)+addr)
+	}
+
+	if addr, neg := negated(m.dstAddr); addr != "" {
+		parts = append(parts, "ip daddr "+nftOp(neg)+addr)
+	}
+
+	if proto := m.proto(); proto != "" {
+		if m.srcPort == "" && m.dstPort == "" {
+			parts = append(parts, "meta l4proto "+proto)
+		}
+
+		if port, neg := negated(m.srcPort); port != "" {
+			parts = append(parts, proto+" sport "+nftOp(neg)+nftPorts(port))
+		}
+
+		if port, neg := negated(m.dstPort); port != "" {
+			parts = append(parts, proto+" dport "+nftOp(neg)+nftPorts(port))
+		}
+	}
+
+	if m.stateful {
+		parts = append(parts, "ct state established,related")
+	}
+
+	return parts, nil
+}
+
+func (this nftables) rule(_ string, m match, verdict, comment string) (string, error) {
+	parts, err := this.match(m)
+	if err != nil {
+		return "", err
+	}
+
+	switch strings.ToLower(verdict) {
+	case "accept":
+		// Return to the calling chain so other rulesets can still be evaluated.
+		verdict = "return"
+	case "drop", "reject":
+		verdict = strings.ToLower(verdict)
+	default:
+		return "", fmt.Errorf("unknown action %s", verdict)
+	}
+
+	parts = append(parts, "counter", verdict, "comment "+quote(comment))
+
+	return strings.Join(parts, " "), nil
+}
+
+func (this nftables) jump(_ string, m match, target string) string {
+	parts, _ := this.match(m)
+	parts = append(parts, "jump "+target)
+
+	return strings.Join(parts, " ")
+}
+
+func (this nftables) snat(m match, addr, port string) (string, error) {
+	parts, err := this.match(m)
+	if err != nil {
+		return "", err
+	}
+
+	if addr == "" {
+		parts = append(parts, "masquerade")
+	} else {
+		to, err := nftTranslation(m, addr, port)
+		if err != nil {
+			return "", err
+		}
+
+		parts = append(parts, "snat to "+to)
+	}
+
+	return strings.Join(parts, " "), nil
+}
+
+func (this nftables) dnat(m match, addr, port string) (string, error) {
+	parts, err := this.match(m)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := nftTranslation(m, addr, port)
+	if err != nil {
+		return "", err
+	}
+
+	parts = append(parts, "dnat to "+to)
+
+	return strings.Join(parts, " "), nil
+}
+
+func nftTranslation(m match, addr, port string) (string, error) {
+	if port == "" {
+		return addr, nil
+	}
+
+	if p := m.proto(); p != "tcp" && p != "udp" {
+		return "", fmt.Errorf("port translation requires tcp or udp protocol")
+	}
+
+	return addr + ":" + port, nil
+}
+
+func nftOp(neg bool) string {
+	if neg {
+		return "!= "
+	}
+
+	return ""
+}
+
+// nftPorts converts a comma-separated list of ports into an anonymous set.
+// Single ports and ranges (80-90) are already valid nftables syntax.
+func nftPorts(ports string) string {
+	if !strings.Contains(ports, ",") {
+		return ports
+	}
+
+	list := strings.Split(ports, ",")
+
+	for i, p := range list {
+		list[i] = strings.TrimSpace(p)
+	}
+
+	return "{ " + strings.Join(list, ", ") + " }"
+}
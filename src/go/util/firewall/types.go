@@ -0,0 +1,167 @@
+package firewall
+
+import (
+	"fmt"
+	"strings"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util"
+
+	"inet.af/netaddr"
+)
+
+type Backend string
+
+const (
+	NFTables Backend = "nftables"
+	IPTables Backend = "iptables"
+)
+
+// ParseBackend returns the firewall backend with the given name, defaulting to
+// nftables if no name is given.
+func ParseBackend(name string) (Backend, error) {
+	switch strings.ToLower(name) {
+	case "", "true", string(NFTables):
+		return NFTables, nil
+	case string(IPTables):
+		return IPTables, nil
+	default:
+		return "", fmt.Errorf("unknown firewall backend %s", name)
+	}
+}
+
+// Path returns the path in a Linux node's image the rendered ruleset file for
+// the backend should be injected to so it's loaded at boot (by the nftables or
+// netfilter-persistent services, respectively).
+func (this Backend) Path() string {
+	if this == IPTables {
+		return "/etc/iptables/rules.v4"
+	}
+
+	return "/etc/nftables.conf"
+}
+
+func (this Backend) template() string {
+	if this == IPTables {
+		return "iptables.tmpl"
+	}
+
+	return "nftables.tmpl"
+}
+
+type Direction string
+
+const (
+	// In applies a ruleset to packets received on an interface, either destined
+	// for the node itself or being forwarded by it.
+	In Direction = "in"
+
+	// Out applies a ruleset to packets sent out an interface, either originating
+	// from the node itself or being forwarded by it.
+	Out Direction = "out"
+)
+
+// Binding applies a ruleset to an interface in the given direction.
+type Binding struct {
+	Interface string
+	Ruleset   string
+	Direction Direction
+}
+
+// NAT represents a source or destination NAT rule. Ports can be a single port,
+// a range (80-90), or a comma-separated list (80,443). A source NAT rule
+// without a translation address results in masquerading.
+type NAT struct {
+	Interface          string
+	SourceAddress      string
+	SourcePort         string
+	DestinationAddress string
+	DestinationPort    string
+	Protocol           string
+	TranslationAddress string
+	TranslationPort    string
+}
+
+// Config contains everything needed to render a firewall for a Linux node.
+type Config struct {
+	Rulesets []ifaces.NodeNetworkRuleset
+	Bindings []Binding
+	SNAT     []NAT
+	DNAT     []NAT
+}
+
+// NewConfig creates a firewall config from the rulesets applied to interfaces
+// and the NAT settings in the given topology network config.
+func NewConfig(network ifaces.NodeNetwork) (Config, error) {
+	var cfg Config
+
+	if network == nil {
+		return cfg, nil
+	}
+
+	rulesets := make(map[string]struct{})
+
+	for _, r := range network.Rulesets() {
+		rulesets[r.Name()] = struct{}{}
+	}
+
+	for _, iface := range network.Interfaces() {
+		bindings := []Binding{
+			{Interface: iface.Name(), Ruleset: iface.RulesetIn(), Direction: In},
+			{Interface: iface.Name(), Ruleset: iface.RulesetOut(), Direction: Out},
+		}
+
+		for _, b := range bindings {
+			if b.Ruleset == "" {
+				continue
+			}
+
+			if _, ok := rulesets[b.Ruleset]; !ok {
+				return cfg, fmt.Errorf("no ruleset named %s (for interface %s) found", b.Ruleset, iface.Name())
+			}
+
+			cfg.Bindings = append(cfg.Bindings, b)
+		}
+	}
+
+	cfg.Rulesets = network.Rulesets()
+
+	for _, n := range network.NAT() {
+		var found bool
+
+		for _, iface := range network.Interfaces() {
+			if iface.Name() == n.Out() {
+				found = true
+				continue
+			}
+
+			if iface.Type() != "ethernet" || iface.Proto() != "static" {
+				continue
+			}
+
+			if !util.StringSliceContains(n.In(), iface.Name()) {
+				continue
+			}
+
+			ip, err := netaddr.ParseIP(iface.Address())
+			if err != nil {
+				return cfg, fmt.Errorf("parsing address for NAT inbound interface %s: %w", iface.Name(), err)
+			}
+
+			prefix := netaddr.IPPrefixFrom(ip, uint8(iface.Mask()))
+
+			cfg.SNAT = append(cfg.SNAT, NAT{Interface: n.Out(), SourceAddress: prefix.Masked().String()})
+		}
+
+		if !found {
+			return cfg, fmt.Errorf("NAT outbound interface %s not found", n.Out())
+		}
+	}
+
+	return cfg, nil
+}
+
+// Empty returns true if the config doesn't result in any firewall or NAT rules.
+func (this Config) Empty() bool {
+	return len(this.Bindings) == 0 && len(this.SNAT) == 0 && len(this.DNAT) == 0
+}
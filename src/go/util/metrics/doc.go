@@ -0,0 +1,5 @@
+// Package metrics defines the Prometheus metrics instrumented throughout
+// phenix. They're registered with the default Prometheus registry and served
+// by the web server at /api/v1/metrics to users allowed to get the `metrics`
+// resource.
+package metrics
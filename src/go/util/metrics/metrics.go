@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace is the prefix of all phenix metric names.
+const Namespace = "phenix"
+
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of web API requests by route, method, and response status.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "status"},
+	)
+
+	BrokerClients = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "broker",
+			Name:      "clients",
+			Help:      "Number of WebSocket clients connected to the broker.",
+		},
+	)
+
+	AppStageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "app",
+			Name:      "stage_duration_seconds",
+			Help:      "Duration of app stages applied to experiments by app, stage, and status.",
+			Buckets:   []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800},
+		},
+		[]string{"app", "stage", "status"},
+	)
+
+	SoHChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "soh",
+			Name:      "checks_total",
+			Help:      "Number of state of health checks run by experiment, check, and result.",
+		},
+		[]string{"experiment", "check", "result"},
+	)
+
+	ScorchRunDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "scorch",
+			Name:      "run_duration_seconds",
+			Help:      "Duration of SCORCH runs by experiment and status.",
+			Buckets:   []float64{1, 10, 30, 60, 300, 600, 1800, 3600, 7200},
+		},
+		[]string{"experiment", "status"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		HTTPRequestDuration,
+		BrokerClients,
+		AppStageDuration,
+		SoHChecks,
+		ScorchRunDuration,
+	)
+}
+
+// Status returns the status label for an operation that returned the given
+// error.
+func Status(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
@@ -502,6 +502,148 @@ func (Minimega) DisconnectVMInterface(opts ...Option) error {
 	return nil
 }
 
+// ImpairVMInterface applies the given impairment to traffic sent out of the
+// given VM interface, replacing any impairment already applied to it. Delay,
+// loss, and rate are applied using the minimega qos API. Since the qos API
+// doesn't support jitter, corruption, or reordering, impairments including any
+// of them are applied as a single netem queueing discipline on the VM's tap
+// interface on the cluster host the VM is scheduled on.
+func (Minimega) ImpairVMInterface(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	delay, jitter, err := o.impairment.Validate()
+	if err != nil {
+		return fmt.Errorf("validating impairment for interface %d on VM %s: %w", o.impairIface, o.vm, err)
+	}
+
+	// Look up the VM once for both clearing and applying the impairment.
+	vm, ok := impairedVM(o, opts)
+	if ok {
+		opts = append(opts, ImpairVMInfo(vm))
+	}
+
+	if err := ClearVMInterfaceImpairment(opts...); err != nil {
+		return err
+	}
+
+	imp := o.impairment
+
+	if imp.Empty() {
+		return nil
+	}
+
+	if imp.NetemOnly() {
+		if !ok {
+			return fmt.Errorf("getting info for VM %s: %w", o.vm, ErrVMNotFound)
+		}
+
+		if o.impairIface < 0 || o.impairIface >= len(vm.Taps) {
+			return fmt.Errorf("interface %d does not exist on VM %s", o.impairIface, o.vm)
+		}
+
+		netem := []string{"tc qdisc replace dev", vm.Taps[o.impairIface], "root netem"}
+
+		if delay != 0 {
+			netem = append(netem, fmt.Sprintf("delay %dus", delay.Microseconds()))
+
+			if jitter != 0 {
+				netem = append(netem, fmt.Sprintf("%dus", jitter.Microseconds()))
+			}
+		}
+
+		if imp.Loss != 0 {
+			netem = append(netem, fmt.Sprintf("loss %g%%", imp.Loss))
+		}
+
+		if imp.Corruption != 0 {
+			netem = append(netem, fmt.Sprintf("corrupt %g%%", imp.Corruption))
+		}
+
+		if imp.Reordering != 0 {
+			netem = append(netem, fmt.Sprintf("reorder %g%%", imp.Reordering))
+		}
+
+		if imp.Rate != "" {
+			bw, unit := imp.rate()
+			netem = append(netem, fmt.Sprintf("rate %s%s", bw, unit))
+		}
+
+		if err := MeshShell(vm.Host, strings.Join(netem, " ")); err != nil {
+			return fmt.Errorf("applying netem impairment to interface %d on VM %s: %w", o.impairIface, o.vm, err)
+		}
+
+		return nil
+	}
+
+	var qos []string
+
+	if imp.Loss != 0 {
+		qos = append(qos, fmt.Sprintf("loss %g", imp.Loss))
+	}
+
+	if imp.Delay != "" {
+		qos = append(qos, fmt.Sprintf("delay %s", delay))
+	}
+
+	if imp.Rate != "" {
+		bw, unit := imp.rate()
+		qos = append(qos, fmt.Sprintf("rate %s %s", bw, unit))
+	}
+
+	for _, q := range qos {
+		cmd := mmcli.NewNamespacedCommand(o.ns)
+		cmd.Command = fmt.Sprintf("qos add %s %d %s", o.vm, o.impairIface, q)
+
+		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+			return fmt.Errorf("adding qos (%s) to interface %d on VM %s in namespace %s: %w", q, o.impairIface, o.vm, o.ns, err)
+		}
+	}
+
+	return nil
+}
+
+// ClearVMInterfaceImpairment removes any impairment applied to traffic sent out
+// of the given VM interface, either via the minimega qos API or netem.
+func (Minimega) ClearVMInterfaceImpairment(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf("qos clear %s %d", o.vm, o.impairIface)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("clearing qos for interface %d on VM %s in namespace %s: %w", o.impairIface, o.vm, o.ns, err)
+	}
+
+	// Impairments applied directly via netem aren't tracked by minimega, so also
+	// remove the root queueing discipline from the VM's tap. An error is expected
+	// here if no netem queueing discipline exists, so it's ignored.
+	if vm, ok := impairedVM(o, opts); ok {
+		if o.impairIface >= 0 && o.impairIface < len(vm.Taps) {
+			if err := MeshShell(vm.Host, "tc qdisc del dev "+vm.Taps[o.impairIface]+" root"); err != nil {
+				log.Debug("removing netem from interface %d on VM %s: %v", o.impairIface, o.vm, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// impairedVM returns the info for the VM being impaired, looking it up if it
+// wasn't provided via the ImpairVMInfo option.
+func impairedVM(o options, opts []Option) (VM, bool) {
+	if o.impairVM != nil {
+		return *o.impairVM, true
+	}
+
+	vms := GetVMInfo(opts...)
+
+	if len(vms) == 0 {
+		return VM{}, false
+	}
+
+	return vms[0], true
+}
+
 func (Minimega) StartVMCapture(opts ...Option) error {
 	o := NewOptions(opts...)
 
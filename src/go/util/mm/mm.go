@@ -21,6 +21,8 @@ type MM interface {
 
 	ConnectVMInterface(...Option) error
 	DisconnectVMInterface(...Option) error
+	ImpairVMInterface(...Option) error
+	ClearVMInterfaceImpairment(...Option) error
 
 	StartVMCapture(...Option) error
 	StopVMCapture(...Option) error
@@ -22,6 +22,10 @@ type options struct {
 	connectIface int
 	connectVLAN  string
 
+	impairIface int
+	impairment  Impairment
+	impairVM    *VM
+
 	captureIface int
 	captureFile  string
 
@@ -98,6 +102,26 @@ func DisonnectInterface(i int) Option {
 	}
 }
 
+func ImpairInterface(i int) Option {
+	return func(o *options) {
+		o.impairIface = i
+	}
+}
+
+func ImpairmentSettings(i Impairment) Option {
+	return func(o *options) {
+		o.impairment = i
+	}
+}
+
+// ImpairVMInfo sets the info (taps and cluster host) of the VM being impaired
+// so it doesn't have to be looked up again for each interface impaired.
+func ImpairVMInfo(v VM) Option {
+	return func(o *options) {
+		o.impairVM = &v
+	}
+}
+
 func CaptureInterface(i int) Option {
 	return func(o *options) {
 		o.captureIface = i
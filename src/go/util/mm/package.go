@@ -60,6 +60,14 @@ func DisconnectVMInterface(opts ...Option) error {
 	return DefaultMM.DisconnectVMInterface(opts...)
 }
 
+func ImpairVMInterface(opts ...Option) error {
+	return DefaultMM.ImpairVMInterface(opts...)
+}
+
+func ClearVMInterfaceImpairment(opts ...Option) error {
+	return DefaultMM.ClearVMInterfaceImpairment(opts...)
+}
+
 func StartVMCapture(opts ...Option) error {
 	return DefaultMM.StartVMCapture(opts...)
 }
@@ -158,6 +158,40 @@ func (node *ExpressionTree) Evaluate(vm *VM) bool {
 
 }
 
+// EvaluateFunc evaluates the expression tree using the given function to match
+// individual search terms, allowing the search syntax to be used for objects
+// other than VMs. Terms are passed to the function in lower case.
+func (node *ExpressionTree) EvaluateFunc(match func(term string) bool) bool {
+	if node == nil {
+		return false
+	}
+
+	if node.left == nil && node.right == nil {
+		return match(node.term)
+	}
+
+	rightSide := false
+	if node.right != nil {
+		rightSide = node.right.EvaluateFunc(match)
+	}
+
+	leftSide := false
+	if node.left != nil {
+		leftSide = node.left.EvaluateFunc(match)
+	}
+
+	switch node.term {
+	case "and":
+		return rightSide && leftSide
+	case "or":
+		return rightSide || leftSide
+	case "not":
+		return !rightSide
+	}
+
+	return false
+}
+
 // Shunting yard algorithm by Edsger Dijkstra
 // for putting search terms and operators into
 // postfix notation
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 )
 
 var ErrHostNotFound = errors.New("host not found")
@@ -237,6 +239,80 @@ type VM struct {
 	UUID string `json:"-"`
 }
 
+// Impairment represents the network conditions to emulate on traffic sent out
+// of a VM interface. Loss, Corruption, and Reordering are percentages, Delay
+// and Jitter are Go duration strings, and Rate is a bandwidth with a kbit,
+// mbit, or gbit unit (e.g. 10mbit).
+type Impairment struct {
+	Delay      string  `json:"delay,omitempty"`
+	Jitter     string  `json:"jitter,omitempty"`
+	Loss       float64 `json:"loss,omitempty"`
+	Corruption float64 `json:"corruption,omitempty"`
+	Reordering float64 `json:"reordering,omitempty"`
+	Rate       string  `json:"rate,omitempty"`
+}
+
+// Empty returns true if no impairment settings are specified.
+func (this Impairment) Empty() bool {
+	return this == Impairment{}
+}
+
+// NetemOnly returns true if the impairment includes settings not supported by
+// the minimega qos API (jitter, corruption, and reordering), which must instead
+// be applied directly using a netem queueing discipline.
+func (this Impairment) NetemOnly() bool {
+	return this.Jitter != "" || this.Corruption != 0 || this.Reordering != 0
+}
+
+var rateRe = regexp.MustCompile(`^(\d+)\s*(kbit|mbit|gbit)$`)
+
+// Validate checks the impairment settings for correctness, returning the
+// parsed delay and jitter durations.
+func (this Impairment) Validate() (time.Duration, time.Duration, error) {
+	var (
+		delay, jitter time.Duration
+		err           error
+	)
+
+	if this.Delay != "" {
+		if delay, err = time.ParseDuration(this.Delay); err != nil {
+			return 0, 0, fmt.Errorf("invalid delay %s: %w", this.Delay, err)
+		}
+	}
+
+	if this.Jitter != "" {
+		if jitter, err = time.ParseDuration(this.Jitter); err != nil {
+			return 0, 0, fmt.Errorf("invalid jitter %s: %w", this.Jitter, err)
+		}
+	}
+
+	if (jitter != 0 || this.Reordering != 0) && delay == 0 {
+		return 0, 0, fmt.Errorf("jitter and reordering require a delay")
+	}
+
+	for name, pct := range map[string]float64{"loss": this.Loss, "corruption": this.Corruption, "reordering": this.Reordering} {
+		if pct < 0 || pct > 100 {
+			return 0, 0, fmt.Errorf("invalid %s percentage %v", name, pct)
+		}
+	}
+
+	if this.Rate != "" && !rateRe.MatchString(strings.ToLower(this.Rate)) {
+		return 0, 0, fmt.Errorf("invalid rate %s (must be a number followed by kbit, mbit, or gbit)", this.Rate)
+	}
+
+	return delay, jitter, nil
+}
+
+// rate returns the bandwidth and unit of the impairment rate.
+func (this Impairment) rate() (string, string) {
+	match := rateRe.FindStringSubmatch(strings.ToLower(this.Rate))
+	if match == nil {
+		return "", ""
+	}
+
+	return match[1], match[2]
+}
+
 type Captures struct {
 	Captures []Capture `json:"captures"`
 }
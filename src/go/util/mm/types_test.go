@@ -0,0 +1,46 @@
+package mm
+
+import "testing"
+
+func TestImpairmentValidate(t *testing.T) {
+	valid := []Impairment{
+		{Delay: "100ms", Jitter: "10ms", Reordering: 25},
+		{Loss: 1.5, Rate: "10mbit"},
+		{Corruption: 0.1, Rate: "512 KBit"},
+	}
+
+	for _, imp := range valid {
+		if _, _, err := imp.Validate(); err != nil {
+			t.Logf("expected %+v to be valid: %v", imp, err)
+			t.FailNow()
+		}
+	}
+
+	invalid := []Impairment{
+		{Delay: "100"},
+		{Jitter: "10ms"},
+		{Reordering: 25},
+		{Loss: 101},
+		{Corruption: -1},
+		{Rate: "10mbps"},
+	}
+
+	for _, imp := range invalid {
+		if _, _, err := imp.Validate(); err == nil {
+			t.Logf("expected %+v to be invalid", imp)
+			t.FailNow()
+		}
+	}
+}
+
+func TestImpairmentNetemOnly(t *testing.T) {
+	if (Impairment{Delay: "100ms", Loss: 1, Rate: "1gbit"}).NetemOnly() {
+		t.Log("expected delay, loss, and rate to be supported by qos")
+		t.FailNow()
+	}
+
+	if !(Impairment{Delay: "100ms", Jitter: "10ms"}).NetemOnly() {
+		t.Log("expected jitter to require netem")
+		t.FailNow()
+	}
+}
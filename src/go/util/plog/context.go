@@ -0,0 +1,114 @@
+package plog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+)
+
+// Keys of fields commonly added to log records.
+const (
+	ExperimentKey = "experiment"
+	AppKey        = "app"
+	StageKey      = "stage"
+	VMKey         = "vm"
+	RequestIDKey  = "request_id"
+)
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of the given context with the given key/value
+// pairs added to the fields included in records logged with it.
+func WithFields(ctx context.Context, kv ...interface{}) context.Context {
+	fields := Fields(ctx)
+
+	for k, v := range pairs(kv) {
+		fields[k] = v
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Fields returns a copy of the fields carried by the given context.
+func Fields(ctx context.Context) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	if ctx == nil {
+		return fields
+	}
+
+	if existing, ok := ctx.Value(fieldsKey{}).(map[string]interface{}); ok {
+		for k, v := range existing {
+			fields[k] = v
+		}
+	}
+
+	return fields
+}
+
+// Detach returns a new background context carrying the fields of the given
+// context, but not its deadline or cancelation. It's useful for work (e.g.
+// starting an experiment) that shouldn't be canceled when the request that
+// started it completes, but should still be correlated with it.
+func Detach(ctx context.Context) context.Context {
+	return context.WithValue(context.Background(), fieldsKey{}, Fields(ctx))
+}
+
+func WithExperiment(ctx context.Context, name string) context.Context {
+	return WithFields(ctx, ExperimentKey, name)
+}
+
+func WithApp(ctx context.Context, name string) context.Context {
+	return WithFields(ctx, AppKey, name)
+}
+
+func WithStage(ctx context.Context, stage string) context.Context {
+	return WithFields(ctx, StageKey, stage)
+}
+
+func WithVM(ctx context.Context, name string) context.Context {
+	return WithFields(ctx, VMKey, name)
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, RequestIDKey, id)
+}
+
+// RequestID returns the request ID carried by the given context, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := Fields(ctx)[RequestIDKey].(string)
+	return id
+}
+
+// NewRequestID returns a new, random request ID.
+func NewRequestID() string {
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+// pairs converts the given list of alternating keys and values to a map. Keys
+// that aren't strings are formatted as strings, errors are converted to their
+// messages, and a key without a value is given an empty value.
+func pairs(kv []interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+
+		if i+1 < len(kv) {
+			// Errors don't encode to JSON in a useful way.
+			if err, ok := kv[i+1].(error); ok {
+				m[key] = err.Error()
+			} else {
+				m[key] = kv[i+1]
+			}
+		} else {
+			m[key] = ""
+		}
+	}
+
+	return m
+}
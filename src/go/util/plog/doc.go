@@ -0,0 +1,7 @@
+// Package plog provides structured logging for phenix. Log records are written
+// as JSON (or human readable text) to each registered handler, and include any
+// fields (e.g. experiment, app, stage, VM, and request ID) carried by the
+// context they're logged with. Existing minilog output can be routed through
+// plog using MinilogWriter so all phenix logs are written as structured
+// records.
+package plog
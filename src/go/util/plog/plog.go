@@ -0,0 +1,173 @@
+package plog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/activeshadow/libminimega/minilog"
+)
+
+type handler struct {
+	w     io.Writer
+	level log.Level
+	json  bool
+}
+
+var (
+	handlers   = make(map[string]handler)
+	handlersMu sync.Mutex
+)
+
+// AddJSONHandler writes records at the given level or higher to the given
+// writer as JSON, one record per line. Any existing handler with the same name
+// is replaced.
+func AddJSONHandler(name string, w io.Writer, level log.Level) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	handlers[name] = handler{w: w, level: level, json: true}
+}
+
+// AddTextHandler writes records at the given level or higher to the given
+// writer as human readable text, one record per line. Any existing handler
+// with the same name is replaced.
+func AddTextHandler(name string, w io.Writer, level log.Level) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	handlers[name] = handler{w: w, level: level}
+}
+
+// DelHandler removes the handler with the given name.
+func DelHandler(name string) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	delete(handlers, name)
+}
+
+func Debug(ctx context.Context, msg string, kv ...interface{}) {
+	logRecord(ctx, log.DEBUG, caller(), msg, kv...)
+}
+
+func Info(ctx context.Context, msg string, kv ...interface{}) {
+	logRecord(ctx, log.INFO, caller(), msg, kv...)
+}
+
+func Warn(ctx context.Context, msg string, kv ...interface{}) {
+	logRecord(ctx, log.WARN, caller(), msg, kv...)
+}
+
+func Error(ctx context.Context, msg string, kv ...interface{}) {
+	logRecord(ctx, log.ERROR, caller(), msg, kv...)
+}
+
+func logRecord(ctx context.Context, level log.Level, source, msg string, kv ...interface{}) {
+	fields := Fields(ctx)
+
+	for k, v := range pairs(kv) {
+		fields[k] = v
+	}
+
+	write(level, Record{
+		Time:    time.Now(),
+		Level:   strings.ToUpper(level.String()),
+		Source:  source,
+		Message: msg,
+		Fields:  fields,
+	})
+}
+
+func write(level log.Level, rec Record) {
+	// Hold the write lock so records written to the same writer by different
+	// goroutines aren't interleaved.
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+
+	for _, h := range handlers {
+		if level < h.level {
+			continue
+		}
+
+		var line []byte
+
+		if h.json {
+			var err error
+
+			if line, err = json.Marshal(rec); err != nil {
+				// Most likely a field value that can't be encoded, so fall back to
+				// encoding all the field values as strings.
+				for k, v := range rec.Fields {
+					rec.Fields[k] = fmt.Sprint(v)
+				}
+
+				line, _ = json.Marshal(rec)
+			}
+		} else {
+			line = []byte(rec.Text())
+		}
+
+		h.w.Write(append(line, '\n'))
+	}
+}
+
+// caller returns the file name and line number of the function that called
+// the logging function.
+func caller() string {
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+}
+
+// MinilogWriter returns a writer to be added as a minilog logger (without
+// color) that converts minilog output to records and writes them to the
+// registered handlers.
+func MinilogWriter() io.Writer {
+	return minilogWriter{}
+}
+
+type minilogWriter struct{}
+
+// Write handles a single line of minilog output, which is in the form
+// `2006/01/02 15:04:05 LEVEL source: message`.
+func (minilogWriter) Write(p []byte) (int, error) {
+	line := strings.TrimSuffix(string(p), "\n")
+
+	// Skip the timestamp added by minilog.
+	if len(line) > 20 {
+		line = line[20:]
+	}
+
+	rec := Record{Time: time.Now(), Level: "INFO"}
+
+	if tokens := strings.SplitN(line, " ", 2); len(tokens) == 2 {
+		if level, err := log.ParseLevel(strings.ToLower(tokens[0])); err == nil {
+			rec.Level = tokens[0]
+
+			if tokens = strings.SplitN(tokens[1], ": ", 2); len(tokens) == 2 {
+				rec.Source = tokens[0]
+				rec.Message = tokens[1]
+			} else {
+				rec.Message = tokens[0]
+			}
+
+			write(level, rec)
+			return len(p), nil
+		}
+	}
+
+	rec.Message = line
+	write(log.INFO, rec)
+
+	return len(p), nil
+}
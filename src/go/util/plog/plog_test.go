@@ -0,0 +1,90 @@
+package plog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	log "github.com/activeshadow/libminimega/minilog"
+)
+
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	AddJSONHandler("test", &buf, log.INFO)
+	defer DelHandler("test")
+
+	ctx := WithRequestID(context.Background(), "1234")
+	ctx = WithExperiment(ctx, "foo")
+
+	Debug(ctx, "not logged")
+	Info(WithApp(ctx, "vrouter"), "configuring app", "error", errors.New("oops"))
+
+	// Detached contexts keep their fields.
+	Warn(Detach(ctx), "detached")
+
+	var records []Record
+
+	scanner := bufio.NewScanner(&buf)
+
+	for scanner.Scan() {
+		rec, err := ParseRecord(scanner.Bytes())
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		records = append(records, rec)
+	}
+
+	if len(records) != 2 {
+		t.Logf("expected 2 records, got %d", len(records))
+		t.FailNow()
+	}
+
+	rec := records[0]
+
+	if rec.Level != "INFO" || rec.Message != "configuring app" {
+		t.Logf("unexpected record %+v", rec)
+		t.FailNow()
+	}
+
+	expected := map[string]interface{}{"request_id": "1234", "experiment": "foo", "app": "vrouter", "error": "oops"}
+
+	for k, v := range expected {
+		if rec.Fields[k] != v {
+			t.Logf("expected field %s to be %v, got %v", k, v, rec.Fields[k])
+			t.FailNow()
+		}
+	}
+
+	if rec := records[1]; rec.Level != "WARN" || rec.Fields["request_id"] != "1234" || rec.Fields["app"] != nil {
+		t.Logf("unexpected detached record %+v", rec)
+		t.FailNow()
+	}
+}
+
+func TestMinilogWriter(t *testing.T) {
+	var buf bytes.Buffer
+
+	AddJSONHandler("test", &buf, log.INFO)
+	defer DelHandler("test")
+
+	w := MinilogWriter()
+
+	w.Write([]byte("2021/01/02 15:04:05 DEBUG server.go:10: not logged\n"))
+	w.Write([]byte("2021/01/02 15:04:05 ERROR server.go:87: unable to start: bad things\n"))
+
+	rec, err := ParseRecord(bytes.TrimSpace(buf.Bytes()))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if rec.Level != "ERROR" || rec.Source != "server.go:87" || rec.Message != "unable to start: bad things" {
+		t.Logf("unexpected record %+v", rec)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,121 @@
+package plog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Record is a single structured log record. When encoded as JSON, fields are
+// included alongside the time, level, source, and message.
+type Record struct {
+	Time    time.Time
+	Level   string
+	Source  string
+	Message string
+	Fields  map[string]interface{}
+}
+
+// reserved are the keys used for a record's top-level values when encoded as
+// JSON, which fields can't override.
+var reserved = map[string]bool{"time": true, "level": true, "source": true, "msg": true}
+
+func (this Record) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(this.Fields)+4)
+
+	for k, v := range this.Fields {
+		if !reserved[k] {
+			m[k] = v
+		}
+	}
+
+	m["time"] = this.Time.Format(time.RFC3339Nano)
+	m["level"] = this.Level
+	m["msg"] = this.Message
+
+	if this.Source != "" {
+		m["source"] = this.Source
+	}
+
+	return json.Marshal(m)
+}
+
+func (this *Record) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	ts, ok := m["time"].(string)
+	if !ok {
+		return fmt.Errorf("missing time")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return fmt.Errorf("parsing time: %w", err)
+	}
+
+	*this = Record{Time: t}
+
+	this.Level, _ = m["level"].(string)
+	this.Source, _ = m["source"].(string)
+	this.Message, _ = m["msg"].(string)
+
+	for k, v := range m {
+		if reserved[k] {
+			continue
+		}
+
+		if this.Fields == nil {
+			this.Fields = make(map[string]interface{})
+		}
+
+		this.Fields[k] = v
+	}
+
+	return nil
+}
+
+// ParseRecord parses a JSON encoded record from a single line of a log file.
+func ParseRecord(line []byte) (Record, error) {
+	var rec Record
+
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return rec, fmt.Errorf("parsing log record: %w", err)
+	}
+
+	return rec, nil
+}
+
+// Text formats the record as a single human readable line, with fields
+// appended in key order as key=value pairs.
+func (this Record) Text() string {
+	var sb strings.Builder
+
+	sb.WriteString(this.Time.Format("2006/01/02 15:04:05"))
+	sb.WriteString(" " + this.Level + " ")
+
+	if this.Source != "" {
+		sb.WriteString(this.Source + ": ")
+	}
+
+	sb.WriteString(this.Message)
+
+	keys := make([]string, 0, len(this.Fields))
+
+	for k := range this.Fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %s=%v", k, this.Fields[k])
+	}
+
+	return sb.String()
+}
@@ -9,8 +9,10 @@ import (
 	"strings"
 	"time"
 
+	"phenix/api/scorch/scorchmd"
 	"phenix/store"
 	"phenix/types"
+	v1 "phenix/types/version/v1"
 	"phenix/util/mm"
 
 	"github.com/olekukonko/tablewriter"
@@ -182,6 +184,54 @@ func PrintTableOfImageConfigs(writer io.Writer, optional []string, imgs ...types
 	table.Render()
 }
 
+// PrintImageManifest writes the given image manifest to the given writer as an
+// ASCII table.
+func PrintImageManifest(writer io.Writer, m *v1.ImageManifest) {
+	table := tablewriter.NewWriter(writer)
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"Key", "Value"})
+
+	table.Append([]string{"Digest", m.Digest})
+	table.Append([]string{"Config Hash", m.ConfigHash})
+	table.Append([]string{"Template Hash", m.TemplateHash})
+	table.Append([]string{"Image Hash", m.ImageHash})
+	table.Append([]string{"Phenix Version", m.Version})
+	table.Append([]string{"Build Time", m.BuildTime})
+	table.Append([]string{"Cached", strconv.FormatBool(m.Cached)})
+	table.Append([]string{"Variant", m.Variant})
+	table.Append([]string{"Release", m.Release})
+	table.Append([]string{"Mirror", m.Mirror})
+	table.Append([]string{"Format", string(m.Format)})
+
+	var files []string
+
+	for _, o := range m.Overlays {
+		files = append(files, o.Name+" "+o.Hash)
+	}
+
+	table.Append([]string{"Overlays", strings.Join(files, "\n")})
+
+	files = nil
+
+	for _, s := range m.Scripts {
+		files = append(files, s.Name+" "+s.Hash)
+	}
+
+	table.Append([]string{"Scripts", strings.Join(files, "\n")})
+
+	var pkgs []string
+
+	for _, p := range m.Packages {
+		pkgs = append(pkgs, strings.TrimSpace(p.Name+" "+p.Version))
+	}
+
+	table.Append([]string{"Packages", strings.Join(pkgs, "\n")})
+
+	table.Render()
+}
+
 func PrintTableOfVLANAliases(writer io.Writer, info map[string]map[string]int) {
 	table := tablewriter.NewWriter(writer)
 	table.SetHeader([]string{"Experiment", "VLAN Alias", "VLAN ID"})
@@ -242,3 +292,33 @@ func PrintTableOfSubnetCaptures(writer io.Writer, captures []mm.Capture) {
 
 	table.Render()
 }
+
+// PrintTableOfScorchRuns writes the runs configured in the given SCORCH
+// metadata to the given writer as an ASCII table. The given status should be
+// nil if no SCORCH runs are currently executing.
+func PrintTableOfScorchRuns(writer io.Writer, md scorchmd.ScorchMetadata, status *scorchmd.ScorchStatus) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Run ID", "Name", "Loops", "Iterations", "Status"})
+
+	for id, run := range md.Runs {
+		loops := 0
+
+		for loop := run.Loop; loop != nil; loop = loop.Loop {
+			loops++
+		}
+
+		state := "idle"
+
+		if status != nil && status.RunID == id {
+			if status.PID == 0 {
+				state = "running (UI)"
+			} else {
+				state = fmt.Sprintf("running (CLI, PID %d)", status.PID)
+			}
+		}
+
+		table.Append([]string{strconv.Itoa(id), run.Name, strconv.Itoa(loops), strconv.Itoa(run.Iterations()), state})
+	}
+
+	table.Render()
+}
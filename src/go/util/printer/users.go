@@ -0,0 +1,116 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	v1 "phenix/types/version/v1"
+	"phenix/util"
+	"phenix/web/rbac"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// PrintTableOfUsers writes the given users to the given writer as an ASCII
+// table.
+func PrintTableOfUsers(writer io.Writer, users ...*rbac.User) {
+	table := tablewriter.NewWriter(writer)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Username", "First Name", "Last Name", "Role", "Resource Names", "API Tokens"})
+
+	for _, u := range users {
+		var names []string
+
+		if role, err := u.Role(); err == nil {
+			for _, p := range role.Spec.Policies {
+				for _, n := range p.ResourceNames {
+					if !util.StringSliceContains(names, n) && n != u.Username() {
+						names = append(names, n)
+					}
+				}
+			}
+		}
+
+		table.Append([]string{u.Username(), u.FirstName(), u.LastName(), u.RoleName(), strings.Join(names, "\n"), fmt.Sprint(len(u.APITokens()))})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfRoles writes the given roles to the given writer as an ASCII
+// table.
+func PrintTableOfRoles(writer io.Writer, roles ...*rbac.Role) {
+	table := tablewriter.NewWriter(writer)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Role Name", "Policies"})
+
+	for _, r := range roles {
+		table.Append([]string{r.ConfigName(), r.Spec.Name, fmt.Sprint(len(r.Spec.Policies))})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfPolicies writes the given policies to the given writer as an
+// ASCII table.
+func PrintTableOfPolicies(writer io.Writer, policies []*v1.PolicySpec) {
+	table := tablewriter.NewWriter(writer)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Resources", "Verbs", "Resource Names", "Selectors"})
+
+	for _, p := range policies {
+		var selectors []string
+
+		for k, v := range p.LabelSelector {
+			selectors = append(selectors, fmt.Sprintf("label %s=%s", k, v))
+		}
+
+		for k, v := range p.AnnotationSelector {
+			selectors = append(selectors, fmt.Sprintf("annotation %s=%s", k, v))
+		}
+
+		sort.Strings(selectors)
+
+		table.Append([]string{
+			strings.Join(p.Resources, "\n"),
+			strings.Join(p.Verbs, ","),
+			strings.Join(p.ResourceNames, "\n"),
+			strings.Join(selectors, "\n"),
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfAPITokens writes the given API tokens to the given writer as an
+// ASCII table.
+func PrintTableOfAPITokens(writer io.Writer, tokens []*v1.APITokenSpec) {
+	table := tablewriter.NewWriter(writer)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"ID", "Description", "Created", "Expires", "Last Used", "Last Used From", "Scope"})
+
+	for _, t := range tokens {
+		scope := "full"
+
+		if len(t.Policies) > 0 {
+			var policies []string
+
+			for _, p := range t.Policies {
+				policies = append(policies, fmt.Sprintf("%s %s %s", strings.Join(p.Verbs, ","), strings.Join(p.Resources, ","), strings.Join(p.ResourceNames, ",")))
+			}
+
+			scope = strings.Join(policies, "\n")
+		}
+
+		lastUsed := t.LastUsed
+		if lastUsed == "" {
+			lastUsed = "never"
+		}
+
+		table.Append([]string{t.ID, t.Description, t.Created, t.Expires, lastUsed, t.LastUsedFrom, scope})
+	}
+
+	table.Render()
+}
@@ -0,0 +1,187 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/gofrs/uuid"
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+const (
+	// etcdPrefix is prepended to the keys publications are written to.
+	etcdPrefix = "phenix-pubsub/"
+
+	// etcdTTL is how long, in seconds, publications are kept in Etcd. They only
+	// need to exist long enough for watchers to be notified of them.
+	etcdTTL = 10
+
+	// etcdQueueSize is the number of publications that can be waiting to be
+	// written to Etcd before new ones are dropped.
+	etcdQueueSize = 1024
+)
+
+type etcdEnvelope struct {
+	Origin string          `json:"origin"`
+	Topic  string          `json:"topic"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// EtcdBus shares publications with other phenix instances by writing them to
+// short-lived Etcd keys that each instance watches.
+type EtcdBus struct {
+	cli *clientv3.Client
+
+	// id identifies this instance so it can ignore its own publications, which
+	// have already been delivered locally.
+	id string
+
+	// queue holds publications waiting to be written to Etcd, so publishers
+	// (like the web broker) aren't held up by Etcd round trips.
+	queue chan etcdEnvelope
+
+	// lease is the lease publications are currently written with, and renew is
+	// when a new one should be granted. They're only used by the goroutine
+	// writing publications.
+	lease clientv3.LeaseID
+	renew time.Time
+}
+
+func NewEtcdBus(cli *clientv3.Client) *EtcdBus {
+	bus := &EtcdBus{
+		cli:   cli,
+		id:    uuid.Must(uuid.NewV4()).String(),
+		queue: make(chan etcdEnvelope, etcdQueueSize),
+	}
+
+	go bus.write()
+
+	return bus
+}
+
+// Publish queues the given publication to be written to Etcd in the
+// background. It only returns an error if the queue is full.
+func (this *EtcdBus) Publish(topic string, data []byte) error {
+	select {
+	case this.queue <- etcdEnvelope{Origin: this.id, Topic: topic, Data: data}:
+		return nil
+	default:
+		return fmt.Errorf("Etcd publication queue is full")
+	}
+}
+
+func (this *EtcdBus) write() {
+	for env := range this.queue {
+		if err := this.put(env); err != nil {
+			log.Error("sharing %s publication: %v", env.Topic, err)
+		}
+	}
+}
+
+func (this *EtcdBus) put(env etcdEnvelope) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	body, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("encoding publication: %w", err)
+	}
+
+	lease, err := this.currentLease(ctx)
+	if err != nil {
+		return fmt.Errorf("granting Etcd lease for publication: %w", err)
+	}
+
+	key := etcdPrefix + env.Topic + "/" + uuid.Must(uuid.NewV4()).String()
+
+	if _, err := this.cli.Put(ctx, key, string(body), clientv3.WithLease(lease)); err != nil {
+		// The lease may have expired early (e.g. Etcd was restarted), so grant a
+		// new one for the next publication.
+		this.lease = 0
+
+		return fmt.Errorf("writing publication to Etcd: %w", err)
+	}
+
+	return nil
+}
+
+// currentLease returns the lease to write publications with. Publications
+// share a lease until it's halfway to expiring, so each one still lives for
+// at least half of etcdTTL without a lease being granted per publication.
+func (this *EtcdBus) currentLease(ctx context.Context) (clientv3.LeaseID, error) {
+	if this.lease != 0 && time.Now().Before(this.renew) {
+		return this.lease, nil
+	}
+
+	lease, err := this.cli.Grant(ctx, etcdTTL)
+	if err != nil {
+		return 0, err
+	}
+
+	this.lease = lease.ID
+	this.renew = time.Now().Add(etcdTTL * time.Second / 2)
+
+	return this.lease, nil
+}
+
+func (this *EtcdBus) Receive(handler func(string, []byte)) {
+	go func() {
+		// rev is the revision to resume watching from when the watch has to be
+		// re-established, so no publications are missed in between.
+		var rev int64
+
+		for {
+			// Requiring a leader closes the watch if the Etcd member loses quorum,
+			// rather than silently waiting on a partitioned member.
+			ctx := clientv3.WithRequireLeader(context.Background())
+
+			opts := []clientv3.OpOption{clientv3.WithPrefix()}
+
+			if rev > 0 {
+				opts = append(opts, clientv3.WithRev(rev))
+			}
+
+			for resp := range this.cli.Watch(ctx, etcdPrefix, opts...) {
+				if err := resp.Err(); err != nil {
+					log.Error("watching Etcd for publications: %v", err)
+
+					// Publications older than the compacted revision are gone, so
+					// resume from the oldest one still available.
+					if resp.CompactRevision > rev {
+						rev = resp.CompactRevision
+					}
+
+					continue
+				}
+
+				rev = resp.Header.Revision + 1
+
+				for _, event := range resp.Events {
+					if event.Type != clientv3.EventTypePut {
+						continue
+					}
+
+					var env etcdEnvelope
+
+					if err := json.Unmarshal(event.Kv.Value, &env); err != nil {
+						log.Error("decoding publication from Etcd: %v", err)
+						continue
+					}
+
+					if env.Origin == this.id {
+						continue
+					}
+
+					handler(env.Topic, env.Data)
+				}
+			}
+
+			log.Warn("Etcd publication watch closed -- re-establishing it")
+
+			time.Sleep(time.Second)
+		}
+	}()
+}
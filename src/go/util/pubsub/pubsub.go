@@ -1,12 +1,55 @@
 package pubsub
 
-import "sync"
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	log "github.com/activeshadow/libminimega/minilog"
+)
+
+// Bus shares publications with other phenix instances so subscribers in each
+// instance receive publications made in any instance.
+type Bus interface {
+	// Publish sends the given JSON encoded publication to other instances.
+	Publish(topic string, data []byte) error
+
+	// Receive calls the given function for each publication sent by other
+	// instances. It doesn't block.
+	Receive(func(topic string, data []byte))
+}
 
 var (
 	mu   sync.RWMutex
 	subs = make(map[string][]chan interface{})
+
+	// Types of publications for topics shared via the bus.
+	shared = make(map[string]reflect.Type)
+
+	bus Bus
 )
 
+// Share marks the given topic as one whose publications should be shared with
+// other phenix instances when a bus is configured. Publications for the topic
+// must be of the same type as the given value and be able to be encoded as
+// JSON.
+func Share(topic string, v interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	shared[topic] = reflect.TypeOf(v)
+}
+
+// SetBus configures the bus to use for sharing publications with other phenix
+// instances.
+func SetBus(b Bus) {
+	mu.Lock()
+	bus = b
+	mu.Unlock()
+
+	b.Receive(receive)
+}
+
 func Subscribe(topic string) chan interface{} {
 	mu.Lock()
 	defer mu.Unlock()
@@ -19,6 +62,28 @@ func Subscribe(topic string) chan interface{} {
 }
 
 func Publish(topic string, msg interface{}) {
+	deliver(topic, msg)
+
+	mu.RLock()
+	b, ok := bus, shared[topic] != nil
+	mu.RUnlock()
+
+	if b == nil || !ok {
+		return
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Error("encoding %s publication to share: %v", topic, err)
+		return
+	}
+
+	if err := b.Publish(topic, data); err != nil {
+		log.Error("sharing %s publication: %v", topic, err)
+	}
+}
+
+func deliver(topic string, msg interface{}) {
 	mu.RLock()
 	defer mu.RUnlock()
 
@@ -26,3 +91,24 @@ func Publish(topic string, msg interface{}) {
 		ch <- msg
 	}
 }
+
+// receive delivers a publication shared by another instance to local
+// subscribers, decoding it to the type registered for the topic.
+func receive(topic string, data []byte) {
+	mu.RLock()
+	typ := shared[topic]
+	mu.RUnlock()
+
+	if typ == nil {
+		return
+	}
+
+	v := reflect.New(typ)
+
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		log.Error("decoding shared %s publication: %v", topic, err)
+		return
+	}
+
+	deliver(topic, v.Elem().Interface())
+}
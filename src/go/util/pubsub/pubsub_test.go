@@ -0,0 +1,92 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+type testBus struct {
+	published map[string][]byte
+	handler   func(string, []byte)
+}
+
+func (this *testBus) Publish(topic string, data []byte) error {
+	this.published[topic] = data
+	return nil
+}
+
+func (this *testBus) Receive(handler func(string, []byte)) {
+	this.handler = handler
+}
+
+type testPub struct {
+	Name  string
+	Count int
+}
+
+func TestSharedPublications(t *testing.T) {
+	b := &testBus{published: make(map[string][]byte)}
+
+	Share("test-shared", testPub{})
+	SetBus(b)
+
+	defer func() {
+		mu.Lock()
+		bus = nil
+		mu.Unlock()
+	}()
+
+	sub := Subscribe("test-shared")
+
+	done := make(chan struct{})
+
+	go func() {
+		Publish("test-shared", testPub{Name: "foo", Count: 1})
+		close(done)
+	}()
+
+	if pub := receiveWithTimeout(t, sub); pub.(testPub).Name != "foo" {
+		t.Logf("expected local publication to be delivered, got %v", pub)
+		t.FailNow()
+	}
+
+	<-done
+
+	if string(b.published["test-shared"]) != `{"Name":"foo","Count":1}` {
+		t.Logf("expected publication to be shared, got %s", b.published["test-shared"])
+		t.FailNow()
+	}
+
+	go b.handler("test-shared", []byte(`{"Name":"bar","Count":2}`))
+
+	pub, ok := receiveWithTimeout(t, sub).(testPub)
+	if !ok {
+		t.Log("expected shared publication to be decoded to registered type")
+		t.FailNow()
+	}
+
+	if pub.Name != "bar" || pub.Count != 2 {
+		t.Logf("unexpected shared publication %+v", pub)
+		t.FailNow()
+	}
+
+	// Topics that aren't shared shouldn't be published to the bus.
+	Publish("test-local", testPub{Name: "baz"})
+
+	if _, ok := b.published["test-local"]; ok {
+		t.Log("expected unshared topic to not be published to bus")
+		t.FailNow()
+	}
+}
+
+func receiveWithTimeout(t *testing.T, sub chan interface{}) interface{} {
+	select {
+	case pub := <-sub:
+		return pub
+	case <-time.After(time.Second):
+		t.Log("timed out waiting for publication")
+		t.FailNow()
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package tap
+
+import (
+	"fmt"
+	"strings"
+
+	"inet.af/netaddr"
+)
+
+// nftTable is the name of the nftables table created in a tap's network
+// namespace for external access.
+const nftTable = "phenix"
+
+// Validate checks the tap's external access firewall rules and port forwards.
+func (this External) Validate() error {
+	if !this.Enabled {
+		if len(this.Firewall.Rules) > 0 || len(this.Forwards) > 0 {
+			return fmt.Errorf("firewall rules and port forwards require external access to be enabled")
+		}
+
+		return nil
+	}
+
+	if _, err := verdict(this.Firewall.Default, "accept"); err != nil {
+		return fmt.Errorf("invalid default firewall action: %w", err)
+	}
+
+	for i, r := range this.Firewall.Rules {
+		if _, err := r.nft(); err != nil {
+			return fmt.Errorf("invalid firewall rule %d: %w", i, err)
+		}
+	}
+
+	for _, f := range this.Forwards {
+		if err := f.validate(); err != nil {
+			return fmt.Errorf("invalid port forward for host port %d: %w", f.HostPort, err)
+		}
+	}
+
+	return nil
+}
+
+// Proto returns the forward's protocol, defaulting to tcp.
+func (this Forward) Proto() string {
+	if this.Protocol == "" {
+		return "tcp"
+	}
+
+	return strings.ToLower(this.Protocol)
+}
+
+func (this Forward) validate() error {
+	if this.HostPort < 1 || this.HostPort > 65535 {
+		return fmt.Errorf("host port must be between 1 and 65535")
+	}
+
+	if this.Port < 1 || this.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+
+	if _, err := netaddr.ParseIP(this.Address); err != nil {
+		return fmt.Errorf("parsing address: %w", err)
+	}
+
+	if p := this.Proto(); p != "tcp" && p != "udp" {
+		return fmt.Errorf("protocol must be tcp or udp")
+	}
+
+	return nil
+}
+
+func (this FirewallRule) nft() (string, error) {
+	var parts []string
+
+	if this.Source != "" {
+		if _, err := netaddr.ParseIPPrefix(prefix(this.Source)); err != nil {
+			return "", fmt.Errorf("parsing source: %w", err)
+		}
+
+		parts = append(parts, "ip saddr "+this.Source)
+	}
+
+	if this.Destination != "" {
+		if _, err := netaddr.ParseIPPrefix(prefix(this.Destination)); err != nil {
+			return "", fmt.Errorf("parsing destination: %w", err)
+		}
+
+		parts = append(parts, "ip daddr "+this.Destination)
+	}
+
+	proto := strings.ToLower(this.Protocol)
+
+	switch proto {
+	case "", "all":
+		if this.Port != 0 {
+			return "", fmt.Errorf("port requires tcp or udp protocol")
+		}
+	case "tcp", "udp":
+		if this.Port != 0 {
+			parts = append(parts, fmt.Sprintf("%s dport %d", proto, this.Port))
+		} else {
+			parts = append(parts, "meta l4proto "+proto)
+		}
+	default:
+		if this.Port != 0 {
+			return "", fmt.Errorf("port requires tcp or udp protocol")
+		}
+
+		parts = append(parts, "meta l4proto "+proto)
+	}
+
+	v, err := verdict(this.Action, "")
+	if err != nil {
+		return "", err
+	}
+
+	parts = append(parts, "counter", v)
+
+	return strings.Join(parts, " "), nil
+}
+
+// netnsCommands returns the nft commands used to configure NAT, firewall rules,
+// and port forwards in the tap's network namespace. The `eth0` interface in the
+// network namespace connects to the system namespace, and the tap itself
+// connects to the VLAN.
+func (this Tap) netnsCommands() ([]string, error) {
+	if err := this.External.Validate(); err != nil {
+		return nil, err
+	}
+
+	policy, _ := verdict(this.External.Firewall.Default, "accept")
+
+	cmds := []string{
+		"add table ip " + nftTable,
+		fmt.Sprintf("add chain ip %s forward { type filter hook forward priority 0 ; policy %s ; }", nftTable, policy),
+		fmt.Sprintf("add rule ip %s forward ct state established,related accept", nftTable),
+		// Always allow traffic for port forwards through, regardless of rules.
+		fmt.Sprintf("add rule ip %s forward ct status dnat accept", nftTable),
+	}
+
+	for _, r := range this.External.Firewall.Rules {
+		rule, _ := r.nft()
+		cmds = append(cmds, fmt.Sprintf("add rule ip %s forward %s", nftTable, rule))
+	}
+
+	cmds = append(cmds,
+		fmt.Sprintf("add chain ip %s prerouting { type nat hook prerouting priority -100 ; }", nftTable),
+		fmt.Sprintf("add chain ip %s postrouting { type nat hook postrouting priority 100 ; }", nftTable),
+		fmt.Sprintf("add rule ip %s postrouting oifname eth0 masquerade", nftTable),
+	)
+
+	for _, f := range this.External.Forwards {
+		cmds = append(cmds,
+			fmt.Sprintf("add rule ip %s prerouting iifname eth0 %s dport %d dnat to %s:%d", nftTable, f.Proto(), f.HostPort, f.Address, f.Port),
+		)
+	}
+
+	if len(this.External.Forwards) > 0 {
+		// Masquerade forwarded traffic so replies from VMs come back through the
+		// tap, even if it's not the default gateway for the VMs.
+		cmds = append(cmds,
+			fmt.Sprintf("add rule ip %s postrouting oifname %s ct status dnat masquerade", nftTable, this.Name),
+		)
+	}
+
+	return cmds, nil
+}
+
+// hostCommands returns the iptables commands used to forward ports on the
+// cluster host to the tap's network namespace (at the given address). If del
+// is true, the commands delete the rules instead.
+func (this Tap) hostCommands(addr netaddr.IP, del bool) []string {
+	op := "-A"
+
+	if del {
+		op = "-D"
+	}
+
+	var cmds []string
+
+	for _, f := range this.External.Forwards {
+		cmds = append(cmds,
+			fmt.Sprintf(
+				"iptables -t nat %s PREROUTING -m addrtype --dst-type LOCAL -p %s --dport %d -j DNAT --to-destination %s:%d",
+				op, f.Proto(), f.HostPort, addr, f.HostPort,
+			),
+			fmt.Sprintf(
+				"iptables %s FORWARD -o %s -d %s -p %s --dport %d -j ACCEPT",
+				op, this.Name, addr, f.Proto(), f.HostPort,
+			),
+		)
+	}
+
+	return cmds
+}
+
+// ActiveForwards returns the port forwards for the tap as created on the given
+// cluster host.
+func (this Tap) ActiveForwards(host string) []ActiveForward {
+	var active []ActiveForward
+
+	if !this.External.Enabled {
+		return nil
+	}
+
+	for _, f := range this.External.Forwards {
+		active = append(active, ActiveForward{
+			VLAN:     this.VLAN,
+			Host:     host,
+			HostPort: f.HostPort,
+			Address:  f.Address,
+			Port:     f.Port,
+			Protocol: f.Proto(),
+		})
+	}
+
+	return active
+}
+
+func verdict(action, def string) (string, error) {
+	if action == "" {
+		action = def
+	}
+
+	switch strings.ToLower(action) {
+	case "accept", "allow":
+		return "accept", nil
+	case "drop", "deny":
+		return "drop", nil
+	default:
+		return "", fmt.Errorf("unknown action %s", action)
+	}
+}
+
+// prefix converts a bare IP address into a prefix for parsing.
+func prefix(addr string) string {
+	if strings.Contains(addr, "/") {
+		return addr
+	}
+
+	return addr + "/32"
+}
@@ -0,0 +1,129 @@
+package tap
+
+import (
+	"strings"
+	"testing"
+
+	"inet.af/netaddr"
+)
+
+func testTap() Tap {
+	return Tap{
+		Name: "abcdefgh-tapapp",
+		VLAN: "EXP",
+		External: External{
+			Enabled: true,
+			Firewall: Firewall{
+				Default: "deny",
+				Rules: []FirewallRule{
+					{Action: "allow", Source: "10.0.0.0/24", Protocol: "tcp", Port: 443},
+					{Action: "allow", Destination: "8.8.8.8", Protocol: "udp"},
+				},
+			},
+			Forwards: []Forward{
+				{HostPort: 8443, Address: "10.0.0.5", Port: 443},
+			},
+		},
+	}
+}
+
+func TestNetNSCommands(t *testing.T) {
+	cmds, err := testTap().netnsCommands()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := []string{
+		"add table ip phenix",
+		"add chain ip phenix forward { type filter hook forward priority 0 ; policy drop ; }",
+		"add rule ip phenix forward ct state established,related accept",
+		"add rule ip phenix forward ct status dnat accept",
+		"add rule ip phenix forward ip saddr 10.0.0.0/24 tcp dport 443 counter accept",
+		"add rule ip phenix forward ip daddr 8.8.8.8 meta l4proto udp counter accept",
+		"add chain ip phenix prerouting { type nat hook prerouting priority -100 ; }",
+		"add chain ip phenix postrouting { type nat hook postrouting priority 100 ; }",
+		"add rule ip phenix postrouting oifname eth0 masquerade",
+		"add rule ip phenix prerouting iifname eth0 tcp dport 8443 dnat to 10.0.0.5:443",
+		"add rule ip phenix postrouting oifname abcdefgh-tapapp ct status dnat masquerade",
+	}
+
+	if len(cmds) != len(expected) {
+		t.Logf("expected %d commands, got %d\n%s", len(expected), len(cmds), strings.Join(cmds, "\n"))
+		t.FailNow()
+	}
+
+	for i, cmd := range cmds {
+		if cmd != expected[i] {
+			t.Logf("expected command %q, got %q", expected[i], cmd)
+			t.FailNow()
+		}
+	}
+}
+
+func TestHostCommands(t *testing.T) {
+	var (
+		tap  = testTap()
+		addr = netaddr.MustParseIP("10.213.47.2")
+	)
+
+	add := tap.hostCommands(addr, false)
+	del := tap.hostCommands(addr, true)
+
+	expected := []string{
+		"iptables -t nat -A PREROUTING -m addrtype --dst-type LOCAL -p tcp --dport 8443 -j DNAT --to-destination 10.213.47.2:8443",
+		"iptables -A FORWARD -o abcdefgh-tapapp -d 10.213.47.2 -p tcp --dport 8443 -j ACCEPT",
+	}
+
+	if len(add) != len(expected) || len(del) != len(expected) {
+		t.Logf("expected %d commands, got %d and %d", len(expected), len(add), len(del))
+		t.FailNow()
+	}
+
+	for i, cmd := range add {
+		if cmd != expected[i] {
+			t.Logf("expected command %q, got %q", expected[i], cmd)
+			t.FailNow()
+		}
+
+		if del[i] != strings.Replace(cmd, " -A ", " -D ", 1) {
+			t.Logf("expected delete command for %q, got %q", cmd, del[i])
+			t.FailNow()
+		}
+	}
+
+	active := tap.ActiveForwards("compute1")
+
+	if len(active) != 1 || active[0].Host != "compute1" || active[0].Protocol != "tcp" || active[0].VLAN != "EXP" {
+		t.Logf("unexpected active forwards %+v", active)
+		t.FailNow()
+	}
+}
+
+func TestExternalValidate(t *testing.T) {
+	invalid := map[string]func(*External){
+		"disabled with forwards": func(e *External) { e.Enabled = false },
+		"unknown default":        func(e *External) { e.Firewall.Default = "reject" },
+		"unknown action":         func(e *External) { e.Firewall.Rules[0].Action = "maybe" },
+		"port without protocol":  func(e *External) { e.Firewall.Rules[0].Protocol = "" },
+		"bad source":             func(e *External) { e.Firewall.Rules[0].Source = "foo" },
+		"bad host port":          func(e *External) { e.Forwards[0].HostPort = 0 },
+		"bad address":            func(e *External) { e.Forwards[0].Address = "foo" },
+		"bad protocol":           func(e *External) { e.Forwards[0].Protocol = "icmp" },
+	}
+
+	if err := testTap().External.Validate(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for name, modify := range invalid {
+		ext := testTap().External
+		modify(&ext)
+
+		if err := ext.Validate(); err == nil {
+			t.Logf("expected error for %s", name)
+			t.FailNow()
+		}
+	}
+}
@@ -20,6 +20,10 @@ func (this *Tap) Init(opts ...Option) {
 }
 
 func (this *Tap) Create(host string) error {
+	if err := this.External.Validate(); err != nil {
+		return fmt.Errorf("validating external access for VLAN %s host tap: %w", this.VLAN, err)
+	}
+
 	if err := this.create(host); err != nil {
 		// attempt to clean up any progress already made
 		this.delete(host)
@@ -146,11 +150,18 @@ func (this *Tap) connect(host string, subnet netaddr.IPPrefix) error {
 		return fmt.Errorf("setting default route for network namespace %s on host %s: %w", this.Name, host, err)
 	}
 
-	log.Info("configuring iptables in network namespace %s on host %s", this.Name, host)
+	log.Info("configuring nftables in network namespace %s on host %s", this.Name, host)
 
-	cmd = fmt.Sprintf("ip netns exec %s iptables -t nat -A POSTROUTING -o eth0 -j MASQUERADE", this.Name)
-	if err := mm.MeshShell(host, cmd); err != nil {
-		return fmt.Errorf("configuring iptables masquerading in network namespace %s on host %s: %w", this.Name, host, err)
+	nft, err := this.netnsCommands()
+	if err != nil {
+		return fmt.Errorf("generating nftables configs for network namespace %s: %w", this.Name, err)
+	}
+
+	for _, c := range nft {
+		cmd = fmt.Sprintf("ip netns exec %s nft %s", this.Name, c)
+		if err := mm.MeshShell(host, cmd); err != nil {
+			return fmt.Errorf("configuring nftables in network namespace %s on host %s: %w", this.Name, host, err)
+		}
 	}
 
 	log.Info("configuring iptables in the system namespace on host %s", host)
@@ -170,6 +181,16 @@ func (this *Tap) connect(host string, subnet netaddr.IPPrefix) error {
 		return fmt.Errorf("configuring iptables forwarding in system namespace on host %s: %w", host, err)
 	}
 
+	if len(this.External.Forwards) > 0 {
+		log.Info("configuring port forwards for tap %s in the system namespace on host %s", this.Name, host)
+	}
+
+	for _, cmd := range this.hostCommands(right, false) {
+		if err := mm.MeshShell(host, cmd); err != nil {
+			return fmt.Errorf("configuring port forwarding in system namespace on host %s: %w", host, err)
+		}
+	}
+
 	log.Info("enabling and configuring veth interface in the system namespace on host %s", host)
 
 	cmd = fmt.Sprintf("ip addr add %s/30 dev %s", left, this.Name)
@@ -217,5 +238,18 @@ func (this Tap) disconnect(host string) error {
 		errs = multierror.Append(errs, fmt.Errorf("deleting iptables forwarding in system namespace on host %s: %w", host, err))
 	}
 
+	for _, cmd := range this.hostCommands(right, true) {
+		if err := mm.MeshShell(host, cmd); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("deleting port forwarding in system namespace on host %s: %w", host, err))
+		}
+	}
+
+	log.Info("deleting nftables configs in network namespace %s on host %s", this.Name, host)
+
+	cmd = fmt.Sprintf("ip netns exec %s nft delete table ip %s", this.Name, nftTable)
+	if err := mm.MeshShell(host, cmd); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("deleting nftables configs in network namespace %s on host %s: %w", this.Name, host, err))
+	}
+
 	return errs
 }
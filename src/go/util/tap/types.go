@@ -19,5 +19,49 @@ type Tap struct {
 
 type External struct {
 	Enabled bool `structs:"enabled" mapstructure:"enabled"`
-	// TODO: add firewall config
+
+	// Firewall filters traffic forwarded between the tap's VLAN and the external
+	// network. If no rules are provided, all traffic is allowed.
+	Firewall Firewall `structs:"firewall" mapstructure:"firewall"`
+
+	// Forwards are ports on the cluster host the tap is created on that are
+	// forwarded to VMs on the tap's VLAN.
+	Forwards []Forward `structs:"forwards" mapstructure:"forwards"`
+}
+
+type Firewall struct {
+	// Default is the action (accept or drop) taken for traffic that doesn't
+	// match any rules. Defaults to accept.
+	Default string         `structs:"default" mapstructure:"default"`
+	Rules   []FirewallRule `structs:"rules" mapstructure:"rules"`
+}
+
+// FirewallRule is an allow (accept) or deny (drop) rule for traffic forwarded
+// between the tap's VLAN and the external network. Empty match fields match
+// all traffic.
+type FirewallRule struct {
+	Action      string `structs:"action" mapstructure:"action"`
+	Source      string `structs:"source" mapstructure:"source"`
+	Destination string `structs:"destination" mapstructure:"destination"`
+	Protocol    string `structs:"protocol" mapstructure:"protocol"`
+	Port        int    `structs:"port" mapstructure:"port"`
+}
+
+// Forward forwards a port on the cluster host the tap is created on to a port
+// on a VM on the tap's VLAN.
+type Forward struct {
+	HostPort int    `structs:"hostPort" mapstructure:"hostPort"`
+	Address  string `structs:"address" mapstructure:"address"`
+	Port     int    `structs:"port" mapstructure:"port"`
+	Protocol string `structs:"protocol" mapstructure:"protocol"`
+}
+
+// ActiveForward is a port forward that has been created on a cluster host.
+type ActiveForward struct {
+	VLAN     string `structs:"vlan" mapstructure:"vlan"`
+	Host     string `structs:"host" mapstructure:"host"`
+	HostPort int    `structs:"hostPort" mapstructure:"hostPort"`
+	Address  string `structs:"address" mapstructure:"address"`
+	Port     int    `structs:"port" mapstructure:"port"`
+	Protocol string `structs:"protocol" mapstructure:"protocol"`
 }
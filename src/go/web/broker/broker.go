@@ -6,23 +6,124 @@ import (
 	"fmt"
 	"strings"
 
+	"phenix/api/soh"
 	"phenix/api/vm"
 	"phenix/app"
+	"phenix/util/metrics"
 	"phenix/util/pubsub"
+	"phenix/web/rbac"
 	"phenix/web/util"
+
+	"github.com/gofrs/uuid"
 )
 
+// historySize is the number of recent publications kept so clients that
+// reconnect can be sent the publications they missed.
+const historySize = 1024
+
 var (
+	// epoch identifies this instance of the broker, since sequence numbers start
+	// over each time phenix is restarted.
+	epoch = uuid.Must(uuid.NewV4()).String()
+
+	// seq is the sequence number of the most recent publication broadcast.
+	seq uint64
+
+	recent = newHistory(historySize)
+
 	clients    = make(map[*Client]bool)
 	broadcast  = make(chan Publish, 1024)
 	register   = make(chan *Client, 1024)
 	unregister = make(chan *Client, 1024)
+
+	subscriptions = make(map[*Subscription]bool)
+	subscribe     = make(chan *Subscription, 1024)
+	unsubscribe   = make(chan *Subscription, 1024)
 )
 
+// broadcastTopic is the pubsub topic publications are broadcast on, so they're
+// broadcast by every phenix instance when pubsub is shared between instances.
+const broadcastTopic = "broadcast"
+
+// broadcastMsg is a publication sent on the broadcast pubsub topic. Unlike
+// Publish, it includes the publication's request policy when encoded.
+type broadcastMsg struct {
+	Policy   *RequestPolicy  `json:"policy,omitempty"`
+	Resource *Resource       `json:"resource"`
+	Result   json.RawMessage `json:"result"`
+}
+
+func init() {
+	pubsub.Share(broadcastTopic, broadcastMsg{})
+}
+
 func Start() {
-	triggerSub := pubsub.Subscribe("trigger-app")
-	delayedSub := pubsub.Subscribe("delayed-start")
+	broadcastSub := pubsub.Subscribe(broadcastTopic)
+
+	// Publications from other packages are converted to broadcasts in their
+	// own goroutine since doing so can require store or minimega lookups,
+	// which shouldn't hold up the broker.
+	go relay(pubsub.Subscribe("trigger-app"), pubsub.Subscribe("delayed-start"), pubsub.Subscribe("soh"))
+
+	for {
+		select {
+		case msg := <-broadcastSub:
+			pub := msg.(broadcastMsg)
+			broadcast <- Publish{RequestPolicy: pub.Policy, Resource: pub.Resource, Result: pub.Result}
+		case cli := <-register:
+			clients[cli] = true
+			metrics.BrokerClients.Set(float64(len(clients)))
+
+			// This is done here so no publications are broadcast between the
+			// client being sent the publications it missed and being registered.
+			cli.replay <- resume(cli)
+		case cli := <-unregister:
+			if _, ok := clients[cli]; ok {
+				cli.Stop()
+				delete(clients, cli)
+				metrics.BrokerClients.Set(float64(len(clients)))
+			}
+		case sub := <-subscribe:
+			subscriptions[sub] = true
+		case sub := <-unsubscribe:
+			delete(subscriptions, sub)
+		case pub := <-broadcast:
+			seq++
+
+			pub.Seq = seq
+			recent.add(pub)
+
+			for cli := range clients {
+				if allowed(cli.role, pub.RequestPolicy) && cli.subscribed(pub.Resource) {
+					select {
+					case cli.publish <- pub:
+					default:
+						cli.Stop()
+						delete(clients, cli)
+						metrics.BrokerClients.Set(float64(len(clients)))
+					}
+				}
+			}
 
+			for sub := range subscriptions {
+				if allowed(sub.role, pub.RequestPolicy) {
+					select {
+					case sub.publish <- pub:
+					default:
+						// Deleting here since the subscription's Close function will
+						// block trying to send to the unsubscribe channel otherwise.
+						delete(subscriptions, sub)
+						go sub.Close()
+					}
+				}
+			}
+		}
+	}
+}
+
+// relay broadcasts publications from other packages (app triggers, delayed VM
+// starts, and state of health updates) to clients of this instance.
+func relay(triggerSub, delayedSub, sohSub chan interface{}) {
 	for {
 		select {
 		case pub := <-triggerSub:
@@ -30,14 +131,14 @@ func Start() {
 
 			typ := fmt.Sprintf("apps/%s", trigger.App)
 
-			policy := NewRequestPolicy("experiments/trigger", "create", trigger.Experiment)
+			policy := NewExperimentRequestPolicy("experiments/trigger", trigger.Experiment, "create")
 			resource := NewResource(typ, trigger.Experiment, trigger.State)
 
 			if trigger.State == "error" {
 				result, _ := json.Marshal(map[string]interface{}{"error": trigger.Error.Error()})
-				broadcast <- Publish{RequestPolicy: policy, Resource: resource, Result: result}
+				BroadcastLocal(policy, resource, result)
 			} else {
-				broadcast <- Publish{RequestPolicy: policy, Resource: resource, Result: nil}
+				BroadcastLocal(policy, resource, nil)
 			}
 		case pub := <-delayedSub:
 			delayed := pub.(string)
@@ -58,45 +159,100 @@ func Start() {
 				continue
 			}
 
-			policy := NewRequestPolicy("vms/start", "update", strings.Join(names, "_"))
-			resource := NewResource("experiment/vm", delayed, "start")
+			policy := NewVMRequestPolicy("vms/start", names[0], names[1], "update")
+			resource := NewVMResource("experiment/vm", names[0], names[1], "start")
 
-			broadcast <- Publish{RequestPolicy: policy, Resource: resource, Result: body}
-		case cli := <-register:
-			clients[cli] = true
-		case cli := <-unregister:
-			if _, ok := clients[cli]; ok {
-				cli.Stop()
-				delete(clients, cli)
-			}
-		case pub := <-broadcast:
-			for cli := range clients {
-				var (
-					policy = pub.RequestPolicy
-					allow  bool
-				)
-
-				if policy == nil {
-					allow = true
-				} else if policy.ResourceName == "" {
-					allow = cli.role.Allowed(policy.Resource, policy.Verb)
-				} else {
-					allow = cli.role.Allowed(policy.Resource, policy.Verb, policy.ResourceName)
-				}
+			BroadcastLocal(policy, resource, body)
+		case pub := <-sohSub:
+			update := pub.(soh.Publication)
 
-				if allow {
-					select {
-					case cli.publish <- pub:
-					default:
-						cli.Stop()
-						delete(clients, cli)
-					}
-				}
+			body, err := json.Marshal(update)
+			if err != nil {
+				continue
 			}
+
+			policy := NewRequestPolicy("vms", "list", "")
+			resource := NewResource("experiment/soh", update.Experiment, "update")
+
+			BroadcastLocal(policy, resource, body)
 		}
 	}
 }
 
+// Broadcast broadcasts the given publication to all clients allowed by the
+// given policy, including clients connected to other phenix instances when
+// pubsub is shared between instances.
 func Broadcast(policy *RequestPolicy, resource *Resource, msg json.RawMessage) {
+	policy.resolve()
+	pubsub.Publish(broadcastTopic, broadcastMsg{Policy: policy, Resource: resource, Result: msg})
+}
+
+// BroadcastLocal is like Broadcast, but only broadcasts the given publication
+// to clients connected to this phenix instance. It's used for publications
+// specific to this instance, like its logs.
+func BroadcastLocal(policy *RequestPolicy, resource *Resource, msg json.RawMessage) {
+	policy.resolve()
 	broadcast <- Publish{RequestPolicy: policy, Resource: resource, Result: msg}
 }
+
+// resume returns the messages to send to the given client when it connects,
+// before any new publications. The first message includes the broker's epoch
+// and current sequence number. If the client is resuming from a previous
+// connection, it's followed by the publications the client missed, or a
+// message telling the client to resync its state if they're no longer
+// available (or the broker has been restarted since).
+func resume(cli *Client) []interface{} {
+	status := map[string]interface{}{"epoch": epoch, "seq": seq}
+
+	if !cli.resuming {
+		result, _ := json.Marshal(status)
+		return []interface{}{Publish{Resource: NewResource("broker", epoch, "connected"), Result: result}}
+	}
+
+	var (
+		missed []Publish
+		ok     bool
+	)
+
+	if cli.resumeEpoch == epoch {
+		missed, ok = recent.since(cli.resumeSeq, seq)
+	}
+
+	if !ok {
+		status["requested"] = cli.resumeSeq
+		result, _ := json.Marshal(status)
+
+		return []interface{}{Publish{Resource: NewResource("broker", epoch, "resync"), Result: result}}
+	}
+
+	result, _ := json.Marshal(status)
+	msgs := []interface{}{Publish{Resource: NewResource("broker", epoch, "connected"), Result: result}}
+
+	for _, pub := range missed {
+		if allowed(cli.role, pub.RequestPolicy) && cli.subscribed(pub.Resource) {
+			msgs = append(msgs, pub)
+		}
+	}
+
+	return msgs
+}
+
+func allowed(role rbac.Role, policy *RequestPolicy) bool {
+	if policy == nil {
+		return true
+	}
+
+	if policy.ResourceName == "" {
+		return role.Allowed(policy.Resource, policy.Verb)
+	}
+
+	if role.Allowed(policy.Resource, policy.Verb, policy.ResourceName) {
+		return true
+	}
+
+	if policy.Object != nil {
+		return role.AllowedObjects(policy.Resource, policy.Verb, *policy.Object)
+	}
+
+	return false
+}
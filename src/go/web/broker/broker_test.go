@@ -0,0 +1,50 @@
+package broker
+
+import (
+	"testing"
+
+	v1 "phenix/types/version/v1"
+	"phenix/web/rbac"
+)
+
+func TestAllowedSelectors(t *testing.T) {
+	role := rbac.Role{
+		Spec: &v1.RoleSpec{
+			Name: "Red Team",
+			Policies: []*v1.PolicySpec{
+				{Resources: []string{"vms/start"}, ResourceNames: []string{"foo_*"}, Verbs: []string{"update"}},
+				{Resources: []string{"vms/start"}, LabelSelector: map[string]string{"team": "red"}, Verbs: []string{"update"}},
+			},
+		},
+	}
+
+	// resolved returns a policy for the given VM with its RBAC object already
+	// looked up, since there's no store or minimega to look it up from.
+	resolved := func(exp, vm string, labels map[string]string) *RequestPolicy {
+		policy := NewVMRequestPolicy("vms/start", exp, vm, "update")
+
+		policy.Object = &rbac.Object{Name: exp + "_" + vm, Labels: labels}
+
+		return policy
+	}
+
+	if !allowed(role, resolved("foo", "host-00", nil)) {
+		t.Log("expected name policy to allow publication for foo_host-00")
+		t.FailNow()
+	}
+
+	if !allowed(role, resolved("bar", "host-00", map[string]string{"team": "red"})) {
+		t.Log("expected label selector to allow publication for bar_host-00")
+		t.FailNow()
+	}
+
+	if allowed(role, resolved("bar", "host-00", map[string]string{"team": "blue"})) {
+		t.Log("expected label selector to deny publication for bar_host-00")
+		t.FailNow()
+	}
+
+	if allowed(role, NewRequestPolicy("vms/start", "bar_host-00", "update")) {
+		t.Log("expected name only policy to deny publication for bar_host-00")
+		t.FailNow()
+	}
+}
@@ -1,17 +1,23 @@
 package broker
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
 	"phenix/api/experiment"
 	"phenix/api/vm"
+	"phenix/util/audit"
 	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/middleware"
 	"phenix/web/proto"
 	"phenix/web/rbac"
 	"phenix/web/util"
@@ -45,6 +51,8 @@ var (
 
 type Client struct {
 	role   rbac.Role
+	user   string
+	addr   string
 	conn   *websocket.Conn
 	connMu sync.Mutex
 
@@ -52,11 +60,30 @@ type Client struct {
 	done    chan struct{}
 	once    sync.Once
 
+	// Context used for handling requests made by this client, which includes
+	// the client's user and role.
+	ctx context.Context
+
 	// Track the VMs this client currently has in view, if any, so we know
 	// what screenshots need to periodically be pushed to the client over
 	// the WebSocket connection.
 	vms  []vmScope
 	vmMu sync.RWMutex
+
+	// Topics this client is subscribed to. If empty, the client receives all
+	// publications allowed by its role.
+	topics  map[string]bool
+	topicMu sync.RWMutex
+
+	// The broker epoch and sequence number of the last publication received by
+	// this client on a previous connection, if it's resuming.
+	resumeEpoch string
+	resumeSeq   uint64
+	resuming    bool
+
+	// Messages to send to the client before any new publications, sent by the
+	// broker when the client is registered.
+	replay chan []interface{}
 }
 
 func NewClient(role rbac.Role, conn *websocket.Conn) *Client {
@@ -65,9 +92,20 @@ func NewClient(role rbac.Role, conn *websocket.Conn) *Client {
 		conn:    conn,
 		publish: make(chan interface{}, 256),
 		done:    make(chan struct{}),
+		ctx:     context.WithValue(context.Background(), "role", role),
+		topics:  make(map[string]bool),
+		replay:  make(chan []interface{}, 1),
 	}
 }
 
+// Resume configures the client to be sent any publications broadcast by the
+// given broker epoch since the given sequence number when it connects.
+func (this *Client) Resume(epoch string, seq uint64) {
+	this.resumeEpoch = epoch
+	this.resumeSeq = seq
+	this.resuming = true
+}
+
 func (this *Client) Go() {
 	register <- this
 
@@ -135,137 +173,297 @@ func (this *Client) read() {
 				continue
 			}
 
-			switch req.Resource.Type {
-			case "experiment/vms":
-			default:
-				log.Error("unexpected WebSocket request resource type: %s", req.Resource.Type)
+			if req.Resource == nil {
+				log.Error("WebSocket request missing resource")
 				continue
 			}
 
-			switch req.Resource.Action {
-			case "list":
+			switch req.Resource.Type {
+			case "experiment/vms":
+				if req.Resource.Action != "list" {
+					this.unexpected(req)
+					continue
+				}
+
+				this.handle(req, this.listVMs)
+			case "subscription":
+				this.handle(req, this.subscription)
 			default:
-				log.Error("unexpected WebSocket request resource action: %s", req.Resource.Action)
-				continue
-			}
+				handler, ok := getRequestHandler(req.Resource.Type, req.Resource.Action)
+				if !ok {
+					this.unexpected(req)
+					continue
+				}
 
-			var payload map[string]interface{}
-			if err := json.Unmarshal(req.Payload, &payload); err != nil {
-				log.Error("cannot unmarshal WebSocket request payload JSON: %v", err)
-				continue
+				// Registered handlers may take a while (e.g. starting an experiment),
+				// so don't block reading further requests while they're handled.
+				go this.handle(req, func(req Request) (json.RawMessage, error) {
+					return handler(this.requestContext(req), req)
+				})
 			}
+		}
+	}
+}
 
-			if !this.role.Allowed("vms", "list") {
-				log.Warn("client access to vms/list forbidden")
-				continue
-			}
+// handle handles the given request using the given function, auditing the
+// request and sending the result to the client.
+func (this *Client) handle(req Request, handler func(Request) (json.RawMessage, error)) {
+	result, err := handler(req)
+
+	switch {
+	case err == nil:
+		this.audit(req, audit.OutcomeSuccess)
+	case errors.Is(err, ErrForbidden):
+		log.Warn("client access to %s %s forbidden", req.Resource.Type, req.Resource.Action)
+		this.audit(req, audit.OutcomeDenied)
+	default:
+		log.Error("handling WebSocket request %s %s for %s: %v", req.Resource.Type, req.Resource.Action, req.Resource.Name, err)
+		this.audit(req, audit.OutcomeFailure)
+	}
 
-			expName := req.Resource.Name
+	this.respond(req, result, err)
+}
 
-			exp, err := experiment.Get(expName)
-			if err != nil {
-				log.Error("getting experiment %s for WebSocket client: %v", expName, err)
-				continue
-			}
+// unexpected logs, audits, and responds to a request with an unsupported
+// resource type or action.
+func (this *Client) unexpected(req Request) {
+	err := fmt.Errorf("unexpected WebSocket request %s %s", req.Resource.Type, req.Resource.Action)
 
-			vms, err := vm.List(expName)
-			if err != nil {
-				log.Error("getting list of VMs for experiment %s: %v", expName, err)
-				continue
-			}
+	log.Error(err.Error())
+	this.audit(req, audit.OutcomeFailure)
+	this.respond(req, nil, err)
+}
 
-			// A Boolean expression tree is built and the fields that
-			// should be searched are determined based on the search string
-			clientFilter, _ := payload["filter"].(string)
-			filterTree := mm.BuildTree(clientFilter)
+// respond sends the result of the given request to the client. Errors are
+// only sent in response to requests that include an ID, since older clients
+// don't expect responses to failed requests.
+func (this *Client) respond(req Request, result json.RawMessage, err error) {
+	pub := Publish{ID: req.ID, Resource: req.Resource, Result: result}
 
-			// If `show_dnb` was not provided client-side, `showDNB` will be false,
-			// which is the default we want.
-			showDNB, _ := payload["show_dnb"].(bool)
+	if err != nil {
+		if req.ID == "" {
+			return
+		}
 
-			allowed := mm.VMs{}
+		pub.Result = nil
+		pub.Error = err.Error()
+	}
 
-			for _, vm := range vms {
-				// If the VM is marked as do not boot, and we're not showing VMs marked as
-				// such, continue on to the next VM right away.
-				if vm.DoNotBoot && !showDNB {
-					continue
-				}
+	this.send(pub)
+}
 
-				// If the filter supplied could not be
-				// parsed, do not add the VM
-				if len(clientFilter) > 0 {
-					if filterTree == nil {
-						continue
-					} else {
-						// If the search string could be parsed,
-						// determine if the VM should be included
-						if !filterTree.Evaluate(&vm) {
-							continue
-						}
-					}
-				}
+// send queues the given message to be published to the client, unless the
+// client has been stopped.
+func (this *Client) send(msg interface{}) {
+	select {
+	case this.publish <- msg:
+	case <-this.done:
+	}
+}
 
-				if this.role.Allowed("vms", "list", fmt.Sprintf("%s_%s", expName, vm.Name)) {
-					if vm.Running {
-						screenshot, err := util.GetScreenshot(expName, vm.Name, "200")
-						if err != nil {
-							log.Error("getting screenshot for WebSocket client: %v", err)
-						} else {
-							vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-						}
-					}
+// requestContext returns the context to use for handling the given request,
+// which includes the client's user and role along with the request's ID (if
+// any) for log correlation.
+func (this *Client) requestContext(req Request) context.Context {
+	ctx := this.ctx
 
-					allowed = append(allowed, vm)
-				}
-			}
+	if req.ID != "" {
+		ctx = plog.WithRequestID(ctx, req.ID)
+	}
 
-			var (
-				sort = payload["sort_column"].(string)
-				asc  = payload["sort_asc"].(bool)
-				page = int(payload["page_number"].(float64))
-				size = int(payload["page_size"].(float64))
-			)
+	return ctx
+}
 
-			payload = map[string]interface{}{"total": len(allowed)}
+func (this *Client) listVMs(req Request) (json.RawMessage, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(req.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal WebSocket request payload JSON: %w", err)
+	}
 
-			if sort != "" {
-				allowed.SortBy(sort, asc)
-			}
+	if !this.role.Allowed("vms", "list") {
+		return nil, fmt.Errorf("%w: listing VMs", ErrForbidden)
+	}
 
-			if page != 0 && size != 0 {
-				allowed = allowed.Paginate(page, size)
-			}
+	expName := req.Resource.Name
 
-			this.vmMu.Lock()
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
 
-			this.vms = nil
+	vms, err := vm.List(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting list of VMs for experiment %s: %w", expName, err)
+	}
 
-			for _, v := range allowed {
-				this.vms = append(this.vms, vmScope{exp: expName, name: v.Name})
-			}
+	// A Boolean expression tree is built and the fields that
+	// should be searched are determined based on the search string
+	clientFilter, _ := payload["filter"].(string)
+	filterTree := mm.BuildTree(clientFilter)
 
-			this.vmMu.Unlock()
+	// If `show_dnb` was not provided client-side, `showDNB` will be false,
+	// which is the default we want.
+	showDNB, _ := payload["show_dnb"].(bool)
 
-			resp := &proto.VMList{Total: uint32(len(allowed))}
+	allowed := mm.VMs{}
 
-			resp.Vms = make([]*proto.VM, len(allowed))
-			for i, v := range allowed {
-				resp.Vms[i] = util.VMToProtobuf(expName, v, exp.Spec.Topology())
-			}
+	for _, vm := range vms {
+		// If the VM is marked as do not boot, and we're not showing VMs marked as
+		// such, continue on to the next VM right away.
+		if vm.DoNotBoot && !showDNB {
+			continue
+		}
 
-			body, err := marshaler.Marshal(resp)
-			if err != nil {
-				log.Error("marshaling experiment %s VMs for WebSocket client: %v", exp, err)
+		// If the filter supplied could not be
+		// parsed, do not add the VM
+		if len(clientFilter) > 0 {
+			if filterTree == nil {
 				continue
+			} else {
+				// If the search string could be parsed,
+				// determine if the VM should be included
+				if !filterTree.Evaluate(&vm) {
+					continue
+				}
 			}
+		}
 
-			this.publish <- Publish{
-				Resource: NewResource("experiment/vms", expName, "list"),
-				Result:   body,
+		if this.role.AllowedObjects("vms", "list", util.VMObject(*exp, vm)) {
+			if vm.Running {
+				screenshot, err := util.GetScreenshot(expName, vm.Name, "200")
+				if err != nil {
+					log.Error("getting screenshot for WebSocket client: %v", err)
+				} else {
+					vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+				}
 			}
+
+			allowed = append(allowed, vm)
 		}
 	}
+
+	var (
+		sort, _ = payload["sort_column"].(string)
+		asc, _  = payload["sort_asc"].(bool)
+		page, _ = payload["page_number"].(float64)
+		size, _ = payload["page_size"].(float64)
+	)
+
+	if sort != "" {
+		allowed.SortBy(sort, asc)
+	}
+
+	if page != 0 && size != 0 {
+		allowed = allowed.Paginate(int(page), int(size))
+	}
+
+	this.vmMu.Lock()
+
+	this.vms = nil
+
+	for _, v := range allowed {
+		this.vms = append(this.vms, vmScope{exp: expName, name: v.Name})
+	}
+
+	this.vmMu.Unlock()
+
+	resp := &proto.VMList{Total: uint32(len(allowed))}
+
+	resp.Vms = make([]*proto.VM, len(allowed))
+	for i, v := range allowed {
+		resp.Vms[i] = util.VMToProtobuf(expName, v, exp.Spec.Topology())
+	}
+
+	body, err := marshaler.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling experiment %s VMs: %w", expName, err)
+	}
+
+	return body, nil
+}
+
+// subscription manages the topics the client is subscribed to. The topic is
+// the name of the request's resource.
+func (this *Client) subscription(req Request) (json.RawMessage, error) {
+	topic := req.Resource.Name
+
+	switch req.Resource.Action {
+	case "subscribe":
+		kind, name, err := parseTopic(topic)
+		if err != nil {
+			return nil, err
+		}
+
+		if !topicAllowed(this.role, kind, name) {
+			return nil, fmt.Errorf("%w: subscribing to %s", ErrForbidden, topic)
+		}
+
+		this.topicMu.Lock()
+		this.topics[topic] = true
+		this.topicMu.Unlock()
+	case "unsubscribe":
+		this.topicMu.Lock()
+		delete(this.topics, topic)
+		this.topicMu.Unlock()
+	case "list":
+	default:
+		return nil, fmt.Errorf("unexpected subscription action %s", req.Resource.Action)
+	}
+
+	return json.Marshal(util.WithRoot("topics", this.Topics()))
+}
+
+// Topics returns the topics the client is currently subscribed to, sorted by
+// name.
+func (this *Client) Topics() []string {
+	this.topicMu.RLock()
+	defer this.topicMu.RUnlock()
+
+	topics := make([]string, 0, len(this.topics))
+
+	for topic := range this.topics {
+		topics = append(topics, topic)
+	}
+
+	sort.Strings(topics)
+
+	return topics
+}
+
+// subscribed returns true if the given resource should be published to the
+// client based on its subscriptions. Clients without any subscriptions
+// receive everything.
+func (this *Client) subscribed(res *Resource) bool {
+	this.topicMu.RLock()
+	defer this.topicMu.RUnlock()
+
+	if len(this.topics) == 0 {
+		return true
+	}
+
+	for topic := range this.topics {
+		// Topics are validated when subscribed to, so this won't fail.
+		kind, name, _ := parseTopic(topic)
+
+		if topicMatches(kind, name, res) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// audit records the given request made by the client over its WebSocket.
+func (this *Client) audit(req Request, outcome audit.Outcome) {
+	audit.Log(audit.Record{
+		User:     this.user,
+		Role:     this.role.Spec.Name,
+		Address:  this.addr,
+		Via:      "websocket",
+		Action:   req.Resource.Type + " " + req.Resource.Action,
+		Resource: req.Resource.Name,
+		Outcome:  outcome,
+	})
 }
 
 func (this *Client) write() {
@@ -274,6 +472,17 @@ func (this *Client) write() {
 	defer ticker.Stop()
 	defer this.Stop()
 
+	// Wait for the broker to register the client so the messages it should be
+	// sent first are sent before any new publications.
+	select {
+	case <-this.done:
+		return
+	case msgs := <-this.replay:
+		if err := this.publisher(msgs...); err != nil {
+			log.Error("publishing missed messages to client: %v", err)
+		}
+	}
+
 	for {
 		select {
 		case <-this.done:
@@ -296,7 +505,10 @@ func (this *Client) write() {
 	}
 }
 
-func (this *Client) publisher(msg interface{}) error {
+// publisher writes the given messages, followed by any other messages waiting
+// to be published, to the client in a single WebSocket message separated by
+// newlines.
+func (this *Client) publisher(msgs ...interface{}) error {
 	this.connMu.Lock()
 	defer this.connMu.Unlock()
 
@@ -311,14 +523,22 @@ func (this *Client) publisher(msg interface{}) error {
 
 	defer w.Close()
 
-	b, err := json.Marshal(msg)
-	if err != nil {
-		log.Error("marshaling message to be published: %v", err)
-		return nil
-	}
+	for i, msg := range msgs {
+		if i > 0 {
+			if _, err := w.Write(newline); err != nil {
+				return fmt.Errorf("writing newline to client connection: %w", err)
+			}
+		}
 
-	if _, err := w.Write(b); err != nil {
-		return fmt.Errorf("writing message to client connection: %w", err)
+		b, err := json.Marshal(msg)
+		if err != nil {
+			log.Error("marshaling message to be published: %v", err)
+			continue
+		}
+
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("writing message to client connection: %w", err)
+		}
 	}
 
 	for i := 0; i < len(this.publish); i++ {
@@ -386,10 +606,10 @@ func (this *Client) screenshots() {
 						continue
 					}
 
-					this.publish <- Publish{
-						Resource: NewResource("experiment/vm/screenshot", fmt.Sprintf("%s/%s", exp, vm), "update"),
+					this.send(Publish{
+						Resource: NewVMResource("experiment/vm/screenshot", exp, vm, "update"),
 						Result:   marshalled,
-					}
+					})
 				}
 			}
 		}
@@ -405,7 +625,32 @@ func ServeWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role := r.Context().Value("role").(rbac.Role)
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		user, _ = ctx.Value("user").(string)
+	)
+
+	client := NewClient(role, conn)
+	client.user = user
+	client.addr = middleware.ClientAddress(r)
+
+	// Clients reconnecting after losing their connection can provide the epoch
+	// and sequence number of the last publication they received to be sent the
+	// publications they missed. If the sequence number is invalid, the client
+	// will be told to resync.
+	if query := r.URL.Query(); query.Get("resume") != "" {
+		if seq, err := strconv.ParseUint(query.Get("resume"), 10, 64); err == nil {
+			client.Resume(query.Get("epoch"), seq)
+		} else {
+			client.Resume("", 0)
+		}
+	}
+
+	// The HTTP request's context is canceled once this function returns, so
+	// only keep the values needed for handling requests.
+	client.ctx = context.WithValue(plog.Detach(ctx), "role", role)
+	client.ctx = context.WithValue(client.ctx, "user", user)
 
-	NewClient(role, conn).Go()
+	client.Go()
 }
@@ -0,0 +1,61 @@
+package broker
+
+// history is a bounded ring buffer of the most recent publications broadcast
+// to clients, used to replay publications missed by clients that reconnect.
+// It's only accessed by the broker's Goroutine, so it isn't locked.
+type history struct {
+	pubs  []Publish
+	start int
+	count int
+}
+
+func newHistory(size int) *history {
+	return &history{pubs: make([]Publish, size)}
+}
+
+// add adds the given publication to the history, replacing the oldest
+// publication if the history is full. Publications must be added in sequence
+// order.
+func (this *history) add(pub Publish) {
+	if len(this.pubs) == 0 {
+		return
+	}
+
+	idx := (this.start + this.count) % len(this.pubs)
+	this.pubs[idx] = pub
+
+	if this.count < len(this.pubs) {
+		this.count++
+	} else {
+		this.start = (this.start + 1) % len(this.pubs)
+	}
+}
+
+// since returns the publications with a sequence number greater than the
+// given sequence number, in sequence order. It returns false if any of those
+// publications are no longer in the history.
+func (this *history) since(seq, current uint64) ([]Publish, bool) {
+	if seq > current {
+		return nil, false
+	}
+
+	if seq == current {
+		return nil, true
+	}
+
+	if this.count == 0 || this.pubs[this.start].Seq > seq+1 {
+		return nil, false
+	}
+
+	var pubs []Publish
+
+	for i := 0; i < this.count; i++ {
+		pub := this.pubs[(this.start+i)%len(this.pubs)]
+
+		if pub.Seq > seq {
+			pubs = append(pubs, pub)
+		}
+	}
+
+	return pubs, true
+}
@@ -0,0 +1,50 @@
+package broker
+
+import "testing"
+
+func TestHistorySince(t *testing.T) {
+	h := newHistory(4)
+
+	pubs, ok := h.since(0, 0)
+	if !ok || len(pubs) != 0 {
+		t.Log("expected nothing missed with empty history")
+		t.FailNow()
+	}
+
+	for seq := uint64(1); seq <= 6; seq++ {
+		h.add(Publish{Seq: seq})
+	}
+
+	// Sequence numbers 3-6 should still be in the history.
+	pubs, ok = h.since(3, 6)
+	if !ok {
+		t.Log("expected missed publications to be available")
+		t.FailNow()
+	}
+
+	if len(pubs) != 3 || pubs[0].Seq != 4 || pubs[2].Seq != 6 {
+		t.Logf("expected publications 4-6, got %+v", pubs)
+		t.FailNow()
+	}
+
+	pubs, ok = h.since(2, 6)
+	if !ok || len(pubs) != 4 || pubs[0].Seq != 3 {
+		t.Logf("expected publications 3-6, got %+v", pubs)
+		t.FailNow()
+	}
+
+	if _, ok := h.since(1, 6); ok {
+		t.Log("expected publication 2 to no longer be available")
+		t.FailNow()
+	}
+
+	if _, ok := h.since(7, 6); ok {
+		t.Log("expected sequence number newer than current to be invalid")
+		t.FailNow()
+	}
+
+	if pubs, ok := h.since(6, 6); !ok || len(pubs) != 0 {
+		t.Log("expected nothing missed when up to date")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,41 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// ErrForbidden is returned (possibly wrapped) by request handlers when the
+// client's role doesn't allow the requested action.
+var ErrForbidden = errors.New("forbidden")
+
+// RequestHandler handles a request made by a client over its WebSocket. The
+// given context includes the user and role of the client, just like the
+// context of HTTP requests. The result returned, if any, is sent to the client
+// in the response to the request.
+type RequestHandler func(ctx context.Context, req Request) (json.RawMessage, error)
+
+var (
+	requestHandlers   = make(map[[2]string]RequestHandler)
+	requestHandlersMu sync.RWMutex
+)
+
+// RegisterRequestHandler registers a handler for requests with the given
+// resource type and action. Any existing handler for the same resource type
+// and action is replaced.
+func RegisterRequestHandler(typ, action string, handler RequestHandler) {
+	requestHandlersMu.Lock()
+	defer requestHandlersMu.Unlock()
+
+	requestHandlers[[2]string{typ, action}] = handler
+}
+
+func getRequestHandler(typ, action string) (RequestHandler, bool) {
+	requestHandlersMu.RLock()
+	defer requestHandlersMu.RUnlock()
+
+	handler, ok := requestHandlers[[2]string{typ, action}]
+	return handler, ok
+}
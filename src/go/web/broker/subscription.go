@@ -0,0 +1,51 @@
+package broker
+
+import (
+	"sync"
+
+	"phenix/web/rbac"
+)
+
+// Subscription receives the same publications broadcast to WebSocket clients,
+// limited to those allowed by the subscriber's role. It's used to stream
+// updates to clients not connected via WebSockets (e.g. gRPC clients).
+type Subscription struct {
+	role rbac.Role
+
+	publish chan Publish
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Subscribe returns a new subscription to publications allowed by the given
+// role. The subscription must be closed when it's no longer needed.
+func Subscribe(role rbac.Role) *Subscription {
+	sub := &Subscription{
+		role:    role,
+		publish: make(chan Publish, 256),
+		done:    make(chan struct{}),
+	}
+
+	subscribe <- sub
+
+	return sub
+}
+
+// Publications returns the channel publications are sent to.
+func (this *Subscription) Publications() <-chan Publish {
+	return this.publish
+}
+
+// Done returns a channel that's closed when the subscription is closed, either
+// by the subscriber or by the broker if the subscriber falls too far behind.
+func (this *Subscription) Done() <-chan struct{} {
+	return this.done
+}
+
+// Close closes the subscription. It's safe to call more than once.
+func (this *Subscription) Close() {
+	this.once.Do(func() {
+		close(this.done)
+		unsubscribe <- this
+	})
+}
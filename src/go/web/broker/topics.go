@@ -0,0 +1,76 @@
+package broker
+
+import (
+	"fmt"
+	"strings"
+
+	"phenix/web/rbac"
+	"phenix/web/util"
+)
+
+// parseTopic splits the given subscription topic into its kind and name,
+// returning an error if the topic isn't supported.
+func parseTopic(topic string) (string, string, error) {
+	kind, name := topic, ""
+
+	if idx := strings.Index(topic, "/"); idx >= 0 {
+		kind, name = topic[:idx], topic[idx+1:]
+	}
+
+	switch kind {
+	case "experiment", "soh":
+		if name == "" {
+			return "", "", fmt.Errorf("missing experiment name for %s topic", kind)
+		}
+	case "logs":
+		switch name {
+		case "", "phenix", "minimega":
+		default:
+			return "", "", fmt.Errorf("unknown log source %s", name)
+		}
+	default:
+		return "", "", fmt.Errorf("unknown subscription topic %s", topic)
+	}
+
+	return kind, name, nil
+}
+
+// topicAllowed returns true if the given role is allowed to subscribe to the
+// given topic. The checks match those done by the HTTP handlers serving the
+// same information.
+func topicAllowed(role rbac.Role, kind, name string) bool {
+	switch kind {
+	case "experiment":
+		return util.ExperimentAllowed(role, "experiments", "get", name)
+	case "soh":
+		return role.Allowed("vms", "list")
+	}
+
+	return true
+}
+
+// topicMatches returns true if the given resource is included in the given
+// subscription topic.
+func topicMatches(kind, name string, res *Resource) bool {
+	if res == nil {
+		return false
+	}
+
+	switch kind {
+	case "experiment":
+		switch {
+		case res.Type == "experiment":
+			return res.Name == name
+		case strings.HasPrefix(res.Type, "experiment/"):
+			return res.Name == name || strings.HasPrefix(res.Name, name+"/")
+		case strings.HasPrefix(res.Type, "apps/"):
+			return res.Name == name
+		}
+	case "soh":
+		return res.Type == "experiment/soh" && res.Name == name
+	case "logs":
+		return res.Type == "log" && (name == "" || res.Name == name)
+	}
+
+	return false
+}
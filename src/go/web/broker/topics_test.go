@@ -0,0 +1,83 @@
+package broker
+
+import "testing"
+
+func TestParseTopic(t *testing.T) {
+	valid := map[string][2]string{
+		"experiment/foo": {"experiment", "foo"},
+		"soh/foo":        {"soh", "foo"},
+		"logs":           {"logs", ""},
+		"logs/minimega":  {"logs", "minimega"},
+	}
+
+	for topic, expected := range valid {
+		kind, name, err := parseTopic(topic)
+		if err != nil {
+			t.Logf("unexpected error parsing topic %s: %v", topic, err)
+			t.FailNow()
+		}
+
+		if kind != expected[0] || name != expected[1] {
+			t.Logf("expected %v for topic %s, got [%s %s]", expected, topic, kind, name)
+			t.FailNow()
+		}
+	}
+
+	for _, topic := range []string{"experiment", "experiment/", "soh", "logs/foo", "vms/foo"} {
+		if _, _, err := parseTopic(topic); err == nil {
+			t.Logf("expected error parsing topic %s", topic)
+			t.FailNow()
+		}
+	}
+}
+
+func TestClientSubscribed(t *testing.T) {
+	cli := &Client{topics: make(map[string]bool)}
+
+	var (
+		exp      = NewResource("experiment", "foo", "start")
+		vm       = NewVMResource("experiment/vm", "foo", "host-00", "start")
+		other    = NewVMResource("experiment/vm", "foobar", "host-00", "start")
+		app      = NewResource("apps/scorch", "foo", "start")
+		health   = NewResource("experiment/soh", "foo", "update")
+		phenix   = NewResource("log", "phenix", "update")
+		minimega = NewResource("log", "minimega", "update")
+	)
+
+	check := func(res *Resource, expected bool) {
+		if cli.subscribed(res) != expected {
+			t.Logf("expected subscribed to be %v for %+v with topics %v", expected, *res, cli.Topics())
+			t.FailNow()
+		}
+	}
+
+	// Clients without subscriptions receive everything.
+	for _, res := range []*Resource{exp, vm, other, app, health, phenix, minimega} {
+		check(res, true)
+	}
+
+	cli.topics["experiment/foo"] = true
+
+	check(exp, true)
+	check(vm, true)
+	check(other, false)
+	check(app, true)
+	check(health, true)
+	check(phenix, false)
+
+	// VM resources are named the same way for every VM action broadcast.
+	for _, action := range []string{"starting", "errorStarting", "start", "stopping", "errorStopping", "stop", "restarting", "update"} {
+		check(NewVMResource("experiment/vm", "foo", "host-00", action), true)
+		check(NewVMResource("experiment/vm", "foobar", "host-00", action), false)
+	}
+
+	check(NewVMResource("experiment/vm/snapshot", "foo", "host-00", "creating"), true)
+	check(NewVMResource("experiment/vm/screenshot", "foobar", "host-00", "update"), false)
+
+	cli.topics = map[string]bool{"soh/foo": true, "logs/phenix": true}
+
+	check(exp, false)
+	check(health, true)
+	check(phenix, true)
+	check(minimega, false)
+}
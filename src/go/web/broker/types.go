@@ -2,18 +2,76 @@ package broker
 
 import (
 	"encoding/json"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/web/rbac"
+	"phenix/web/util"
 )
 
 type RequestPolicy struct {
 	Resource     string
 	ResourceName string
 	Verb         string
+
+	// Experiment and VM name the experiment (and VM in it) the publication is
+	// about, if any, so policies using label and annotation selectors can be
+	// matched against it.
+	Experiment string
+	VM         string
+
+	// Object is the RBAC object (name, labels, and annotations) for the
+	// experiment or VM. It's looked up when the publication is broadcast, not
+	// by the broker, so slow store and minimega lookups never hold up the
+	// broker. It's included when the policy is shared with other instances.
+	Object *rbac.Object `json:",omitempty"`
 }
 
 func NewRequestPolicy(r, rn, v string) *RequestPolicy {
 	return &RequestPolicy{Resource: r, ResourceName: rn, Verb: v}
 }
 
+// NewExperimentRequestPolicy returns a policy for a publication about the given
+// experiment.
+func NewExperimentRequestPolicy(r, exp, v string) *RequestPolicy {
+	return &RequestPolicy{Resource: r, ResourceName: exp, Verb: v, Experiment: exp}
+}
+
+// NewVMRequestPolicy returns a policy for a publication about the given VM in
+// the given experiment.
+func NewVMRequestPolicy(r, exp, name, v string) *RequestPolicy {
+	return &RequestPolicy{Resource: r, ResourceName: exp + "_" + name, Verb: v, Experiment: exp, VM: name}
+}
+
+// resolve looks up the RBAC object for the experiment or VM the publication
+// is about, if it's about one and the object hasn't been looked up already.
+// The object is left nil if the experiment or VM no longer exists.
+func (this *RequestPolicy) resolve() {
+	if this == nil || this.Experiment == "" || this.Object != nil {
+		return
+	}
+
+	exp, err := experiment.Get(this.Experiment)
+	if err != nil {
+		return
+	}
+
+	var obj rbac.Object
+
+	if this.VM == "" {
+		obj = util.ExperimentObject(*exp)
+	} else {
+		v, err := vm.Get(this.Experiment, this.VM)
+		if err != nil {
+			return
+		}
+
+		obj = util.VMObject(*exp, *v)
+	}
+
+	this.Object = &obj
+}
+
 type Resource struct {
 	Type   string `json:"type"`
 	Name   string `json:"name"`
@@ -24,21 +82,63 @@ func NewResource(t, n, a string) *Resource {
 	return &Resource{Type: t, Name: n, Action: a}
 }
 
+// NewVMResource returns a resource for the given VM in the given experiment.
+// VM resources are always named <exp>/<vm> so clients (and subscriptions) can
+// tell which experiment they're in.
+func NewVMResource(t, exp, vm, a string) *Resource {
+	return &Resource{Type: t, Name: exp + "/" + vm, Action: a}
+}
+
 type Publish struct {
 	RequestPolicy *RequestPolicy  `json:"-"`
+	ID            string          `json:"id,omitempty"`
+	Seq           uint64          `json:"seq,omitempty"`
 	Resource      *Resource       `json:"resource"`
 	Result        json.RawMessage `json:"result"`
+	Error         string          `json:"error,omitempty"`
 }
 
 type Request struct {
+	ID       string          `json:"id,omitempty"`
 	Resource *Resource       `json:"resource"`
 	Payload  json.RawMessage `json:"request"`
 }
 
 /*
+Each publication broadcast to clients includes a sequence number, which
+increases by one with each broadcast. When a client connects, it's first sent
+the broker's epoch, which changes each time phenix is restarted, and the
+current sequence number:
+
+{
+	"resource": {
+		"type": "broker",
+		"name": "<epoch>",
+		"action": "connected"
+	},
+	"result": {
+		"epoch": "<epoch>",
+		"seq": 42
+	}
+}
+
+Clients reconnecting after losing their connection can include the epoch and
+the sequence number of the last publication they received in the WebSocket URL
+(e.g. /api/v1/ws?epoch=<epoch>&resume=42) to be sent the publications they
+missed right after the message above. If the missed publications are no longer
+available, or the epoch doesn't match, the client is instead sent a message
+with the "resync" action and should refresh its state. Since each phenix
+instance has its own epoch, clients that reconnect to a different instance
+(e.g. behind a load balancer) are always told to resync.
+
+Requests can include an ID, which is included in the response to the request
+so clients can correlate the two. Responses to requests that fail include an
+error instead of a result.
+
 Request:
 
 {
+	"id": "<request ID>",
 	"resource": {
 		"type": "experiment/vms",
 		"name": "<exp name>",
@@ -55,6 +155,7 @@ Request:
 Response:
 
 {
+	"id": "<request ID>",
 	"resource": {
 		"type": "experiment/vms",
 		"name": "<exp name>",
@@ -67,6 +168,45 @@ Response:
 	}
 }
 
+Error Response:
+
+{
+	"id": "<request ID>",
+	"resource": {
+		"type": "experiment/vm",
+		"name": "<exp name>/<vm name>",
+		"action": "start"
+	},
+	"error": "forbidden"
+}
+
+Experiment Actions:
+
+	type: experiment, name: <exp name>, action: start|stop
+
+VM Actions:
+
+	type: experiment/vm, name: <exp name>/<vm name>, action: start|stop|restart|redeploy|snapshot
+
+	The redeploy action accepts an optional request of the form
+	{"cpus": 1, "ram": 512, "disk": "<disk>", "injects": true}, and the snapshot
+	action requires a request of the form {"filename": "<snapshot name>"}.
+
+Subscriptions:
+
+	type: subscription, name: <topic>, action: subscribe|unsubscribe|list
+
+	Clients without any subscriptions receive all updates allowed by their role.
+	Once subscribed to one or more topics, clients only receive updates for
+	those topics (in addition to responses to their own requests). Topics are:
+
+	experiment/<exp name>  all updates for an experiment, its VMs, and its apps
+	soh/<exp name>         state of health updates for an experiment
+	logs                   phenix and minimega log updates
+	logs/<source>          log updates for a single source (phenix or minimega)
+
+	The response to each subscription request lists the client's current topics.
+
 Screenshot Updates:
 
 {
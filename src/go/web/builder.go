@@ -207,7 +207,7 @@ func CreateExperimentFromBuilder(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments", "get", req.Name),
+		broker.NewExperimentRequestPolicy("experiments", req.Name, "get"),
 		broker.NewResource("experiment", req.Name, "create"),
 		body,
 	)
@@ -430,7 +430,7 @@ func UpdateExperimentFromBuilder(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments", "get", req.Name),
+		broker.NewExperimentRequestPolicy("experiments", req.Name, "get"),
 		broker.NewResource("experiment", req.Name, action),
 		body,
 	)
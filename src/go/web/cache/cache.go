@@ -18,15 +18,25 @@ const (
 	StatusSnapshotting Status = "snapshotting"
 	StatusRestoring    Status = "restoring"
 	StatusCommitting   Status = "committing"
+
+	// StatusUnknown is returned when trying to lock a key fails for reasons
+	// other than the key already being locked.
+	StatusUnknown Status = "unknown"
 )
 
 var DefaultCache Cache = NewGoCache()
 
+// LocalCache is only ever local to this phenix instance, even when the default
+// cache is shared with other instances. It's used for large values that are
+// cheap to regenerate (like VM screenshots) and not worth sending to Etcd.
+var LocalCache Cache = NewGoCache()
+
 type Cache interface {
 	Get(string) ([]byte, bool)
 	Set(string, []byte) error
 
 	SetWithExpire(string, []byte, time.Duration) error
+	Delete(string) error
 
 	Lock(string, Status, time.Duration) Status
 	Locked(string) Status
@@ -45,6 +55,10 @@ func SetWithExpire(key string, val []byte, exp time.Duration) error {
 	return DefaultCache.SetWithExpire(key, val, exp)
 }
 
+func Delete(key string) error {
+	return DefaultCache.Delete(key)
+}
+
 func Lock(key string, status Status, exp time.Duration) Status {
 	return DefaultCache.Lock(key, status, exp)
 }
@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"go.etcd.io/etcd/v3/clientv3"
+)
+
+// etcdPrefix is prepended to all keys written to Etcd by the cache so they
+// don't collide with keys written by the config store.
+const etcdPrefix = "phenix-cache/"
+
+// etcdTimeout is the maximum amount of time to wait for each Etcd operation.
+const etcdTimeout = 5 * time.Second
+
+// etcdLockTTL is the TTL of the lease each lock is attached to. The lease is
+// kept alive for as long as the lock is held, so this is only how long a lock
+// outlives the instance holding it if that instance goes away.
+const etcdLockTTL = 15 * time.Second
+
+// EtcdCache is a cache shared by all phenix instances using the same Etcd
+// cluster. Values set with an expiration and locks are attached to Etcd leases
+// so they're removed by Etcd when they expire, even if the instance that set
+// them goes away.
+type EtcdCache struct {
+	cli *clientv3.Client
+
+	// locks held by this instance, keyed by lock key
+	locks   map[string]etcdLock
+	locksMu sync.Mutex
+}
+
+// etcdLock is a lock held by this instance.
+type etcdLock struct {
+	lease  clientv3.LeaseID
+	cancel context.CancelFunc
+}
+
+func NewEtcdCache(cli *clientv3.Client) *EtcdCache {
+	return &EtcdCache{cli: cli, locks: make(map[string]etcdLock)}
+}
+
+func (this *EtcdCache) Get(key string) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	resp, err := this.cli.Get(ctx, etcdPrefix+key)
+	if err != nil {
+		log.Error("getting cache key %s from Etcd: %v", key, err)
+		return nil, false
+	}
+
+	if resp.Count == 0 {
+		return nil, false
+	}
+
+	return resp.Kvs[0].Value, true
+}
+
+func (this *EtcdCache) Set(key string, val []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	_, err := this.cli.Put(ctx, etcdPrefix+key, string(val))
+	return err
+}
+
+func (this *EtcdCache) SetWithExpire(key string, val []byte, exp time.Duration) error {
+	if exp <= 0 {
+		return this.Set(key, val)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	lease, err := this.cli.Grant(ctx, leaseTTL(exp))
+	if err != nil {
+		return err
+	}
+
+	_, err = this.cli.Put(ctx, etcdPrefix+key, string(val), clientv3.WithLease(lease.ID))
+	return err
+}
+
+func (this *EtcdCache) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	_, err := this.cli.Delete(ctx, etcdPrefix+key)
+	return err
+}
+
+// Lock ignores the given expiration. Locks are instead attached to a lease that
+// is kept alive until the lock is released, so long running operations don't
+// lose their lock part way through but locks held by an instance that goes
+// away are still released.
+func (this *EtcdCache) Lock(key string, status Status, _ time.Duration) Status {
+	key = etcdPrefix + "LOCK|" + key
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	lease, err := this.cli.Grant(ctx, leaseTTL(etcdLockTTL))
+	if err != nil {
+		log.Error("granting Etcd lease for lock %s: %v", key, err)
+		return StatusUnknown
+	}
+
+	// Only create the lock if it doesn't already exist, otherwise get the status
+	// of the existing lock.
+	resp, err := this.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(status), clientv3.WithLease(lease.ID))).
+		Else(clientv3.OpGet(key)).
+		Commit()
+
+	if err != nil {
+		log.Error("acquiring Etcd lock %s: %v", key, err)
+		this.cli.Revoke(ctx, lease.ID)
+
+		return StatusUnknown
+	}
+
+	if !resp.Succeeded {
+		this.cli.Revoke(ctx, lease.ID)
+
+		// This *might* happen if the lock expires or is deleted between the
+		// comparison and the get.
+		if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			return Status(kvs[0].Value)
+		}
+
+		return ""
+	}
+
+	// Not bound to ctx, since the lease needs to be kept alive well after this
+	// function returns.
+	kaCtx, kaCancel := context.WithCancel(context.Background())
+
+	ch, err := this.cli.KeepAlive(kaCtx, lease.ID)
+	if err != nil {
+		log.Error("keeping Etcd lease for lock %s alive: %v", key, err)
+
+		kaCancel()
+		this.cli.Revoke(ctx, lease.ID)
+
+		return StatusUnknown
+	}
+
+	// The keep alive responses have to be consumed. The channel is closed when
+	// the lock is released or the lease can no longer be kept alive.
+	go func() {
+		for range ch {
+		}
+	}()
+
+	this.locksMu.Lock()
+	defer this.locksMu.Unlock()
+
+	this.locks[key] = etcdLock{lease: lease.ID, cancel: kaCancel}
+
+	return ""
+}
+
+func (this *EtcdCache) Locked(key string) Status {
+	v, ok := this.Get("LOCK|" + key)
+	if !ok {
+		return ""
+	}
+
+	return Status(v)
+}
+
+// Unlock only releases the lock if it's held by this instance, and only if the
+// lock in Etcd is still attached to the lease it was acquired with, so a lock
+// that expired and was acquired again (possibly by another instance) isn't
+// released out from under its new holder.
+func (this *EtcdCache) Unlock(key string) {
+	key = etcdPrefix + "LOCK|" + key
+
+	this.locksMu.Lock()
+	lock, ok := this.locks[key]
+	delete(this.locks, key)
+	this.locksMu.Unlock()
+
+	if !ok {
+		log.Warn("not releasing Etcd lock %s: lock not held by this instance", key)
+		return
+	}
+
+	lock.cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	_, err := this.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.LeaseValue(key), "=", lock.lease)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+
+	if err != nil {
+		log.Error("releasing Etcd lock %s: %v", key, err)
+	}
+
+	// Revoking the lease deletes the lock too, so this also covers the delete
+	// above failing.
+	if _, err := this.cli.Revoke(ctx, lock.lease); err != nil {
+		log.Error("revoking Etcd lease for lock %s: %v", key, err)
+	}
+}
+
+// leaseTTL converts the given expiration to an Etcd lease TTL, which has a
+// granularity of one second.
+func leaseTTL(exp time.Duration) int64 {
+	return int64(math.Max(1, math.Ceil(exp.Seconds())))
+}
@@ -33,6 +33,11 @@ func (this *GoCache) SetWithExpire(key string, val []byte, exp time.Duration) er
 	return nil
 }
 
+func (this *GoCache) Delete(key string) error {
+	this.c.Delete(key)
+	return nil
+}
+
 func (this *GoCache) Lock(key string, status Status, exp time.Duration) Status {
 	key = "LOCK|" + key
 
@@ -0,0 +1,191 @@
+// Package client is a Go client for the phenix web API. The API it supports is
+// documented by the OpenAPI spec served at /api/v1/openapi.json.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// Error is returned for API responses with a non-successful status code.
+type Error struct {
+	Status  int
+	Message string
+	URL     string
+}
+
+func (this Error) Error() string {
+	if this.Message == "" {
+		return fmt.Sprintf("phenix API returned %d %s", this.Status, http.StatusText(this.Status))
+	}
+
+	return fmt.Sprintf("phenix API returned %d: %s", this.Status, this.Message)
+}
+
+type Client struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+type Option func(*Client)
+
+// WithToken sets the JWT or API token used to authenticate requests.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to make requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// New returns a client for the phenix web API at the given endpoint (e.g.
+// `http://localhost:3000`). If phenix is served with a base path, it should be
+// included in the endpoint.
+func New(endpoint string, opts ...Option) *Client {
+	c := &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/") + "/api/v1",
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Token returns the token the client uses to authenticate requests.
+func (this *Client) Token() string {
+	return this.token
+}
+
+// Do makes a request to the given API path (e.g. `/experiments`), encoding the
+// given body as JSON if it's not nil. The response body is decoded into out if
+// it's not nil, using protobuf JSON encoding if out is a protobuf message. Use
+// Request for requests and responses that aren't JSON.
+func (this *Client) Do(method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+
+	if in != nil {
+		var (
+			data []byte
+			err  error
+		)
+
+		if msg, ok := in.(proto.Message); ok {
+			data, err = protojson.Marshal(msg)
+		} else {
+			data, err = json.Marshal(in)
+		}
+
+		if err != nil {
+			return fmt.Errorf("encoding request body: %w", err)
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	resp, err := this.Request(method, path, query, "application/json", body)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+
+	if msg, ok := out.(proto.Message); ok {
+		err = unmarshaler.Unmarshal(data, msg)
+	} else {
+		err = json.Unmarshal(data, out)
+	}
+
+	if err != nil {
+		return fmt.Errorf("decoding response body: %w", err)
+	}
+
+	return nil
+}
+
+// Request makes a request to the given API path with the given body and
+// content type, returning the response if its status code is successful. The
+// caller is responsible for closing the response body.
+func (this *Client) Request(method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	u := this.endpoint + path
+
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if this.token != "" {
+		req.Header.Set("X-phenix-auth-token", "Bearer "+this.token)
+	}
+
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request to %s %s: %w", method, path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+
+	return resp, nil
+}
+
+// responseError converts an unsuccessful response into an Error. The web API
+// responds with either a plain text message or a JSON encoded error.
+func responseError(resp *http.Response) error {
+	err := Error{Status: resp.StatusCode}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	var webErr struct {
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	}
+
+	if json.Unmarshal(body, &webErr) == nil && webErr.Message != "" {
+		err.Message = webErr.Message
+		err.URL = webErr.URL
+	} else {
+		err.Message = strings.TrimSpace(string(body))
+	}
+
+	return err
+}
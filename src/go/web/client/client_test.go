@@ -0,0 +1,75 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"username": "foo", "token": "secret", "role": "Global Admin"}`))
+	})
+
+	mux.HandleFunc("/api/v1/experiments", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-phenix-auth-token") != "Bearer secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		w.Write([]byte(`{"experiments": [{"name": "foo", "vm_count": 2, "running": true}]}`))
+	})
+
+	mux.HandleFunc("/api/v1/experiments/bar", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "unable to get experiment bar", "url": "/api/v1/errors/1234"}`))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := New(server.URL + "/")
+
+	if _, err := c.GetExperiments(); err == nil {
+		t.Log("expected error getting experiments before logging in")
+		t.FailNow()
+	}
+
+	if _, err := c.Login("foo", "bar"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if c.Token() != "secret" {
+		t.Logf("expected token from login to be used, got %s", c.Token())
+		t.FailNow()
+	}
+
+	exps, err := c.GetExperiments()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(exps) != 1 || exps[0].Name != "foo" || exps[0].VmCount != 2 || !exps[0].Running {
+		t.Logf("unexpected experiments %v", exps)
+		t.FailNow()
+	}
+
+	_, err = c.GetExperiment("bar")
+
+	var apiErr Error
+
+	if !errors.As(err, &apiErr) {
+		t.Logf("expected API error, got %v", err)
+		t.FailNow()
+	}
+
+	if apiErr.Status != http.StatusNotFound || apiErr.Message != "unable to get experiment bar" {
+		t.Logf("unexpected API error %+v", apiErr)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"phenix/store"
+)
+
+func configPath(kind, name string) string {
+	return "/configs/" + url.PathEscape(strings.ToLower(kind)) + "/" + url.PathEscape(name)
+}
+
+// GetConfigs returns the configs the client is allowed to list, optionally
+// limited to the given kind.
+func (this *Client) GetConfigs(kind string) ([]store.Config, error) {
+	query := url.Values{}
+
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+
+	var resp struct {
+		Configs []store.Config `json:"configs"`
+	}
+
+	if err := this.Do(http.MethodGet, "/configs", query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Configs, nil
+}
+
+// GetConfig returns the config of the given kind with the given name.
+func (this *Client) GetConfig(kind, name string) (*store.Config, error) {
+	var resp store.Config
+
+	if err := this.Do(http.MethodGet, configPath(kind, name), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// CreateConfig creates the given config.
+func (this *Client) CreateConfig(c store.Config) error {
+	return this.Do(http.MethodPost, "/configs", nil, c, nil)
+}
+
+// UpdateConfig updates the config of the given kind with the given name. The
+// config's name can be changed by the update.
+func (this *Client) UpdateConfig(kind, name string, c store.Config) error {
+	return this.Do(http.MethodPut, configPath(kind, name), nil, c, nil)
+}
+
+// DeleteConfig deletes the config of the given kind with the given name.
+func (this *Client) DeleteConfig(kind, name string) error {
+	return this.Do(http.MethodDelete, configPath(kind, name), nil, nil, nil)
+}
+
+// GetHistory returns history events matching the given event (e.g. by type or
+// metadata), limited to events between since and until if they're not zero.
+func (this *Client) GetHistory(filter store.Event, since, until time.Time) (store.Events, error) {
+	req := struct {
+		store.Event
+
+		Since time.Time `json:"since"`
+		Until time.Time `json:"until"`
+	}{
+		Event: filter,
+		Since: since,
+		Until: until,
+	}
+
+	var resp struct {
+		History store.Events `json:"history"`
+	}
+
+	if err := this.Do(http.MethodPost, "/history", nil, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.History, nil
+}
+
+// GetOpenAPI returns the OpenAPI spec for the web API as JSON.
+func (this *Client) GetOpenAPI() ([]byte, error) {
+	resp, err := this.Request(http.MethodGet, "/openapi.json", nil, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
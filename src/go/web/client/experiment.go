@@ -0,0 +1,112 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+
+	"phenix/web/proto"
+)
+
+// GetExperiments returns the experiments the client is allowed to list.
+func (this *Client) GetExperiments() ([]*proto.Experiment, error) {
+	var resp proto.ExperimentList
+
+	if err := this.Do(http.MethodGet, "/experiments", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Experiments, nil
+}
+
+// GetExperiment returns the experiment with the given name, including its VMs.
+func (this *Client) GetExperiment(name string) (*proto.Experiment, error) {
+	var resp proto.Experiment
+
+	if err := this.Do(http.MethodGet, "/experiments/"+url.PathEscape(name), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// CreateExperiment creates a new experiment.
+func (this *Client) CreateExperiment(req *proto.CreateExperimentRequest) error {
+	return this.Do(http.MethodPost, "/experiments", nil, req, nil)
+}
+
+// DeleteExperiment deletes the experiment with the given name.
+func (this *Client) DeleteExperiment(name string) error {
+	return this.Do(http.MethodDelete, "/experiments/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// StartExperiment starts the experiment with the given name.
+func (this *Client) StartExperiment(name string) (*proto.Experiment, error) {
+	var resp proto.Experiment
+
+	if err := this.Do(http.MethodPost, "/experiments/"+url.PathEscape(name)+"/start", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// StopExperiment stops the experiment with the given name.
+func (this *Client) StopExperiment(name string) (*proto.Experiment, error) {
+	var resp proto.Experiment
+
+	if err := this.Do(http.MethodPost, "/experiments/"+url.PathEscape(name)+"/stop", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// GetExperimentSchedule returns the hosts the VMs in the given experiment are
+// scheduled on.
+func (this *Client) GetExperimentSchedule(name string) ([]*proto.Schedule, error) {
+	var resp proto.ExperimentSchedule
+
+	if err := this.Do(http.MethodGet, "/experiments/"+url.PathEscape(name)+"/schedule", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Schedule, nil
+}
+
+// ScheduleExperiment schedules the VMs in the given experiment using the given
+// scheduling algorithm.
+func (this *Client) ScheduleExperiment(name, algorithm string) ([]*proto.Schedule, error) {
+	var (
+		req  = &proto.UpdateScheduleRequest{Algorithm: algorithm}
+		resp proto.ExperimentSchedule
+	)
+
+	if err := this.Do(http.MethodPost, "/experiments/"+url.PathEscape(name)+"/schedule", nil, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Schedule, nil
+}
+
+// GetTopologies returns the names of the topologies the client is allowed to
+// list.
+func (this *Client) GetTopologies() ([]string, error) {
+	var resp proto.TopologyList
+
+	if err := this.Do(http.MethodGet, "/topologies", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Topologies, nil
+}
+
+// GetApplications returns the names of the apps the client is allowed to list.
+func (this *Client) GetApplications() ([]string, error) {
+	var resp proto.AppList
+
+	if err := this.Do(http.MethodGet, "/applications", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Applications, nil
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	v1 "phenix/types/version/v1"
+	"phenix/web/proto"
+)
+
+// Login logs in with the given username and password. The token returned is
+// used to authenticate subsequent requests made by the client.
+func (this *Client) Login(user, pass string) (*proto.LoginResponse, error) {
+	var (
+		req  = &proto.LoginRequest{User: user, Pass: pass}
+		resp proto.LoginResponse
+	)
+
+	if err := this.Do(http.MethodPost, "/login", nil, req, &resp); err != nil {
+		return nil, err
+	}
+
+	this.token = resp.Token
+
+	return &resp, nil
+}
+
+// GetUsers returns the users the client is allowed to list.
+func (this *Client) GetUsers() ([]*proto.User, error) {
+	var resp proto.UserList
+
+	if err := this.Do(http.MethodGet, "/users", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Users, nil
+}
+
+// GetUser returns the user with the given username.
+func (this *Client) GetUser(username string) (*proto.User, error) {
+	var resp proto.User
+
+	if err := this.Do(http.MethodGet, "/users/"+url.PathEscape(username), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// CreateUser creates a new user.
+func (this *Client) CreateUser(req *proto.CreateUserRequest) (*proto.User, error) {
+	var resp proto.User
+
+	if err := this.Do(http.MethodPost, "/users", nil, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// DeleteUser deletes the user with the given username.
+func (this *Client) DeleteUser(username string) error {
+	return this.Do(http.MethodDelete, "/users/"+url.PathEscape(username), nil, nil, nil)
+}
+
+// APIToken is a newly created API token. The token itself is only available
+// when it's created.
+type APIToken struct {
+	ID      string    `json:"id"`
+	Token   string    `json:"token"`
+	Desc    string    `json:"desc"`
+	Expires time.Time `json:"exp"`
+}
+
+// GetUserTokens returns the details of the given user's API tokens.
+func (this *Client) GetUserTokens(username string) ([]v1.APITokenSpec, error) {
+	var resp struct {
+		Tokens []v1.APITokenSpec `json:"tokens"`
+	}
+
+	if err := this.Do(http.MethodGet, "/users/"+url.PathEscape(username)+"/tokens", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Tokens, nil
+}
+
+// CreateUserToken creates an API token for the given user that expires after
+// the given lifetime. If policies are provided, they limit what the token is
+// allowed to do beyond what the user's role already allows.
+func (this *Client) CreateUserToken(username string, lifetime time.Duration, desc string, policies ...*v1.PolicySpec) (*APIToken, error) {
+	req := struct {
+		Lifetime string           `json:"lifetime"`
+		Desc     string           `json:"desc"`
+		Policies []*v1.PolicySpec `json:"policies,omitempty"`
+	}{
+		Lifetime: lifetime.String(),
+		Desc:     desc,
+		Policies: policies,
+	}
+
+	var resp APIToken
+
+	if err := this.Do(http.MethodPost, "/users/"+url.PathEscape(username)+"/tokens", nil, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// DeleteUserToken revokes the API token with the given ID for the given user.
+func (this *Client) DeleteUserToken(username, id string) error {
+	return this.Do(http.MethodDelete, "/users/"+url.PathEscape(username)+"/tokens/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"phenix/web/proto"
+)
+
+func vmPath(exp, name string) string {
+	return "/experiments/" + url.PathEscape(exp) + "/vms/" + url.PathEscape(name)
+}
+
+// GetVMs returns the VMs in the given experiment.
+func (this *Client) GetVMs(exp string) ([]*proto.VM, error) {
+	var resp proto.VMList
+
+	if err := this.Do(http.MethodGet, "/experiments/"+url.PathEscape(exp)+"/vms", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Vms, nil
+}
+
+// GetVM returns the given VM in the given experiment.
+func (this *Client) GetVM(exp, name string) (*proto.VM, error) {
+	return this.vmAction(http.MethodGet, vmPath(exp, name), nil)
+}
+
+// UpdateVM updates the given VM (e.g. its CPUs, memory, or disk) in a stopped
+// experiment, or its interface VLAN in a running experiment.
+func (this *Client) UpdateVM(req *proto.UpdateVMRequest) (*proto.VM, error) {
+	return this.vmAction(http.MethodPatch, vmPath(req.Exp, req.Name), req)
+}
+
+// StartVM starts (unpauses) the given VM in a running experiment.
+func (this *Client) StartVM(exp, name string) (*proto.VM, error) {
+	return this.vmAction(http.MethodPost, vmPath(exp, name)+"/start", nil)
+}
+
+// StopVM stops (pauses) the given VM in a running experiment.
+func (this *Client) StopVM(exp, name string) (*proto.VM, error) {
+	return this.vmAction(http.MethodPost, vmPath(exp, name)+"/stop", nil)
+}
+
+// RestartVM restarts the given VM in a running experiment.
+func (this *Client) RestartVM(exp, name string) (*proto.VM, error) {
+	return this.vmAction(http.MethodGet, vmPath(exp, name)+"/restart", nil)
+}
+
+// DeleteVM kills the given VM in a running experiment.
+func (this *Client) DeleteVM(exp, name string) error {
+	return this.Do(http.MethodDelete, vmPath(exp, name), nil, nil, nil)
+}
+
+// GetScreenshot returns a PNG screenshot of the given VM, scaled to the given
+// size if it's not empty.
+func (this *Client) GetScreenshot(exp, name, size string) ([]byte, error) {
+	query := url.Values{}
+
+	if size != "" {
+		query.Set("size", size)
+	}
+
+	resp, err := this.Request(http.MethodGet, vmPath(exp, name)+"/screenshot.png", query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (this *Client) vmAction(method, path string, req *proto.UpdateVMRequest) (*proto.VM, error) {
+	var (
+		resp proto.VM
+		body interface{}
+	)
+
+	// Avoid passing a typed nil as the request body.
+	if req != nil {
+		body = req
+	}
+
+	if err := this.Do(method, path, nil, body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
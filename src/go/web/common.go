@@ -2,10 +2,13 @@ package web
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,8 +18,11 @@ import (
 	"phenix/types"
 	"phenix/util/mm"
 	"phenix/util/notes"
+	"phenix/util/plog"
 	"phenix/web/broker"
 	"phenix/web/cache"
+	"phenix/web/proto"
+	"phenix/web/rbac"
 	"phenix/web/util"
 	"phenix/web/weberror"
 
@@ -27,9 +33,19 @@ var (
 	// Track context cancelers and wait groups for periodically running apps.
 	cancelers = make(map[string][]context.CancelFunc)
 	waiters   = make(map[string]*sync.WaitGroup)
+
+	// Sort keys supported by list endpoints.
+	experimentSortKeys = []string{"name", "topology", "scenario", "start_time", "running", "vm_count"}
+	vmSortKeys         = []string{"name", "host", "uptime", "cpus", "ram", "disk", "state"}
+	configSortKeys     = []string{"kind", "name", "created", "updated"}
 )
 
-func startExperiment(name string) ([]byte, error) {
+func startExperiment(ctx context.Context, name string) (*proto.Experiment, error) {
+	// We don't want to use the request's context for starting the experiment or
+	// running periodic apps, but we do want to keep its logging fields so logs
+	// can be correlated with the request that started the experiment.
+	ctx = plog.WithExperiment(plog.Detach(ctx), name)
+
 	if err := cache.LockExperimentForStarting(name); err != nil {
 		err := weberror.NewWebError(err, "unable to lock experiment %s for starting", name)
 		return nil, err.SetStatus(http.StatusConflict)
@@ -38,7 +54,7 @@ func startExperiment(name string) ([]byte, error) {
 	defer cache.UnlockExperiment(name)
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments/start", "update", name),
+		broker.NewExperimentRequestPolicy("experiments/start", name, "update"),
 		broker.NewResource("experiment", name, "starting"),
 		nil,
 	)
@@ -51,8 +67,7 @@ func startExperiment(name string) ([]byte, error) {
 	status := make(chan result)
 
 	go func() {
-		// We don't want to use the HTTP request's context here.
-		ctx, cancel := context.WithCancel(context.Background())
+		ctx, cancel := context.WithCancel(ctx)
 		cancelers[name] = append(cancelers[name], cancel)
 
 		ctx = notes.Context(ctx, false)
@@ -90,14 +105,14 @@ func startExperiment(name string) ([]byte, error) {
 
 			go func() {
 				for err := range ch {
-					log.Warn("delayed error starting experiment %s: %v", name, err)
+					plog.Warn(ctx, "delayed error starting experiment", "error", err)
 
 					var delayErr experiment.DelayedVMError
 
 					if errors.As(err, &delayErr) {
 						broker.Broadcast(
-							broker.NewRequestPolicy("experiments/start", "update", name),
-							broker.NewResource("experiment/vm", fmt.Sprintf("%s/%s", name, delayErr.VM), "error"),
+							broker.NewExperimentRequestPolicy("experiments/start", name, "update"),
+							broker.NewVMResource("experiment/vm", name, delayErr.VM, "error"),
 							json.RawMessage(fmt.Sprintf(`{"error": "unable to start delayed VM %s"}`, delayErr.VM)),
 						)
 					}
@@ -121,7 +136,7 @@ func startExperiment(name string) ([]byte, error) {
 		case s := <-status:
 			if s.err != nil {
 				broker.Broadcast(
-					broker.NewRequestPolicy("experiments/start", "update", name),
+					broker.NewExperimentRequestPolicy("experiments/start", name, "update"),
 					broker.NewResource("experiment", name, "errorStarting"),
 					nil,
 				)
@@ -130,8 +145,7 @@ func startExperiment(name string) ([]byte, error) {
 				return nil, err.SetStatus(http.StatusBadRequest)
 			}
 
-			// We don't want to use the HTTP request's context here.
-			ctx, cancel := context.WithCancel(context.Background())
+			ctx, cancel := context.WithCancel(ctx)
 			cancelers[name] = append(cancelers[name], cancel)
 
 			var wg sync.WaitGroup
@@ -142,7 +156,7 @@ func startExperiment(name string) ([]byte, error) {
 				delete(cancelers, name)
 				delete(waiters, name)
 
-				fmt.Printf("Error scheduling experiment apps to run periodically: %v\n", err)
+				plog.Error(ctx, "scheduling experiment apps to run periodically", "error", err)
 			}
 
 			vms, err := vm.List(name)
@@ -151,19 +165,21 @@ func startExperiment(name string) ([]byte, error) {
 				log.Error("listing VMs in experiment %s - %v", name, err)
 			}
 
-			body, err := marshaler.Marshal(util.ExperimentToProtobuf(*s.exp, "", vms))
+			pb := util.ExperimentToProtobuf(*s.exp, "", vms)
+
+			body, err := marshaler.Marshal(pb)
 			if err != nil {
 				err := weberror.NewWebError(err, "unable to start experiment %s", name)
 				return nil, err.SetStatus(http.StatusInternalServerError)
 			}
 
 			broker.Broadcast(
-				broker.NewRequestPolicy("experiments/start", "update", name),
+				broker.NewExperimentRequestPolicy("experiments/start", name, "update"),
 				broker.NewResource("experiment", name, "start"),
 				body,
 			)
 
-			return body, nil
+			return pb, nil
 		default:
 			p, err := mm.GetLaunchProgress(name, count)
 			if err != nil {
@@ -184,7 +200,7 @@ func startExperiment(name string) ([]byte, error) {
 			marshalled, _ := json.Marshal(status)
 
 			broker.Broadcast(
-				broker.NewRequestPolicy("experiments/start", "update", name),
+				broker.NewExperimentRequestPolicy("experiments/start", name, "update"),
 				broker.NewResource("experiment", name, "progress"),
 				marshalled,
 			)
@@ -194,7 +210,9 @@ func startExperiment(name string) ([]byte, error) {
 	}
 }
 
-func stopExperiment(name string) ([]byte, error) {
+func stopExperiment(ctx context.Context, name string) (*proto.Experiment, error) {
+	ctx = plog.WithExperiment(plog.Detach(ctx), name)
+
 	if err := cache.LockExperimentForStopping(name); err != nil {
 		err := weberror.NewWebError(err, "unable to lock experiment %s for stopping", name)
 		return nil, err.SetStatus(http.StatusConflict)
@@ -203,7 +221,7 @@ func stopExperiment(name string) ([]byte, error) {
 	defer cache.UnlockExperiment(name)
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments/stop", "update", name),
+		broker.NewExperimentRequestPolicy("experiments/stop", name, "update"),
 		broker.NewResource("experiment", name, "stopping"),
 		nil,
 	)
@@ -221,9 +239,9 @@ func stopExperiment(name string) ([]byte, error) {
 	delete(cancelers, name)
 	delete(waiters, name)
 
-	if err := experiment.Stop(name); err != nil {
+	if err := experiment.Stop(ctx, name); err != nil {
 		broker.Broadcast(
-			broker.NewRequestPolicy("experiments/stop", "update", name),
+			broker.NewExperimentRequestPolicy("experiments/stop", name, "update"),
 			broker.NewResource("experiment", name, "errorStopping"),
 			nil,
 		)
@@ -242,17 +260,759 @@ func stopExperiment(name string) ([]byte, error) {
 		// TODO
 	}
 
-	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	pb := util.ExperimentToProtobuf(*exp, "", vms)
+
+	body, err := marshaler.Marshal(pb)
 	if err != nil {
 		err := weberror.NewWebError(err, "unable to stop experiment %s", name)
 		return nil, err.SetStatus(http.StatusInternalServerError)
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments/stop", "update", name),
+		broker.NewExperimentRequestPolicy("experiments/stop", name, "update"),
 		broker.NewResource("experiment", name, "stop"),
 		body,
 	)
 
-	return body, nil
+	return pb, nil
+}
+
+// The functions below are shared by the REST and gRPC handlers. Callers are
+// responsible for checking the user is allowed to act on the given experiment or
+// VM before calling them.
+
+// listExperiments returns the requested page of experiments the given role is
+// allowed to list, along with the total number of experiments matching the
+// given list options. Screenshots of the given size are included for running
+// VMs in the page of experiments if size isn't empty.
+func listExperiments(role rbac.Role, opts util.ListOptions, size string) ([]*proto.Experiment, int, error) {
+	experiments, err := experiment.List()
+	if err != nil {
+		log.Error("getting experiments - %v", err)
+	}
+
+	type listed struct {
+		exp    types.Experiment
+		status cache.Status
+		vms    mm.VMs
+	}
+
+	var allowed []listed
+
+	for _, exp := range experiments {
+		if !role.AllowedObjects("experiments", "list", util.ExperimentObject(exp)) {
+			continue
+		}
+
+		// This will happen if another handler is currently acting on the
+		// experiment.
+		status := cache.IsExperimentLocked(exp.Metadata.Name)
+
+		if status == "" {
+			if exp.Running() {
+				status = cache.StatusStarted
+			} else {
+				status = cache.StatusStopped
+			}
+		}
+
+		if !opts.Match(exp.Metadata.Name, exp.Metadata.Annotations["topology"], exp.Metadata.Annotations["scenario"], string(status)) {
+			continue
+		}
+
+		// TODO: limit per-experiment VMs based on RBAC
+
+		vms, err := vm.List(exp.Spec.ExperimentName())
+		if err != nil {
+			// TODO
+		}
+
+		allowed = append(allowed, listed{exp: exp, status: status, vms: vms})
+	}
+
+	opts.SortWith(allowed, map[string]func(int, int) bool{
+		"name": func(i, j int) bool {
+			return strings.ToLower(allowed[i].exp.Metadata.Name) < strings.ToLower(allowed[j].exp.Metadata.Name)
+		},
+		"topology": func(i, j int) bool {
+			return allowed[i].exp.Metadata.Annotations["topology"] < allowed[j].exp.Metadata.Annotations["topology"]
+		},
+		"scenario": func(i, j int) bool {
+			return allowed[i].exp.Metadata.Annotations["scenario"] < allowed[j].exp.Metadata.Annotations["scenario"]
+		},
+		"start_time": func(i, j int) bool {
+			return allowed[i].exp.Status.StartTime() < allowed[j].exp.Status.StartTime()
+		},
+		"running": func(i, j int) bool {
+			return !allowed[i].exp.Running() && allowed[j].exp.Running()
+		},
+		"vm_count": func(i, j int) bool {
+			return len(allowed[i].vms) < len(allowed[j].vms)
+		},
+	})
+
+	start, end := opts.Page(len(allowed))
+	page := make([]*proto.Experiment, 0, end-start)
+
+	for _, l := range allowed[start:end] {
+		if l.exp.Running() && size != "" {
+			for i, v := range l.vms {
+				if !v.Running {
+					continue
+				}
+
+				screenshot, err := util.GetScreenshot(l.exp.Spec.ExperimentName(), v.Name, size)
+				if err != nil {
+					log.Error("getting screenshot - %v", err)
+					continue
+				}
+
+				v.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+
+				l.vms[i] = v
+			}
+		}
+
+		page = append(page, util.ExperimentToProtobuf(l.exp, l.status, l.vms))
+	}
+
+	return page, len(allowed), nil
+}
+
+func createExperiment(ctx context.Context, req *proto.CreateExperimentRequest) (*proto.Experiment, error) {
+	if err := cache.LockExperimentForCreation(req.Name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock experiment %s for creation", req.Name)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockExperiment(req.Name)
+
+	opts := []experiment.CreateOption{
+		experiment.CreateWithName(req.Name),
+		experiment.CreateWithTopology(req.Topology),
+		experiment.CreateWithScenario(req.Scenario),
+		experiment.CreateWithVLANMin(int(req.VlanMin)),
+		experiment.CreateWithVLANMax(int(req.VlanMax)),
+	}
+
+	if req.WorkflowBranch != "" {
+		annotations := map[string]string{"phenix.workflow/branch": req.WorkflowBranch}
+		opts = append(opts, experiment.CreateWithAnnotations(annotations))
+	}
+
+	if err := experiment.Create(ctx, opts...); err != nil {
+		return nil, weberror.NewWebError(err, "unable to create experiment %s", req.Name)
+	}
+
+	if warns := notes.Warnings(ctx, true); warns != nil {
+		for _, warn := range warns {
+			log.Warn("%v", warn)
+		}
+	}
+
+	exp, err := experiment.Get(req.Name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", req.Name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	vms, err := vm.List(req.Name)
+	if err != nil {
+		// TODO
+		log.Error("listing VMs in experiment %s - %v", req.Name, err)
+	}
+
+	pb := util.ExperimentToProtobuf(*exp, "", vms)
+
+	body, err := marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling experiment %s", req.Name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		broker.NewExperimentRequestPolicy("experiments", req.Name, "get"),
+		broker.NewResource("experiment", req.Name, "create"),
+		body,
+	)
+
+	return pb, nil
+}
+
+func deleteExperiment(name string) error {
+	if err := cache.LockExperimentForDeletion(name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock experiment %s for deletion", name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockExperiment(name)
+
+	if err := experiment.Delete(name); err != nil {
+		return weberror.NewWebError(err, "unable to delete experiment %s", name)
+	}
+
+	broker.Broadcast(
+		broker.NewExperimentRequestPolicy("experiments", name, "delete"),
+		broker.NewResource("experiment", name, "delete"),
+		nil,
+	)
+
+	return nil
+}
+
+// listVMs returns the experiment along with the requested page of VMs in it
+// the given role is allowed to list and the total number of VMs matching the
+// given list options. Screenshots of the given size are included for running
+// VMs in the page if size isn't empty.
+func listVMs(role rbac.Role, expName string, opts util.ListOptions, size string) (*types.Experiment, mm.VMs, int, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, nil, 0, weberror.NewWebError(err, "unable to get experiment %s", expName)
+	}
+
+	vms, err := vm.List(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to list VMs in experiment %s", expName)
+		return nil, nil, 0, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	allowed := mm.VMs{}
+
+	for _, vm := range vms {
+		if role.AllowedObjects("vms", "list", util.VMObject(*exp, vm)) && opts.MatchVM(vm) {
+			allowed = append(allowed, vm)
+		}
+	}
+
+	opts.SortWith(allowed, vmLessFuncs(allowed))
+
+	start, end := opts.Page(len(allowed))
+	page := allowed[start:end]
+
+	if size != "" {
+		for i, vm := range page {
+			if !vm.Running {
+				continue
+			}
+
+			screenshot, err := util.GetScreenshot(expName, vm.Name, size)
+			if err != nil {
+				log.Error("getting screenshot: %v", err)
+			} else {
+				page[i].Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+			}
+		}
+	}
+
+	return exp, page, len(allowed), nil
+}
+
+// vmLessFuncs returns the functions used to sort the given VMs, keyed by the
+// sort keys supported by VM list endpoints.
+func vmLessFuncs(vms mm.VMs) map[string]func(int, int) bool {
+	return map[string]func(int, int) bool{
+		"name": func(i, j int) bool {
+			return strings.ToLower(vms[i].Name) < strings.ToLower(vms[j].Name)
+		},
+		"host": func(i, j int) bool {
+			return strings.ToLower(vms[i].Host) < strings.ToLower(vms[j].Host)
+		},
+		"uptime": func(i, j int) bool {
+			return vms[i].Uptime < vms[j].Uptime
+		},
+		"cpus": func(i, j int) bool {
+			return vms[i].CPUs < vms[j].CPUs
+		},
+		"ram": func(i, j int) bool {
+			return vms[i].RAM < vms[j].RAM
+		},
+		"disk": func(i, j int) bool {
+			return strings.ToLower(vms[i].Disk) < strings.ToLower(vms[j].Disk)
+		},
+		"state": func(i, j int) bool {
+			return vms[i].State < vms[j].State
+		},
+	}
+}
+
+func getVM(expName, name, size string) (*proto.VM, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, weberror.NewWebError(err, "unable to get experiment %s", expName)
+	}
+
+	vm, err := vm.Get(expName, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if vm.Running && size != "" {
+		screenshot, err := util.GetScreenshot(expName, name, size)
+		if err != nil {
+			log.Error("getting screenshot: %v", err)
+		} else {
+			vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+		}
+	}
+
+	return util.VMToProtobuf(expName, *vm, exp.Spec.Topology()), nil
+}
+
+func killVM(ctx context.Context, expName, name string) error {
+	ctx = vmContext(ctx, expName, name)
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return weberror.NewWebError(err, "unable to get experiment %s", expName)
+	}
+
+	if !exp.Running() {
+		return weberror.NewWebError(nil, "experiment %s not running", expName)
+	}
+
+	if err := mm.KillVM(mm.NS(expName), mm.VMName(name)); err != nil {
+		plog.Error(ctx, "killing VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to kill VM %s in experiment %s", name, expName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "killed VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms", expName, name, "delete"),
+		broker.NewVMResource("experiment/vm", expName, name, "delete"),
+		nil,
+	)
+
+	return nil
+}
+
+func startVM(ctx context.Context, expName, name string) (*proto.VM, error) {
+	ctx = vmContext(ctx, expName, name)
+
+	if err := cache.LockVMForStarting(expName, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s in experiment %s for starting", name, expName)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/start", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "starting"),
+		nil,
+	)
+
+	if err := mm.StartVM(mm.NS(expName), mm.VMName(name)); err != nil {
+		broker.Broadcast(
+			broker.NewVMRequestPolicy("vms/start", expName, name, "update"),
+			broker.NewVMResource("experiment/vm", expName, name, "errorStarting"),
+			nil,
+		)
+
+		plog.Error(ctx, "starting VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to start VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	pb, err := getVM(expName, name, "215")
+	if err != nil {
+		broker.Broadcast(
+			broker.NewVMRequestPolicy("vms/start", expName, name, "update"),
+			broker.NewVMResource("experiment/vm", expName, name, "errorStarting"),
+			nil,
+		)
+
+		return nil, err
+	}
+
+	body, err := marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "started VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/start", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "start"),
+		body,
+	)
+
+	return pb, nil
+}
+
+func stopVM(ctx context.Context, expName, name string) (*proto.VM, error) {
+	ctx = vmContext(ctx, expName, name)
+
+	if err := cache.LockVMForStopping(expName, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s in experiment %s for stopping", name, expName)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/stop", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "stopping"),
+		nil,
+	)
+
+	if err := mm.StopVM(mm.NS(expName), mm.VMName(name)); err != nil {
+		broker.Broadcast(
+			broker.NewVMRequestPolicy("vms/stop", expName, name, "update"),
+			broker.NewVMResource("experiment/vm", expName, name, "errorStopping"),
+			nil,
+		)
+
+		plog.Error(ctx, "stopping VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to stop VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	pb, err := getVM(expName, name, "")
+	if err != nil {
+		broker.Broadcast(
+			broker.NewVMRequestPolicy("vms/stop", expName, name, "update"),
+			broker.NewVMResource("experiment/vm", expName, name, "errorStopping"),
+			nil,
+		)
+
+		return nil, err
+	}
+
+	body, err := marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "stopped VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/stop", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "stop"),
+		body,
+	)
+
+	return pb, nil
+}
+
+func restartVM(ctx context.Context, expName, name string) (*proto.VM, error) {
+	ctx = vmContext(ctx, expName, name)
+
+	if err := cache.LockVMForStarting(expName, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s in experiment %s for restarting", name, expName)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/restart", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "restarting"),
+		nil,
+	)
+
+	if err := vm.Restart(expName, name); err != nil {
+		plog.Error(ctx, "restarting VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to restart VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	pb, err := getVM(expName, name, "215")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "restarted VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/restart", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "update"),
+		body,
+	)
+
+	return pb, nil
+}
+
+func shutdownVM(ctx context.Context, expName, name string) (*proto.VM, error) {
+	ctx = vmContext(ctx, expName, name)
+
+	if err := cache.LockVMForStopping(expName, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s in experiment %s for shutdown", name, expName)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	if err := vm.Shutdown(expName, name); err != nil {
+		plog.Error(ctx, "shutting down VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to shutdown VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	pb, err := getVM(expName, name, "")
+	if err != nil {
+		return nil, err
+	}
+
+	pb.Running = false
+
+	body, err := marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "shut down VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/shutdown", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "shutdown"),
+		body,
+	)
+
+	return pb, nil
+}
+
+// redeployVM redeploys the given VM. If the given request is nil, the VM is
+// redeployed using its current CPU, memory, and disk settings.
+func redeployVM(ctx context.Context, expName, name string, req *proto.VMRedeployRequest, inject bool) (*proto.VM, error) {
+	ctx = vmContext(ctx, expName, name)
+
+	if err := cache.LockVMForRedeploying(expName, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s in experiment %s for redeploying", name, expName)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", expName)
+		return nil, err.SetStatus(http.StatusBadRequest)
+	}
+
+	v, err := vm.Get(expName, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	v.Busy = true
+
+	body, _ := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/redeploy", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "redeploying"),
+		body,
+	)
+
+	opts := []vm.RedeployOption{
+		vm.CPU(v.CPUs),
+		vm.Memory(v.RAM),
+		vm.Disk(v.Disk),
+		vm.Inject(inject),
+	}
+
+	if req != nil {
+		opts = []vm.RedeployOption{
+			vm.CPU(int(req.Cpus)),
+			vm.Memory(int(req.Ram)),
+			vm.Disk(req.Disk),
+			vm.Inject(req.Injects),
+		}
+	}
+
+	redeployed := make(chan error, 1)
+
+	go func() {
+		redeployed <- vm.Redeploy(expName, name, opts...)
+	}()
+
+	// HACK: mandatory sleep time to make it seem like a redeploy is
+	// happening client-side, even when the redeploy is fast (like for
+	// Linux VMs).
+	time.Sleep(5 * time.Second)
+
+	if err := <-redeployed; err != nil {
+		broker.Broadcast(
+			broker.NewVMRequestPolicy("vms/redeploy", expName, name, "update"),
+			broker.NewVMResource("experiment/vm", expName, name, "errorRedeploying"),
+			nil,
+		)
+
+		plog.Error(ctx, "redeploying VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to redeploy VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	// Get the VM details again since redeploying may have changed them.
+	pb, err := getVM(expName, name, "215")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err = marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling VM %s in experiment %s", name, expName)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "redeployed VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/redeploy", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "redeployed"),
+		body,
+	)
+
+	return pb, nil
+}
+
+func snapshotVM(ctx context.Context, expName, name, filename string) error {
+	ctx = vmContext(ctx, expName, name)
+
+	if err := cache.LockVMForSnapshotting(expName, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s in experiment %s for snapshotting", name, expName)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/snapshots", expName, name, "create"),
+		broker.NewVMResource("experiment/vm/snapshot", expName, name, "creating"),
+		nil,
+	)
+
+	status := make(chan string)
+
+	go func() {
+		for {
+			s := <-status
+
+			if s == "completed" {
+				return
+			}
+
+			progress, err := strconv.ParseFloat(s, 64)
+			if err == nil {
+				plog.Info(ctx, "snapshot progress", "percent", progress)
+
+				status := map[string]interface{}{
+					"percent": progress / 100,
+				}
+
+				marshalled, _ := json.Marshal(status)
+
+				broker.Broadcast(
+					broker.NewVMRequestPolicy("vms/snapshots", expName, name, "create"),
+					broker.NewVMResource("experiment/vm/snapshot", expName, name, "progress"),
+					marshalled,
+				)
+			}
+		}
+	}()
+
+	cb := func(s string) { status <- s }
+
+	if err := vm.Snapshot(expName, name, filename, cb); err != nil {
+		broker.Broadcast(
+			broker.NewVMRequestPolicy("vms/snapshots", expName, name, "create"),
+			broker.NewVMResource("experiment/vm/snapshot", expName, name, "errorCreating"),
+			nil,
+		)
+
+		plog.Error(ctx, "snapshotting VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to snapshot VM %s in experiment %s", name, expName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "snapshotted VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/snapshots", expName, name, "create"),
+		broker.NewVMResource("experiment/vm/snapshot", expName, name, "create"),
+		nil,
+	)
+
+	return nil
+}
+
+func restoreVM(ctx context.Context, expName, name, snap string) error {
+	ctx = vmContext(ctx, expName, name)
+
+	if err := cache.LockVMForRestoring(expName, name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock VM %s in experiment %s for restoring", name, expName)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/snapshots", expName, name, "create"),
+		broker.NewVMResource("experiment/vm/snapshot", expName, name, "restoring"),
+		nil,
+	)
+
+	if err := vm.Restore(expName, name, snap); err != nil {
+		broker.Broadcast(
+			broker.NewVMRequestPolicy("vms/snapshots", expName, name, "create"),
+			broker.NewVMResource("experiment/vm/snapshot", expName, name, "errorRestoring"),
+			nil,
+		)
+
+		plog.Error(ctx, "restoring VM", "error", err)
+
+		err := weberror.NewWebError(err, "unable to restore VM %s in experiment %s", name, expName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info(ctx, "restored VM")
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/snapshots", expName, name, "create"),
+		broker.NewVMResource("experiment/vm/snapshot", expName, name, "restore"),
+		nil,
+	)
+
+	return nil
+}
+
+// httpError writes the given error to the response, using the error's status
+// if it's a web error.
+func httpError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+
+	var web *weberror.WebError
+
+	if errors.As(err, &web) {
+		status = web.Status
+	}
+
+	http.Error(w, err.Error(), status)
+}
+
+// vmContext returns a copy of the given context with the given experiment and
+// VM names added to the fields logged with it.
+func vmContext(ctx context.Context, expName, name string) context.Context {
+	return plog.WithVM(plog.WithExperiment(ctx, expName), name)
 }
@@ -47,30 +47,59 @@ func GetConfigs(w http.ResponseWriter, r *http.Request) error {
 		kind = "all"
 	}
 
+	opts, err := util.ParseListOptions(query, configSortKeys...)
+	if err != nil {
+		return weberror.NewWebError(err, "invalid list options")
+	}
+
 	configs, err := config.List(kind)
 	if err != nil {
 		return weberror.NewWebError(err, "unable to get configs from store")
 	}
 
-	var allowed []store.Config
+	allowed := []store.Config{}
 
 	for _, cfg := range configs {
 		if !role.Allowed("config", "list", cfg.FullName()) {
 			continue
 		}
 
+		if !opts.Match(cfg.Kind, cfg.Metadata.Name) {
+			continue
+		}
+
 		cfg.Spec = nil
 		cfg.Status = nil
 
 		allowed = append(allowed, cfg)
 	}
 
-	body, err := json.Marshal(util.WithRoot("configs", allowed))
+	opts.SortWith(allowed, map[string]func(int, int) bool{
+		"kind":    func(i, j int) bool { return allowed[i].Kind < allowed[j].Kind },
+		"name":    func(i, j int) bool { return allowed[i].Metadata.Name < allowed[j].Metadata.Name },
+		"created": func(i, j int) bool { return allowed[i].Metadata.Created < allowed[j].Metadata.Created },
+		"updated": func(i, j int) bool { return allowed[i].Metadata.Updated < allowed[j].Metadata.Updated },
+	})
+
+	start, end := opts.Page(len(allowed))
+
+	resp := map[string]interface{}{
+		"configs":     allowed[start:end],
+		"total":       len(allowed),
+		"next_cursor": opts.NextCursor(len(allowed)),
+	}
+
+	body, err := json.Marshal(resp)
 	if err != nil {
 		err := weberror.NewWebError(err, "unable to process configs")
 		return err.SetStatus(http.StatusInternalServerError)
 	}
 
+	if body, err = opts.SelectFields(body, "configs"); err != nil {
+		err := weberror.NewWebError(err, "unable to process configs")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(body)
 
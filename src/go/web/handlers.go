@@ -28,6 +28,8 @@ import (
 	"phenix/api/vm"
 	"phenix/app"
 	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
 	putil "phenix/util"
 	"phenix/util/mm"
 	"phenix/util/notes"
@@ -73,65 +75,37 @@ func GetExperiments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	experiments, err := experiment.List()
+	opts, err := util.ParseListOptions(query, experimentSortKeys...)
 	if err != nil {
-		log.Error("getting experiments - %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	allowed := []*proto.Experiment{}
-
-	for _, exp := range experiments {
-		if !role.Allowed("experiments", "list", exp.Metadata.Name) {
-			continue
-		}
-
-		// This will happen if another handler is currently acting on the
-		// experiment.
-		status := cache.IsExperimentLocked(exp.Metadata.Name)
-
-		if status == "" {
-			if exp.Running() {
-				status = cache.StatusStarted
-			} else {
-				status = cache.StatusStopped
-			}
-		}
-
-		// TODO: limit per-experiment VMs based on RBAC
-
-		vms, err := vm.List(exp.Spec.ExperimentName())
-		if err != nil {
-			// TODO
-		}
-
-		if exp.Running() && size != "" {
-			for i, v := range vms {
-				if !v.Running {
-					continue
-				}
-
-				screenshot, err := util.GetScreenshot(exp.Spec.ExperimentName(), v.Name, size)
-				if err != nil {
-					log.Error("getting screenshot - %v", err)
-					continue
-				}
-
-				v.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-
-				vms[i] = v
-			}
-		}
+	allowed, total, err := listExperiments(role, opts, size)
+	if err != nil {
+		httpError(w, err)
+		return
+	}
 
-		allowed = append(allowed, util.ExperimentToProtobuf(exp, status, vms))
+	resp := &proto.ExperimentList{
+		Experiments: allowed,
+		Total:       uint32(total),
+		NextCursor:  opts.NextCursor(total),
 	}
 
-	body, err := marshaler.Marshal(&proto.ExperimentList{Experiments: allowed})
+	body, err := marshaler.Marshal(resp)
 	if err != nil {
 		log.Error("marshaling experiments - %v", err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
+	if body, err = opts.SelectFields(body, "experiments"); err != nil {
+		log.Error("selecting experiment fields - %v", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
 	w.Write(body)
 }
 
@@ -164,65 +138,12 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := cache.LockExperimentForCreation(req.Name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockExperiment(req.Name)
-
-	opts := []experiment.CreateOption{
-		experiment.CreateWithName(req.Name),
-		experiment.CreateWithTopology(req.Topology),
-		experiment.CreateWithScenario(req.Scenario),
-		experiment.CreateWithVLANMin(int(req.VlanMin)),
-		experiment.CreateWithVLANMax(int(req.VlanMax)),
-	}
-
-	if req.WorkflowBranch != "" {
-		annotations := map[string]string{"phenix.workflow/branch": req.WorkflowBranch}
-		opts = append(opts, experiment.CreateWithAnnotations(annotations))
-	}
-
-	if err := experiment.Create(ctx, opts...); err != nil {
+	if _, err := createExperiment(ctx, &req); err != nil {
 		log.Error("creating experiment %s - %v", req.Name, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if warns := notes.Warnings(ctx, true); warns != nil {
-		for _, warn := range warns {
-			log.Warn("%v", warn)
-		}
-	}
-
-	exp, err := experiment.Get(req.Name)
-	if err != nil {
-		log.Error("getting experiment %s - %v", req.Name, err)
-		http.Error(w, "", http.StatusInternalServerError)
-		return
-	}
-
-	vms, err := vm.List(req.Name)
-	if err != nil {
-		// TODO
-		log.Error("listing VMs in experiment %s - %v", req.Name, err)
-	}
-
-	body, err = marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
-	if err != nil {
-		log.Error("marshaling experiment %s - %v", req.Name, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("experiments", "get", req.Name),
-		broker.NewResource("experiment", req.Name, "create"),
-		body,
-	)
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -237,7 +158,7 @@ func UpdateExperiment(w http.ResponseWriter, r *http.Request) error {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments", "patch", name) {
+	if !util.ExperimentAllowed(role, "experiments", "patch", name) {
 		err := weberror.NewWebError(nil, "updating experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
@@ -305,7 +226,7 @@ func GetExperiment(w http.ResponseWriter, r *http.Request) error {
 		clientFilter = query.Get("filter")
 	)
 
-	if !role.Allowed("experiments", "get", name) {
+	if !util.ExperimentAllowed(role, "experiments", "get", name) {
 		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
@@ -348,7 +269,7 @@ func GetExperiment(w http.ResponseWriter, r *http.Request) error {
 			}
 		}
 
-		if role.Allowed("vms", "list", fmt.Sprintf("%s_%s", name, vm.Name)) {
+		if role.AllowedObjects("vms", "list", util.VMObject(*exp, vm)) {
 			if vm.Running && size != "" {
 				screenshot, err := util.GetScreenshot(name, vm.Name, size)
 				if err != nil {
@@ -399,32 +320,18 @@ func DeleteExperiment(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments", "delete", name) {
+	if !util.ExperimentAllowed(role, "experiments", "delete", name) {
 		log.Warn("deleting experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockExperimentForDeletion(name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockExperiment(name)
-
-	if err := experiment.Delete(name); err != nil {
+	if err := deleteExperiment(name); err != nil {
 		log.Error("deleting experiment %s - %v", name, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		httpError(w, err)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("experiments", "delete", name),
-		broker.NewResource("experiment", name, "delete"),
-		nil,
-	)
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -439,16 +346,22 @@ func StartExperiment(w http.ResponseWriter, r *http.Request) error {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments/start", "update", name) {
+	if !util.ExperimentAllowed(role, "experiments/start", "update", name) {
 		err := weberror.NewWebError(nil, "starting experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
 
-	body, err := startExperiment(name)
+	exp, err := startExperiment(r.Context(), name)
 	if err != nil {
 		return err
 	}
 
+	body, err := marshaler.Marshal(exp)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling experiment %s - %v", name, err)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
 	w.Write(body)
 	return nil
 }
@@ -464,16 +377,22 @@ func StopExperiment(w http.ResponseWriter, r *http.Request) error {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments/stop", "update", name) {
+	if !util.ExperimentAllowed(role, "experiments/stop", "update", name) {
 		err := weberror.NewWebError(nil, "stopping experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
 
-	body, err := stopExperiment(name)
+	exp, err := stopExperiment(r.Context(), name)
 	if err != nil {
 		return err
 	}
 
+	body, err := marshaler.Marshal(exp)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling experiment %s - %v", name, err)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
 	w.Write(body)
 	return nil
 }
@@ -492,14 +411,14 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 		appsFilter = query.Get("apps")
 	)
 
-	if !role.Allowed("experiments/trigger", "create", name) {
+	if !util.ExperimentAllowed(role, "experiments/trigger", "create", name) {
 		log.Warn("triggering experiment %s apps not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments/trigger", "create", name),
+		broker.NewExperimentRequestPolicy("experiments/trigger", name, "create"),
 		broker.NewResource("experiment/apps", name, "triggered"),
 		nil,
 	)
@@ -522,7 +441,7 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 				humanized := putil.HumanizeError(err, "Unable to trigger running stage for %s app in %s experiment", a, name)
 
 				broker.Broadcast(
-					broker.NewRequestPolicy("experiments/trigger", "create", name),
+					broker.NewExperimentRequestPolicy("experiments/trigger", name, "create"),
 					broker.NewResource("experiment/apps", name, "triggerError"),
 					[]byte(humanized.Humanize()),
 				)
@@ -533,7 +452,7 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 		}
 
 		broker.Broadcast(
-			broker.NewRequestPolicy("experiments/trigger", "create", name),
+			broker.NewExperimentRequestPolicy("experiments/trigger", name, "create"),
 			broker.NewResource("experiment/apps", name, "triggerSuccess"),
 			notes.ToJSON(ctx),
 		)
@@ -556,14 +475,14 @@ func CancelTriggeredExperimentApps(w http.ResponseWriter, r *http.Request) {
 		appsFilter = query.Get("apps")
 	)
 
-	if !role.Allowed("experiments/trigger", "delete", name) {
+	if !util.ExperimentAllowed(role, "experiments/trigger", "delete", name) {
 		log.Warn("canceling triggered experiment %s apps not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments/trigger", "delete", name),
+		broker.NewExperimentRequestPolicy("experiments/trigger", name, "delete"),
 		broker.NewResource("experiment/apps", name, "cancelTrigger"),
 		nil,
 	)
@@ -584,7 +503,7 @@ func CancelTriggeredExperimentApps(w http.ResponseWriter, r *http.Request) {
 		}
 
 		broker.Broadcast(
-			broker.NewRequestPolicy("experiments/trigger", "delete", name),
+			broker.NewExperimentRequestPolicy("experiments/trigger", name, "delete"),
 			broker.NewResource("experiment/apps", name, "cancelTriggerSuccess"),
 			notes.ToJSON(ctx),
 		)
@@ -604,7 +523,7 @@ func GetExperimentSchedule(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments/schedule", "get", name) {
+	if !util.ExperimentAllowed(role, "experiments/schedule", "get", name) {
 		log.Warn("getting experiment schedule for %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -647,7 +566,7 @@ func ScheduleExperiment(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments/schedule", "create", name) {
+	if !util.ExperimentAllowed(role, "experiments/schedule", "create", name) {
 		log.Warn("creating experiment schedule for %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -699,7 +618,7 @@ func ScheduleExperiment(w http.ResponseWriter, r *http.Request) {
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("experiments/schedule", "create", name),
+		broker.NewExperimentRequestPolicy("experiments/schedule", name, "create"),
 		broker.NewResource("experiment", name, "schedule"),
 		body,
 	)
@@ -718,7 +637,7 @@ func GetExperimentCaptures(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments/captures", "list", name) {
+	if !util.ExperimentAllowed(role, "experiments/captures", "list", name) {
 		log.Warn("listing experiment captures for %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -762,7 +681,7 @@ func GetExperimentFiles(w http.ResponseWriter, r *http.Request) {
 		clientFilter = query.Get("filter")
 	)
 
-	if !role.Allowed("experiments/files", "list", name) {
+	if !util.ExperimentAllowed(role, "experiments/files", "list", name) {
 		log.Warn("listing experiment files for %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -810,7 +729,7 @@ func GetExperimentFile(w http.ResponseWriter, r *http.Request) {
 		path  = query.Get("path")
 	)
 
-	if !role.Allowed("experiments/files", "get", name) {
+	if !util.ExperimentAllowed(role, "experiments/files", "get", name) {
 		log.Warn("getting experiment file for %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -848,7 +767,7 @@ func GetExperimentApps(w http.ResponseWriter, r *http.Request) error {
 		name = mux.Vars(r)["name"]
 	)
 
-	if !role.Allowed("experiments/apps", "get", name) {
+	if !util.ExperimentAllowed(role, "experiments/apps", "get", name) {
 		err := weberror.NewWebError(nil, "getting experiment apps for %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
@@ -887,10 +806,6 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		expName = vars["exp"]
 		query   = r.URL.Query()
 		size    = query.Get("screenshot")
-		sortCol = query.Get("sortCol")
-		sortDir = query.Get("sortDir")
-		pageNum = query.Get("pageNum")
-		perPage = query.Get("perPage")
 	)
 
 	if !role.Allowed("vms", "list") {
@@ -898,47 +813,19 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	exp, err := experiment.Get(expName)
+	opts, err := util.ParseListOptions(query, vmSortKeys...)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	vms, err := vm.List(expName)
+	exp, allowed, total, err := listVMs(role, expName, opts, size)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	allowed := mm.VMs{}
-
-	for _, vm := range vms {
-		if role.Allowed("vms", "list", fmt.Sprintf("%s_%s", expName, vm.Name)) {
-			if vm.Running && size != "" {
-				screenshot, err := util.GetScreenshot(expName, vm.Name, size)
-				if err != nil {
-					log.Error("getting screenshot: %v", err)
-				} else {
-					vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-				}
-			}
-
-			allowed = append(allowed, vm)
-		}
-	}
-
-	if sortCol != "" && sortDir != "" {
-		allowed.SortBy(sortCol, sortDir == "asc")
-	}
-
-	if pageNum != "" && perPage != "" {
-		n, _ := strconv.Atoi(pageNum)
-		s, _ := strconv.Atoi(perPage)
-
-		allowed = allowed.Paginate(n, s)
-	}
-
-	resp := &proto.VMList{Total: uint32(len(allowed))}
+	resp := &proto.VMList{Total: uint32(total), NextCursor: opts.NextCursor(total)}
 
 	resp.Vms = make([]*proto.VM, len(allowed))
 	for i, v := range allowed {
@@ -951,6 +838,11 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if body, err = opts.SelectFields(body, "vms"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Write(body)
 }
 
@@ -968,33 +860,18 @@ func GetVM(w http.ResponseWriter, r *http.Request) {
 		size    = query.Get("screenshot")
 	)
 
-	if !role.Allowed("vms", "get", fmt.Sprintf("%s_%s", expName, name)) {
+	if !util.VMAllowed(role, "vms", "get", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	exp, err := experiment.Get(expName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	vm, err := vm.Get(expName, name)
+	vm, err := getVM(expName, name, size)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	if vm.Running && size != "" {
-		screenshot, err := util.GetScreenshot(expName, name, size)
-		if err != nil {
-			log.Error("getting screenshot: %v", err)
-		} else {
-			vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-		}
-	}
-
-	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *vm, exp.Spec.Topology()))
+	body, err := marshaler.Marshal(vm)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1014,7 +891,7 @@ func UpdateVM(w http.ResponseWriter, r *http.Request) {
 		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms", "patch", fmt.Sprintf("%s_%s", expName, name)) {
+	if !util.VMAllowed(role, "vms", "patch", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -1087,8 +964,8 @@ func UpdateVM(w http.ResponseWriter, r *http.Request) {
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms", "patch", fmt.Sprintf("%s_%s", expName, name)),
-		broker.NewResource("experiment/vm", fmt.Sprintf("%s/%s", expName, name), "update"),
+		broker.NewVMRequestPolicy("vms", expName, name, "patch"),
+		broker.NewVMResource("experiment/vm", expName, name, "update"),
 		body,
 	)
 
@@ -1123,7 +1000,7 @@ func UpdateVMs(w http.ResponseWriter, r *http.Request) {
 	for index, vmRequest := range req.Vms {
 
 		// Skip any vms that are not allowed to be updated
-		if !role.Allowed("vms", "patch", fmt.Sprintf("%s_%s", expName, vmRequest.Name)) {
+		if !util.VMAllowed(role, "vms", "patch", expName, vmRequest.Name) {
 			log.Error("%s_%s is forbidden", expName, vmRequest.Name)
 			continue
 		}
@@ -1201,33 +1078,16 @@ func DeleteVM(w http.ResponseWriter, r *http.Request) {
 		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms", "delete", fmt.Sprintf("%s_%s", expName, name)) {
+	if !util.VMAllowed(role, "vms", "delete", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	exp, err := experiment.Get(expName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if !exp.Running() {
-		http.Error(w, "experiment not running", http.StatusBadRequest)
-		return
-	}
-
-	if err := mm.KillVM(mm.NS(expName), mm.VMName(name)); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := killVM(r.Context(), expName, name); err != nil {
+		httpError(w, err)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms", "delete", fmt.Sprintf("%s_%s", expName, name)),
-		broker.NewResource("experiment/vm", fmt.Sprintf("%s/%s", expName, name), "delete"),
-		nil,
-	)
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -1236,87 +1096,30 @@ func StartVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("StartVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		expName  = vars["exp"]
-		name     = vars["name"]
-		fullName = expName + "_" + name
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms/start", "update", fullName) {
+	if !util.VMAllowed(role, "vms/start", "update", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockVMForStarting(expName, name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockVM(expName, name)
-
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/start", "update", fullName),
-		broker.NewResource("experiment/vm", name, "starting"),
-		nil,
-	)
-
-	if err := mm.StartVM(mm.NS(expName), mm.VMName(name)); err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/start", "update", fullName),
-			broker.NewResource("experiment/vm", name, "errorStarting"),
-			nil,
-		)
-
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	exp, err := experiment.Get(expName)
-	if err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/start", "update", fullName),
-			broker.NewResource("experiment/vm", name, "errorStarting"),
-			nil,
-		)
-
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	v, err := vm.Get(expName, name)
+	v, err := startVM(r.Context(), expName, name)
 	if err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/start", "update", fullName),
-			broker.NewResource("experiment/vm", name, "errorStarting"),
-			nil,
-		)
-
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	screenshot, err := util.GetScreenshot(expName, name, "215")
-	if err != nil {
-		log.Error("getting screenshot - %v", err)
-	} else {
-		v.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-	}
-
-	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	body, err := marshaler.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/start", "update", fullName),
-		broker.NewResource("experiment/vm", expName+"/"+name, "start"),
-		body,
-	)
-
 	w.Write(body)
 }
 
@@ -1325,80 +1128,30 @@ func StopVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("StopVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		expName  = vars["exp"]
-		name     = vars["name"]
-		fullName = expName + "_" + name
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms/stop", "update", fullName) {
+	if !util.VMAllowed(role, "vms/stop", "update", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockVMForStopping(expName, name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockVM(expName, name)
-
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/stop", "update", fullName),
-		broker.NewResource("experiment/vm", name, "stopping"),
-		nil,
-	)
-
-	if err := mm.StopVM(mm.NS(expName), mm.VMName(name)); err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/stop", "update", fullName),
-			broker.NewResource("experiment/vm", name, "errorStopping"),
-			nil,
-		)
-
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	exp, err := experiment.Get(expName)
+	v, err := stopVM(r.Context(), expName, name)
 	if err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/stop", "update", fullName),
-			broker.NewResource("experiment/vm", name, "errorStopping"),
-			nil,
-		)
-
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	v, err := vm.Get(expName, name)
-	if err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/stop", "update", fullName),
-			broker.NewResource("experiment/vm", name, "errorStopping"),
-			nil,
-		)
-
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	body, err := marshaler.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/stop", "update", fullName),
-		broker.NewResource("experiment/vm", expName+"/"+name, "stop"),
-		body,
-	)
-
 	w.Write(body)
 }
 
@@ -1407,69 +1160,30 @@ func RestartVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("RestartVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		expName  = vars["exp"]
-		name     = vars["name"]
-		fullName = expName + "_" + name
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms/restart", "update", fullName) {
+	if !util.VMAllowed(role, "vms/restart", "update", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockVMForStarting(expName, name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockVM(expName, name)
-
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/restart", "update", fullName),
-		broker.NewResource("experiment/vm", name, "restarting"),
-		nil,
-	)
-
-	if err := vm.Restart(expName, name); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	exp, err := experiment.Get(expName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	v, err := vm.Get(expName, name)
+	v, err := restartVM(r.Context(), expName, name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	screenshot, err := util.GetScreenshot(expName, name, "215")
-	if err != nil {
-		log.Error("getting screenshot - %v", err)
-	} else {
-		v.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-	}
-
-	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	body, err := marshaler.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/restart", "update", fullName),
-		broker.NewResource("experiment/vm", expName+"/"+name, "update"),
-		body,
-	)
-
 	w.Write(body)
 }
 
@@ -1478,58 +1192,30 @@ func ShutdownVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("ShutdownVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		expName  = vars["exp"]
-		name     = vars["name"]
-		fullName = expName + "_" + name
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms/shutdown", "update", fullName) {
+	if !util.VMAllowed(role, "vms/shutdown", "update", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockVMForStopping(expName, name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockVM(expName, name)
-
-	if err := vm.Shutdown(expName, name); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	exp, err := experiment.Get(expName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	v, err := vm.Get(expName, name)
+	v, err := shutdownVM(r.Context(), expName, name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	v.Running = false
-
-	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	body, err := marshaler.Marshal(v)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/shutdown", "update", fullName),
-		broker.NewResource("experiment/vm", expName+"/"+name, "shutdown"),
-		body,
-	)
-
 	w.Write(body)
 }
 
@@ -1538,15 +1224,14 @@ func ResetVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("ResetVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		expName  = vars["exp"]
-		name     = vars["name"]
-		fullName = expName + "_" + name
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms/reset", "update", fullName) {
+	if !util.VMAllowed(role, "vms/reset", "update", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -1583,8 +1268,8 @@ func ResetVM(w http.ResponseWriter, r *http.Request) {
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms/reset", "update", fullName),
-		broker.NewResource("experiment/vm", expName+"/"+name, "reset"),
+		broker.NewVMRequestPolicy("vms/reset", expName, name, "update"),
+		broker.NewVMResource("experiment/vm", expName, name, "reset"),
 		body,
 	)
 
@@ -1596,139 +1281,51 @@ func RedeployVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("RedeployVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		expName  = vars["exp"]
-		name     = vars["name"]
-		fullName = expName + "_" + name
-		query    = r.URL.Query()
-		inject   = query.Get("replicate-injects") != ""
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		name    = vars["name"]
+		query   = r.URL.Query()
+		inject  = query.Get("replicate-injects") != ""
 	)
 
-	if !role.Allowed("vms/redeploy", "update", fullName) {
+	if !util.VMAllowed(role, "vms/redeploy", "update", expName, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockVMForRedeploying(expName, name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockVM(expName, name)
-
-	exp, err := experiment.Get(expName)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	v, err := vm.Get(expName, name)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	v.Busy = true
-
-	body, _ := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
-	if err != nil {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil && err != io.EOF {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/redeploy", "update", fullName),
-		broker.NewResource("experiment/vm", expName+"/"+name, "redeploying"),
-		body,
-	)
+	var req *proto.VMRedeployRequest
 
-	redeployed := make(chan error)
+	if len(body) > 0 {
+		req = new(proto.VMRedeployRequest)
 
-	go func() {
-		defer close(redeployed)
-
-		body, err := ioutil.ReadAll(r.Body)
-		if err != nil && err != io.EOF {
-			redeployed <- err
+		// Update VM struct with values from POST request body.
+		if err := unmarshaler.Unmarshal(body, req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+	}
 
-		opts := []vm.RedeployOption{
-			vm.CPU(v.CPUs),
-			vm.Memory(v.RAM),
-			vm.Disk(v.Disk),
-			vm.Inject(inject),
-		}
-
-		// `body` will be nil if err above was EOF.
-		if body != nil {
-			var req proto.VMRedeployRequest
-
-			// Update VM struct with values from POST request body.
-			if err := unmarshaler.Unmarshal(body, &req); err != nil {
-				redeployed <- err
-				return
-			}
-
-			opts = []vm.RedeployOption{
-				vm.CPU(int(req.Cpus)),
-				vm.Memory(int(req.Ram)),
-				vm.Disk(req.Disk),
-				vm.Inject(req.Injects),
-			}
-		}
-
-		if err := vm.Redeploy(expName, name, opts...); err != nil {
-			redeployed <- err
-		}
-
-		v.Busy = false
-	}()
-
-	// HACK: mandatory sleep time to make it seem like a redeploy is
-	// happening client-side, even when the redeploy is fast (like for
-	// Linux VMs).
-	time.Sleep(5 * time.Second)
-
-	err = <-redeployed
+	pb, err := redeployVM(r.Context(), expName, name, req, inject)
 	if err != nil {
-		log.Error("redeploying VM %s - %v", fullName, err)
-
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/redeploy", "update", fullName),
-			broker.NewResource("experiment/vm", expName+"/"+name, "errorRedeploying"),
-			nil,
-		)
-
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error("redeploying VM %s_%s - %v", expName, name, err)
+		httpError(w, err)
 		return
 	}
 
-	// Get the VM details again since redeploying may have changed them.
-	v, err = vm.Get(expName, name)
+	body, err = marshaler.Marshal(pb)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	screenshot, err := util.GetScreenshot(expName, name, "215")
-	if err != nil {
-		log.Error("getting screenshot - %v", err)
-	} else {
-		v.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-	}
-
-	body, _ = marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
-
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/redeploy", "update", fullName),
-		broker.NewResource("experiment/vm", expName+"/"+name, "redeployed"),
-		body,
-	)
-
 	w.Write(body)
 }
 
@@ -1747,7 +1344,7 @@ func GetScreenshot(w http.ResponseWriter, r *http.Request) {
 		encode = query.Get("base64") != ""
 	)
 
-	if !role.Allowed("vms/screenshot", "get", exp+"/"+name) {
+	if !util.VMPathAllowed(role, "vms/screenshot", "get", exp, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -1768,13 +1365,90 @@ func GetScreenshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(screenshot)
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(screenshot)
+}
+
+// GET /experiments/{exp}/vms/{name}/captures
+func GetVMCaptures(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVMCaptures HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !util.VMAllowed(role, "vms/captures", "list", exp, name) {
+		log.Warn("getting captures for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	captures := mm.GetVMCaptures(mm.NS(exp), mm.VMName(name))
+
+	body, err := marshaler.Marshal(&proto.CaptureList{Captures: util.CapturesToProtobuf(captures)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
+// POST /experiments/{exp}/vms/{name}/captures
+func StartVMCapture(w http.ResponseWriter, r *http.Request) {
+	log.Debug("StartVMCapture HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !util.VMAllowed(role, "vms/captures", "create", exp, name) {
+		log.Warn("starting capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error("reading request body - %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var req proto.StartCaptureRequest
+	err = unmarshaler.Unmarshal(body, &req)
+	if err != nil {
+		log.Error("unmarshaling request body - %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := vm.StartCapture(exp, name, int(req.Interface), req.Filename); err != nil {
+		log.Error("starting VM capture for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/captures", exp, name, "create"),
+		broker.NewVMResource("experiment/vm/capture", exp, name, "start"),
+		body,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// GET /experiments/{exp}/vms/{name}/captures
-func GetVMCaptures(w http.ResponseWriter, r *http.Request) {
-	log.Debug("GetVMCaptures HTTP handler called")
+// DELETE /experiments/{exp}/vms/{name}/captures
+func StopVMCaptures(w http.ResponseWriter, r *http.Request) {
+	log.Debug("StopVMCaptures HTTP handler called")
 
 	var (
 		ctx  = r.Context()
@@ -1784,26 +1458,30 @@ func GetVMCaptures(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("vms/captures", "list", fmt.Sprintf("%s_%s", exp, name)) {
-		log.Warn("getting captures for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+	if !util.VMAllowed(role, "vms/captures", "delete", exp, name) {
+		log.Warn("stopping capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	captures := mm.GetVMCaptures(mm.NS(exp), mm.VMName(name))
-
-	body, err := marshaler.Marshal(&proto.CaptureList{Captures: util.CapturesToProtobuf(captures)})
-	if err != nil {
+	if err := vm.StopCaptures(exp, name); err != nil {
+		log.Error("stopping VM capture for VM %s in experiment %s - %v", name, exp, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(body)
+	broker.Broadcast(
+		broker.NewVMRequestPolicy("vms/captures", exp, name, "delete"),
+		broker.NewVMResource("experiment/vm/capture", exp, name, "stop"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// POST /experiments/{exp}/vms/{name}/captures
-func StartVMCapture(w http.ResponseWriter, r *http.Request) {
-	log.Debug("StartVMCapture HTTP handler called")
+// PUT /experiments/{exp}/vms/{name}/nets/{iface}/impairment
+func ImpairVMInterface(w http.ResponseWriter, r *http.Request) {
+	log.Debug("ImpairVMInterface HTTP handler called")
 
 	var (
 		ctx  = r.Context()
@@ -1813,12 +1491,19 @@ func StartVMCapture(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("vms/captures", "create", fmt.Sprintf("%s_%s", exp, name)) {
-		log.Warn("starting capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+	if !util.VMAllowed(role, "vms/impairment", "update", exp, name) {
+		log.Warn("impairing interface for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
+	iface, err := strconv.Atoi(vars["iface"])
+	if err != nil {
+		log.Error("parsing interface index - %v", err)
+		http.Error(w, "interface index must be an integer", http.StatusBadRequest)
+		return
+	}
+
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Error("reading request body - %v", err)
@@ -1826,7 +1511,7 @@ func StartVMCapture(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req proto.StartCaptureRequest
+	var req proto.ImpairmentRequest
 	err = unmarshaler.Unmarshal(body, &req)
 	if err != nil {
 		log.Error("unmarshaling request body - %v", err)
@@ -1834,24 +1519,39 @@ func StartVMCapture(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := vm.StartCapture(exp, name, int(req.Interface), req.Filename); err != nil {
-		log.Error("starting VM capture for VM %s in experiment %s - %v", name, exp, err)
+	imp := mm.Impairment{
+		Delay:      req.Delay,
+		Jitter:     req.Jitter,
+		Loss:       req.Loss,
+		Corruption: req.Corruption,
+		Reordering: req.Reordering,
+		Rate:       req.Rate,
+	}
+
+	if _, _, err := imp.Validate(); err != nil {
+		log.Error("validating impairment for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := vm.Impair(exp, name, iface, imp); err != nil {
+		log.Error("impairing interface %d for VM %s in experiment %s - %v", iface, name, exp, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms/captures", "create", fmt.Sprintf("%s_%s", exp, name)),
-		broker.NewResource("experiment/vm/capture", fmt.Sprintf("%s/%s", exp, name), "start"),
+		broker.NewVMRequestPolicy("vms/impairment", exp, name, "update"),
+		broker.NewVMResource("experiment/vm/impairment", exp, name, "update"),
 		body,
 	)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// DELETE /experiments/{exp}/vms/{name}/captures
-func StopVMCaptures(w http.ResponseWriter, r *http.Request) {
-	log.Debug("StopVMCaptures HTTP handler called")
+// DELETE /experiments/{exp}/vms/{name}/nets/{iface}/impairment
+func ClearVMInterfaceImpairment(w http.ResponseWriter, r *http.Request) {
+	log.Debug("ClearVMInterfaceImpairment HTTP handler called")
 
 	var (
 		ctx  = r.Context()
@@ -1861,21 +1561,28 @@ func StopVMCaptures(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("vms/captures", "delete", fmt.Sprintf("%s_%s", exp, name)) {
-		log.Warn("stopping capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+	if !util.VMAllowed(role, "vms/impairment", "delete", exp, name) {
+		log.Warn("clearing interface impairment for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := vm.StopCaptures(exp, name); err != nil {
-		log.Error("stopping VM capture for VM %s in experiment %s - %v", name, exp, err)
+	iface, err := strconv.Atoi(vars["iface"])
+	if err != nil {
+		log.Error("parsing interface index - %v", err)
+		http.Error(w, "interface index must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	if err := vm.ClearImpairment(exp, name, iface); err != nil {
+		log.Error("clearing impairment on interface %d for VM %s in experiment %s - %v", iface, name, exp, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms/captures", "delete", fmt.Sprintf("%s_%s", exp, name)),
-		broker.NewResource("experiment/vm/capture", fmt.Sprintf("%s/%s", exp, name), "stop"),
+		broker.NewVMRequestPolicy("vms/impairment", exp, name, "delete"),
+		broker.NewVMResource("experiment/vm/impairment", exp, name, "delete"),
 		nil,
 	)
 
@@ -1893,7 +1600,7 @@ func StartCaptureSubnet(w http.ResponseWriter, r *http.Request) {
 		exp  = vars["exp"]
 	)
 
-	if !role.Allowed("exp/captureSubnet", "create", exp) {
+	if !util.ExperimentAllowed(role, "exp/captureSubnet", "create", exp) {
 		log.Warn("starting subnet capture for experiment %s is not allowed for %s", exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -1944,7 +1651,7 @@ func StopCaptureSubnet(w http.ResponseWriter, r *http.Request) {
 		exp  = vars["exp"]
 	)
 
-	if !role.Allowed("exp/captureSubnet", "create", exp) {
+	if !util.ExperimentAllowed(role, "exp/captureSubnet", "create", exp) {
 		log.Warn("starting subnet capture for experiment %s is not allowed for %s", exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -1997,7 +1704,7 @@ func GetVMSnapshots(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("vms/snapshots", "list", fmt.Sprintf("%s_%s", exp, name)) {
+	if !util.VMAllowed(role, "vms/snapshots", "list", exp, name) {
 		log.Warn("listing snapshots for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -2024,15 +1731,14 @@ func SnapshotVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("SnapshotVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		exp      = vars["exp"]
-		name     = vars["name"]
-		fullName = exp + "_" + name
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
 	)
 
-	if !role.Allowed("vms/snapshots", "create", fullName) {
+	if !util.VMAllowed(role, "vms/snapshots", "create", exp, name) {
 		log.Warn("snapshotting VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -2053,69 +1759,12 @@ func SnapshotVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := cache.LockVMForSnapshotting(exp, name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockVM(exp, name)
-
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/snapshots", "create", fullName),
-		broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "creating"),
-		nil,
-	)
-
-	status := make(chan string)
-
-	go func() {
-		for {
-			s := <-status
-
-			if s == "completed" {
-				return
-			}
-
-			progress, err := strconv.ParseFloat(s, 64)
-			if err == nil {
-				log.Info("snapshot percent complete: %v", progress)
-
-				status := map[string]interface{}{
-					"percent": progress / 100,
-				}
-
-				marshalled, _ := json.Marshal(status)
-
-				broker.Broadcast(
-					broker.NewRequestPolicy("vms/snapshots", "create", fullName),
-					broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "progress"),
-					marshalled,
-				)
-			}
-		}
-	}()
-
-	cb := func(s string) { status <- s }
-
-	if err := vm.Snapshot(exp, name, req.Filename, cb); err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/snapshots", "create", fullName),
-			broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "errorCreating"),
-			nil,
-		)
-
+	if err := snapshotVM(r.Context(), exp, name, req.Filename); err != nil {
 		log.Error("snapshotting VM %s in experiment %s - %v", name, exp, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/snapshots", "create", fullName),
-		broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "create"),
-		nil,
-	)
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -2124,53 +1773,26 @@ func RestoreVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("RestoreVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		exp      = vars["exp"]
-		name     = vars["name"]
-		fullName = exp + "_" + name
-		snap     = vars["snapshot"]
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+		snap = vars["snapshot"]
 	)
 
-	if !role.Allowed("vms/snapshots", "update", fullName) {
+	if !util.VMAllowed(role, "vms/snapshots", "update", exp, name) {
 		log.Warn("restoring VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockVMForRestoring(exp, name); err != nil {
-		log.Warn(err.Error())
-		http.Error(w, err.Error(), http.StatusConflict)
-		return
-	}
-
-	defer cache.UnlockVM(exp, name)
-
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/snapshots", "create", fullName),
-		broker.NewResource("experiment/vm/snapshot", fmt.Sprintf("%s/%s", exp, name), "restoring"),
-		nil,
-	)
-
-	if err := vm.Restore(exp, name, snap); err != nil {
-		broker.Broadcast(
-			broker.NewRequestPolicy("vms/snapshots", "create", fullName),
-			broker.NewResource("experiment/vm/snapshot", fmt.Sprintf("%s/%s", exp, name), "errorRestoring"),
-			nil,
-		)
-
+	if err := restoreVM(r.Context(), exp, name, snap); err != nil {
 		log.Error("restoring VM %s in experiment %s - %v", name, exp, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, err)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/snapshots", "create", fullName),
-		broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "restore"),
-		nil,
-	)
-
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -2179,15 +1801,14 @@ func CommitVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("CommitVM HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		expName  = vars["exp"]
-		name     = vars["name"]
-		fullName = expName + "_" + name
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		name    = vars["name"]
 	)
 
-	if !role.Allowed("vms/commit", "create", fullName) {
+	if !util.VMAllowed(role, "vms/commit", "create", expName, name) {
 		log.Warn("committing VM %s in experiment %s not allowed for %s", name, expName, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -2251,8 +1872,8 @@ func CommitVM(w http.ResponseWriter, r *http.Request) {
 	body, _ = marshaler.Marshal(payload)
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms/commit", "create", fullName),
-		broker.NewResource("experiment/vm/commit", expName+"/"+name, "committing"),
+		broker.NewVMRequestPolicy("vms/commit", expName, name, "create"),
+		broker.NewVMResource("experiment/vm/commit", expName, name, "committing"),
 		body,
 	)
 
@@ -2269,8 +1890,8 @@ func CommitVM(w http.ResponseWriter, r *http.Request) {
 			marshalled, _ := json.Marshal(status)
 
 			broker.Broadcast(
-				broker.NewRequestPolicy("vms/commit", "create", fullName),
-				broker.NewResource("experiment/vm/commit", expName+"/"+name, "progress"),
+				broker.NewVMRequestPolicy("vms/commit", expName, name, "create"),
+				broker.NewVMResource("experiment/vm/commit", expName, name, "progress"),
 				marshalled,
 			)
 		}
@@ -2280,8 +1901,8 @@ func CommitVM(w http.ResponseWriter, r *http.Request) {
 
 	if _, err = vm.CommitToDisk(expName, name, filename, cb); err != nil {
 		broker.Broadcast(
-			broker.NewRequestPolicy("vms/commit", "create", fullName),
-			broker.NewResource("experiment/vm/commit", expName+"/"+name, "errorCommitting"),
+			broker.NewVMRequestPolicy("vms/commit", expName, name, "create"),
+			broker.NewVMResource("experiment/vm/commit", expName, name, "errorCommitting"),
 			nil,
 		)
 
@@ -2293,8 +1914,8 @@ func CommitVM(w http.ResponseWriter, r *http.Request) {
 	exp, err := experiment.Get(expName)
 	if err != nil {
 		broker.Broadcast(
-			broker.NewRequestPolicy("vms/commit", "create", fullName),
-			broker.NewResource("experiment/vm/commit", expName+"/"+name, "errorCommitting"),
+			broker.NewVMRequestPolicy("vms/commit", expName, name, "create"),
+			broker.NewVMResource("experiment/vm/commit", expName, name, "errorCommitting"),
 			nil,
 		)
 
@@ -2305,8 +1926,8 @@ func CommitVM(w http.ResponseWriter, r *http.Request) {
 	v, err := vm.Get(expName, name)
 	if err != nil {
 		broker.Broadcast(
-			broker.NewRequestPolicy("vms/commit", "create", fullName),
-			broker.NewResource("experiment/vm/commit", expName+"/"+name, "errorCommitting"),
+			broker.NewVMRequestPolicy("vms/commit", expName, name, "create"),
+			broker.NewVMResource("experiment/vm/commit", expName, name, "errorCommitting"),
 			nil,
 		)
 
@@ -2318,8 +1939,8 @@ func CommitVM(w http.ResponseWriter, r *http.Request) {
 	body, _ = marshaler.Marshal(payload)
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms/commit", "create", fmt.Sprintf("%s_%s", expName, name)),
-		broker.NewResource("experiment/vm/commit", expName+"/"+name, "commit"),
+		broker.NewVMRequestPolicy("vms/commit", expName, name, "create"),
+		broker.NewVMResource("experiment/vm/commit", expName, name, "commit"),
 		body,
 	)
 
@@ -2331,15 +1952,14 @@ func CreateVMMemorySnapshot(w http.ResponseWriter, r *http.Request) {
 	log.Debug("CreateVMMemorySnapshot HTTP handler called")
 
 	var (
-		ctx      = r.Context()
-		role     = ctx.Value("role").(rbac.Role)
-		vars     = mux.Vars(r)
-		exp      = vars["exp"]
-		name     = vars["name"]
-		fullName = exp + "_" + name
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
 	)
 
-	if !role.Allowed("vms/memorySnapshot", "create", fullName) {
+	if !util.VMAllowed(role, "vms/memorySnapshot", "create", exp, name) {
 		log.Warn("Capturing memory snapshot of VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
@@ -2392,8 +2012,8 @@ func CreateVMMemorySnapshot(w http.ResponseWriter, r *http.Request) {
 	body, _ = marshaler.Marshal(payload)
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms/memorySnapshot", "create", fullName),
-		broker.NewResource("experiment/vm/memorySnapshot", exp+"/"+name, "committing"),
+		broker.NewVMRequestPolicy("vms/memorySnapshot", exp, name, "create"),
+		broker.NewVMResource("experiment/vm/memorySnapshot", exp, name, "committing"),
 		body,
 	)
 
@@ -2420,8 +2040,8 @@ func CreateVMMemorySnapshot(w http.ResponseWriter, r *http.Request) {
 				marshalled, _ := json.Marshal(status)
 
 				broker.Broadcast(
-					broker.NewRequestPolicy("vms/memorySnapshot", "create", fullName),
-					broker.NewResource("experiment/vm/memorySnapshot", exp+"/"+name, "progress"),
+					broker.NewVMRequestPolicy("vms/memorySnapshot", exp, name, "create"),
+					broker.NewVMResource("experiment/vm/memorySnapshot", exp, name, "progress"),
 					marshalled,
 				)
 			}
@@ -2432,8 +2052,8 @@ func CreateVMMemorySnapshot(w http.ResponseWriter, r *http.Request) {
 
 	if _, err = vm.MemorySnapshot(exp, name, filename, cb); err != nil {
 		broker.Broadcast(
-			broker.NewRequestPolicy("vms/memorySnapshot", "create", fullName),
-			broker.NewResource("experiment/vm/memorySnapshot", exp+"/"+name, "errorCommitting"),
+			broker.NewVMRequestPolicy("vms/memorySnapshot", exp, name, "create"),
+			broker.NewVMResource("experiment/vm/memorySnapshot", exp, name, "errorCommitting"),
 			nil,
 		)
 
@@ -2443,8 +2063,8 @@ func CreateVMMemorySnapshot(w http.ResponseWriter, r *http.Request) {
 	}
 
 	broker.Broadcast(
-		broker.NewRequestPolicy("vms/memorySnapshot", "create", fmt.Sprintf("%s_%s", exp, name)),
-		broker.NewResource("experiment/vm/memorySnapshot", exp+"/"+name, "commit"),
+		broker.NewVMRequestPolicy("vms/memorySnapshot", exp, name, "create"),
+		broker.NewVMResource("experiment/vm/memorySnapshot", exp, name, "commit"),
 		nil,
 	)
 
@@ -2467,12 +2087,21 @@ func GetAllVMs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts, err := util.ParseListOptions(query, append(vmSortKeys, "experiment")...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	exps, err := experiment.List()
 	if err != nil {
 		log.Error("getting experiments: %v", err)
 	}
 
-	allowed := []*proto.VM{}
+	var (
+		allowed mm.VMs
+		running = make(map[string]types.Experiment)
+	)
 
 	for _, exp := range exps {
 		if !exp.Running() {
@@ -2481,13 +2110,16 @@ func GetAllVMs(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
+		running[exp.Metadata.Name] = exp
+
 		// TODO: handle error
 		vms, _ := vm.List(exp.Spec.ExperimentName())
 
 		for _, vm := range vms {
-			id := exp.Metadata.Name + "/" + vm.Name
+			obj := util.VMObject(exp, vm)
+			obj.Name = exp.Metadata.Name + "/" + vm.Name
 
-			if !role.Allowed("vms", "list", id) {
+			if !role.AllowedObjects("vms", "list", obj) {
 				continue
 			}
 
@@ -2496,20 +2128,42 @@ func GetAllVMs(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 
-			if size != "" {
-				screenshot, err := util.GetScreenshot(exp.Metadata.Name, vm.Name, size)
-				if err != nil {
-					log.Error("getting screenshot: %v", err)
-				} else {
-					vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-				}
+			if !opts.MatchVM(vm) {
+				continue
 			}
 
-			allowed = append(allowed, util.VMToProtobuf(exp.Metadata.Name, vm, exp.Spec.Topology()))
+			vm.Experiment = exp.Metadata.Name
+			allowed = append(allowed, vm)
 		}
 	}
 
-	resp := &proto.VMList{Total: uint32(len(allowed)), Vms: allowed}
+	less := vmLessFuncs(allowed)
+
+	less["experiment"] = func(i, j int) bool {
+		return allowed[i].Experiment < allowed[j].Experiment
+	}
+
+	opts.SortWith(allowed, less)
+
+	start, end := opts.Page(len(allowed))
+
+	resp := &proto.VMList{Total: uint32(len(allowed)), NextCursor: opts.NextCursor(len(allowed))}
+	resp.Vms = make([]*proto.VM, 0, end-start)
+
+	for _, vm := range allowed[start:end] {
+		exp := running[vm.Experiment]
+
+		if size != "" {
+			screenshot, err := util.GetScreenshot(exp.Metadata.Name, vm.Name, size)
+			if err != nil {
+				log.Error("getting screenshot: %v", err)
+			} else {
+				vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+			}
+		}
+
+		resp.Vms = append(resp.Vms, util.VMToProtobuf(exp.Metadata.Name, vm, exp.Spec.Topology()))
+	}
 
 	body, err := marshaler.Marshal(resp)
 	if err != nil {
@@ -2517,6 +2171,11 @@ func GetAllVMs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if body, err = opts.SelectFields(body, "vms"); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.Write(body)
 }
 
@@ -2737,23 +2396,12 @@ func GetLogs(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotImplemented)
 	}
 
-	type LogLine struct {
-		Source    string `json:"source"`
-		Timestamp string `json:"timestamp"`
-		Epoch     int64  `json:"epoch"`
-		Level     string `json:"level"`
-		Log       string `json:"log"`
-
-		// Not exported so it doesn't get included in serialized JSON.
-		ts time.Time
-	}
-
 	var (
 		since time.Duration
 		limit int
 
-		logs    = make(map[int][]LogLine)
-		logChan = make(chan LogLine)
+		logs    = make(map[int][]logEntry)
+		logChan = make(chan logEntry)
 		done    = make(chan struct{})
 		wait    errgroup.Group
 
@@ -2803,35 +2451,16 @@ func GetLogs(w http.ResponseWriter, r *http.Request) {
 			var (
 				scanner = bufio.NewScanner(f)
 				// Used to detect multi-line logs in tailed log files.
-				body *LogLine
+				body *logEntry
 			)
 
 			for scanner.Scan() {
-				parts := logLineRegex.FindStringSubmatch(scanner.Text())
-
-				if len(parts) == 4 {
-					ts, err := time.ParseInLocation("2006/01/02 15:04:05", parts[1], time.Local)
-					if err != nil {
-						continue
-					}
-
-					if time.Since(ts) > since {
+				if entry := parseLogEntry(name, scanner.Text()); entry != nil {
+					if time.Since(entry.ts) > since {
 						continue
 					}
 
-					if parts[2] == "WARNING" {
-						parts[2] = "WARN"
-					}
-
-					body = &LogLine{
-						Source:    name,
-						Timestamp: parts[1],
-						Epoch:     ts.Unix(),
-						Level:     parts[2],
-						Log:       parts[3],
-
-						ts: ts,
-					}
+					body = entry
 				} else if body != nil {
 					body.Log = scanner.Text()
 				} else {
@@ -2862,7 +2491,7 @@ func GetLogs(w http.ResponseWriter, r *http.Request) {
 	var (
 		idx, offset int
 		ts          = make([]int, len(logs))
-		limited     []LogLine
+		limited     []logEntry
 	)
 
 	// Put log timestamps into slice so they can be sorted.
@@ -3200,6 +2829,13 @@ func CreateUserToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Don't let scoped API tokens be used to create new tokens that aren't
+	// limited to the same scope.
+	if api, ok := ctx.Value("api-token").(*v1.APITokenSpec); ok && len(api.Policies) > 0 {
+		http.Error(w, "scoped API tokens cannot create new tokens", http.StatusForbidden)
+		return
+	}
+
 	u, err := rbac.GetUser(uname)
 	if err != nil {
 		http.Error(w, "unable to get user", http.StatusInternalServerError)
@@ -3212,44 +2848,55 @@ func CreateUserToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var data map[string]string
+	var req struct {
+		Lifetime string           `json:"lifetime"`
+		Desc     string           `json:"desc"`
+		Policies []*v1.PolicySpec `json:"policies"`
+	}
 
-	if err := json.Unmarshal(body, &data); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	dur, err := time.ParseDuration(data["lifetime"])
-	if err != nil {
+	dur, err := time.ParseDuration(req.Lifetime)
+	if err != nil || dur <= 0 {
 		http.Error(w, "invalid token lifetime provided", http.StatusBadRequest)
 		return
 	}
 
-	exp := time.Now().Add(dur)
+	if err := rbac.ValidatePolicies(req.Policies); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		id  = rbac.NewTokenID()
+		exp = time.Now().Add(dur)
+	)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	signed, err := signToken(jwt.MapClaims{
 		"sub": u.Username(),
 		"exp": exp.Unix(),
+		"jti": id,
 	})
-
-	// Sign and get the complete encoded token as a string using the secret
-	signed, err := token.SignedString([]byte(o.jwtKey))
 	if err != nil {
 		http.Error(w, "failed to sign JWT", http.StatusInternalServerError)
 		return
 	}
 
 	note := fmt.Sprintf("manually generated - %s", time.Now().Format(time.RFC3339))
-	if desc := data["desc"]; desc != "" {
-		note = data["desc"]
+	if req.Desc != "" {
+		note = req.Desc
 	}
 
-	if err := u.AddToken(signed, note); err != nil {
+	if err := u.AddAPIToken(id, signed, note, exp, req.Policies); err != nil {
 		http.Error(w, "", http.StatusInternalServerError)
 		return
 	}
 
 	resp := map[string]string{
+		"id":    id,
 		"token": signed,
 		"desc":  note,
 		"exp":   exp.Format(time.RFC3339),
@@ -3259,6 +2906,77 @@ func CreateUserToken(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// GET /users/{username}/tokens
+func GetUserTokens(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetUserTokens HTTP handler called")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		uname = vars["username"]
+	)
+
+	if !role.Allowed("users", "get", uname) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	u, err := rbac.GetUser(uname)
+	if err != nil {
+		http.Error(w, "unable to get user", http.StatusNotFound)
+		return
+	}
+
+	tokens := make([]v1.APITokenSpec, 0)
+
+	for _, t := range u.APITokens() {
+		token := *t
+		token.Hash = ""
+
+		tokens = append(tokens, token)
+	}
+
+	body, _ := json.Marshal(util.WithRoot("tokens", tokens))
+	w.Write(body)
+}
+
+// DELETE /users/{username}/tokens/{id}
+func DeleteUserToken(w http.ResponseWriter, r *http.Request) {
+	log.Debug("DeleteUserToken HTTP handler called")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		uname = vars["username"]
+		id    = vars["id"]
+	)
+
+	if !role.Allowed("users", "patch", uname) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	u, err := rbac.GetUser(uname)
+	if err != nil {
+		http.Error(w, "unable to get user", http.StatusNotFound)
+		return
+	}
+
+	if err := u.DeleteAPIToken(id); err != nil {
+		if errors.Is(err, rbac.ErrTokenNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func Signup(w http.ResponseWriter, r *http.Request) {
 	log.Debug("Signup HTTP handler called")
 
@@ -3288,13 +3006,10 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 	u.Spec.FirstName = req.GetFirstName()
 	u.Spec.LastName = req.GetLastName()
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	signed, err := signToken(jwt.MapClaims{
 		"sub": u.Username(),
 		"exp": time.Now().Add(o.jwtLifetime).Unix(),
 	})
-
-	// Sign and get the complete encoded token as a string using the secret
-	signed, err := token.SignedString([]byte(o.jwtKey))
 	if err != nil {
 		http.Error(w, "failed to sign JWT", http.StatusInternalServerError)
 		return
@@ -3401,13 +3116,10 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	signed, err := signToken(jwt.MapClaims{
 		"sub": u.Username(),
 		"exp": time.Now().Add(o.jwtLifetime).Unix(),
 	})
-
-	// Sign and get the complete encoded token as a string using the secret
-	signed, err := token.SignedString([]byte(o.jwtKey))
 	if err != nil {
 		http.Error(w, "failed to sign JWT", http.StatusInternalServerError)
 		return
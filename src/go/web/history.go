@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"phenix/store"
+	"phenix/util/audit"
 	"phenix/web/rbac"
 	"phenix/web/util"
 	"phenix/web/weberror"
@@ -14,6 +16,11 @@ import (
 )
 
 // POST /history
+//
+// The request body is an event to match history events against (e.g. by type
+// or metadata), optionally limited to events between `since` and `until`.
+// Matching events are returned as JSON lines instead of JSON if the `format`
+// query parameter is set to `jsonl`.
 func GetHistory(w http.ResponseWriter, r *http.Request) error {
 	log.Debug("GetHistory HTTP handler called")
 
@@ -33,21 +40,38 @@ func GetHistory(w http.ResponseWriter, r *http.Request) error {
 		return err.SetStatus(http.StatusInternalServerError)
 	}
 
-	var event store.Event
+	var filter struct {
+		store.Event
 
-	if err := json.Unmarshal(body, &event); err != nil {
+		Since time.Time `json:"since"`
+		Until time.Time `json:"until"`
+	}
+
+	if err := json.Unmarshal(body, &filter); err != nil {
 		return weberror.NewWebError(err, "invalid history event filter provided")
 	}
 
-	events, err := store.GetEventsBy(event)
+	events, err := store.GetEventsBy(filter.Event)
 	if err != nil {
 		err := weberror.NewWebError(err, "unable to get matching history events")
 		return err.SetStatus(http.StatusInternalServerError)
 	}
 
+	events = events.Between(filter.Since, filter.Until)
+
 	// sort in descending order, so most recent event is first
 	events.SortByTimestamp(false)
 
+	if r.URL.Query().Get("format") == "jsonl" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+
+		if err := audit.Export(w, events); err != nil {
+			log.Error("exporting history events: %v", err)
+		}
+
+		return nil
+	}
+
 	body, err = json.Marshal(util.WithRoot("history", events))
 	if err != nil {
 		err := weberror.NewWebError(err, "unable to process history events")
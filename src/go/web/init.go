@@ -3,7 +3,13 @@ package web
 import (
 	"errors"
 	"fmt"
+
+	"phenix/store"
+	"phenix/util/pubsub"
+	"phenix/web/cache"
 	"phenix/web/rbac"
+
+	log "github.com/activeshadow/libminimega/minilog"
 )
 
 func Init() error {
@@ -26,5 +32,15 @@ func Init() error {
 		return fmt.Errorf("saving updated global-admin role: %w", err)
 	}
 
+	// When using Etcd for the store, also use it for the cache and to share
+	// events so multiple UI instances can serve the same cluster without
+	// duplicating operations or missing events.
+	if cli, ok := store.EtcdClient(); ok {
+		log.Info("using Etcd for UI cache and events")
+
+		cache.DefaultCache = cache.NewEtcdCache(cli)
+		pubsub.SetBus(pubsub.NewEtcdBus(cli))
+	}
+
 	return nil
 }
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"net/http"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// signToken signs a JWT with the given claims using the active key in the JWT
+// key set if one is configured, falling back to the JWT signing key otherwise.
+func signToken(claims jwt.MapClaims) (string, error) {
+	if jwtKeys != nil {
+		return jwtKeys.Sign(claims)
+	}
+
+	// Sign and get the complete encoded token as a string using the secret
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(o.jwtKey))
+}
+
+// GET /.well-known/jwks.json
+func GetJWKS(w http.ResponseWriter, r *http.Request) {
+	if jwtKeys == nil {
+		http.Error(w, "JWT key set not configured", http.StatusNotFound)
+		return
+	}
+
+	body, err := jwtKeys.JWKS()
+	if err != nil {
+		log.Error("getting JWT key set: %v", err)
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,381 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type Algorithm string
+
+const (
+	RS256 Algorithm = "RS256"
+	EdDSA Algorithm = "EdDSA"
+)
+
+// ParseAlgorithm returns the signing algorithm with the given name, defaulting
+// to RS256 if no name is given.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch name {
+	case "", string(RS256):
+		return RS256, nil
+	case string(EdDSA):
+		return EdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported JWT signing algorithm %s", name)
+	}
+}
+
+func (this Algorithm) method() jwt.SigningMethod {
+	if this == EdDSA {
+		return SigningMethodEdDSA
+	}
+
+	return jwt.SigningMethodRS256
+}
+
+// manifestName is the name of the file in a key set directory tracking which
+// keys are in the set and which key is used for signing. Private keys are
+// stored alongside the manifest as `<key ID>.pem`.
+const manifestName = "keyset.json"
+
+var ErrNoActiveKey = errors.New("no active signing key in key set")
+
+// KeyInfo describes a key in a key set.
+type KeyInfo struct {
+	ID      string     `json:"kid"`
+	Alg     Algorithm  `json:"alg"`
+	Created time.Time  `json:"created"`
+	Retired *time.Time `json:"retired,omitempty"`
+}
+
+type manifest struct {
+	Active string    `json:"active"`
+	Keys   []KeyInfo `json:"keys"`
+}
+
+type key struct {
+	info    KeyInfo
+	private crypto.Signer
+}
+
+// KeySet is a set of keys used to sign and verify JWTs. The active key is used
+// to sign new tokens, while all keys in the set (including keys retired by
+// rotation) can be used to verify tokens. The key set is reloaded from disk
+// when it's rotated, so running servers pick up new keys without a restart.
+type KeySet struct {
+	sync.RWMutex
+
+	dir      string
+	manifest os.FileInfo
+	active   string
+	keys     map[string]key
+}
+
+// Load loads the key set in the given directory.
+func Load(dir string) (*KeySet, error) {
+	set := &KeySet{dir: dir}
+
+	if err := set.refresh(); err != nil {
+		return nil, err
+	}
+
+	if set.active == "" {
+		return nil, fmt.Errorf("%w %s (run `phenix ui rotate-keys` to create one)", ErrNoActiveKey, dir)
+	}
+
+	return set, nil
+}
+
+// Sign signs the given claims with the key set's active key.
+func (this *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if err := this.refresh(); err != nil {
+		return "", err
+	}
+
+	this.RLock()
+	defer this.RUnlock()
+
+	k, ok := this.keys[this.active]
+	if !ok {
+		return "", ErrNoActiveKey
+	}
+
+	token := jwt.NewWithClaims(k.info.Alg.method(), claims)
+	token.Header["kid"] = k.info.ID
+
+	return token.SignedString(k.private)
+}
+
+// Keyfunc returns the public key to use to verify the given token based on the
+// key ID in the token's header. It's meant to be used when parsing tokens.
+func (this *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	if err := this.refresh(); err != nil {
+		return nil, err
+	}
+
+	this.RLock()
+	defer this.RUnlock()
+
+	k, ok := this.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %s", kid)
+	}
+
+	// Don't let tokens choose how they're verified.
+	if token.Method.Alg() != string(k.info.Alg) {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+
+	return k.private.Public(), nil
+}
+
+// JWKS returns the public keys in the key set as a JSON Web Key Set.
+func (this *KeySet) JWKS() ([]byte, error) {
+	if err := this.refresh(); err != nil {
+		return nil, err
+	}
+
+	this.RLock()
+	defer this.RUnlock()
+
+	keys := make([]map[string]string, 0, len(this.keys))
+
+	for _, k := range this.keys {
+		jwk := map[string]string{"kid": k.info.ID, "alg": string(k.info.Alg), "use": "sig"}
+
+		switch pub := k.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(pub)
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	return json.Marshal(map[string]interface{}{"keys": keys})
+}
+
+// refresh reloads the key set from disk if it has changed since it was last
+// loaded.
+func (this *KeySet) refresh() error {
+	info, err := os.Stat(filepath.Join(this.dir, manifestName))
+	if err != nil {
+		return fmt.Errorf("getting key set manifest: %w", err)
+	}
+
+	// The manifest is replaced (not modified in place) when the key set is
+	// rotated, so it's a different file if the key set has changed.
+	this.RLock()
+	current := this.manifest != nil && os.SameFile(info, this.manifest) && info.ModTime().Equal(this.manifest.ModTime())
+	this.RUnlock()
+
+	if current {
+		return nil
+	}
+
+	m, err := readManifest(this.dir)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]key)
+
+	for _, k := range m.Keys {
+		private, err := readKey(this.dir, k.ID)
+		if err != nil {
+			return err
+		}
+
+		keys[k.ID] = key{info: k, private: private}
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.manifest = info
+	this.active = m.Active
+	this.keys = keys
+
+	return nil
+}
+
+// Rotate creates a new signing key in the key set in the given directory
+// (creating the key set if it doesn't exist yet) and retires the current
+// active key. Retired keys are still used to verify tokens until they've been
+// retired longer than the given retention period, at which point they're
+// removed from the key set and tokens signed with them are no longer valid.
+// The ID of the new key and the IDs of any removed keys are returned.
+func Rotate(dir string, alg Algorithm, retain time.Duration) (string, []string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, fmt.Errorf("creating key set directory: %w", err)
+	}
+
+	m, err := readManifest(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", nil, err
+	}
+
+	var private crypto.Signer
+
+	switch alg {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported algorithm %s", alg)
+	}
+
+	if err != nil {
+		return "", nil, fmt.Errorf("generating signing key: %w", err)
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	var (
+		now    = time.Now().UTC()
+		kid    = hex.EncodeToString(id)
+		keys   []KeyInfo
+		pruned []string
+	)
+
+	if err := writeKey(dir, kid, private); err != nil {
+		return "", nil, err
+	}
+
+	for _, info := range m.Keys {
+		if info.ID == m.Active {
+			info.Retired = &now
+		}
+
+		if info.Retired != nil && now.Sub(*info.Retired) > retain {
+			pruned = append(pruned, info.ID)
+			continue
+		}
+
+		keys = append(keys, info)
+	}
+
+	m.Active = kid
+	m.Keys = append(keys, KeyInfo{ID: kid, Alg: alg, Created: now})
+
+	if err := writeManifest(dir, m); err != nil {
+		return "", nil, err
+	}
+
+	for _, id := range pruned {
+		os.Remove(filepath.Join(dir, id+".pem"))
+	}
+
+	return kid, pruned, nil
+}
+
+// Keys returns the keys in the key set in the given directory and the ID of
+// the active key.
+func Keys(dir string) ([]KeyInfo, string, error) {
+	m, err := readManifest(dir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return m.Keys, m.Active, nil
+}
+
+func readManifest(dir string) (manifest, error) {
+	var m manifest
+
+	body, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return m, fmt.Errorf("reading key set manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(body, &m); err != nil {
+		return m, fmt.Errorf("parsing key set manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// writeManifest writes the manifest to a temporary file first, then renames it
+// so servers using the key set never see a partially written manifest.
+func writeManifest(dir string, m manifest) error {
+	body, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling key set manifest: %w", err)
+	}
+
+	tmp := filepath.Join(dir, manifestName+".tmp")
+
+	if err := os.WriteFile(tmp, body, 0600); err != nil {
+		return fmt.Errorf("writing key set manifest: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(dir, manifestName)); err != nil {
+		return fmt.Errorf("writing key set manifest: %w", err)
+	}
+
+	return nil
+}
+
+func readKey(dir, kid string) (crypto.Signer, error) {
+	body, err := os.ReadFile(filepath.Join(dir, kid+".pem"))
+	if err != nil {
+		return nil, fmt.Errorf("reading key %s: %w", kid, err)
+	}
+
+	block, _ := pem.Decode(body)
+	if block == nil {
+		return nil, fmt.Errorf("decoding key %s: no PEM data found", kid)
+	}
+
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing key %s: %w", kid, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type for key %s", kid)
+	}
+
+	return signer, nil
+}
+
+func writeKey(dir, kid string, private crypto.Signer) error {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return fmt.Errorf("marshaling key %s: %w", kid, err)
+	}
+
+	body := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), body, 0600); err != nil {
+		return fmt.Errorf("writing key %s: %w", kid, err)
+	}
+
+	return nil
+}
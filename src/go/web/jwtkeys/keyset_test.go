@@ -0,0 +1,132 @@
+package jwtkeys
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func sign(t *testing.T, set *KeySet) string {
+	signed, err := set.Sign(jwt.MapClaims{"sub": "foo", "exp": time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return signed
+}
+
+func rotate(t *testing.T, dir string, alg Algorithm, retain time.Duration) string {
+	kid, _, err := Rotate(dir, alg, retain)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return kid
+}
+
+func TestLoadEmpty(t *testing.T) {
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Log("expected error loading empty key set")
+		t.FailNow()
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+
+	first := rotate(t, dir, RS256, time.Hour)
+
+	set, err := Load(dir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	old := sign(t, set)
+
+	// Running servers should pick up the new key without reloading the set.
+	second := rotate(t, dir, EdDSA, time.Hour)
+
+	current := sign(t, set)
+
+	for _, signed := range []string{old, current} {
+		if _, err := jwt.Parse(signed, set.Keyfunc); err != nil {
+			t.Logf("verifying token during rotation: %v", err)
+			t.FailNow()
+		}
+	}
+
+	token, _ := jwt.Parse(current, set.Keyfunc)
+
+	if token.Header["kid"] != second || token.Method.Alg() != "EdDSA" {
+		t.Logf("expected token signed by new key %s, got %v (%s)", second, token.Header["kid"], token.Method.Alg())
+		t.FailNow()
+	}
+
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+
+	body, _ := set.JWKS()
+
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(jwks.Keys) != 2 {
+		t.Logf("expected 2 keys in JWKS, got %d", len(jwks.Keys))
+		t.FailNow()
+	}
+
+	// With no retention, the next rotation removes the key retired by the
+	// previous rotation.
+	_, pruned, err := Rotate(dir, RS256, 0)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(pruned) != 1 || pruned[0] != first {
+		t.Logf("expected key %s to be pruned, got %v", first, pruned)
+		t.FailNow()
+	}
+
+	if _, err := jwt.Parse(old, set.Keyfunc); err == nil {
+		t.Log("expected error verifying token signed by pruned key")
+		t.FailNow()
+	}
+}
+
+func TestKeyfuncAlgorithmMismatch(t *testing.T) {
+	dir := t.TempDir()
+	rotate(t, dir, EdDSA, time.Hour)
+
+	set, err := Load(dir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	keys, active, _ := Keys(dir)
+
+	// An HS256 token claiming to be signed by the EdDSA key shouldn't verify.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "foo"})
+	token.Header["kid"] = active
+
+	signed, _ := token.SignedString([]byte("secret"))
+
+	if _, err := jwt.Parse(signed, set.Keyfunc); err == nil {
+		t.Log("expected error verifying token with mismatched algorithm")
+		t.FailNow()
+	}
+
+	if len(keys) != 1 || keys[0].Alg != EdDSA {
+		t.Logf("unexpected keys %+v", keys)
+		t.FailNow()
+	}
+}
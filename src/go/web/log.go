@@ -6,11 +6,14 @@ import (
 	"regexp"
 	"time"
 
+	"phenix/util/plog"
 	"phenix/web/broker"
 
 	"github.com/hpcloud/tail"
 )
 
+// logLineRegex matches lines logged by minimega (and user apps not logging
+// structured records) using minilog's text format.
 var logLineRegex = regexp.MustCompile(`\A(\d{4}\/\d{2}\/\d{2} \d{2}:\d{2}:\d{2})\s* (DEBUG|INFO|WARN|WARNING|ERROR|FATAL) .*?: (.*)\z`)
 
 type LogKind int
@@ -61,8 +64,8 @@ func PublishLogs(ctx context.Context, phenix, minimega string) {
 
 	// Used to detect multi-line logs in tailed log files.
 	var (
-		mmBody     map[string]interface{}
-		phenixBody map[string]interface{}
+		mmBody     *logEntry
+		phenixBody *logEntry
 	)
 
 	for {
@@ -70,59 +73,35 @@ func PublishLogs(ctx context.Context, phenix, minimega string) {
 		case <-ctx.Done():
 			return
 		case l := <-logs:
-			parts := logLineRegex.FindStringSubmatch(l.line)
-
 			switch l.kind {
 			case LOG_PHENIX:
-				if len(parts) == 4 {
-					ts, err := time.ParseInLocation("2006/01/02 15:04:05", parts[1], time.Local)
-					if err != nil {
-						continue
-					}
-
-					phenixBody = map[string]interface{}{
-						"source":    "phenix",
-						"timestamp": parts[1],
-						"epoch":     ts.Unix(),
-						"level":     parts[2],
-						"log":       parts[3],
-					}
+				if entry := parseLogEntry("phenix", l.line); entry != nil {
+					phenixBody = entry
 				} else if phenixBody != nil {
-					phenixBody["log"] = l.line
+					phenixBody.Log = l.line
 				} else {
 					continue
 				}
 
 				marshalled, _ := json.Marshal(phenixBody)
 
-				broker.Broadcast(
+				broker.BroadcastLocal(
 					nil,
 					broker.NewResource("log", "phenix", "update"),
 					marshalled,
 				)
 			case LOG_MINIMEGA:
-				if len(parts) == 4 {
-					ts, err := time.ParseInLocation("2006/01/02 15:04:05", parts[1], time.Local)
-					if err != nil {
-						continue
-					}
-
-					mmBody = map[string]interface{}{
-						"source":    "minimega",
-						"timestamp": parts[1],
-						"epoch":     ts.Unix(),
-						"level":     parts[2],
-						"log":       parts[3],
-					}
+				if entry := parseLogEntry("minimega", l.line); entry != nil {
+					mmBody = entry
 				} else if mmBody != nil {
-					mmBody["log"] = l.line
+					mmBody.Log = l.line
 				} else {
 					continue
 				}
 
 				marshalled, _ := json.Marshal(mmBody)
 
-				broker.Broadcast(
+				broker.BroadcastLocal(
 					nil,
 					broker.NewResource("log", "minimega", "update"),
 					marshalled,
@@ -133,3 +112,59 @@ func PublishLogs(ctx context.Context, phenix, minimega string) {
 		}
 	}
 }
+
+type logEntry struct {
+	Source    string                 `json:"source"`
+	Timestamp string                 `json:"timestamp"`
+	Epoch     int64                  `json:"epoch"`
+	Level     string                 `json:"level"`
+	Log       string                 `json:"log"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+
+	// Not exported so it doesn't get included in serialized JSON.
+	ts time.Time
+}
+
+// parseLogEntry parses a line from the given source's log file, returning nil
+// if the line isn't a new log entry (e.g. it's a continuation of a multi-line
+// log). Lines are expected to be structured records, falling back to
+// minilog's text format for minimega and user apps that don't log structured
+// records.
+func parseLogEntry(source, line string) *logEntry {
+	if rec, err := plog.ParseRecord([]byte(line)); err == nil {
+		return &logEntry{
+			Source:    source,
+			Timestamp: rec.Time.Local().Format("2006/01/02 15:04:05"),
+			Epoch:     rec.Time.Unix(),
+			Level:     rec.Level,
+			Log:       rec.Message,
+			Fields:    rec.Fields,
+
+			ts: rec.Time,
+		}
+	}
+
+	parts := logLineRegex.FindStringSubmatch(line)
+	if len(parts) != 4 {
+		return nil
+	}
+
+	ts, err := time.ParseInLocation("2006/01/02 15:04:05", parts[1], time.Local)
+	if err != nil {
+		return nil
+	}
+
+	if parts[2] == "WARNING" {
+		parts[2] = "WARN"
+	}
+
+	return &logEntry{
+		Source:    source,
+		Timestamp: parts[1],
+		Epoch:     ts.Unix(),
+		Level:     parts[2],
+		Log:       parts[3],
+
+		ts: ts,
+	}
+}
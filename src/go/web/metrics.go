@@ -0,0 +1,121 @@
+package web
+
+import (
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/util/metrics"
+	"phenix/util/mm"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	experimentsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "", "experiments"),
+		"Number of experiments by state.",
+		[]string{"state"}, nil,
+	)
+
+	vmsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "", "vms"),
+		"Number of VMs in running experiments by state and cluster host.",
+		[]string{"state", "host"}, nil,
+	)
+
+	vlanPoolSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "vlan", "pool_size"),
+		"Size of the VLAN range configured for each experiment.",
+		[]string{"experiment"}, nil,
+	)
+
+	vlansAllocatedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "vlan", "allocated"),
+		"Number of VLANs allocated to each running experiment.",
+		[]string{"experiment"}, nil,
+	)
+)
+
+// GET /metrics
+//
+// Metrics include experiment names and cluster host details, so scraping them
+// requires the same authentication as the rest of the API. Prometheus can use
+// an API token as a bearer token.
+func GetMetrics(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetMetrics HTTP handler called")
+
+	role := r.Context().Value("role").(rbac.Role)
+
+	if !role.Allowed("metrics", "get") {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	promhttp.Handler().ServeHTTP(w, r)
+}
+
+// stateCollector collects metrics for the current state of experiments, VMs,
+// and VLANs each time metrics are scraped.
+type stateCollector struct{}
+
+func (stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- experimentsDesc
+	ch <- vmsDesc
+	ch <- vlanPoolSizeDesc
+	ch <- vlansAllocatedDesc
+}
+
+func (stateCollector) Collect(ch chan<- prometheus.Metric) {
+	exps, err := experiment.List()
+	if err != nil {
+		log.Error("collecting experiment metrics: %v", err)
+		return
+	}
+
+	var (
+		experiments = map[string]int{string(cache.StatusStarted): 0, string(cache.StatusStopped): 0}
+		vms         = make(map[[2]string]int)
+	)
+
+	for _, exp := range exps {
+		name := exp.Metadata.Name
+
+		state := string(cache.IsExperimentLocked(name))
+
+		if state == "" {
+			if exp.Running() {
+				state = string(cache.StatusStarted)
+			} else {
+				state = string(cache.StatusStopped)
+			}
+		}
+
+		experiments[state]++
+
+		if min, max := exp.Spec.VLANs().Min(), exp.Spec.VLANs().Max(); min > 0 && max >= min {
+			ch <- prometheus.MustNewConstMetric(vlanPoolSizeDesc, prometheus.GaugeValue, float64(max-min+1), name)
+		}
+
+		if !exp.Running() {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(vlansAllocatedDesc, prometheus.GaugeValue, float64(len(exp.Status.VLANs())), name)
+
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			vms[[2]string{vm.State, vm.Host}]++
+		}
+	}
+
+	for state, count := range experiments {
+		ch <- prometheus.MustNewConstMetric(experimentsDesc, prometheus.GaugeValue, float64(count), state)
+	}
+
+	for labels, count := range vms {
+		ch <- prometheus.MustNewConstMetric(vmsDesc, prometheus.GaugeValue, float64(count), labels[0], labels[1])
+	}
+}
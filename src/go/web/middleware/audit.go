@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"phenix/util/audit"
+	"phenix/web/rbac"
+
+	"github.com/gorilla/mux"
+)
+
+// auditedGets are routes that don't modify anything but still need to be
+// audited, either because they act on a resource despite being GET requests
+// (e.g. resetting a VM) or because they open an interactive session.
+var auditedGets = map[string]bool{
+	"/experiments/{exp}/vms/{name}/reset":                true,
+	"/experiments/{exp}/vms/{name}/restart":              true,
+	"/experiments/{exp}/vms/{name}/shutdown":             true,
+	"/experiments/{exp}/vms/{name}/vnc/ws":               true,
+	"/experiments/{exp}/vms/{name}/files/download":       true,
+	"/experiments/{name}/scorch/terminals/{pid}/ws/{id}": true,
+	"/console/{pid}/ws":                                  true,
+}
+
+// unauditedPosts are routes that use POST requests to query resources.
+var unauditedPosts = map[string]bool{
+	"/history":          true,
+	"/configs/download": true,
+}
+
+// Audit records an audit event for each request that modifies a resource or
+// opens an interactive session, including who made the request, where it came
+// from, and whether it succeeded. It must be used after the Auth middleware.
+func Audit(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action, ok := auditAction(r)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		var (
+			ctx     = r.Context()
+			user, _ = ctx.Value("user").(string)
+			role, _ = ctx.Value("role").(rbac.Role)
+		)
+
+		entry := audit.Record{
+			User:     user,
+			Address:  ClientAddress(r),
+			Via:      "http",
+			Action:   action,
+			Resource: resourcePath(r),
+		}
+
+		if role.Spec != nil {
+			entry.Role = role.Spec.Name
+		}
+
+		logged := false
+
+		record := func(status int) {
+			if logged {
+				return
+			}
+
+			entry.Status = status
+			entry.Outcome = audit.OutcomeFromStatus(status)
+
+			audit.Log(entry)
+			logged = true
+		}
+
+		// Interactive sessions (e.g. VNC) are audited when they're opened rather
+		// than when they're closed.
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK, hijacked: record}
+
+		h.ServeHTTP(rec, r)
+
+		record(rec.status)
+	})
+}
+
+// auditAction returns the action to record for the given request (its method
+// and route template, e.g. `POST /experiments/{name}/start`) and whether it
+// should be audited at all.
+func auditAction(r *http.Request) (string, bool) {
+	if r.Method == http.MethodOptions {
+		return "", false
+	}
+
+	tmpl := routeTemplate(r)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !auditedGets[tmpl] {
+			return "", false
+		}
+	case http.MethodPost:
+		if unauditedPosts[tmpl] {
+			return "", false
+		}
+	}
+
+	return r.Method + " " + tmpl, true
+}
+
+// routeTemplate returns the template of the route matching the given request,
+// without the API prefix (e.g. `/experiments/{name}/start`), falling back to
+// the request's path if it didn't match a route.
+func routeTemplate(r *http.Request) string {
+	var tmpl string
+
+	if route := mux.CurrentRoute(r); route != nil {
+		tmpl, _ = route.GetPathTemplate()
+	}
+
+	// Route templates include the API prefix, which isn't useful to include.
+	if idx := strings.Index(tmpl, "/api/v1"); idx >= 0 {
+		tmpl = tmpl[idx+len("/api/v1"):]
+	}
+
+	if tmpl == "" {
+		tmpl = r.URL.Path
+	}
+
+	return tmpl
+}
+
+// resourcePath returns the path of the resource the given request acts on,
+// without the API prefix.
+func resourcePath(r *http.Request) string {
+	path := r.URL.Path
+
+	if idx := strings.Index(path, "/api/v1"); idx >= 0 {
+		path = path[idx+len("/api/v1"):]
+	}
+
+	if q := r.URL.Query().Get("path"); q != "" {
+		path += "?path=" + q
+	}
+
+	return path
+}
+
+// statusRecorder tracks the status code written to a response. It supports
+// hijacking and flushing so it can wrap WebSocket and streaming handlers.
+type statusRecorder struct {
+	http.ResponseWriter
+
+	status   int
+	hijacked func(int)
+}
+
+func (this *statusRecorder) WriteHeader(status int) {
+	this.status = status
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := this.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	this.status = http.StatusSwitchingProtocols
+
+	if this.hijacked != nil {
+		this.hijacked(this.status)
+	}
+
+	return conn, rw, nil
+}
+
+func (this *statusRecorder) Flush() {
+	if flusher, ok := this.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+	"phenix/web/rbac"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestAudit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var events []store.Event
+
+	m := store.NewMockStore(ctrl)
+	m.EXPECT().AddEvent(gomock.Any()).DoAndReturn(func(e store.Event) error {
+		events = append(events, e)
+		return nil
+	}).AnyTimes()
+
+	store.DefaultStore = m
+
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	forbidden := func(w http.ResponseWriter, r *http.Request) { http.Error(w, "forbidden", http.StatusForbidden) }
+
+	api.HandleFunc("/experiments", ok).Methods("GET")
+	api.HandleFunc("/experiments/{name}/start", forbidden).Methods("POST")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/reset", ok).Methods("GET")
+	api.HandleFunc("/history", ok).Methods("POST")
+
+	// Stand in for the Auth middleware.
+	api.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := rbac.Role{Spec: &v1.RoleSpec{Name: "Experiment User"}}
+
+			ctx := context.WithValue(r.Context(), "user", "alice")
+			ctx = context.WithValue(ctx, "role", role)
+
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+
+	api.Use(Audit)
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/api/v1/experiments", nil),
+		httptest.NewRequest("POST", "/api/v1/experiments/foo/start", nil),
+		httptest.NewRequest("GET", "/api/v1/experiments/foo/vms/bar/reset", nil),
+		httptest.NewRequest("POST", "/api/v1/history", nil),
+	}
+
+	for _, req := range requests {
+		req.RemoteAddr = "10.0.0.1:54321"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(events) != 2 {
+		t.Logf("expected 2 audit events, got %d", len(events))
+		t.FailNow()
+	}
+
+	expected := []map[string]string{
+		{"user": "alice", "role": "Experiment User", "address": "10.0.0.1", "action": "POST /experiments/{name}/start", "resource": "/experiments/foo/start", "outcome": "denied", "status": "403"},
+		{"user": "alice", "action": "GET /experiments/{exp}/vms/{name}/reset", "resource": "/experiments/foo/vms/bar/reset", "outcome": "success", "status": "200"},
+	}
+
+	for i, md := range expected {
+		if events[i].Type != store.EventTypeAudit {
+			t.Logf("expected audit event, got %s", events[i].Type)
+			t.FailNow()
+		}
+
+		for k, v := range md {
+			if events[i].Metadata[k] != v {
+				t.Logf("expected event %d metadata %s to be %s, got %s", i, k, v, events[i].Metadata[k])
+				t.FailNow()
+			}
+		}
+	}
+}
@@ -3,16 +3,31 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
-	"phenix/web/rbac"
 	"strings"
 
+	v1 "phenix/types/version/v1"
+	"phenix/web/jwtkeys"
+	"phenix/web/rbac"
+
 	log "github.com/activeshadow/libminimega/minilog"
 	jwtmiddleware "github.com/cescoferraro/go-jwt-middleware"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 )
 
+// publicPaths are the API paths that don't require authentication. They're
+// matched exactly so other routes that happen to contain them (for example, a
+// config named `login`) still require authentication.
+var publicPaths = map[string]bool{
+	"/api/v1/signup":              true,
+	"/api/v1/login":               true,
+	"/api/v1/login/oidc":          true,
+	"/api/v1/login/oidc/callback": true,
+	"/api/v1/openapi.json":        true,
+}
+
 func fromPhenixAuthTokenHeader(r *http.Request) (string, error) {
 	authHeader := r.Header.Get("X-phenix-auth-token")
 	if authHeader == "" {
@@ -40,7 +55,9 @@ func NoAuth(h http.Handler) http.Handler {
 	})
 }
 
-func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
+// Auth validates the JWT included in requests, verifying HS256 tokens with the
+// given signing key and any other tokens with the given key set (if not nil).
+func Auth(jwtKey, proxyAuthHeader string, keys *jwtkeys.KeySet) mux.MiddlewareFunc {
 	tokenMiddleware := jwtmiddleware.New(
 		jwtmiddleware.Options{
 			// Setting this to true since some resource paths don't require
@@ -55,11 +72,8 @@ func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
 			// Note that we're not using the default Authorization header to allow for
 			// proxy authentication via basic auth (or other means of proxy
 			// authentication that might end up overwriting the Authorization header).
-			Extractor: jwtmiddleware.FromFirst(fromPhenixAuthTokenHeader, jwtmiddleware.FromParameter("token")),
-			ValidationKeyGetter: func(_ *jwt.Token) (interface{}, error) {
-				return []byte(jwtKey), nil
-			},
-			SigningMethod: jwt.SigningMethodHS256,
+			Extractor:           jwtmiddleware.FromFirst(fromPhenixAuthTokenHeader, jwtmiddleware.FromParameter("token")),
+			ValidationKeyGetter: keyFunc(jwtKey, keys),
 			ErrorHandler: func(w http.ResponseWriter, r *http.Request, e string) {
 				log.Error("Error validating auth token: %s", e)
 
@@ -72,12 +86,7 @@ func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
 
 	userMiddleware := func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if strings.HasSuffix(r.URL.Path, "/signup") {
-				h.ServeHTTP(w, r)
-				return
-			}
-
-			if strings.HasSuffix(r.URL.Path, "/login") {
+			if publicPaths[r.URL.Path] {
 				h.ServeHTTP(w, r)
 				return
 			}
@@ -102,26 +111,17 @@ func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
 				}
 			}
 
-			user, err := rbac.GetUser(claim["sub"].(string))
+			user, role, api, err := authorize(token, ClientAddress(r))
 			if err != nil {
-				http.Error(w, "user error", http.StatusUnauthorized)
-				return
-			}
-
-			// Check to see that the token is still associated w/ the user (ie. the
-			// user didn't delete it because it became compromised).
-			if err := user.ValidateToken(token.Raw); err != nil {
-				http.Error(w, "user token error", http.StatusUnauthorized)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
 
-			role, err := user.Role()
-			if err != nil {
-				http.Error(w, "user role error", http.StatusUnauthorized)
-				return
+			if api != nil {
+				ctx = context.WithValue(ctx, "api-token", api)
 			}
 
-			ctx = context.WithValue(ctx, "user", user.Username())
+			ctx = context.WithValue(ctx, "user", user)
 			ctx = context.WithValue(ctx, "role", role)
 			ctx = context.WithValue(ctx, "jwt", token.Raw)
 
@@ -147,7 +147,7 @@ func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
 		})
 	}
 
-	if jwtKey == "" {
+	if jwtKey == "" && keys == nil {
 		log.Info("no JWT signing key provided -- disabling auth")
 		return func(h http.Handler) http.Handler { return NoAuth(h) }
 	} else if strings.HasPrefix(jwtKey, "dev|") {
@@ -158,3 +158,201 @@ func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
 	// First validate the token itself, then ensure the user in the token is valid.
 	return func(h http.Handler) http.Handler { return tokenMiddleware.Handler(userMiddleware(h)) }
 }
+
+// Authenticate validates the given token the same way the Auth middleware does
+// for HTTP requests, returning the name of the user the token was issued to and
+// the role it grants. The address is that of the client presenting the token.
+// It's used to authenticate requests to APIs not served over HTTP.
+func Authenticate(jwtKey string, keys *jwtkeys.KeySet, raw, addr string) (string, rbac.Role, error) {
+	if jwtKey == "" && keys == nil {
+		role, err := rbac.RoleFromConfig("global-admin")
+		if err != nil {
+			return "", rbac.Role{}, fmt.Errorf("getting global-admin role: %w", err)
+		}
+
+		return "global-admin", *role, nil
+	}
+
+	if strings.HasPrefix(jwtKey, "dev|") {
+		creds := strings.Split(jwtKey, "|")
+
+		role, err := rbac.RoleFromConfig(creds[2])
+		if err != nil {
+			return "", rbac.Role{}, fmt.Errorf("getting %s role: %w", creds[2], err)
+		}
+
+		return creds[1], *role, nil
+	}
+
+	if raw == "" {
+		return "", rbac.Role{}, fmt.Errorf("missing user token")
+	}
+
+	token, err := jwt.Parse(raw, keyFunc(jwtKey, keys))
+	if err != nil {
+		return "", rbac.Role{}, fmt.Errorf("invalid user token: %w", err)
+	}
+
+	if !token.Valid {
+		return "", rbac.Role{}, fmt.Errorf("invalid user token")
+	}
+
+	user, role, _, err := authorize(token, addr)
+	return user, role, err
+}
+
+// keyFunc returns the function used to get the key to validate tokens with,
+// verifying HS256 tokens with the given signing key and any other tokens with
+// the given key set (if not nil).
+func keyFunc(jwtKey string, keys *jwtkeys.KeySet) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		if token.Method == jwt.SigningMethodHS256 {
+			if jwtKey == "" {
+				return nil, fmt.Errorf("HS256 tokens not accepted")
+			}
+
+			return []byte(jwtKey), nil
+		}
+
+		if keys == nil {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		return keys.Keyfunc(token)
+	}
+}
+
+// authorize ensures the user the given (already validated) token was issued to
+// is valid and still has the token, returning the user's name and role. If the
+// token is an API token, its details are returned as well and the role is
+// limited to the token's scope.
+func authorize(token *jwt.Token, addr string) (string, rbac.Role, *v1.APITokenSpec, error) {
+	claim, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", rbac.Role{}, nil, fmt.Errorf("user token error")
+	}
+
+	sub, _ := claim["sub"].(string)
+
+	user, err := rbac.GetUser(sub)
+	if err != nil {
+		return "", rbac.Role{}, nil, fmt.Errorf("user error")
+	}
+
+	role, err := user.Role()
+	if err != nil {
+		return "", rbac.Role{}, nil, fmt.Errorf("user role error")
+	}
+
+	// API tokens include their ID. Check to see that the token is still
+	// associated w/ the user (ie. the user didn't revoke it because it
+	// became compromised) and limit the user's role to the token's scope.
+	if id, _ := claim["jti"].(string); id != "" {
+		api, err := user.ValidateAPIToken(id, token.Raw, addr)
+		if err != nil {
+			log.Error("rejecting API token %s for user %s: %v", id, user.Username(), err)
+			return "", rbac.Role{}, nil, fmt.Errorf("user token error")
+		}
+
+		return user.Username(), role.Scoped(api.Policies), api, nil
+	}
+
+	if err := user.ValidateToken(token.Raw); err != nil {
+		return "", rbac.Role{}, nil, fmt.Errorf("user token error")
+	}
+
+	return user.Username(), role, nil, nil
+}
+
+// trustedProxies are the networks of the proxies allowed to set the address of
+// the original client via the X-Forwarded-For header.
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the proxies allowed to set the address of the original
+// client via the X-Forwarded-For header. Each proxy can be an IP address or a
+// CIDR network. The header is ignored if no proxies are trusted.
+func SetTrustedProxies(proxies []string) error {
+	var networks []*net.IPNet
+
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy address %s", proxy)
+			}
+
+			if ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy network %s: %w", proxy, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	trustedProxies = networks
+
+	return nil
+}
+
+// ClientAddress returns the address of the client that made the given request.
+// If the request came from a trusted proxy, the original client address is
+// taken from the X-Forwarded-For header, skipping any other trusted proxies in
+// it. Addresses added to the header before the last untrusted address could
+// have been set by the client, so they're ignored.
+func ClientAddress(r *http.Request) string {
+	addr := r.RemoteAddr
+
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	if !trustedProxy(addr) {
+		return addr
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+
+		if hop == "" {
+			continue
+		}
+
+		addr = hop
+
+		if !trustedProxy(hop) {
+			break
+		}
+	}
+
+	return addr
+}
+
+func trustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
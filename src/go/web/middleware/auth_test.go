@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthPublicPaths(t *testing.T) {
+	handler := Auth("secret", "", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := map[string]int{
+		"/api/v1/login":                    http.StatusOK,
+		"/api/v1/login/oidc":               http.StatusOK,
+		"/api/v1/login/oidc/callback":      http.StatusOK,
+		"/api/v1/configs/login/oidc":       http.StatusForbidden,
+		"/api/v1/configs/foo/login":        http.StatusForbidden,
+		"/api/v1/experiments/login/oidc/x": http.StatusForbidden,
+		"/api/v1/openapi.json":             http.StatusOK,
+		"/api/v1/configs/foo/openapi.json": http.StatusForbidden,
+	}
+
+	for path, code := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+
+		if rec.Code != code {
+			t.Logf("expected status %d for %s, got %d", code, path, rec.Code)
+			t.FailNow()
+		}
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	defer SetTrustedProxies(nil)
+
+	request := func(remote, fwd string) *http.Request {
+		r := httptest.NewRequest("GET", "/api/v1/experiments", nil)
+		r.RemoteAddr = remote
+
+		if fwd != "" {
+			r.Header.Set("X-Forwarded-For", fwd)
+		}
+
+		return r
+	}
+
+	if addr := ClientAddress(request("192.0.2.1:1234", "10.0.0.1")); addr != "192.0.2.1" {
+		t.Logf("expected X-Forwarded-For to be ignored without trusted proxies, got %s", addr)
+		t.FailNow()
+	}
+
+	if err := SetTrustedProxies([]string{"192.0.2.1", "10.1.0.0/16"}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	tests := []struct {
+		remote, fwd, expected string
+	}{
+		{"192.0.2.1:1234", "10.0.0.1", "10.0.0.1"},
+		{"192.0.2.1:1234", "", "192.0.2.1"},
+		{"192.0.2.1:1234", "6.6.6.6, 10.0.0.1, 10.1.2.3", "10.0.0.1"},
+		{"192.0.2.2:1234", "10.0.0.1", "192.0.2.2"},
+	}
+
+	for _, test := range tests {
+		if addr := ClientAddress(request(test.remote, test.fwd)); addr != test.expected {
+			t.Logf("expected address %s for %s forwarded for %q, got %s", test.expected, test.remote, test.fwd, addr)
+			t.FailNow()
+		}
+	}
+
+	if err := SetTrustedProxies([]string{"not-an-address"}); err == nil {
+		t.Log("expected error for invalid trusted proxy")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"phenix/util/metrics"
+)
+
+// Metrics records the duration of each request by route, method, and response
+// status. The duration of WebSocket requests is recorded when the connection
+// is upgraded rather than when it's closed.
+func Metrics(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start    = time.Now()
+			observed bool
+		)
+
+		observe := func(status int) {
+			if observed {
+				return
+			}
+
+			metrics.HTTPRequestDuration.
+				WithLabelValues(routeTemplate(r), r.Method, strconv.Itoa(status)).
+				Observe(time.Since(start).Seconds())
+
+			observed = true
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK, hijacked: observe}
+
+		h.ServeHTTP(rec, r)
+
+		observe(rec.status)
+	})
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"phenix/util/metrics"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+
+	api.HandleFunc("/experiments/{name}/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "conflict", http.StatusConflict)
+	}).Methods("POST")
+
+	api.Use(Metrics)
+
+	for _, name := range []string{"foo", "bar"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/experiments/"+name+"/start", nil))
+	}
+
+	observer := metrics.HTTPRequestDuration.WithLabelValues("/experiments/{name}/start", "POST", "409")
+
+	var m dto.Metric
+
+	if err := observer.(prometheus.Metric).Write(&m); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if count := m.GetHistogram().GetSampleCount(); count != 2 {
+		t.Logf("expected 2 requests observed for route, got %d", count)
+		t.FailNow()
+	}
+}
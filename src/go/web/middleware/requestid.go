@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"phenix/util/plog"
+)
+
+// RequestIDHeader is the header used to pass request IDs between clients and
+// the phenix API.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID adds a request ID to the context of each request so all logs
+// generated while handling the request can be correlated. The request ID
+// provided by the client, if any, is used. Otherwise, a new one is generated.
+// In either case, the request ID is included in the response headers.
+func RequestID(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+
+		if id == "" {
+			id = plog.NewRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := plog.WithRequestID(r.Context(), id)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -42,7 +42,7 @@ func MountVM(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	role := r.Context().Value("role").(rbac.Role)
 
-	if !role.Allowed("vms/mount", "post", fmt.Sprintf("%s/%s", vars["exp"], vars["name"])) {
+	if !util.VMPathAllowed(role, "vms/mount", "post", vars["exp"], vars["name"]) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -81,7 +81,7 @@ func UnmountVM(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	role := r.Context().Value("role").(rbac.Role)
 
-	if !role.Allowed("vms/mount", "delete", fmt.Sprintf("%s/%s", vars["exp"], vars["name"])) {
+	if !util.VMPathAllowed(role, "vms/mount", "delete", vars["exp"], vars["name"]) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -129,7 +129,7 @@ func GetMountFiles(w http.ResponseWriter, r *http.Request) {
 	basePath := mm.GetLocalMountPath(vars["exp"], vars["name"])
 
 	role := r.Context().Value("role").(rbac.Role)
-	if !role.Allowed("vms/mount", "list", fmt.Sprintf("%s/%s", vars["exp"], vars["name"])) {
+	if !util.VMPathAllowed(role, "vms/mount", "list", vars["exp"], vars["name"]) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -240,7 +240,7 @@ func DownloadMountFile(w http.ResponseWriter, r *http.Request) {
 	basePath := mm.GetLocalMountPath(vars["exp"], vars["name"])
 
 	role := r.Context().Value("role").(rbac.Role)
-	if !role.Allowed("vms/mount", "get", fmt.Sprintf("%s/%s", vars["exp"], vars["name"])) {
+	if !util.VMPathAllowed(role, "vms/mount", "get", vars["exp"], vars["name"]) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -290,7 +290,7 @@ func UploadMountFile(w http.ResponseWriter, r *http.Request) {
 	basePath := mm.GetLocalMountPath(vars["exp"], vars["name"])
 
 	role := r.Context().Value("role").(rbac.Role)
-	if !role.Allowed("vms/mount", "patch", fmt.Sprintf("%s/%s", vars["exp"], vars["name"])) {
+	if !util.VMPathAllowed(role, "vms/mount", "patch", vars["exp"], vars["name"]) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
@@ -0,0 +1,199 @@
+package web
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"time"
+
+	"phenix/web/cache"
+	"phenix/web/oidc"
+	"phenix/web/rbac"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// oidcLoginTimeout is how long a user has to complete login with the OIDC
+// provider after being redirected to it.
+const oidcLoginTimeout = 10 * time.Minute
+
+var oidcProvider *oidc.Provider
+
+type oidcLogin struct {
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+}
+
+// oidcCallbackPage stores the phenix token for the logged in user the same
+// way the UI does when a user chooses to be remembered at login, then loads
+// the UI.
+var oidcCallbackPage = template.Must(template.New("callback").Parse(`<!DOCTYPE html>
+<html>
+  <head><title>phēnix</title></head>
+  <body>
+    <script>
+      localStorage.setItem('phenix.user',  {{.Username}});
+      localStorage.setItem('phenix.token', {{.Token}});
+      localStorage.setItem('phenix.role',  {{.Role}});
+      localStorage.setItem('phenix.auth',  'true');
+
+      window.location.replace({{.BasePath}});
+    </script>
+  </body>
+</html>
+`))
+
+// GET /login/oidc
+func OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	log.Debug("OIDCLogin HTTP handler called")
+
+	if oidcProvider == nil {
+		http.Error(w, "OIDC login not enabled", http.StatusNotFound)
+		return
+	}
+
+	var (
+		state = oidc.RandomString()
+		login = oidcLogin{Verifier: oidc.RandomString(), Nonce: oidc.RandomString()}
+	)
+
+	authURL, err := oidcProvider.AuthCodeURL(r.Context(), state, login.Verifier, login.Nonce)
+	if err != nil {
+		log.Error("generating OIDC auth URL: %v", err)
+		http.Error(w, "OIDC provider unavailable", http.StatusBadGateway)
+		return
+	}
+
+	body, _ := json.Marshal(login)
+
+	if err := cache.SetWithExpire("oidc/"+state, body, oidcLoginTimeout); err != nil {
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// GET /login/oidc/callback
+func OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	log.Debug("OIDCCallback HTTP handler called")
+
+	if oidcProvider == nil {
+		http.Error(w, "OIDC login not enabled", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+
+	if e := query.Get("error"); e != "" {
+		log.Error("OIDC login failed: %s (%s)", e, query.Get("error_description"))
+		http.Error(w, "OIDC login failed: "+e, http.StatusUnauthorized)
+		return
+	}
+
+	key := "oidc/" + query.Get("state")
+
+	body, ok := cache.Get(key)
+	if !ok || len(body) == 0 {
+		http.Error(w, "invalid or expired OIDC login state", http.StatusBadRequest)
+		return
+	}
+
+	var login oidcLogin
+
+	if err := json.Unmarshal(body, &login); err != nil {
+		http.Error(w, "invalid OIDC login state", http.StatusBadRequest)
+		return
+	}
+
+	// Login states can only be used once.
+	if err := cache.Delete(key); err != nil {
+		log.Error("deleting OIDC login state: %v", err)
+		http.Error(w, "OIDC login failed", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := oidcProvider.Exchange(r.Context(), query.Get("code"), login.Verifier, login.Nonce)
+	if err != nil {
+		log.Error("exchanging OIDC authorization code: %v", err)
+		http.Error(w, "OIDC login failed", http.StatusUnauthorized)
+		return
+	}
+
+	rname, resources, err := oidcProvider.Config().Role(id)
+	if err != nil {
+		log.Error("mapping OIDC user to role: %v", err)
+		http.Error(w, "user not authorized", http.StatusForbidden)
+		return
+	}
+
+	// The role is applied to new users, and to existing users when it's mapped
+	// from their groups or claims so changes at the provider take effect at
+	// their next login. The default role never replaces a role an admin gave
+	// an existing user.
+	apply := oidcProvider.Config().Mapped(id)
+
+	u, err := rbac.GetUser(id.Username)
+	if err == nil {
+		// Only link logins to users created via OIDC for the same provider
+		// account; otherwise anyone able to get an account at the provider with
+		// the same username claim could take over another user. Users created
+		// before subjects were recorded have to be deleted to be re-provisioned.
+		if !u.OIDCBound(id.Issuer, id.Subject) {
+			log.Warn("refusing OIDC login for user %s from subject %s (user is local or bound to another subject)", id.Username, id.Subject)
+			http.Error(w, "user not authorized", http.StatusForbidden)
+			return
+		}
+	} else {
+		log.Info("creating user %s from OIDC login (subject %s)", id.Username, id.Subject)
+
+		if u = rbac.NewOIDCUser(id.Username, id.Issuer, id.Subject); u == nil {
+			http.Error(w, "unable to create user", http.StatusInternalServerError)
+			return
+		}
+
+		apply = true
+	}
+
+	u.Spec.FirstName = id.FirstName
+	u.Spec.LastName = id.LastName
+
+	if apply {
+		if err := u.SetRoleByName(rname, resources...); err != nil {
+			log.Error("setting role for OIDC user %s: %v", id.Username, err)
+			http.Error(w, "user role error", http.StatusInternalServerError)
+			return
+		}
+	} else if err := u.UpdateFirstName(id.FirstName); err != nil {
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	} else if err := u.UpdateLastName(id.LastName); err != nil {
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	signed, err := signToken(jwt.MapClaims{
+		"sub": u.Username(),
+		"exp": time.Now().Add(o.jwtLifetime).Unix(),
+	})
+	if err != nil {
+		http.Error(w, "failed to sign JWT", http.StatusInternalServerError)
+		return
+	}
+
+	if err := u.AddToken(signed, time.Now().Format(time.RFC3339)); err != nil {
+		http.Error(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]string{
+		"Username": u.Username(),
+		"Token":    signed,
+		"Role":     u.RoleName(),
+		"BasePath": o.basePath,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	oidcCallbackPage.Execute(w, data)
+}
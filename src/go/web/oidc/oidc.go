@@ -0,0 +1,419 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Config configures login via an OpenID Connect provider using the
+// authorization code flow with PKCE.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// UsernameClaim is the ID token claim used as the phenix username. Defaults
+	// to `email`, in which case the `email_verified` claim must be true.
+	UsernameClaim string
+
+	// GroupsClaim is the ID token claim containing the groups the user is a
+	// member of. Defaults to `groups`.
+	GroupsClaim string
+
+	// RoleMappings map groups or claim values to phenix roles. The first mapping
+	// that matches a user is used.
+	RoleMappings []RoleMapping
+
+	// DefaultRole is the role given to users that don't match any role
+	// mappings. If empty, such users aren't allowed to login.
+	DefaultRole string
+}
+
+// Identity is a user identity from a verified ID token.
+type Identity struct {
+	Issuer    string
+	Subject   string
+	Username  string
+	FirstName string
+	LastName  string
+	Groups    []string
+	Claims    jwt.MapClaims
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider handles logins via an OpenID Connect provider. Provider metadata and
+// signing keys are fetched from the provider the first time they're needed.
+type Provider struct {
+	sync.Mutex
+
+	config Config
+	client *http.Client
+
+	discovery *discovery
+	keys      map[string]interface{}
+}
+
+// NewProvider returns a new provider for the given config, setting defaults
+// for any optional config values not provided.
+func NewProvider(c Config) (*Provider, error) {
+	if c.Issuer == "" {
+		return nil, fmt.Errorf("no OIDC issuer provided")
+	}
+
+	if c.ClientID == "" {
+		return nil, fmt.Errorf("no OIDC client ID provided")
+	}
+
+	if c.RedirectURL == "" {
+		return nil, fmt.Errorf("no OIDC redirect URL provided")
+	}
+
+	c.Issuer = strings.TrimSuffix(c.Issuer, "/")
+
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "profile", "email", "groups"}
+	}
+
+	if c.UsernameClaim == "" {
+		c.UsernameClaim = "email"
+	}
+
+	if c.GroupsClaim == "" {
+		c.GroupsClaim = "groups"
+	}
+
+	return &Provider{config: c, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Config returns the provider's config, including defaults.
+func (this *Provider) Config() Config {
+	return this.config
+}
+
+// AuthCodeURL returns the URL to redirect users to for login. The given
+// verifier is used to generate the PKCE code challenge, and the same verifier
+// and nonce must be passed to Exchange when the user is redirected back.
+func (this *Provider) AuthCodeURL(ctx context.Context, state, verifier, nonce string) (string, error) {
+	d, err := this.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("parsing OIDC authorization endpoint: %w", err)
+	}
+
+	query := u.Query()
+
+	query.Set("response_type", "code")
+	query.Set("client_id", this.config.ClientID)
+	query.Set("redirect_uri", this.config.RedirectURL)
+	query.Set("scope", strings.Join(this.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", Challenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// Exchange exchanges the given authorization code for an ID token and returns
+// the identity in the token once it has been verified.
+func (this *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	d, err := this.getDiscovery(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {this.config.RedirectURL},
+		"client_id":     {this.config.ClientID},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, fmt.Errorf("creating OIDC token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if this.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(this.config.ClientID), url.QueryEscape(this.config.ClientSecret))
+	}
+
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return Identity{}, fmt.Errorf("requesting OIDC token: %w", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Identity{}, fmt.Errorf("reading OIDC token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("OIDC token request failed (%s): %s", resp.Status, body)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+
+	if err := json.Unmarshal(body, &token); err != nil {
+		return Identity{}, fmt.Errorf("decoding OIDC token response: %w", err)
+	}
+
+	if token.IDToken == "" {
+		return Identity{}, fmt.Errorf("OIDC token response missing ID token")
+	}
+
+	claims, err := this.verify(ctx, token.IDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("verifying OIDC ID token: %w", err)
+	}
+
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return Identity{}, fmt.Errorf("OIDC ID token nonce mismatch")
+	}
+
+	return this.identity(claims)
+}
+
+func (this *Provider) verify(ctx context.Context, raw string) (jwt.MapClaims, error) {
+	claims := make(jwt.MapClaims)
+
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unsupported signing method %s", token.Method.Alg())
+		}
+
+		kid, _ := token.Header["kid"].(string)
+
+		return this.getKey(ctx, kid)
+	}
+
+	if _, err := jwt.ParseWithClaims(raw, claims, keyFunc); err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != this.config.Issuer {
+		return nil, fmt.Errorf("unexpected issuer %s", iss)
+	}
+
+	if !contains(stringSlice(claims["aud"]), this.config.ClientID) {
+		return nil, fmt.Errorf("token not issued for client %s", this.config.ClientID)
+	}
+
+	return claims, nil
+}
+
+func (this *Provider) identity(claims jwt.MapClaims) (Identity, error) {
+	id := Identity{Issuer: this.config.Issuer, Claims: claims}
+
+	// The subject is the only claim guaranteed to be unique and never reassigned
+	// by the provider, so it's what users are bound to.
+	id.Subject, _ = claims["sub"].(string)
+
+	if id.Subject == "" {
+		return id, fmt.Errorf("OIDC ID token missing sub claim")
+	}
+
+	id.Username, _ = claims[this.config.UsernameClaim].(string)
+
+	if id.Username == "" {
+		return id, fmt.Errorf("OIDC ID token missing %s claim", this.config.UsernameClaim)
+	}
+
+	// Anyone can claim any email address with some providers, so email
+	// addresses can only be used as usernames once the provider has verified
+	// them.
+	if this.config.UsernameClaim == "email" && !verified(claims["email_verified"]) {
+		return id, fmt.Errorf("OIDC user email %s has not been verified", id.Username)
+	}
+
+	id.FirstName, _ = claims["given_name"].(string)
+	id.LastName, _ = claims["family_name"].(string)
+	id.Groups = stringSlice(claims[this.config.GroupsClaim])
+
+	return id, nil
+}
+
+func (this *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.discovery != nil {
+		return this.discovery, nil
+	}
+
+	var d discovery
+
+	if err := this.getJSON(ctx, this.config.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("getting OIDC provider metadata: %w", err)
+	}
+
+	if strings.TrimSuffix(d.Issuer, "/") != this.config.Issuer {
+		return nil, fmt.Errorf("OIDC provider metadata issuer %s does not match %s", d.Issuer, this.config.Issuer)
+	}
+
+	this.discovery = &d
+
+	return this.discovery, nil
+}
+
+// getKey returns the provider's signing key with the given ID, refreshing the
+// provider's key set if the key isn't known (in case keys were rotated).
+func (this *Provider) getKey(ctx context.Context, kid string) (interface{}, error) {
+	this.Lock()
+	key, ok := this.keys[kid]
+	this.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	d, err := this.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwks
+
+	if err := this.getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("getting OIDC provider keys: %w", err)
+	}
+
+	keys, err := set.publicKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	this.Lock()
+	this.keys = keys
+	this.Unlock()
+
+	// Allow tokens without a key ID if the provider only has one key.
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %s", kid)
+}
+
+func (this *Provider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response from %s: %s", u, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// RandomString returns a random, URL-safe string suitable for use as a login
+// state, nonce, or PKCE verifier.
+func RandomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Challenge returns the S256 PKCE code challenge for the given verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// stringSlice converts a claim that can be a single string or a list of strings
+// into a slice.
+func stringSlice(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		var s []string
+
+		for _, i := range v {
+			if str, ok := i.(string); ok {
+				s = append(s, str)
+			}
+		}
+
+		return s
+	default:
+		return nil
+	}
+}
+
+func contains(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// verified returns true if the given claim value is true. Some providers
+// encode boolean claims as strings.
+func verified(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
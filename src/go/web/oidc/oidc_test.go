@@ -0,0 +1,285 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// provider is a minimal stand-in for an OpenID Connect provider. The ID token
+// returned from the token endpoint contains the claims in the claims field,
+// along with the standard claims for the code last authorized.
+type provider struct {
+	*httptest.Server
+
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+
+	// challenges maps authorization codes to the PKCE challenge and nonce
+	// provided when the code was authorized.
+	challenges map[string][2]string
+}
+
+func newProvider(t *testing.T) *provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	p := &provider{key: key, claims: make(jwt.MapClaims), challenges: make(map[string][2]string)}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/keys",
+		})
+	})
+
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{
+					"kty": "RSA",
+					"kid": "test",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				},
+			},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "phenix" || secret != "secret" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+
+		r.ParseForm()
+
+		c, ok := p.challenges[r.PostForm.Get("code")]
+		if !ok || Challenge(r.PostForm.Get("code_verifier")) != c[0] {
+			http.Error(w, "invalid grant", http.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   p.URL,
+			"sub":   "248289761001",
+			"aud":   []string{"phenix"},
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": c[1],
+		}
+
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+
+		signed, _ := token.SignedString(key)
+
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+// authorize simulates a user logging in via the given auth code URL, returning
+// the authorization code the provider would redirect back with.
+func (this *provider) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	query := u.Query()
+
+	if query.Get("code_challenge_method") != "S256" {
+		t.Logf("unexpected code challenge method %s", query.Get("code_challenge_method"))
+		t.FailNow()
+	}
+
+	code := RandomString()
+	this.challenges[code] = [2]string{query.Get("code_challenge"), query.Get("nonce")}
+
+	return code
+}
+
+func newTestProvider(t *testing.T, p *provider) *Provider {
+	c := Config{
+		Issuer:       p.URL,
+		ClientID:     "phenix",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:3000/api/v1/login/oidc/callback",
+	}
+
+	provider, err := NewProvider(c)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return provider
+}
+
+func TestExchange(t *testing.T) {
+	p := newProvider(t)
+	p.claims["email"] = "alice@example.com"
+	p.claims["email_verified"] = true
+	p.claims["given_name"] = "Alice"
+	p.claims["groups"] = []string{"admins", "users"}
+
+	provider := newTestProvider(t, p)
+
+	var (
+		ctx      = context.Background()
+		verifier = RandomString()
+		nonce    = RandomString()
+	)
+
+	authURL, err := provider.AuthCodeURL(ctx, "state", verifier, nonce)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	id, err := provider.Exchange(ctx, p.authorize(t, authURL), verifier, nonce)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if id.Username != "alice@example.com" || id.FirstName != "Alice" || id.Subject != "248289761001" || id.Issuer != p.URL {
+		t.Logf("unexpected identity %+v", id)
+		t.FailNow()
+	}
+
+	if len(id.Groups) != 2 || id.Groups[0] != "admins" {
+		t.Logf("unexpected groups %v", id.Groups)
+		t.FailNow()
+	}
+}
+
+func TestExchangeErrors(t *testing.T) {
+	p := newProvider(t)
+	p.claims["email"] = "alice@example.com"
+
+	provider := newTestProvider(t, p)
+
+	var (
+		ctx      = context.Background()
+		verifier = RandomString()
+		nonce    = RandomString()
+	)
+
+	authURL, _ := provider.AuthCodeURL(ctx, "state", verifier, nonce)
+
+	if _, err := provider.Exchange(ctx, p.authorize(t, authURL), RandomString(), nonce); err == nil {
+		t.Log("expected error for invalid PKCE verifier")
+		t.FailNow()
+	}
+
+	if _, err := provider.Exchange(ctx, p.authorize(t, authURL), verifier, RandomString()); err == nil {
+		t.Log("expected error for nonce mismatch")
+		t.FailNow()
+	}
+
+	p.claims["aud"] = "other"
+
+	if _, err := provider.Exchange(ctx, p.authorize(t, authURL), verifier, nonce); err == nil {
+		t.Log("expected error for audience mismatch")
+		t.FailNow()
+	}
+
+	delete(p.claims, "aud")
+
+	if _, err := provider.Exchange(ctx, p.authorize(t, authURL), verifier, nonce); err == nil {
+		t.Log("expected error for unverified email")
+		t.FailNow()
+	}
+
+	p.claims["email_verified"] = "true"
+
+	if _, err := provider.Exchange(ctx, p.authorize(t, authURL), verifier, nonce); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	delete(p.claims, "email")
+
+	if _, err := provider.Exchange(ctx, p.authorize(t, authURL), verifier, nonce); err == nil {
+		t.Log("expected error for missing username claim")
+		t.FailNow()
+	}
+}
+
+func TestRoleMappings(t *testing.T) {
+	var mappings []RoleMapping
+
+	for _, s := range []string{"admins:Global Admin", "dept=research:Experiment User:exp1:exp2"} {
+		m, err := ParseRoleMapping(s)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		mappings = append(mappings, m)
+	}
+
+	if _, err := ParseRoleMapping("admins"); err == nil {
+		t.Log("expected error for mapping missing role")
+		t.FailNow()
+	}
+
+	c := Config{RoleMappings: mappings}
+
+	role, resources, err := c.Role(Identity{Groups: []string{"users", "admins"}})
+	if err != nil || role != "Global Admin" || resources[0] != "*" {
+		t.Logf("unexpected role %s (%v): %v", role, resources, err)
+		t.FailNow()
+	}
+
+	role, resources, err = c.Role(Identity{Claims: jwt.MapClaims{"dept": "research"}})
+	if err != nil || role != "Experiment User" || len(resources) != 2 {
+		t.Logf("unexpected role %s (%v): %v", role, resources, err)
+		t.FailNow()
+	}
+
+	if c.Mapped(Identity{Username: "bob"}) || !c.Mapped(Identity{Groups: []string{"admins"}}) {
+		t.Log("unexpected result checking if users are mapped")
+		t.FailNow()
+	}
+
+	if _, _, err := c.Role(Identity{Username: "bob"}); err == nil {
+		t.Log("expected error for unmapped user without default role")
+		t.FailNow()
+	}
+
+	c.DefaultRole = "Global Viewer"
+
+	if role, _, _ := c.Role(Identity{Username: "bob"}); role != "Global Viewer" {
+		t.Logf("expected default role, got %s", role)
+		t.FailNow()
+	}
+}
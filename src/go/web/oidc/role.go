@@ -0,0 +1,80 @@
+package oidc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RoleMapping maps users in a group, or users with a specific claim value, to a
+// phenix role limited to the given resource names.
+type RoleMapping struct {
+	Group         string
+	Claim         string
+	Value         string
+	Role          string
+	ResourceNames []string
+}
+
+// ParseRoleMapping parses a role mapping in the form
+// `<group>:<role>[:<resource>...]` or `<claim>=<value>:<role>[:<resource>...]`.
+// If no resource names are provided, the role applies to all resources.
+func ParseRoleMapping(s string) (RoleMapping, error) {
+	tokens := strings.Split(s, ":")
+
+	if len(tokens) < 2 || tokens[0] == "" || tokens[1] == "" {
+		return RoleMapping{}, fmt.Errorf("invalid OIDC role mapping %s", s)
+	}
+
+	m := RoleMapping{Role: tokens[1], ResourceNames: tokens[2:]}
+
+	if claim := strings.SplitN(tokens[0], "=", 2); len(claim) == 2 {
+		m.Claim, m.Value = claim[0], claim[1]
+	} else {
+		m.Group = tokens[0]
+	}
+
+	if len(m.ResourceNames) == 0 {
+		m.ResourceNames = []string{"*"}
+	}
+
+	return m, nil
+}
+
+// Matches returns true if the given identity is in the mapping's group or has
+// the mapping's claim value.
+func (this RoleMapping) Matches(id Identity) bool {
+	if this.Group != "" {
+		return contains(id.Groups, this.Group)
+	}
+
+	return contains(stringSlice(id.Claims[this.Claim]), this.Value)
+}
+
+// Mapped returns true if any of the role mappings match the given identity.
+func (this Config) Mapped(id Identity) bool {
+	for _, m := range this.RoleMappings {
+		if m.Matches(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Role returns the role name and resource names for the given identity based
+// on the first matching role mapping, falling back to the default role for all
+// resources. An error is returned if no mappings match and there's no default
+// role.
+func (this Config) Role(id Identity) (string, []string, error) {
+	for _, m := range this.RoleMappings {
+		if m.Matches(id) {
+			return m.Role, m.ResourceNames, nil
+		}
+	}
+
+	if this.DefaultRole != "" {
+		return this.DefaultRole, []string{"*"}, nil
+	}
+
+	return "", nil, fmt.Errorf("no OIDC role mapping matches user %s", id.Username)
+}
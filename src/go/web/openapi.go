@@ -0,0 +1,69 @@
+package web
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"sync"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPISpec []byte
+	openAPIErr  error
+)
+
+// LoadOpenAPI loads and validates the OpenAPI spec for the web API.
+func LoadOpenAPI() (*openapi3.T, error) {
+	t, err := openapi3.NewLoader().LoadFromData(OpenAPI)
+	if err != nil {
+		return nil, fmt.Errorf("loading OpenAPI spec for web API: %w", err)
+	}
+
+	if err := t.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("validating OpenAPI spec for web API: %w", err)
+	}
+
+	return t, nil
+}
+
+// GET /openapi.json
+func GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetOpenAPI HTTP handler called")
+
+	openAPIOnce.Do(func() {
+		var t *openapi3.T
+
+		t, openAPIErr = LoadOpenAPI()
+		if openAPIErr != nil {
+			return
+		}
+
+		// The spec is served relative to the base path the server is configured
+		// with.
+		t.Servers = openapi3.Servers{{URL: o.basePath + "api/v1"}}
+
+		openAPISpec, openAPIErr = t.MarshalJSON()
+	})
+
+	if openAPIErr != nil {
+		log.Error("serving OpenAPI spec: %v", openAPIErr)
+		http.Error(w, "unable to load OpenAPI spec", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// OpenAPI is the spec for the web API served at /api/v1/openapi.json. It's
+// also used to build the API docs served at /docs/. Every route added in
+// addAPIRoutes must have a matching operation in it, which is enforced by
+// TestOpenAPIMatchesRouter.
+//
+//go:embed public/docs/openapi.yml
+var OpenAPI []byte
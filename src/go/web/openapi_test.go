@@ -0,0 +1,100 @@
+package web
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	spec, err := LoadOpenAPI()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Enable optional features so their routes are registered too.
+	o = newServerOptions()
+	o.features["vm-mount"] = true
+
+	router := mux.NewRouter()
+	addAPIRoutes(router)
+
+	// Path variables in routes can include regular expressions, which aren't
+	// part of the path template in the spec.
+	vars := regexp.MustCompile(`\{(\w+):[^}]+\}`)
+
+	routed := make(map[string]bool)
+
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+
+		tmpl = vars.ReplaceAllString(tmpl, "{$1}")
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+
+		for _, method := range methods {
+			if method == "OPTIONS" {
+				continue
+			}
+
+			routed[method+" "+tmpl] = true
+
+			item := spec.Paths.Find(tmpl)
+			if item == nil || item.GetOperation(method) == nil {
+				t.Logf("route %s %s not documented in OpenAPI spec", method, tmpl)
+				t.Fail()
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for path, item := range spec.Paths {
+		for method := range item.Operations() {
+			if !routed[strings.ToUpper(method)+" "+path] {
+				t.Logf("OpenAPI spec documents %s %s, which isn't routed", method, path)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestOpenAPIOperationIDs(t *testing.T) {
+	spec, err := LoadOpenAPI()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	ids := make(map[string]string)
+
+	for path, item := range spec.Paths {
+		for method, op := range item.Operations() {
+			if op.OperationID == "" {
+				t.Logf("missing operation ID for %s %s", method, path)
+				t.FailNow()
+			}
+
+			if other, ok := ids[op.OperationID]; ok {
+				t.Logf("duplicate operation ID %s for %s %s and %s", op.OperationID, method, path, other)
+				t.FailNow()
+			}
+
+			ids[op.OperationID] = method + " " + path
+		}
+	}
+}
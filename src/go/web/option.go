@@ -3,15 +3,18 @@ package web
 import (
 	"strings"
 	"time"
+
+	"phenix/web/oidc"
 )
 
 type ServerOption func(*serverOptions)
 
 type serverOptions struct {
-	endpoint   string
-	unixSocket string
-	users      []string
-	allowCORS  bool
+	endpoint     string
+	grpcEndpoint string
+	unixSocket   string
+	users        []string
+	allowCORS    bool
 
 	tlsKeyPath string
 	tlsCrtPath string
@@ -27,9 +30,13 @@ type serverOptions struct {
 	minimegaConsole bool
 
 	jwtKey      string
+	jwtKeySet   string
 	jwtLifetime time.Duration
 
 	proxyAuthHeader string
+	trustedProxies  []string
+
+	oidc oidc.Config
 
 	features map[string]bool
 }
@@ -51,6 +58,10 @@ func newServerOptions(opts ...ServerOption) serverOptions {
 		o.publishLogs = true
 	}
 
+	if o.oidc.Issuer != "" {
+		o.features["oidc-login"] = true
+	}
+
 	if !strings.HasPrefix(o.basePath, "/") {
 		o.basePath = "/" + o.basePath
 	}
@@ -85,6 +96,14 @@ func ServeOnEndpoint(e string) ServerOption {
 	}
 }
 
+// ServeGRPCOnEndpoint configures the server to also serve the gRPC API on the
+// given endpoint.
+func ServeGRPCOnEndpoint(e string) ServerOption {
+	return func(o *serverOptions) {
+		o.grpcEndpoint = e
+	}
+}
+
 func ServeOnUnixSocket(s string) ServerOption {
 	return func(o *serverOptions) {
 		o.unixSocket = s
@@ -97,6 +116,15 @@ func ServeWithJWTKey(k string) ServerOption {
 	}
 }
 
+// ServeWithJWTKeySet configures the server to sign JWTs with the active key
+// in the key set in the given directory. Tokens signed with the JWT key (if
+// provided) are still accepted.
+func ServeWithJWTKeySet(d string) ServerOption {
+	return func(o *serverOptions) {
+		o.jwtKeySet = d
+	}
+}
+
 func ServeWithUsers(u []string) ServerOption {
 	return func(o *serverOptions) {
 		if len(u) > 0 {
@@ -166,6 +194,18 @@ func ServeWithProxyAuthHeader(h string) ServerOption {
 	}
 }
 
+func ServeWithTrustedProxies(p []string) ServerOption {
+	return func(o *serverOptions) {
+		o.trustedProxies = p
+	}
+}
+
+func ServeWithOIDC(c oidc.Config) ServerOption {
+	return func(o *serverOptions) {
+		o.oidc = c
+	}
+}
+
 func ServeWithFeatures(f []string) ServerOption {
 	return func(o *serverOptions) {
 		if f == nil {
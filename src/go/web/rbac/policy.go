@@ -1,6 +1,7 @@
 package rbac
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -11,6 +12,44 @@ type Policy struct {
 	Spec *v1.PolicySpec
 }
 
+// ParsePolicy parses a policy in the form of
+// `<resources>:<verbs>[:<resource names>]`, where each is a comma-separated
+// list (e.g. `vms,vms/*:get,list:foo_*`).
+func ParsePolicy(s string) (*v1.PolicySpec, error) {
+	tokens := strings.Split(s, ":")
+
+	if len(tokens) < 2 || len(tokens) > 3 {
+		return nil, fmt.Errorf("invalid policy %s (expected <resources>:<verbs>[:<resource names>])", s)
+	}
+
+	split := func(s string) []string {
+		var list []string
+
+		for _, e := range strings.Split(s, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				list = append(list, e)
+			}
+		}
+
+		return list
+	}
+
+	policy := &v1.PolicySpec{
+		Resources: split(tokens[0]),
+		Verbs:     split(tokens[1]),
+	}
+
+	if len(tokens) == 3 {
+		policy.ResourceNames = split(tokens[2])
+	}
+
+	if err := ValidatePolicies([]*v1.PolicySpec{policy}); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
 func (this Policy) resourceNameAllowed(name string) bool {
 	var allowed bool
 
@@ -34,6 +73,48 @@ func (this Policy) resourceNameAllowed(name string) bool {
 	return allowed
 }
 
+// objectAllowed returns true if the given object matches the policy's resource
+// names and selectors. Policies with selectors but no resource names match any
+// object with matching labels and annotations.
+func (this Policy) objectAllowed(obj Object) bool {
+	if this.hasSelectors() {
+		if !selectorMatches(this.Spec.LabelSelector, obj.Labels) {
+			return false
+		}
+
+		if !selectorMatches(this.Spec.AnnotationSelector, obj.Annotations) {
+			return false
+		}
+
+		if len(this.Spec.ResourceNames) == 0 {
+			return true
+		}
+	}
+
+	return this.resourceNameAllowed(obj.Name)
+}
+
+func (this Policy) hasSelectors() bool {
+	return len(this.Spec.LabelSelector) > 0 || len(this.Spec.AnnotationSelector) > 0
+}
+
+// selectorMatches returns true if every key in the selector is present in the
+// given values with a value matching the selector's (glob) pattern.
+func selectorMatches(selector, values map[string]string) bool {
+	for k, pattern := range selector {
+		v, ok := values[k]
+		if !ok {
+			return false
+		}
+
+		if matched, _ := filepath.Match(pattern, v); !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (this Policy) verbAllowed(verb string) bool {
 	for _, v := range this.Spec.Verbs {
 		if v == "*" || v == verb {
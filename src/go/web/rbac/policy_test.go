@@ -0,0 +1,142 @@
+package rbac
+
+import (
+	"testing"
+
+	v1 "phenix/types/version/v1"
+)
+
+func TestSelectorPolicies(t *testing.T) {
+	role := Role{
+		Spec: &v1.RoleSpec{
+			Name: "Red Team",
+			Policies: []*v1.PolicySpec{
+				{Resources: []string{"experiments"}, ResourceNames: []string{"foo"}, Verbs: []string{"get"}},
+				{Resources: []string{"experiments"}, AnnotationSelector: map[string]string{"team": "red"}, Verbs: []string{"get"}},
+				{Resources: []string{"vms"}, ResourceNames: []string{"bar_*"}, LabelSelector: map[string]string{"role": "attack*"}, Verbs: []string{"get"}},
+			},
+		},
+	}
+
+	if !role.Allowed("experiments", "get", "foo") {
+		t.Log("expected name policy to allow getting experiment foo")
+		t.FailNow()
+	}
+
+	// Selector policies never match when only names are known.
+	if role.Allowed("experiments", "get", "bar") {
+		t.Log("expected name only check to deny getting experiment bar")
+		t.FailNow()
+	}
+
+	red := Object{Name: "bar", Annotations: map[string]string{"team": "red"}}
+
+	if !role.AllowedObjects("experiments", "get", red) {
+		t.Log("expected annotation selector to allow getting experiment bar")
+		t.FailNow()
+	}
+
+	blue := Object{Name: "bar", Annotations: map[string]string{"team": "blue"}}
+
+	if role.AllowedObjects("experiments", "get", blue) {
+		t.Log("expected annotation selector to deny getting experiment bar")
+		t.FailNow()
+	}
+
+	if role.AllowedObjects("experiments", "delete", red) {
+		t.Log("expected annotation selector to deny deleting experiment bar")
+		t.FailNow()
+	}
+
+	// Policies with both names and selectors require both to match.
+	vm := Object{Name: "bar_kali", Labels: map[string]string{"role": "attacker"}}
+
+	if !role.AllowedObjects("vms", "get", vm) {
+		t.Log("expected label selector to allow getting VM bar_kali")
+		t.FailNow()
+	}
+
+	vm.Name = "foo_kali"
+
+	if role.AllowedObjects("vms", "get", vm) {
+		t.Log("expected resource names to deny getting VM foo_kali")
+		t.FailNow()
+	}
+
+	vm = Object{Name: "bar_web", Labels: map[string]string{"role": "server"}}
+
+	if role.AllowedObjects("vms", "get", vm) {
+		t.Log("expected label selector to deny getting VM bar_web")
+		t.FailNow()
+	}
+
+	if role.AllowedObjects("vms", "get", Object{Name: "bar_web"}) {
+		t.Log("expected label selector to deny getting unlabeled VM bar_web")
+		t.FailNow()
+	}
+}
+
+func TestScopedSelectorPolicies(t *testing.T) {
+	role := Role{
+		Spec: &v1.RoleSpec{
+			Name: "Experiment Admin",
+			Policies: []*v1.PolicySpec{
+				{Resources: []string{"experiments"}, ResourceNames: []string{"*"}, Verbs: []string{"*"}},
+			},
+		},
+	}
+
+	scoped := role.Scoped([]*v1.PolicySpec{
+		{Resources: []string{"experiments"}, AnnotationSelector: map[string]string{"team": "red"}, Verbs: []string{"get"}},
+	})
+
+	if !scoped.AllowedObjects("experiments", "get", Object{Name: "foo", Annotations: map[string]string{"team": "red"}}) {
+		t.Log("expected scoped role to allow getting red team experiment")
+		t.FailNow()
+	}
+
+	if scoped.AllowedObjects("experiments", "get", Object{Name: "foo"}) {
+		t.Log("expected scoped role to deny getting experiment without annotations")
+		t.FailNow()
+	}
+
+	if scoped.Allowed("experiments", "get", "foo") {
+		t.Log("expected scoped role to deny name only check")
+		t.FailNow()
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("vms, vms/*:get,list:foo_*")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(policy.Resources) != 2 || policy.Resources[1] != "vms/*" {
+		t.Logf("unexpected resources %v", policy.Resources)
+		t.FailNow()
+	}
+
+	if len(policy.Verbs) != 2 || policy.Verbs[0] != "get" {
+		t.Logf("unexpected verbs %v", policy.Verbs)
+		t.FailNow()
+	}
+
+	if len(policy.ResourceNames) != 1 || policy.ResourceNames[0] != "foo_*" {
+		t.Logf("unexpected resource names %v", policy.ResourceNames)
+		t.FailNow()
+	}
+
+	if policy, _ := ParsePolicy("experiments:list"); policy.ResourceNames != nil {
+		t.Logf("expected no resource names, got %v", policy.ResourceNames)
+		t.FailNow()
+	}
+
+	for _, invalid := range []string{"vms", "vms:", ":get", "vms[:get", "vms:get:foo[", "vms:get:foo:bar"} {
+		if _, err := ParsePolicy(invalid); err == nil {
+			t.Logf("expected error for policy %s", invalid)
+			t.FailNow()
+		}
+	}
+}
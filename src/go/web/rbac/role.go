@@ -33,6 +33,10 @@ type Role struct {
 
 	config         *store.Config
 	mappedPolicies map[string][]Policy
+
+	// scope, if set, further limits what's allowed by the role (e.g. for scoped
+	// API tokens).
+	scope *Role
 }
 
 func RoleFromConfig(name string) (*Role, error) {
@@ -54,6 +58,69 @@ func RoleFromConfig(name string) (*Role, error) {
 	return &Role{Spec: &role, config: c}, nil
 }
 
+// GetRoles returns all the roles in the store.
+func GetRoles() ([]*Role, error) {
+	configs, err := config.List("role")
+	if err != nil {
+		return nil, fmt.Errorf("getting role configs: %w", err)
+	}
+
+	roles := make([]*Role, len(configs))
+
+	for i, c := range configs {
+		var role v1.RoleSpec
+
+		if err := mapstructure.Decode(c.Spec, &role); err != nil {
+			return nil, fmt.Errorf("decoding role: %w", err)
+		}
+
+		c := c
+		roles[i] = &Role{Spec: &role, config: &c}
+	}
+
+	return roles, nil
+}
+
+// NewRole creates a new role in the store with the given config name, role
+// name, and policies.
+func NewRole(name, rname string, policies []*v1.PolicySpec) (*Role, error) {
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("roles must include at least one policy")
+	}
+
+	if err := ValidatePolicies(policies); err != nil {
+		return nil, fmt.Errorf("validating role policies: %w", err)
+	}
+
+	spec := &v1.RoleSpec{
+		Name:     rname,
+		Policies: policies,
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Role",
+		Metadata: store.ConfigMetadata{Name: name},
+		Spec:     structs.MapDefaultCase(spec, structs.CASESNAKE),
+	}
+
+	if err := store.Create(c); err != nil {
+		return nil, fmt.Errorf("creating role in store: %w", err)
+	}
+
+	return &Role{Spec: spec, config: c}, nil
+}
+
+// ConfigName returns the name of the role's config in the store, which can
+// differ from the role's name (e.g. `global-admin` vs `Global Admin`).
+func (this Role) ConfigName() string {
+	if this.config == nil {
+		return ""
+	}
+
+	return this.config.Metadata.Name
+}
+
 func (this Role) Save() error {
 	this.config.Spec = structs.MapDefaultCase(this.Spec, structs.CASESNAKE)
 
@@ -125,15 +192,57 @@ func (this *Role) AddPolicy(r, rn, v []string) {
 	this.Spec.Policies = append(this.Spec.Policies, policy)
 }
 
+// Scoped returns a copy of the role that only allows what's allowed by both
+// the role and the given policies. If no policies are given, the role is
+// returned as-is.
+func (this Role) Scoped(policies []*v1.PolicySpec) Role {
+	if len(policies) == 0 {
+		return this
+	}
+
+	this.scope = &Role{Spec: &v1.RoleSpec{Name: this.Spec.Name, Policies: policies}}
+
+	return this
+}
+
+// Object is a resource being accessed, along with the labels and annotations
+// matched against policy selectors.
+type Object struct {
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Allowed returns true if the role allows the given verb on the given resource
+// for any of the given resource names. Since only names are known, policies
+// with selectors never match; use AllowedObjects when labels and annotations
+// for the resource are available.
 func (this Role) Allowed(resource, verb string, names ...string) bool {
+	objs := make([]Object, len(names))
+
+	for i, n := range names {
+		objs[i] = Object{Name: n}
+	}
+
+	return this.AllowedObjects(resource, verb, objs...)
+}
+
+// AllowedObjects returns true if the role allows the given verb on the given
+// resource for any of the given objects, matching objects against policies by
+// name, labels, and annotations.
+func (this Role) AllowedObjects(resource, verb string, objs ...Object) bool {
+	if this.scope != nil && !this.scope.AllowedObjects(resource, verb, objs...) {
+		return false
+	}
+
 	for _, policy := range this.policiesForResource(resource) {
 		if policy.verbAllowed(verb) {
-			if len(names) == 0 {
+			if len(objs) == 0 {
 				return true
 			}
 
-			for _, n := range names {
-				if policy.resourceNameAllowed(n) {
+			for _, o := range objs {
+				if policy.objectAllowed(o) {
 					return true
 				}
 			}
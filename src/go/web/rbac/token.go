@@ -0,0 +1,250 @@
+package rbac
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	v1 "phenix/types/version/v1"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/activeshadow/structs"
+)
+
+// tokenUsageInterval is how often the last used details for API tokens are
+// flushed to the store.
+const tokenUsageInterval = time.Minute
+
+// tokenUse is when and where an API token was last used.
+type tokenUse struct {
+	at   time.Time
+	from string
+}
+
+var (
+	// pendingUsage holds API token usage not yet flushed to the store, keyed by
+	// username and token ID.
+	pendingUsage   = make(map[string]map[string]tokenUse)
+	pendingUsageMu sync.Mutex
+	usageFlusher   sync.Once
+)
+
+var (
+	ErrTokenNotFound = fmt.Errorf("token not found for user")
+	ErrTokenExpired  = fmt.Errorf("token expired")
+)
+
+// HashToken returns the hash of the given token as stored in a user's config.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTokenID returns a new random ID for an API token.
+func NewTokenID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// ValidatePolicies checks that the given policies can be used in a role or to
+// scope an API token.
+func ValidatePolicies(policies []*v1.PolicySpec) error {
+	for _, policy := range policies {
+		if len(policy.Resources) == 0 || len(policy.Verbs) == 0 {
+			return fmt.Errorf("policies must include resources and verbs")
+		}
+
+		// Checking to make sure patterns are valid. Thus, the string provided to
+		// match them against is useless.
+		for _, r := range policy.Resources {
+			if _, err := filepath.Match(r, "useless"); err != nil {
+				return fmt.Errorf("invalid resource %s", r)
+			}
+		}
+
+		for _, n := range policy.ResourceNames {
+			if _, err := filepath.Match(n, "useless"); err != nil {
+				return fmt.Errorf("%w: %s", ErrResourceNameInvalid, n)
+			}
+		}
+
+		for _, selector := range []map[string]string{policy.LabelSelector, policy.AnnotationSelector} {
+			for k, v := range selector {
+				if _, err := filepath.Match(v, "useless"); err != nil {
+					return fmt.Errorf("invalid selector %s=%s", k, v)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (this User) APITokens() []*v1.APITokenSpec {
+	return this.Spec.APITokens
+}
+
+// AddAPIToken stores a hash of the given API token along with its details. The
+// token must expire, and if policies are provided they limit what the token is
+// allowed to do beyond what the user's role already allows.
+func (this User) AddAPIToken(id, token, desc string, exp time.Time, policies []*v1.PolicySpec) error {
+	if exp.IsZero() {
+		return fmt.Errorf("API tokens must have an expiration")
+	}
+
+	if err := ValidatePolicies(policies); err != nil {
+		return fmt.Errorf("validating token policies: %w", err)
+	}
+
+	spec := &v1.APITokenSpec{
+		ID:          id,
+		Hash:        HashToken(token),
+		Description: desc,
+		Created:     time.Now().Format(time.RFC3339),
+		Expires:     exp.Format(time.RFC3339),
+		Policies:    policies,
+	}
+
+	this.Spec.APITokens = append(this.pruneAPITokens(), spec)
+
+	if err := this.save(); err != nil {
+		return fmt.Errorf("persisting new API token: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAPIToken revokes the API token with the given ID.
+func (this User) DeleteAPIToken(id string) error {
+	for i, t := range this.Spec.APITokens {
+		if t.ID == id {
+			this.Spec.APITokens = append(this.Spec.APITokens[:i], this.Spec.APITokens[i+1:]...)
+
+			if err := this.save(); err != nil {
+				return fmt.Errorf("deleting API token: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrTokenNotFound, id)
+}
+
+// ValidateAPIToken checks that the given token matches the stored API token
+// with the given ID and hasn't expired, recording the time it was used and the
+// address it was used from. Usage is recorded in memory and flushed to the
+// store in the background (see flushTokenUsage), so validating a token never
+// writes the user's config and never fails because a write failed.
+func (this User) ValidateAPIToken(id, token, from string) (*v1.APITokenSpec, error) {
+	for _, t := range this.Spec.APITokens {
+		if t.ID != id {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(HashToken(token))) != 1 {
+			return nil, ErrTokenNotFound
+		}
+
+		exp, err := time.Parse(time.RFC3339, t.Expires)
+		if err != nil || time.Now().After(exp) {
+			return nil, ErrTokenExpired
+		}
+
+		recordTokenUsage(this.Username(), id, from)
+
+		return t, nil
+	}
+
+	return nil, ErrTokenNotFound
+}
+
+// pruneAPITokens returns the user's API tokens that haven't expired.
+func (this User) pruneAPITokens() []*v1.APITokenSpec {
+	var tokens []*v1.APITokenSpec
+
+	for _, t := range this.Spec.APITokens {
+		if exp, err := time.Parse(time.RFC3339, t.Expires); err == nil && time.Now().Before(exp) {
+			tokens = append(tokens, t)
+		}
+	}
+
+	return tokens
+}
+
+func recordTokenUsage(user, id, from string) {
+	usageFlusher.Do(func() {
+		go func() {
+			for range time.Tick(tokenUsageInterval) {
+				flushTokenUsage()
+			}
+		}()
+	})
+
+	pendingUsageMu.Lock()
+	defer pendingUsageMu.Unlock()
+
+	if pendingUsage[user] == nil {
+		pendingUsage[user] = make(map[string]tokenUse)
+	}
+
+	pendingUsage[user][id] = tokenUse{at: time.Now(), from: from}
+}
+
+// flushTokenUsage writes pending API token usage to the store. Each user is
+// re-read while holding the user write lock and only the last used details of
+// tokens the user still has are updated, so tokens revoked (or any other
+// changes made) since the usage was recorded aren't overwritten.
+func flushTokenUsage() {
+	pendingUsageMu.Lock()
+	pending := pendingUsage
+	pendingUsage = make(map[string]map[string]tokenUse)
+	pendingUsageMu.Unlock()
+
+	for user, uses := range pending {
+		if err := writeTokenUsage(user, uses); err != nil {
+			log.Warn("recording API token usage for user %s: %v", user, err)
+		}
+	}
+}
+
+func writeTokenUsage(uname string, uses map[string]tokenUse) error {
+	userMu.Lock()
+	defer userMu.Unlock()
+
+	user, err := GetUser(uname)
+	if err != nil {
+		return err
+	}
+
+	var updated bool
+
+	for _, t := range user.Spec.APITokens {
+		if use, ok := uses[t.ID]; ok {
+			t.LastUsed = use.at.Format(time.RFC3339)
+			t.LastUsedFrom = use.from
+
+			updated = true
+		}
+	}
+
+	if !updated {
+		return nil
+	}
+
+	user.config.Spec = structs.MapDefaultCase(user.Spec, structs.CASESNAKE)
+
+	return user.update()
+}
+
+func (this User) save() error {
+	this.config.Spec = structs.MapDefaultCase(this.Spec, structs.CASESNAKE)
+	return this.Save()
+}
@@ -0,0 +1,142 @@
+package rbac
+
+import (
+	"testing"
+
+	v1 "phenix/types/version/v1"
+
+	"github.com/activeshadow/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+func TestScopedRole(t *testing.T) {
+	role := Role{
+		Spec: &v1.RoleSpec{
+			Name: "Experiment Admin",
+			Policies: []*v1.PolicySpec{
+				{Resources: []string{"experiments", "experiments/*"}, ResourceNames: []string{"*"}, Verbs: []string{"*"}},
+			},
+		},
+	}
+
+	scoped := role.Scoped([]*v1.PolicySpec{
+		{Resources: []string{"experiments"}, ResourceNames: []string{"foo"}, Verbs: []string{"get", "list"}},
+		{Resources: []string{"vms"}, ResourceNames: []string{"*"}, Verbs: []string{"*"}},
+	})
+
+	if !scoped.Allowed("experiments", "get", "foo") {
+		t.Log("expected scoped role to allow getting experiment foo")
+		t.FailNow()
+	}
+
+	if scoped.Allowed("experiments", "get", "bar") {
+		t.Log("expected scoped role to deny getting experiment bar")
+		t.FailNow()
+	}
+
+	if scoped.Allowed("experiments", "delete", "foo") {
+		t.Log("expected scoped role to deny deleting experiment foo")
+		t.FailNow()
+	}
+
+	// Allowed by the scope, but not by the role itself.
+	if scoped.Allowed("vms", "get", "foo_host") {
+		t.Log("expected scoped role to deny getting VMs not allowed by role")
+		t.FailNow()
+	}
+
+	if !role.Scoped(nil).Allowed("experiments", "delete", "bar") {
+		t.Log("expected unscoped role to allow deleting experiment bar")
+		t.FailNow()
+	}
+}
+
+func TestValidatePolicies(t *testing.T) {
+	valid := []*v1.PolicySpec{{Resources: []string{"vms/*"}, ResourceNames: []string{"foo_*"}, Verbs: []string{"get"}}}
+
+	if err := ValidatePolicies(valid); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	invalid := [][]*v1.PolicySpec{
+		{{Resources: []string{"vms"}}},
+		{{Resources: []string{"vms["}, Verbs: []string{"get"}}},
+		{{Resources: []string{"vms"}, ResourceNames: []string{"foo["}, Verbs: []string{"get"}}},
+	}
+
+	for _, p := range invalid {
+		if err := ValidatePolicies(p); err == nil {
+			t.Logf("expected error for policies %+v", p[0])
+			t.FailNow()
+		}
+	}
+}
+
+func TestAPITokenSpecRoundTrip(t *testing.T) {
+	spec := &v1.UserSpec{
+		Username: "foo",
+		APITokens: []*v1.APITokenSpec{
+			{
+				ID:       "abc123",
+				Hash:     HashToken("token"),
+				Expires:  "2030-01-01T00:00:00Z",
+				Policies: []*v1.PolicySpec{{Resources: []string{"vms"}, ResourceNames: []string{"*"}, Verbs: []string{"list"}}},
+			},
+		},
+	}
+
+	var decoded v1.UserSpec
+
+	if err := mapstructure.Decode(structs.MapDefaultCase(spec, structs.CASESNAKE), &decoded); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(decoded.APITokens) != 1 {
+		t.Logf("expected 1 API token, got %d", len(decoded.APITokens))
+		t.FailNow()
+	}
+
+	token := decoded.APITokens[0]
+
+	if token.ID != "abc123" || token.Hash != HashToken("token") || len(token.Policies) != 1 || token.Policies[0].Verbs[0] != "list" {
+		t.Logf("unexpected decoded API token %+v", token)
+		t.FailNow()
+	}
+}
+
+func TestValidateAPITokenUsage(t *testing.T) {
+	// The user has no store config, so validating the token would fail (or
+	// panic) if it wrote the user's config.
+	user := User{
+		Spec: &v1.UserSpec{
+			Username:  "usage",
+			APITokens: []*v1.APITokenSpec{{ID: "abc123", Hash: HashToken("token"), Expires: "2999-01-01T00:00:00Z"}},
+		},
+	}
+
+	if _, err := user.ValidateAPIToken("abc123", "token", "10.0.0.1"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := user.ValidateAPIToken("abc123", "wrong", "10.0.0.1"); err == nil {
+		t.Log("expected error for wrong token")
+		t.FailNow()
+	}
+
+	pendingUsageMu.Lock()
+	use, ok := pendingUsage["usage"]["abc123"]
+	pendingUsageMu.Unlock()
+
+	if !ok || use.from != "10.0.0.1" {
+		t.Logf("expected pending usage from 10.0.0.1, got %+v", use)
+		t.FailNow()
+	}
+
+	if user.Spec.APITokens[0].LastUsed != "" {
+		t.Log("expected usage to not be recorded on the user directly")
+		t.FailNow()
+	}
+}
@@ -3,6 +3,7 @@ package rbac
 import (
 	"encoding/base64"
 	"fmt"
+	"sync"
 
 	"phenix/api/config"
 	"phenix/store"
@@ -76,6 +77,31 @@ func NewUser(u, p string) *User {
 	return &User{Spec: spec, config: c}
 }
 
+// NewOIDCUser creates a user for a login via an OIDC provider. The user has no
+// local password, so they can only login via the provider, and is bound to the
+// given provider issuer and subject.
+func NewOIDCUser(u, issuer, subject string) *User {
+	spec := &v1.UserSpec{
+		Username:    u,
+		Source:      "oidc",
+		OIDCIssuer:  issuer,
+		OIDCSubject: subject,
+	}
+
+	c := &store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "User",
+		Metadata: store.ConfigMetadata{Name: u},
+		Spec:     structs.MapDefaultCase(spec, structs.CASESNAKE),
+	}
+
+	if err := store.Create(c); err != nil {
+		return nil
+	}
+
+	return &User{Spec: spec, config: c}
+}
+
 func GetUsers() ([]*User, error) {
 	configs, err := config.List("user")
 	if err != nil {
@@ -122,6 +148,22 @@ func (this User) LastName() string {
 	return this.Spec.LastName
 }
 
+// OIDC returns true if the user was created via an OIDC login and hasn't
+// since been given a local password.
+func (this User) OIDC() bool {
+	return this.Spec.Source == "oidc" && this.Spec.Password == ""
+}
+
+// OIDCBound returns true if the user was created via an OIDC login for the
+// given provider issuer and subject.
+func (this User) OIDCBound(issuer, subject string) bool {
+	if !this.OIDC() || this.Spec.OIDCIssuer == "" || this.Spec.OIDCSubject == "" {
+		return false
+	}
+
+	return this.Spec.OIDCIssuer == issuer && this.Spec.OIDCSubject == subject
+}
+
 func (this User) RoleName() string {
 	if this.Spec.Role == nil {
 		disabled, err := RoleFromConfig("disabled")
@@ -164,6 +206,12 @@ func (this User) UpdatePassword(old, new string) error {
 		return err
 	}
 
+	return this.SetPassword(new)
+}
+
+// SetPassword sets the user's password without requiring their current
+// password (e.g. when an admin resets it).
+func (this User) SetPassword(new string) error {
 	hashed, err := bcrypt.GenerateFromPassword([]byte(new), bcrypt.DefaultCost)
 	if err != nil {
 		return fmt.Errorf("generating password hash: %w", err)
@@ -179,14 +227,14 @@ func (this User) UpdatePassword(old, new string) error {
 	return nil
 }
 
+// AddToken stores a hash of the given session token along with a note about
+// when or why it was created.
 func (this User) AddToken(token, note string) error {
 	if this.Spec.Tokens == nil {
 		this.Spec.Tokens = make(map[string]string)
 	}
 
-	enc := base64.StdEncoding.EncodeToString([]byte(token))
-
-	this.Spec.Tokens[enc] = note
+	this.Spec.Tokens[HashToken(token)] = note
 	this.config.Spec = structs.MapDefaultCase(this.Spec, structs.CASESNAKE)
 
 	if err := this.Save(); err != nil {
@@ -197,9 +245,10 @@ func (this User) AddToken(token, note string) error {
 }
 
 func (this User) DeleteToken(token string) error {
-	enc := base64.StdEncoding.EncodeToString([]byte(token))
+	delete(this.Spec.Tokens, HashToken(token))
 
-	delete(this.Spec.Tokens, enc)
+	// Tokens created before tokens were stored hashed were stored base64 encoded.
+	delete(this.Spec.Tokens, base64.StdEncoding.EncodeToString([]byte(token)))
 
 	this.config.Spec = structs.MapDefaultCase(this.Spec, structs.CASESNAKE)
 
@@ -211,15 +260,15 @@ func (this User) DeleteToken(token string) error {
 }
 
 func (this User) ValidateToken(token string) error {
-	for enc := range this.Spec.Tokens {
-		t, _ := base64.StdEncoding.DecodeString(enc)
+	if _, ok := this.Spec.Tokens[HashToken(token)]; ok {
+		return nil
+	}
 
-		if token == string(t) {
-			return nil
-		}
+	if _, ok := this.Spec.Tokens[base64.StdEncoding.EncodeToString([]byte(token))]; ok {
+		return nil
 	}
 
-	return fmt.Errorf("token not found for user")
+	return ErrTokenNotFound
 }
 
 func (this User) ValidatePassword(p string) error {
@@ -234,7 +283,19 @@ func (this User) ValidatePassword(p string) error {
 	return nil
 }
 
+// userMu serializes writes to user configs so writes that re-read a user
+// before updating it (like flushing API token usage) can't be interleaved with
+// other writes.
+var userMu sync.Mutex
+
 func (this User) Save() error {
+	userMu.Lock()
+	defer userMu.Unlock()
+
+	return this.update()
+}
+
+func (this User) update() error {
 	if err := store.Update(this.config); err != nil {
 		return fmt.Errorf("updating user in store: %w", err)
 	}
@@ -255,6 +316,27 @@ func (this User) Role() (Role, error) {
 	return Role{Spec: this.Spec.Role}, nil
 }
 
+// SetRoleByName sets the user's role to the role with the given name, limited
+// to the given resource names (if any). The user is always allowed to get
+// their own user details.
+func (this *User) SetRoleByName(name string, resources ...string) error {
+	role, err := RoleFromConfig(name)
+	if err != nil {
+		return fmt.Errorf("getting %s role for user %s: %w", name, this.Username(), err)
+	}
+
+	role.SetResourceNames(resources...)
+
+	// allow user to get their own user details
+	role.AddPolicy(
+		[]string{"users"},
+		[]string{this.Username()},
+		[]string{"get"},
+	)
+
+	return this.SetRole(role)
+}
+
 func (this *User) SetRole(role *Role) error {
 	this.Spec.Role = role.Spec
 	this.config.Spec = structs.MapDefaultCase(this.Spec, structs.CASESNAKE)
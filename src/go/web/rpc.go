@@ -0,0 +1,556 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"phenix/api/vm"
+	"phenix/util/audit"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/middleware"
+	"phenix/web/proto"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Read-only gRPC methods, which aren't audited.
+var unauditedRPCs = map[string]bool{
+	"ListExperiments": true,
+	"GetExperiment":   true,
+	"ListVMs":         true,
+	"GetVM":           true,
+	"ListVMSnapshots": true,
+	"StreamEvents":    true,
+}
+
+// rpcServer implements the phenix gRPC API. RBAC checks and the actions taken
+// are the same as the corresponding REST handlers.
+type rpcServer struct {
+	proto.UnimplementedPhenixServer
+}
+
+// newRPCServer returns a gRPC server with the phenix API registered, using TLS
+// if it's enabled for the web server.
+func newRPCServer() (*grpc.Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(rpcUnaryAuth),
+		grpc.StreamInterceptor(rpcStreamAuth),
+	}
+
+	if o.tlsEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(o.tlsCrtPath, o.tlsKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	server := grpc.NewServer(opts...)
+	proto.RegisterPhenixServer(server, new(rpcServer))
+
+	return server, nil
+}
+
+func serveRPC(endpoint string) error {
+	server, err := newRPCServer()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Starting gRPC server on %s", endpoint)
+
+	return server.Serve(listener)
+}
+
+func (rpcServer) ListExperiments(ctx context.Context, req *proto.ListExperimentsRequest) (*proto.ExperimentList, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !role.Allowed("experiments", "list") {
+		return nil, rpcForbidden(ctx, "listing experiments")
+	}
+
+	exps, total, err := listExperiments(role, util.ListOptions{}, req.Screenshot)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return &proto.ExperimentList{Experiments: exps, Total: uint32(total)}, nil
+}
+
+func (rpcServer) GetExperiment(ctx context.Context, req *proto.ExperimentRequest) (*proto.Experiment, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.ExperimentAllowed(role, "experiments", "get", req.Name) {
+		return nil, rpcForbidden(ctx, "getting experiment "+req.Name)
+	}
+
+	exp, vms, _, err := listVMs(role, req.Name, util.ListOptions{}, "")
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return util.ExperimentToProtobuf(*exp, cache.IsExperimentLocked(req.Name), vms), nil
+}
+
+func (rpcServer) CreateExperiment(ctx context.Context, req *proto.CreateExperimentRequest) (*proto.Experiment, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !role.Allowed("experiments", "create") {
+		return nil, rpcForbidden(ctx, "creating experiments")
+	}
+
+	exp, err := createExperiment(ctx, req)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return exp, nil
+}
+
+func (rpcServer) DeleteExperiment(ctx context.Context, req *proto.ExperimentRequest) (*emptypb.Empty, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.ExperimentAllowed(role, "experiments", "delete", req.Name) {
+		return nil, rpcForbidden(ctx, "deleting experiment "+req.Name)
+	}
+
+	if err := deleteExperiment(req.Name); err != nil {
+		return nil, rpcError(err)
+	}
+
+	return new(emptypb.Empty), nil
+}
+
+func (rpcServer) StartExperiment(ctx context.Context, req *proto.ExperimentRequest) (*proto.Experiment, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.ExperimentAllowed(role, "experiments/start", "update", req.Name) {
+		return nil, rpcForbidden(ctx, "starting experiment "+req.Name)
+	}
+
+	exp, err := startExperiment(ctx, req.Name)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return exp, nil
+}
+
+func (rpcServer) StopExperiment(ctx context.Context, req *proto.ExperimentRequest) (*proto.Experiment, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.ExperimentAllowed(role, "experiments/stop", "update", req.Name) {
+		return nil, rpcForbidden(ctx, "stopping experiment "+req.Name)
+	}
+
+	exp, err := stopExperiment(ctx, req.Name)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return exp, nil
+}
+
+func (rpcServer) ListVMs(ctx context.Context, req *proto.ListVMsRequest) (*proto.VMList, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !role.Allowed("vms", "list") {
+		return nil, rpcForbidden(ctx, "listing VMs")
+	}
+
+	exp, vms, total, err := listVMs(role, req.Exp, util.ListOptions{}, req.Screenshot)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	resp := &proto.VMList{Total: uint32(total)}
+
+	resp.Vms = make([]*proto.VM, len(vms))
+	for i, v := range vms {
+		resp.Vms[i] = util.VMToProtobuf(req.Exp, v, exp.Spec.Topology())
+	}
+
+	return resp, nil
+}
+
+func (rpcServer) GetVM(ctx context.Context, req *proto.VMRequest) (*proto.VM, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms", "get", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "getting VM "+req.Exp+"/"+req.Name)
+	}
+
+	v, err := getVM(req.Exp, req.Name, req.Screenshot)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return v, nil
+}
+
+func (rpcServer) StartVM(ctx context.Context, req *proto.VMRequest) (*proto.VM, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms/start", "update", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "starting VM "+req.Exp+"/"+req.Name)
+	}
+
+	v, err := startVM(ctx, req.Exp, req.Name)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return v, nil
+}
+
+func (rpcServer) StopVM(ctx context.Context, req *proto.VMRequest) (*proto.VM, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms/stop", "update", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "stopping VM "+req.Exp+"/"+req.Name)
+	}
+
+	v, err := stopVM(ctx, req.Exp, req.Name)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return v, nil
+}
+
+func (rpcServer) RestartVM(ctx context.Context, req *proto.VMRequest) (*proto.VM, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms/restart", "update", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "restarting VM "+req.Exp+"/"+req.Name)
+	}
+
+	v, err := restartVM(ctx, req.Exp, req.Name)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return v, nil
+}
+
+func (rpcServer) ShutdownVM(ctx context.Context, req *proto.VMRequest) (*proto.VM, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms/shutdown", "update", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "shutting down VM "+req.Exp+"/"+req.Name)
+	}
+
+	v, err := shutdownVM(ctx, req.Exp, req.Name)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+
+	return v, nil
+}
+
+func (rpcServer) KillVM(ctx context.Context, req *proto.VMRequest) (*emptypb.Empty, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms", "delete", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "killing VM "+req.Exp+"/"+req.Name)
+	}
+
+	if err := killVM(ctx, req.Exp, req.Name); err != nil {
+		return nil, rpcError(err)
+	}
+
+	return new(emptypb.Empty), nil
+}
+
+func (rpcServer) ListVMSnapshots(ctx context.Context, req *proto.VMRequest) (*proto.SnapshotList, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms/snapshots", "list", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "listing snapshots for VM "+req.Exp+"/"+req.Name)
+	}
+
+	snapshots, err := vm.Snapshots(req.Exp, req.Name)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &proto.SnapshotList{Snapshots: snapshots}, nil
+}
+
+func (rpcServer) SnapshotVM(ctx context.Context, req *proto.VMSnapshotRequest) (*emptypb.Empty, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms/snapshots", "create", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "snapshotting VM "+req.Exp+"/"+req.Name)
+	}
+
+	if err := snapshotVM(ctx, req.Exp, req.Name, req.Snapshot); err != nil {
+		return nil, rpcError(err)
+	}
+
+	return new(emptypb.Empty), nil
+}
+
+func (rpcServer) RestoreVM(ctx context.Context, req *proto.VMSnapshotRequest) (*emptypb.Empty, error) {
+	role := ctx.Value("role").(rbac.Role)
+
+	if !util.VMAllowed(role, "vms/snapshots", "update", req.Exp, req.Name) {
+		return nil, rpcForbidden(ctx, "restoring VM "+req.Exp+"/"+req.Name)
+	}
+
+	if err := restoreVM(ctx, req.Exp, req.Name, req.Snapshot); err != nil {
+		return nil, rpcError(err)
+	}
+
+	return new(emptypb.Empty), nil
+}
+
+func (rpcServer) StreamEvents(req *proto.StreamEventsRequest, stream proto.Phenix_StreamEventsServer) error {
+	var (
+		ctx  = stream.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	types := make(map[string]bool)
+
+	for _, typ := range req.Types {
+		types[typ] = true
+	}
+
+	sub := broker.Subscribe(role)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-sub.Done():
+			return status.Error(codes.ResourceExhausted, "event stream fell too far behind")
+		case pub := <-sub.Publications():
+			if pub.Resource == nil {
+				continue
+			}
+
+			if len(types) > 0 && !types[pub.Resource.Type] {
+				continue
+			}
+
+			event := &proto.Event{
+				Type:   pub.Resource.Type,
+				Name:   pub.Resource.Name,
+				Action: pub.Resource.Action,
+				Result: pub.Result,
+			}
+
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// rpcUnaryAuth authenticates unary gRPC calls using the same tokens accepted by
+// the REST API, adding the user and role to the call's context and auditing
+// calls that modify resources.
+func rpcUnaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := rpcAuthenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+
+	method := info.FullMethod[strings.LastIndex(info.FullMethod, "/")+1:]
+
+	if !unauditedRPCs[method] {
+		rpcAudit(ctx, method, rpcResource(req), err)
+	}
+
+	return resp, err
+}
+
+// rpcStreamAuth authenticates streaming gRPC calls the same way rpcUnaryAuth
+// does for unary calls.
+func rpcStreamAuth(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := rpcAuthenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+func (this *authenticatedStream) Context() context.Context {
+	return this.ctx
+}
+
+// rpcAuthenticate validates the token included in the call's `authorization`
+// metadata, returning a context with the authenticated user and role added.
+func rpcAuthenticate(ctx context.Context) (context.Context, error) {
+	var token string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range []string{"authorization", "x-phenix-auth-token"} {
+			if vals := md.Get(key); len(vals) > 0 {
+				parts := strings.Fields(vals[0])
+
+				if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+					return nil, status.Errorf(codes.Unauthenticated, "%s metadata format must be 'Bearer {token}'", key)
+				}
+
+				token = parts[1]
+				break
+			}
+		}
+	}
+
+	addr := rpcClientAddress(ctx)
+
+	user, role, err := middleware.Authenticate(o.jwtKey, jwtKeys, token, addr)
+	if err != nil {
+		log.Error("Rejecting unauthenticated gRPC call from %s: %v", addr, err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	ctx = context.WithValue(ctx, "user", user)
+	ctx = context.WithValue(ctx, "role", role)
+
+	return plog.WithRequestID(ctx, rpcRequestID(ctx)), nil
+}
+
+// rpcRequestID returns the request ID provided by the client in the call's
+// metadata, or a new one if the client didn't provide one.
+func rpcRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vals := md.Get("x-request-id"); len(vals) > 0 && vals[0] != "" {
+			return vals[0]
+		}
+	}
+
+	return plog.NewRequestID()
+}
+
+func rpcClientAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+
+	return p.Addr.String()
+}
+
+// rpcResource returns the name of the experiment or VM the given request acts
+// on, formatted the same way as the REST API paths for auditing.
+func rpcResource(req interface{}) string {
+	switch req := req.(type) {
+	case *proto.ExperimentRequest:
+		return "/experiments/" + req.Name
+	case *proto.CreateExperimentRequest:
+		return "/experiments/" + req.Name
+	case *proto.VMRequest:
+		return "/experiments/" + req.Exp + "/vms/" + req.Name
+	case *proto.VMSnapshotRequest:
+		return "/experiments/" + req.Exp + "/vms/" + req.Name + "/snapshots/" + req.Snapshot
+	default:
+		return ""
+	}
+}
+
+func rpcAudit(ctx context.Context, method, resource string, err error) {
+	var (
+		user, _ = ctx.Value("user").(string)
+		role, _ = ctx.Value("role").(rbac.Role)
+	)
+
+	entry := audit.Record{
+		User:     user,
+		Address:  rpcClientAddress(ctx),
+		Via:      "grpc",
+		Action:   method,
+		Resource: resource,
+		Outcome:  audit.OutcomeSuccess,
+	}
+
+	if role.Spec != nil {
+		entry.Role = role.Spec.Name
+	}
+
+	if err != nil {
+		entry.Detail = err.Error()
+
+		switch status.Code(err) {
+		case codes.PermissionDenied, codes.Unauthenticated:
+			entry.Outcome = audit.OutcomeDenied
+		default:
+			entry.Outcome = audit.OutcomeFailure
+		}
+	}
+
+	audit.Log(entry)
+}
+
+func rpcForbidden(ctx context.Context, action string) error {
+	user, _ := ctx.Value("user").(string)
+
+	log.Warn("%s not allowed for %s", action, user)
+	return status.Errorf(codes.PermissionDenied, "%s not allowed for %s", action, user)
+}
+
+// rpcError converts errors returned by the shared REST and gRPC helpers into
+// gRPC status errors, mapping the HTTP status of web errors to the closest gRPC
+// status code.
+func rpcError(err error) error {
+	var web *weberror.WebError
+
+	if !errors.As(err, &web) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code := codes.Internal
+
+	switch web.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.Aborted
+	}
+
+	return status.Error(code, web.Error())
+}
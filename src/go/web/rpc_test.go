@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"phenix/web/weberror"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRPCError(t *testing.T) {
+	cases := map[int]codes.Code{
+		http.StatusBadRequest:          codes.InvalidArgument,
+		http.StatusForbidden:           codes.PermissionDenied,
+		http.StatusNotFound:            codes.NotFound,
+		http.StatusConflict:            codes.Aborted,
+		http.StatusInternalServerError: codes.Internal,
+	}
+
+	for code, expected := range cases {
+		err := weberror.NewWebError(errors.New("oops"), "unable to do thing").SetStatus(code)
+
+		if actual := status.Code(rpcError(err)); actual != expected {
+			t.Logf("expected status %d to map to %v, got %v", code, expected, actual)
+			t.FailNow()
+		}
+	}
+
+	if actual := status.Code(rpcError(errors.New("oops"))); actual != codes.Internal {
+		t.Logf("expected plain error to map to %v, got %v", codes.Internal, actual)
+		t.FailNow()
+	}
+}
+
+func TestRPCAuthenticate(t *testing.T) {
+	o = newServerOptions(ServeWithJWTKey("secret"))
+
+	if _, err := rpcAuthenticate(context.Background()); status.Code(err) != codes.Unauthenticated {
+		t.Logf("expected missing token to be unauthenticated, got %v", err)
+		t.FailNow()
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "secret"))
+
+	if _, err := rpcAuthenticate(ctx); status.Code(err) != codes.Unauthenticated {
+		t.Logf("expected malformed token to be unauthenticated, got %v", err)
+		t.FailNow()
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer foobar"))
+
+	if _, err := rpcAuthenticate(ctx); status.Code(err) != codes.Unauthenticated {
+		t.Logf("expected invalid token to be unauthenticated, got %v", err)
+		t.FailNow()
+	}
+}
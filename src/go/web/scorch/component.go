@@ -16,6 +16,7 @@ type ComponentUpdate struct {
 	Stage   string // component stage
 	Status  string // component status
 	Output  []byte // component output
+	Label   string // parameter label for current iteration
 
 	done chan struct{}
 }
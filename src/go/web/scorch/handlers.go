@@ -457,7 +457,7 @@ func GetPipelines(w http.ResponseWriter, r *http.Request) error {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments", "get", name) {
+	if !util.ExperimentAllowed(role, "experiments", "get", name) {
 		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
@@ -537,7 +537,7 @@ func GetPipeline(w http.ResponseWriter, r *http.Request) error {
 		return weberror.NewWebError(err, "invalid loop number '%s' provided", vars["loop"])
 	}
 
-	if !role.Allowed("experiments", "get", exp) {
+	if !util.ExperimentAllowed(role, "experiments", "get", exp) {
 		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", exp, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
@@ -573,12 +573,12 @@ func StartPipeline(w http.ResponseWriter, r *http.Request) error {
 		return weberror.NewWebError(err, "invalid run ID '%s' provided", vars["run"])
 	}
 
-	if !role.Allowed("experiments/trigger", "create", name) {
+	if !util.ExperimentAllowed(role, "experiments/trigger", "create", name) {
 		err := weberror.NewWebError(nil, "starting Scorch runs for experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
 
-	if !role.Allowed("experiments", "get", name) {
+	if !util.ExperimentAllowed(role, "experiments", "get", name) {
 		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
@@ -616,7 +616,7 @@ func StartPipeline(w http.ResponseWriter, r *http.Request) error {
 		log.Debug("executing Scorch run %d for experiment %s", run, name)
 
 		broker.Broadcast(
-			broker.NewRequestPolicy("experiments/trigger", "create", name),
+			broker.NewExperimentRequestPolicy("experiments/trigger", name, "create"),
 			broker.NewResource("apps/scorch", key, "start"),
 			nil,
 		)
@@ -626,7 +626,7 @@ func StartPipeline(w http.ResponseWriter, r *http.Request) error {
 				log.Error("executing Scorch run %d for experiment %s: %v", run, name, err)
 
 				broker.Broadcast(
-					broker.NewRequestPolicy("experiments/trigger", "create", name),
+					broker.NewExperimentRequestPolicy("experiments/trigger", name, "create"),
 					broker.NewResource("apps/scorch", key, "error"),
 					[]byte(fmt.Sprintf(`{"error": "failed to execute Scorch run %d for experiment %s"}`, run, name)),
 				)
@@ -635,7 +635,7 @@ func StartPipeline(w http.ResponseWriter, r *http.Request) error {
 			log.Debug("Scorch run %d for experiment %s executed successfully", run, name)
 
 			broker.Broadcast(
-				broker.NewRequestPolicy("experiments/trigger", "create", name),
+				broker.NewExperimentRequestPolicy("experiments/trigger", name, "create"),
 				broker.NewResource("apps/scorch", key, "success"),
 				nil,
 			)
@@ -674,7 +674,7 @@ func CancelPipeline(w http.ResponseWriter, r *http.Request) error {
 		return weberror.NewWebError(err, "invalid run ID '%s' provided", vars["run"])
 	}
 
-	if !role.Allowed("experiments/trigger", "delete", name) {
+	if !util.ExperimentAllowed(role, "experiments/trigger", "delete", name) {
 		err := weberror.NewWebError(nil, "canceling Scorch runs for experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
@@ -692,10 +692,18 @@ func CancelPipeline(w http.ResponseWriter, r *http.Request) error {
 		delete(cancelers, key)
 
 		broker.Broadcast(
-			broker.NewRequestPolicy("experiments/trigger", "delete", name),
+			broker.NewExperimentRequestPolicy("experiments/trigger", name, "delete"),
 			broker.NewResource("apps/scorch", key, "success"),
 			nil,
 		)
+	} else if exp, err := experiment.Get(name); err == nil && exp.Status.AppRunning()["scorch"] {
+		// The run may have been started by another phenix process (e.g. from the
+		// command line).
+		log.Debug("canceling Scorch run %d for experiment %s in another process", run, name)
+
+		if err := scorchexe.Cancel(exp, run); err != nil {
+			return weberror.NewWebError(err, "unable to cancel Scorch run %d for experiment %s", run, name)
+		}
 	}
 
 	w.WriteHeader(http.StatusNoContent)
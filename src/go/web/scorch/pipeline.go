@@ -65,6 +65,7 @@ type pipeline struct {
 	Pipeline []*node   `json:"pipeline"`
 	Loop     *pipeline `json:"loop,omitempty"`
 	Name     string    `json:"name,omitempty"`
+	Label    string    `json:"label,omitempty"`
 
 	exp    string
 	runID  int
@@ -483,6 +484,12 @@ func updatePipeline(update PipelineUpdate) error {
 		return fmt.Errorf("getting pipeline %d for experiment %s: %w", update.Run, update.Exp, err)
 	}
 
+	// Label the pipeline with the parameters for the iteration currently being
+	// executed (only set when the run uses a parameter matrix).
+	if update.Label != "" {
+		pl.Label = update.Label
+	}
+
 	if update.CmpName == "" {
 		if pl.setStageStatus(update.Stage, update.Status) {
 			broadcastPipeline(update.Exp, update.Run, update.Loop, pl)
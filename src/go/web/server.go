@@ -2,13 +2,16 @@ package web
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 
 	"phenix/web/broker"
+	"phenix/web/jwtkeys"
 	"phenix/web/middleware"
+	"phenix/web/oidc"
 	"phenix/web/rbac"
 	"phenix/web/scorch"
 	"phenix/web/util"
@@ -17,6 +20,7 @@ import (
 	log "github.com/activeshadow/libminimega/minilog"
 	assetfs "github.com/elazarl/go-bindata-assetfs"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type route struct {
@@ -25,26 +29,12 @@ type route struct {
 	methods []string
 }
 
-var o serverOptions
+var (
+	o       serverOptions
+	jwtKeys *jwtkeys.KeySet
+)
 
 func ConfigureUsers(users []string) error {
-	setUserRole := func(user *rbac.User, rname string, resources ...string) {
-		if role, err := rbac.RoleFromConfig(rname); err == nil {
-			role.SetResourceNames(resources...)
-
-			// allow user to get their own user details
-			role.AddPolicy(
-				[]string{"users"},
-				[]string{user.Username()},
-				[]string{"get"},
-			)
-
-			user.SetRole(role)
-		} else {
-			log.Error("getting %s role for user %s: %v", rname, user.Username(), err)
-		}
-	}
-
 	for _, u := range users {
 		creds := strings.Split(u, ":")
 		uname := creds[0]
@@ -57,7 +47,9 @@ func ConfigureUsers(users []string) error {
 			if user.RoleName() != rname {
 				log.Debug("updating role for existing user %s from %s to %s", user.Username(), user.RoleName(), rname)
 
-				setUserRole(user, rname, creds[3:]...)
+				if err := user.SetRoleByName(rname, creds[3:]...); err != nil {
+					log.Error("%v", err)
+				}
 			}
 
 			continue
@@ -67,7 +59,9 @@ func ConfigureUsers(users []string) error {
 
 		user := rbac.NewUser(uname, pword)
 
-		setUserRole(user, rname, creds[3:]...)
+		if err := user.SetRoleByName(rname, creds[3:]...); err != nil {
+			log.Error("%v", err)
+		}
 	}
 
 	return nil
@@ -78,6 +72,32 @@ func Start(opts ...ServerOption) error {
 
 	ConfigureUsers(o.users)
 
+	if o.jwtKeySet != "" {
+		keys, err := jwtkeys.Load(o.jwtKeySet)
+		if err != nil {
+			return fmt.Errorf("loading JWT key set: %w", err)
+		}
+
+		log.Info("Signing JWTs using key set at %s", o.jwtKeySet)
+
+		jwtKeys = keys
+	}
+
+	if err := middleware.SetTrustedProxies(o.trustedProxies); err != nil {
+		return fmt.Errorf("configuring trusted proxies: %w", err)
+	}
+
+	if o.oidc.Issuer != "" {
+		provider, err := oidc.NewProvider(o.oidc)
+		if err != nil {
+			return fmt.Errorf("configuring OIDC login: %w", err)
+		}
+
+		log.Info("OIDC login enabled using issuer %s", o.oidc.Issuer)
+
+		oidcProvider = provider
+	}
+
 	var (
 		router = mux.NewRouter().StrictSlash(true)
 		assets http.FileSystem
@@ -96,6 +116,7 @@ func Start(opts ...ServerOption) error {
 
 	router.HandleFunc("/features", GetFeatures).Methods("GET")
 	router.HandleFunc("/version", GetVersion).Methods("GET")
+	router.HandleFunc("/.well-known/jwks.json", GetJWKS).Methods("GET")
 	router.HandleFunc("/builder", GetBuilder).Methods("GET")
 	router.HandleFunc("/builder/save", SaveBuilderTopology).Methods("POST")
 
@@ -132,6 +153,107 @@ func Start(opts ...ServerOption) error {
 
 	api := router.PathPrefix("/api/v1").Subrouter()
 
+	addAPIRoutes(api)
+
+	api.Use(middleware.RequestID)
+	api.Use(middleware.Metrics)
+
+	if o.allowCORS {
+		log.Info("CORS is enabled on HTTP API endpoints")
+		api.Use(middleware.AllowCORS)
+	}
+
+	switch o.logMiddleware {
+	case "full":
+		log.Info("full HTTP logging is enabled")
+		api.Use(middleware.LogFull)
+	case "requests":
+		log.Info("requests-only HTTP logging is enabled")
+		api.Use(middleware.LogRequests)
+	}
+
+	api.Use(middleware.Auth(o.jwtKey, o.proxyAuthHeader, jwtKeys))
+	api.Use(middleware.Audit)
+
+	prometheus.MustRegister(stateCollector{})
+
+	registerBrokerHandlers()
+
+	log.Info("Starting websockets broker")
+
+	go broker.Start()
+
+	log.Info("Starting scorch processors")
+
+	go scorch.Start(o.basePath)
+
+	log.Info("Starting log publisher")
+
+	go PublishLogs(context.Background(), o.phenixLogs, o.minimegaLogs)
+
+	log.Info("Using base path '%s'", o.basePath)
+	log.Info("Using JWT lifetime of %v", o.jwtLifetime)
+
+	if o.unixSocket != "" {
+		var (
+			router = mux.NewRouter().StrictSlash(true)
+			api    = router.PathPrefix("/api/v1").Subrouter()
+		)
+
+		addRoutesToRouter(api, workflowRoutes...)
+		addRoutesToRouter(api, errorRoutes...)
+
+		api.Use(middleware.RequestID)
+		api.Use(middleware.NoAuth)
+		api.Use(middleware.Audit)
+
+		os.Remove(o.unixSocket)
+
+		log.Info("Starting Unix socket server at '%s'", o.unixSocket)
+
+		server := http.Server{Handler: router}
+		listener, err := net.Listen("unix", o.unixSocket)
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			if err := server.Serve(listener); err != nil {
+				log.Error("serving Unix socket: %v", err)
+			}
+		}()
+	}
+
+	if o.grpcEndpoint != "" {
+		go func() {
+			if err := serveRPC(o.grpcEndpoint); err != nil {
+				log.Error("serving gRPC API: %v", err)
+			}
+		}()
+	}
+
+	if o.tlsEnabled() {
+		log.Info("Starting HTTPS server on %s", o.endpoint)
+		return http.ListenAndServeTLS(o.endpoint, o.tlsCrtPath, o.tlsKeyPath, router)
+	} else {
+		log.Info("Starting HTTP server on %s", o.endpoint)
+		return http.ListenAndServe(o.endpoint, router)
+	}
+}
+
+var workflowRoutes = []route{
+	{"/workflow/apply/{branch}", weberror.ErrorHandler(ApplyWorkflow), []string{"POST"}},
+	{"/workflow/configs/{branch}", weberror.ErrorHandler(WorkflowUpsertConfig), []string{"POST"}},
+}
+
+var errorRoutes = []route{
+	{"/errors/{uuid}", weberror.ErrorHandler(GetError), []string{"GET"}},
+}
+
+// addAPIRoutes registers the web API routes with the given router. Routes
+// added here must also be documented in the OpenAPI spec at
+// public/docs/openapi.yml.
+func addAPIRoutes(api *mux.Router) {
 	// OPTIONS method needed for CORS
 	api.Handle("/builder/topologies", weberror.ErrorHandler(GetBuilderTopologies)).Methods("GET", "OPTIONS")
 	api.Handle("/builder/topologies/{name}", weberror.ErrorHandler(GetBuilderTopology)).Methods("GET", "OPTIONS")
@@ -190,6 +312,8 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", GetVMCaptures).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", StartVMCapture).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", StopVMCaptures).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/nets/{iface}/impairment", ImpairVMInterface).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/nets/{iface}/impairment", ClearVMInterfaceImpairment).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots", GetVMSnapshots).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots", SnapshotVM).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots/{snapshot}", RestoreVM).Methods("POST", "OPTIONS")
@@ -211,99 +335,29 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/disks", GetDisks).Methods("GET", "OPTIONS")
 	api.HandleFunc("/hosts", GetClusterHosts).Methods("GET", "OPTIONS")
 	api.HandleFunc("/logs", GetLogs).Methods("GET", "OPTIONS")
+	api.HandleFunc("/metrics", GetMetrics).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users", GetUsers).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users", CreateUser).Methods("POST", "OPTIONS")
 	api.HandleFunc("/users/{username}", GetUser).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/{username}", UpdateUser).Methods("PATCH", "OPTIONS")
 	api.HandleFunc("/users/{username}", DeleteUser).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/users/{username}/tokens", GetUserTokens).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users/{username}/tokens", CreateUserToken).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/{username}/tokens/{id}", DeleteUserToken).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/signup", Signup).Methods("POST", "OPTIONS")
 	api.HandleFunc("/login", Login).Methods("GET", "POST", "OPTIONS")
+	api.HandleFunc("/login/oidc", OIDCLogin).Methods("GET", "OPTIONS")
+	api.HandleFunc("/login/oidc/callback", OIDCCallback).Methods("GET", "OPTIONS")
 	api.HandleFunc("/logout", Logout).Methods("GET", "OPTIONS")
 	api.Handle("/history", weberror.ErrorHandler(GetHistory)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/openapi.json", GetOpenAPI).Methods("GET", "OPTIONS")
 	api.HandleFunc("/ws", broker.ServeWS).Methods("GET")
 	api.HandleFunc("/console", CreateConsole).Methods("POST", "OPTIONS")
 	api.HandleFunc("/console/{pid}/ws", WsConsole).Methods("GET", "OPTIONS")
 	api.HandleFunc("/console/{pid}/size", ResizeConsole).Methods("POST", "OPTIONS").Queries("cols", "{cols:[0-9]+}", "rows", "{rows:[0-9]+}")
 
-	workflowRoutes := []route{
-		{"/workflow/apply/{branch}", weberror.ErrorHandler(ApplyWorkflow), []string{"POST"}},
-		{"/workflow/configs/{branch}", weberror.ErrorHandler(WorkflowUpsertConfig), []string{"POST"}},
-	}
-
-	errorRoutes := []route{
-		{"/errors/{uuid}", weberror.ErrorHandler(GetError), []string{"GET"}},
-	}
-
 	addRoutesToRouter(api, workflowRoutes...)
 	addRoutesToRouter(api, errorRoutes...)
-
-	if o.allowCORS {
-		log.Info("CORS is enabled on HTTP API endpoints")
-		api.Use(middleware.AllowCORS)
-	}
-
-	switch o.logMiddleware {
-	case "full":
-		log.Info("full HTTP logging is enabled")
-		api.Use(middleware.LogFull)
-	case "requests":
-		log.Info("requests-only HTTP logging is enabled")
-		api.Use(middleware.LogRequests)
-	}
-
-	api.Use(middleware.Auth(o.jwtKey, o.proxyAuthHeader))
-
-	log.Info("Starting websockets broker")
-
-	go broker.Start()
-
-	log.Info("Starting scorch processors")
-
-	go scorch.Start(o.basePath)
-
-	log.Info("Starting log publisher")
-
-	go PublishLogs(context.Background(), o.phenixLogs, o.minimegaLogs)
-
-	log.Info("Using base path '%s'", o.basePath)
-	log.Info("Using JWT lifetime of %v", o.jwtLifetime)
-
-	if o.unixSocket != "" {
-		var (
-			router = mux.NewRouter().StrictSlash(true)
-			api    = router.PathPrefix("/api/v1").Subrouter()
-		)
-
-		addRoutesToRouter(api, workflowRoutes...)
-		addRoutesToRouter(api, errorRoutes...)
-
-		api.Use(middleware.NoAuth)
-
-		os.Remove(o.unixSocket)
-
-		log.Info("Starting Unix socket server at '%s'", o.unixSocket)
-
-		server := http.Server{Handler: router}
-		listener, err := net.Listen("unix", o.unixSocket)
-		if err != nil {
-			return err
-		}
-
-		go func() {
-			if err := server.Serve(listener); err != nil {
-				log.Error("serving Unix socket: %v", err)
-			}
-		}()
-	}
-
-	if o.tlsEnabled() {
-		log.Info("Starting HTTPS server on %s", o.endpoint)
-		return http.ListenAndServeTLS(o.endpoint, o.tlsCrtPath, o.tlsKeyPath, router)
-	} else {
-		log.Info("Starting HTTP server on %s", o.endpoint)
-		return http.ListenAndServe(o.endpoint, router)
-	}
 }
 
 func addRoutesToRouter(router *mux.Router, routes ...route) {
@@ -0,0 +1,265 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"phenix/util/mm"
+)
+
+// ListOptions control the filtering, sorting, pagination and fields included
+// in responses from list endpoints. They're parsed from the following request
+// query parameters:
+//
+//	filter - search filter using the same syntax as the VM search in the UI
+//	sort   - sort key, prefixed with `-` to sort in descending order
+//	offset - number of results to skip
+//	limit  - maximum number of results to return (all results if not set)
+//	cursor - cursor returned with the previous page of results (overrides offset)
+//	fields - comma separated list of fields to include for each result
+//
+// The sortCol, sortDir, pageNum and perPage query parameters previously used by
+// the UI are also supported.
+type ListOptions struct {
+	Filter string
+	Sort   string
+	Asc    bool
+	Offset int
+	Limit  int
+	Fields []string
+
+	tree *mm.ExpressionTree
+}
+
+// ParseListOptions parses list options from the given query, returning an error
+// if any of them are invalid. If sort keys are provided, the sort key requested
+// (if any) must be one of them.
+func ParseListOptions(query url.Values, keys ...string) (ListOptions, error) {
+	opts := ListOptions{Filter: query.Get("filter"), Asc: true}
+
+	if opts.Filter != "" {
+		if opts.tree = mm.BuildTree(opts.Filter); opts.tree == nil {
+			return opts, fmt.Errorf("invalid filter %s", opts.Filter)
+		}
+	}
+
+	if s := query.Get("sort"); s != "" {
+		opts.Sort = strings.TrimPrefix(s, "-")
+		opts.Asc = !strings.HasPrefix(s, "-")
+
+		if len(keys) > 0 && !contains(keys, opts.Sort) {
+			return opts, fmt.Errorf("invalid sort key %s (must be one of %s)", opts.Sort, strings.Join(keys, ", "))
+		}
+	} else if s := query.Get("sortCol"); s != "" {
+		// Unknown sort columns were previously ignored, so keep doing so.
+		opts.Sort = s
+		opts.Asc = query.Get("sortDir") != "desc"
+	}
+
+	var err error
+
+	if opts.Offset, err = nonNegativeInt(query, "offset"); err != nil {
+		return opts, err
+	}
+
+	if opts.Limit, err = nonNegativeInt(query, "limit"); err != nil {
+		return opts, err
+	}
+
+	if c := query.Get("cursor"); c != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(c)
+		if err != nil {
+			return opts, fmt.Errorf("invalid cursor %s", c)
+		}
+
+		if opts.Offset, err = strconv.Atoi(string(decoded)); err != nil || opts.Offset < 0 {
+			return opts, fmt.Errorf("invalid cursor %s", c)
+		}
+	} else if query.Get("pageNum") != "" && query.Get("perPage") != "" {
+		page, _ := strconv.Atoi(query.Get("pageNum"))
+		size, _ := strconv.Atoi(query.Get("perPage"))
+
+		if page > 0 && size > 0 {
+			opts.Offset = (page - 1) * size
+			opts.Limit = size
+		}
+	}
+
+	if f := query.Get("fields"); f != "" {
+		for _, field := range strings.Split(f, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				opts.Fields = append(opts.Fields, field)
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// MatchVM returns true if the given VM matches the filter, or if there isn't a
+// filter.
+func (this ListOptions) MatchVM(vm mm.VM) bool {
+	if this.tree == nil {
+		return true
+	}
+
+	return this.tree.Evaluate(&vm)
+}
+
+// Match returns true if any of the given values contain a term in the filter
+// (joined using the filter's Boolean operators), or if there isn't a filter.
+func (this ListOptions) Match(values ...string) bool {
+	if this.tree == nil {
+		return true
+	}
+
+	return this.tree.EvaluateFunc(func(term string) bool {
+		for _, v := range values {
+			if strings.Contains(strings.ToLower(v), term) {
+				return true
+			}
+		}
+
+		return false
+	})
+}
+
+// SortWith sorts the given slice using the given less functions, keyed by sort
+// key, if a sort key was requested. Ties are left in their original order.
+func (this ListOptions) SortWith(slice interface{}, less map[string]func(i, j int) bool) {
+	fn, ok := less[this.Sort]
+	if !ok {
+		return
+	}
+
+	sort.SliceStable(slice, func(i, j int) bool {
+		if this.Asc {
+			return fn(i, j)
+		}
+
+		return fn(j, i)
+	})
+}
+
+// Page returns the start and end indexes of the requested page of results,
+// given the total number of results.
+func (this ListOptions) Page(total int) (int, int) {
+	start := this.Offset
+	if start > total {
+		start = total
+	}
+
+	end := total
+	if this.Limit > 0 && start+this.Limit < total {
+		end = start + this.Limit
+	}
+
+	return start, end
+}
+
+// NextCursor returns the cursor for the page of results following the requested
+// page, or an empty string if the requested page is the last page.
+func (this ListOptions) NextCursor(total int) string {
+	if _, end := this.Page(total); end < total {
+		return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	}
+
+	return ""
+}
+
+// SelectFields limits the objects in the array at the given key of the given
+// JSON encoded object to the requested fields, if any. Fields may be nested
+// using dot notation (e.g. `metadata.name`).
+func (this ListOptions) SelectFields(body []byte, key string) ([]byte, error) {
+	if len(this.Fields) == 0 {
+		return body, nil
+	}
+
+	var resp map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	var items []map[string]interface{}
+
+	if err := json.Unmarshal(resp[key], &items); err != nil {
+		return nil, fmt.Errorf("decoding %s in response: %w", key, err)
+	}
+
+	for i, item := range items {
+		items[i] = selectFields(item, this.Fields)
+	}
+
+	selected, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s in response: %w", key, err)
+	}
+
+	resp[key] = selected
+
+	return json.Marshal(resp)
+}
+
+func selectFields(obj map[string]interface{}, fields []string) map[string]interface{} {
+	var (
+		selected = make(map[string]interface{})
+		nested   = make(map[string][]string)
+	)
+
+	for _, field := range fields {
+		parts := strings.SplitN(field, ".", 2)
+
+		if len(parts) == 1 {
+			if v, ok := obj[field]; ok {
+				selected[field] = v
+			}
+
+			continue
+		}
+
+		nested[parts[0]] = append(nested[parts[0]], parts[1])
+	}
+
+	for field, sub := range nested {
+		if _, ok := selected[field]; ok {
+			// The entire field was already selected.
+			continue
+		}
+
+		if v, ok := obj[field].(map[string]interface{}); ok {
+			selected[field] = selectFields(v, sub)
+		}
+	}
+
+	return selected
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func nonNegativeInt(query url.Values, key string) (int, error) {
+	v := query.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("invalid %s %s (must be a non-negative integer)", key, v)
+	}
+
+	return i, nil
+}
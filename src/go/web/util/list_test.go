@@ -0,0 +1,134 @@
+package util
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseListOptions(t *testing.T) {
+	query := url.Values{"sort": {"-name"}, "offset": {"2"}, "limit": {"2"}, "fields": {"name, running"}}
+
+	opts, err := ParseListOptions(query, "name", "host")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if opts.Sort != "name" || opts.Asc || opts.Offset != 2 || opts.Limit != 2 {
+		t.Logf("unexpected list options %+v", opts)
+		t.FailNow()
+	}
+
+	if !reflect.DeepEqual(opts.Fields, []string{"name", "running"}) {
+		t.Logf("unexpected fields %v", opts.Fields)
+		t.FailNow()
+	}
+
+	if _, err := ParseListOptions(url.Values{"sort": {"foo"}}, "name", "host"); err == nil {
+		t.Log("expected error for invalid sort key")
+		t.FailNow()
+	}
+
+	if _, err := ParseListOptions(url.Values{"limit": {"-1"}}); err == nil {
+		t.Log("expected error for negative limit")
+		t.FailNow()
+	}
+
+	// Deprecated pagination query parameters used by the UI.
+	opts, err = ParseListOptions(url.Values{"pageNum": {"3"}, "perPage": {"10"}})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if opts.Offset != 20 || opts.Limit != 10 {
+		t.Logf("expected offset 20 and limit 10, got %d and %d", opts.Offset, opts.Limit)
+		t.FailNow()
+	}
+}
+
+func TestListOptionsPaging(t *testing.T) {
+	opts, _ := ParseListOptions(url.Values{"limit": {"2"}})
+
+	items := []string{"a", "b", "c", "d", "e"}
+
+	var paged []string
+
+	for {
+		start, end := opts.Page(len(items))
+		paged = append(paged, items[start:end]...)
+
+		cursor := opts.NextCursor(len(items))
+		if cursor == "" {
+			break
+		}
+
+		opts, _ = ParseListOptions(url.Values{"limit": {"2"}, "cursor": {cursor}})
+	}
+
+	if !reflect.DeepEqual(items, paged) {
+		t.Logf("expected paging through all items to return %v, got %v", items, paged)
+		t.FailNow()
+	}
+
+	opts, _ = ParseListOptions(url.Values{"offset": {"10"}})
+
+	if start, end := opts.Page(len(items)); start != end {
+		t.Logf("expected empty page past the end of items, got %d:%d", start, end)
+		t.FailNow()
+	}
+}
+
+func TestListOptionsFilterAndSort(t *testing.T) {
+	opts, err := ParseListOptions(url.Values{"filter": {"foo and not bar"}, "sort": {"-name"}})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var matched []string
+
+	for _, name := range []string{"foo-1", "foo-bar", "baz", "foo-2"} {
+		if opts.Match(name) {
+			matched = append(matched, name)
+		}
+	}
+
+	opts.SortWith(matched, map[string]func(int, int) bool{
+		"name": func(i, j int) bool { return matched[i] < matched[j] },
+	})
+
+	if expected := []string{"foo-2", "foo-1"}; !reflect.DeepEqual(expected, matched) {
+		t.Logf("expected %v, got %v", expected, matched)
+		t.FailNow()
+	}
+}
+
+func TestListOptionsSelectFields(t *testing.T) {
+	opts, _ := ParseListOptions(url.Values{"fields": {"kind,metadata.name"}})
+
+	body := []byte(`{"configs": [{"kind": "Topology", "metadata": {"name": "foo", "created": "now"}, "spec": {}}], "total": 1}`)
+
+	body, err := opts.SelectFields(body, "configs")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var resp map[string]interface{}
+	json.Unmarshal(body, &resp)
+
+	expected := map[string]interface{}{
+		"configs": []interface{}{
+			map[string]interface{}{"kind": "Topology", "metadata": map[string]interface{}{"name": "foo"}},
+		},
+		"total": float64(1),
+	}
+
+	if !reflect.DeepEqual(expected, resp) {
+		t.Logf("expected %v, got %v", expected, resp)
+		t.FailNow()
+	}
+}
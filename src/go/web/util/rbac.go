@@ -0,0 +1,105 @@
+package util
+
+import (
+	"fmt"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/web/rbac"
+)
+
+// ExperimentObject returns the object matched against RBAC policies for the
+// given experiment.
+func ExperimentObject(exp types.Experiment) rbac.Object {
+	return rbac.Object{
+		Name:        exp.Metadata.Name,
+		Labels:      exp.Metadata.Labels,
+		Annotations: exp.Metadata.Annotations,
+	}
+}
+
+// VMObject returns the object matched against RBAC policies for the given VM
+// in the given experiment. VMs inherit their experiment's labels and
+// annotations so policies selecting experiments by them also select their VMs,
+// with labels and annotations on the VM's topology node taking precedence.
+func VMObject(exp types.Experiment, vm mm.VM) rbac.Object {
+	var (
+		labels      = make(map[string]string)
+		annotations = make(map[string]string)
+	)
+
+	for k, v := range exp.Metadata.Labels {
+		labels[k] = v
+	}
+
+	for k, v := range vm.Labels {
+		labels[k] = v
+	}
+
+	for k, v := range exp.Metadata.Annotations {
+		annotations[k] = v
+	}
+
+	for k, v := range vm.Annotations {
+		annotations[k] = fmt.Sprint(v)
+	}
+
+	return rbac.Object{
+		Name:        fmt.Sprintf("%s_%s", exp.Metadata.Name, vm.Name),
+		Labels:      labels,
+		Annotations: annotations,
+	}
+}
+
+// ExperimentAllowed returns true if the given role allows the given verb on the
+// given experiment resource. If it's not allowed by name alone, the experiment
+// is matched against any policy selectors.
+func ExperimentAllowed(role rbac.Role, resource, verb, name string) bool {
+	if role.Allowed(resource, verb, name) {
+		return true
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return false
+	}
+
+	return role.AllowedObjects(resource, verb, ExperimentObject(*exp))
+}
+
+// VMAllowed returns true if the given role allows the given verb on the given
+// VM resource. If it's not allowed by name alone, the VM is matched against any
+// policy selectors.
+func VMAllowed(role rbac.Role, resource, verb, expName, vmName string) bool {
+	return vmAllowed(role, resource, verb, fmt.Sprintf("%s_%s", expName, vmName), expName, vmName)
+}
+
+// VMPathAllowed is like VMAllowed, but matches policy resource names against
+// <exp>/<vm> instead of <exp>_<vm>, as the VNC, screenshot, and mount
+// endpoints always have.
+func VMPathAllowed(role rbac.Role, resource, verb, expName, vmName string) bool {
+	return vmAllowed(role, resource, verb, fmt.Sprintf("%s/%s", expName, vmName), expName, vmName)
+}
+
+func vmAllowed(role rbac.Role, resource, verb, name, expName, vmName string) bool {
+	if role.Allowed(resource, verb, name) {
+		return true
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return false
+	}
+
+	v, err := vm.Get(expName, vmName)
+	if err != nil {
+		return false
+	}
+
+	obj := VMObject(*exp, *v)
+	obj.Name = name
+
+	return role.AllowedObjects(resource, verb, obj)
+}
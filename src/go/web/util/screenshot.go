@@ -11,7 +11,9 @@ import (
 func GetScreenshot(expName, vmName, size string) ([]byte, error) {
 	name := fmt.Sprintf("%s_%s", expName, vmName)
 
-	if screenshot, ok := cache.Get(name); ok {
+	// Screenshots are refreshed often and are expensive to share between phenix
+	// instances, so they're only cached locally.
+	if screenshot, ok := cache.LocalCache.Get(name); ok {
 		return screenshot, nil
 	}
 
@@ -24,7 +26,7 @@ func GetScreenshot(expName, vmName, size string) ([]byte, error) {
 		return nil, fmt.Errorf("VM screenshot not found")
 	}
 
-	cache.SetWithExpire(name, screenshot, 10*time.Second)
+	cache.LocalCache.SetWithExpire(name, screenshot, 10*time.Second)
 
 	return screenshot, nil
 }
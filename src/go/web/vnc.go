@@ -30,7 +30,7 @@ func GetVNC(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("vms/vnc", "get", exp+"/"+name) {
+	if !util.VMPathAllowed(role, "vms/vnc", "get", exp, name) {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
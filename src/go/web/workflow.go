@@ -135,7 +135,7 @@ func ApplyWorkflow(w http.ResponseWriter, r *http.Request) error {
 		if wf.AutoRestart() {
 			cache.UnlockExperiment(expName)
 
-			if _, err := startExperiment(expName); err != nil {
+			if _, err := startExperiment(ctx, expName); err != nil {
 				return err
 			}
 		}
@@ -154,7 +154,7 @@ func ApplyWorkflow(w http.ResponseWriter, r *http.Request) error {
 
 			var err error
 
-			if _, err = stopExperiment(expName); err != nil {
+			if _, err = stopExperiment(ctx, expName); err != nil {
 				return err
 			}
 
@@ -291,7 +291,7 @@ func ApplyWorkflow(w http.ResponseWriter, r *http.Request) error {
 		if wf.AutoRestart() {
 			cache.UnlockExperiment(expName)
 
-			if _, err := startExperiment(expName); err != nil {
+			if _, err := startExperiment(ctx, expName); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,120 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"phenix/web/broker"
+	"phenix/web/proto"
+	"phenix/web/rbac"
+	"phenix/web/util"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// registerBrokerHandlers registers handlers for the experiment and VM actions
+// WebSocket clients can request via the broker. Each handler enforces the same
+// role checks as the HTTP handler for the same action.
+func registerBrokerHandlers() {
+	broker.RegisterRequestHandler("experiment", "start", func(ctx context.Context, req broker.Request) (json.RawMessage, error) {
+		name := req.Resource.Name
+
+		if !util.ExperimentAllowed(wsRole(ctx), "experiments/start", "update", name) {
+			return nil, fmt.Errorf("%w: starting experiment %s", broker.ErrForbidden, name)
+		}
+
+		return wsResult(startExperiment(ctx, name))
+	})
+
+	broker.RegisterRequestHandler("experiment", "stop", func(ctx context.Context, req broker.Request) (json.RawMessage, error) {
+		name := req.Resource.Name
+
+		if !util.ExperimentAllowed(wsRole(ctx), "experiments/stop", "update", name) {
+			return nil, fmt.Errorf("%w: stopping experiment %s", broker.ErrForbidden, name)
+		}
+
+		return wsResult(stopExperiment(ctx, name))
+	})
+
+	wsVMAction("start", "vms/start", "update", func(ctx context.Context, exp, name string, _ json.RawMessage) (protobuf.Message, error) {
+		return startVM(ctx, exp, name)
+	})
+
+	wsVMAction("stop", "vms/stop", "update", func(ctx context.Context, exp, name string, _ json.RawMessage) (protobuf.Message, error) {
+		return stopVM(ctx, exp, name)
+	})
+
+	wsVMAction("restart", "vms/restart", "update", func(ctx context.Context, exp, name string, _ json.RawMessage) (protobuf.Message, error) {
+		return restartVM(ctx, exp, name)
+	})
+
+	wsVMAction("redeploy", "vms/redeploy", "update", func(ctx context.Context, exp, name string, payload json.RawMessage) (protobuf.Message, error) {
+		var req *proto.VMRedeployRequest
+
+		if len(payload) > 0 && string(payload) != "null" {
+			req = new(proto.VMRedeployRequest)
+
+			if err := unmarshaler.Unmarshal(payload, req); err != nil {
+				return nil, fmt.Errorf("unmarshaling redeploy request: %w", err)
+			}
+		}
+
+		return redeployVM(ctx, exp, name, req, false)
+	})
+
+	wsVMAction("snapshot", "vms/snapshots", "create", func(ctx context.Context, exp, name string, payload json.RawMessage) (protobuf.Message, error) {
+		var req proto.SnapshotRequest
+
+		if err := unmarshaler.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("unmarshaling snapshot request: %w", err)
+		}
+
+		if req.Filename == "" {
+			return nil, fmt.Errorf("missing snapshot filename")
+		}
+
+		return nil, snapshotVM(ctx, exp, name, req.Filename)
+	})
+}
+
+// wsVMAction registers a handler for the given VM action, which is only called
+// if the client's role allows the given verb on the given resource for the VM.
+// The resource name of requests for VM actions is `<exp name>/<vm name>`.
+func wsVMAction(action, resource, verb string, fn func(context.Context, string, string, json.RawMessage) (protobuf.Message, error)) {
+	broker.RegisterRequestHandler("experiment/vm", action, func(ctx context.Context, req broker.Request) (json.RawMessage, error) {
+		names := strings.SplitN(req.Resource.Name, "/", 2)
+
+		if len(names) != 2 || names[0] == "" || names[1] == "" {
+			return nil, fmt.Errorf("invalid VM name %s (expected <exp name>/<vm name>)", req.Resource.Name)
+		}
+
+		exp, name := names[0], names[1]
+
+		if !util.VMAllowed(wsRole(ctx), resource, verb, exp, name) {
+			return nil, fmt.Errorf("%w: %s VM %s in experiment %s", broker.ErrForbidden, action, name, exp)
+		}
+
+		return wsResult(fn(ctx, exp, name, req.Payload))
+	})
+}
+
+func wsRole(ctx context.Context) rbac.Role {
+	role, _ := ctx.Value("role").(rbac.Role)
+	return role
+}
+
+// wsResult marshals the given result, if any, to be included in the response
+// to a WebSocket request.
+func wsResult(msg protobuf.Message, err error) (json.RawMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	if msg == nil {
+		return nil, nil
+	}
+
+	return marshaler.Marshal(msg)
+}
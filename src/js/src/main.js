@@ -28,7 +28,8 @@ Vue.use(Buefy, {
 })
 
 Vue.use( VueResource )
-Vue.use( VueNativeSock, `//${location.host}${process.env.BASE_URL}`, { connectManually: true, reconnection: true } );
+// Reconnecting is handled by App so the connection can be resumed.
+Vue.use( VueNativeSock, `//${location.host}${process.env.BASE_URL}`, { connectManually: true, reconnection: false } );
 
 Vue.filter( 'lowercase', function( value ) {
   if ( value == null ) { return value }